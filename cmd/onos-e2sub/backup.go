@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+)
+
+// runBackup writes a snapshot of a running onos-e2sub to a file
+func runBackup(args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ExitOnError)
+	address := flags.String("address", "localhost:5151", "address of the onos-e2sub admin API")
+	file := flags.String("file", "e2sub.jsonl", "path of the snapshot file to write")
+	_ = flags.Parse(args)
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/api/v1/backup", *address))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return readError(resp)
+	}
+
+	out, err := os.Create(*file)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote snapshot to %s\n", *file)
+	return nil
+}
+
+// runRestore restores a snapshot file into an empty onos-e2sub deployment
+func runRestore(args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	address := flags.String("address", "localhost:5151", "address of the onos-e2sub admin API")
+	file := flags.String("file", "e2sub.jsonl", "path of the snapshot file to restore")
+	skipTasks := flags.Bool("skipTasks", false, "skip the snapshot's tasks so subscriptions are placed again")
+	skipEndpoints := flags.Bool("skipEndpoints", false, "skip the snapshot's endpoints and terminations so terminations register again")
+	_ = flags.Parse(args)
+
+	in, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	query := url.Values{}
+	query.Set("skipTasks", strconv.FormatBool(*skipTasks))
+	query.Set("skipEndpoints", strconv.FormatBool(*skipEndpoints))
+	resp, err := http.Post(fmt.Sprintf("http://%s/api/v1/restore?%s", *address, query.Encode()), "application/x-ndjson", in)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return readError(resp)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Restored %s: %s", *file, body)
+	return nil
+}
+
+// readError returns the error reported by a failed admin API request
+func readError(resp *http.Response) error {
+	body, _ := ioutil.ReadAll(resp.Body)
+	return fmt.Errorf("%s: %s", resp.Status, body)
+}
@@ -6,6 +6,8 @@ package main
 
 import (
 	"flag"
+	"os"
+
 	"github.com/onosproject/onos-e2sub/pkg/manager"
 	"github.com/onosproject/onos-lib-go/pkg/certs"
 	"github.com/onosproject/onos-lib-go/pkg/logging"
@@ -14,9 +16,26 @@ import (
 var log = logging.GetLogger("main")
 
 func main() {
+	// Administrative commands run against the admin API of a running onos-e2sub
+	if len(os.Args) > 1 {
+		commands := map[string]func([]string) error{
+			"backup":  runBackup,
+			"restore": runRestore,
+		}
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+
 	caPath := flag.String("caPath", "", "path to CA certificate")
 	keyPath := flag.String("keyPath", "", "path to client private key")
 	certPath := flag.String("certPath", "", "path to client certificate")
+	adminHost := flag.String("adminHost", "localhost", "host address on which to serve the admin API")
+	adminPort := flag.Int("adminPort", 5151, "port on which to serve the admin API")
+	healthPort := flag.Int("healthPort", 5152, "port on which to serve the health checks")
 	ready := make(chan bool)
 	flag.Parse()
 
@@ -27,10 +46,13 @@ func main() {
 
 	log.Info("Starting onos-e2sub")
 	cfg := manager.Config{
-		CAPath:   *caPath,
-		KeyPath:  *keyPath,
-		CertPath: *certPath,
-		GRPCPort: 5150,
+		CAPath:     *caPath,
+		KeyPath:    *keyPath,
+		CertPath:   *certPath,
+		GRPCPort:   5150,
+		AdminHost:  *adminHost,
+		AdminPort:  *adminPort,
+		HealthPort: *healthPort,
 	}
 
 	log.Info("Starting onos-e2sub")
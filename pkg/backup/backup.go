@@ -0,0 +1,550 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package backup
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	taskapi "github.com/onosproject/onos-api/go/onos/e2sub/task"
+	"github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	"github.com/onosproject/onos-e2sub/pkg/store/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/store/task"
+	"github.com/onosproject/onos-e2sub/pkg/store/termination"
+	"github.com/onosproject/onos-e2sub/pkg/store/txn"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+)
+
+var log = logging.GetLogger("backup")
+
+const (
+	// Format identifies an onos-e2sub snapshot
+	Format = "onos-e2sub/snapshot"
+	// Version is the version of the snapshot format written by Export
+	// Version 2 adds subscription metadata and termination entries.
+	Version = 2
+)
+
+// maxLineSize is the maximum size of a snapshot line
+const maxLineSize = 16 * 1024 * 1024
+
+// chunkSize is the maximum number of objects restored in a transaction
+// Each transaction is journaled as a single entry, so a snapshot is restored in chunks to keep
+// the entries within the size of a store message.
+const chunkSize = 100
+
+// Kind is the kind of object in a snapshot entry
+type Kind string
+
+const (
+	// KindSubscription is the kind of subscription entries
+	KindSubscription Kind = "Subscription"
+	// KindTask is the kind of subscription task entries
+	KindTask Kind = "SubscriptionTask"
+	// KindEndpoint is the kind of termination endpoint entries
+	KindEndpoint Kind = "TerminationEndpoint"
+	// KindMetadata is the kind of subscription metadata entries
+	KindMetadata Kind = "Metadata"
+	// KindTermination is the kind of termination state entries
+	KindTermination Kind = "Termination"
+)
+
+// Header is the first line of a snapshot
+type Header struct {
+	// Format identifies the file as an onos-e2sub snapshot
+	Format string `json:"format"`
+	// Version is the version of the snapshot format
+	Version int `json:"version"`
+	// Created is the time at which the snapshot was created
+	Created time.Time `json:"created"`
+	// Counts is the number of entries of each kind in the snapshot
+	Counts map[Kind]int `json:"counts"`
+}
+
+// Entry is a line of a snapshot holding a single object
+type Entry struct {
+	// Kind is the kind of the object
+	Kind Kind `json:"kind"`
+	// Object is the JSON encoded object
+	Object json.RawMessage `json:"object"`
+}
+
+// metadataObject is the object of a subscription metadata entry
+// The ID is not part of the metadata's own encoding, which is keyed by the subscription ID.
+type metadataObject struct {
+	ID subapi.ID `json:"id"`
+	metadata.Metadata
+}
+
+// terminationObject is the object of a termination state entry
+type terminationObject struct {
+	ID epapi.ID `json:"id"`
+	termination.Termination
+}
+
+// Stores are the stores a snapshot is exported from and imported to
+type Stores struct {
+	Subscriptions subscription.Store
+	Tasks         task.Store
+	Endpoints     endpoint.Store
+	Metadata      metadata.Store
+	Terminations  termination.Store
+	// Transactions is the journal through which the subscriptions, tasks and metadata are imported
+	Transactions txn.Store
+}
+
+// snapshot is the content of a snapshot
+type snapshot struct {
+	subs         []*subapi.Subscription
+	tasks        []*taskapi.SubscriptionTask
+	eps          []*epapi.TerminationEndpoint
+	metas        []*metadata.Metadata
+	terminations []*termination.Termination
+}
+
+// counts returns the number of objects of each kind in the snapshot
+func (s *snapshot) counts() map[Kind]int {
+	return map[Kind]int{
+		KindSubscription: len(s.subs),
+		KindTask:         len(s.tasks),
+		KindEndpoint:     len(s.eps),
+		KindMetadata:     len(s.metas),
+		KindTermination:  len(s.terminations),
+	}
+}
+
+// Export writes a snapshot of the subscriptions, tasks, endpoints, subscription metadata and
+// termination state to the given writer
+// The snapshot is a line of JSON for its Header followed by a line of JSON for each Entry.
+func Export(ctx context.Context, stores Stores, writer io.Writer) (*Header, error) {
+	subs, err := stores.Subscriptions.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := stores.Tasks.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	eps, err := stores.Endpoints.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metas, err := stores.Metadata.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	terms, err := stores.Terminations.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	header := &Header{
+		Format:  Format,
+		Version: Version,
+		Created: time.Now(),
+		Counts: map[Kind]int{
+			KindSubscription: len(subs),
+			KindTask:         len(tasks),
+			KindEndpoint:     len(eps),
+			KindMetadata:     len(metas),
+			KindTermination:  len(terms),
+		},
+	}
+	log.Infof("Exporting snapshot %+v", header)
+
+	encoder := json.NewEncoder(writer)
+	if err := encoder.Encode(header); err != nil {
+		return nil, err
+	}
+	for i := range subs {
+		if err := encodeEntry(encoder, KindSubscription, &subs[i]); err != nil {
+			return nil, err
+		}
+	}
+	for i := range tasks {
+		if err := encodeEntry(encoder, KindTask, &tasks[i]); err != nil {
+			return nil, err
+		}
+	}
+	for i := range eps {
+		if err := encodeEntry(encoder, KindEndpoint, &eps[i]); err != nil {
+			return nil, err
+		}
+	}
+	for i := range metas {
+		if err := encodeEntry(encoder, KindMetadata, &metadataObject{ID: metas[i].ID, Metadata: metas[i]}); err != nil {
+			return nil, err
+		}
+	}
+	for i := range terms {
+		if err := encodeEntry(encoder, KindTermination, &terminationObject{ID: terms[i].ID, Termination: terms[i]}); err != nil {
+			return nil, err
+		}
+	}
+	return header, nil
+}
+
+func encodeEntry(encoder *json.Encoder, kind Kind, object interface{}) error {
+	bytes, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	return encoder.Encode(&Entry{
+		Kind:   kind,
+		Object: bytes,
+	})
+}
+
+// ImportOptions are options for importing a snapshot
+type ImportOptions struct {
+	// SkipTasks skips the snapshot's tasks, leaving the subscriptions to be placed again
+	SkipTasks bool
+	// SkipEndpoints skips the snapshot's endpoints and their termination state, leaving them to be
+	// registered by the terminations
+	SkipEndpoints bool
+}
+
+// Imported is the number of objects of each kind imported from a snapshot
+type Imported map[Kind]int
+
+// Import restores a snapshot read from the given reader into empty stores
+// The whole snapshot is read and validated before any object is restored. Import fails with a
+// conflict if any store to be restored already holds objects. The subscriptions, tasks and
+// subscription metadata are restored in transactions of at most chunkSize objects, each holding
+// whole subscriptions with their metadata and tasks. If a transaction fails, the objects restored
+// by earlier transactions and the endpoints and termination state restored before them are deleted
+// again, so a failed import can be retried.
+func Import(ctx context.Context, stores Stores, reader io.Reader, opts ImportOptions) (Imported, error) {
+	snap, err := decode(reader)
+	if err != nil {
+		return nil, err
+	}
+	if opts.SkipTasks {
+		snap.tasks = nil
+	}
+	if opts.SkipEndpoints {
+		snap.eps = nil
+		snap.terminations = nil
+	}
+
+	if err := checkEmpty(ctx, stores, opts); err != nil {
+		return nil, err
+	}
+
+	// Endpoints and termination state cannot be written in a transaction, so they are restored first
+	// and deleted again if the rest of the snapshot cannot be restored
+	var eps []epapi.ID
+	var terms []epapi.ID
+	var restored []*chunk
+	cleanup := func() {
+		for i := len(restored) - 1; i >= 0; i-- {
+			restored[i].delete(ctx, stores)
+		}
+		for _, id := range terms {
+			if err := stores.Terminations.Delete(ctx, id); err != nil && !errors.IsNotFound(err) {
+				log.Warnf("Failed to delete restored Termination %s: %s", id, err)
+			}
+		}
+		for _, id := range eps {
+			if err := stores.Endpoints.Delete(ctx, id); err != nil && !errors.IsNotFound(err) {
+				log.Warnf("Failed to delete restored TerminationEndpoint %s: %s", id, err)
+			}
+		}
+	}
+	for _, ep := range snap.eps {
+		ep.Revision = 0
+		if err := stores.Endpoints.Create(ctx, ep); err != nil {
+			cleanup()
+			return nil, err
+		}
+		eps = append(eps, ep.ID)
+	}
+	for _, term := range snap.terminations {
+		term.Revision = 0
+		if err := stores.Terminations.Create(ctx, term); err != nil {
+			cleanup()
+			return nil, err
+		}
+		terms = append(terms, term.ID)
+	}
+
+	transactor := txn.NewTransactor(stores.Transactions, txn.Stores{
+		Subscriptions: stores.Subscriptions,
+		Tasks:         stores.Tasks,
+		Metadata:      stores.Metadata,
+	})
+	for _, c := range snap.chunks() {
+		if err := c.commit(ctx, transactor); err != nil {
+			cleanup()
+			return nil, err
+		}
+		restored = append(restored, c)
+	}
+
+	imported := Imported(snap.counts())
+	log.Infof("Imported snapshot %+v", imported)
+	return imported, nil
+}
+
+// chunk is a set of objects of a snapshot restored in a single transaction
+type chunk struct {
+	subs  []*subapi.Subscription
+	tasks []*taskapi.SubscriptionTask
+	metas []*metadata.Metadata
+}
+
+// size returns the number of objects in the chunk
+func (c *chunk) size() int {
+	return len(c.subs) + len(c.tasks) + len(c.metas)
+}
+
+// commit restores the objects of the chunk in a transaction
+// The metadata is created before the subscriptions to ensure it's available when the subscriptions
+// are reconciled.
+func (c *chunk) commit(ctx context.Context, transactor *txn.Transactor) error {
+	tx := transactor.Begin()
+	for _, meta := range c.metas {
+		meta.Revision = 0
+		tx.CreateMetadata(meta)
+	}
+	for _, sub := range c.subs {
+		sub.Revision = 0
+		tx.CreateSubscription(sub)
+	}
+	for _, t := range c.tasks {
+		t.Revision = 0
+		tx.CreateTask(t)
+	}
+	return tx.Commit(ctx)
+}
+
+// delete deletes the restored objects of the chunk
+func (c *chunk) delete(ctx context.Context, stores Stores) {
+	for _, t := range c.tasks {
+		if err := stores.Tasks.Delete(ctx, t.ID); err != nil && !errors.IsNotFound(err) {
+			log.Warnf("Failed to delete restored SubscriptionTask %s: %s", t.ID, err)
+		}
+	}
+	for _, sub := range c.subs {
+		if err := stores.Subscriptions.Delete(ctx, sub.ID); err != nil && !errors.IsNotFound(err) {
+			log.Warnf("Failed to delete restored Subscription %s: %s", sub.ID, err)
+		}
+	}
+	for _, meta := range c.metas {
+		if err := stores.Metadata.Delete(ctx, meta.ID); err != nil && !errors.IsNotFound(err) {
+			log.Warnf("Failed to delete restored Metadata %s: %s", meta.ID, err)
+		}
+	}
+}
+
+// chunks splits the subscriptions, tasks and metadata of the snapshot into chunks of at most
+// chunkSize objects
+// A subscription is restored in the same chunk as its metadata and tasks, unless they exceed a
+// chunk together. The metadata and tasks of subscriptions missing from the snapshot follow.
+func (s *snapshot) chunks() []*chunk {
+	metas := make(map[subapi.ID]*metadata.Metadata)
+	for _, meta := range s.metas {
+		metas[meta.ID] = meta
+	}
+	tasks := make(map[subapi.ID][]*taskapi.SubscriptionTask)
+	for _, t := range s.tasks {
+		tasks[t.SubscriptionID] = append(tasks[t.SubscriptionID], t)
+	}
+
+	chunks := make([]*chunk, 0)
+	current := &chunk{}
+	add := func(c *chunk) {
+		if current.size() > 0 && current.size()+c.size() > chunkSize {
+			chunks = append(chunks, current)
+			current = &chunk{}
+		}
+		current.subs = append(current.subs, c.subs...)
+		current.tasks = append(current.tasks, c.tasks...)
+		current.metas = append(current.metas, c.metas...)
+	}
+	for _, sub := range s.subs {
+		c := &chunk{subs: []*subapi.Subscription{sub}}
+		if meta, ok := metas[sub.ID]; ok {
+			c.metas = append(c.metas, meta)
+			delete(metas, sub.ID)
+		}
+		for _, t := range tasks[sub.ID] {
+			// Tasks beyond a chunk are restored on their own after the subscription
+			if c.size() == chunkSize {
+				add(c)
+				c = &chunk{}
+			}
+			c.tasks = append(c.tasks, t)
+		}
+		delete(tasks, sub.ID)
+		add(c)
+	}
+	for _, meta := range s.metas {
+		if _, ok := metas[meta.ID]; ok {
+			add(&chunk{metas: []*metadata.Metadata{meta}})
+		}
+	}
+	for _, t := range s.tasks {
+		if _, ok := tasks[t.SubscriptionID]; ok {
+			add(&chunk{tasks: []*taskapi.SubscriptionTask{t}})
+		}
+	}
+	if current.size() > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+// checkEmpty verifies the stores to be restored hold no objects
+func checkEmpty(ctx context.Context, stores Stores, opts ImportOptions) error {
+	subs, err := stores.Subscriptions.List(ctx)
+	if err != nil {
+		return err
+	}
+	if len(subs) > 0 {
+		return errors.NewConflict("cannot import into a deployment with %d subscriptions", len(subs))
+	}
+	metas, err := stores.Metadata.List(ctx)
+	if err != nil {
+		return err
+	}
+	if len(metas) > 0 {
+		return errors.NewConflict("cannot import into a deployment with %d subscription metadata", len(metas))
+	}
+	if !opts.SkipTasks {
+		tasks, err := stores.Tasks.List(ctx)
+		if err != nil {
+			return err
+		}
+		if len(tasks) > 0 {
+			return errors.NewConflict("cannot import into a deployment with %d tasks", len(tasks))
+		}
+	}
+	if !opts.SkipEndpoints {
+		eps, err := stores.Endpoints.List(ctx)
+		if err != nil {
+			return err
+		}
+		if len(eps) > 0 {
+			return errors.NewConflict("cannot import into a deployment with %d endpoints", len(eps))
+		}
+		terms, err := stores.Terminations.List(ctx)
+		if err != nil {
+			return err
+		}
+		if len(terms) > 0 {
+			return errors.NewConflict("cannot import into a deployment with %d terminations", len(terms))
+		}
+	}
+	return nil
+}
+
+// decode reads and validates a snapshot
+// Snapshots of earlier versions, which hold no metadata or termination entries, are accepted.
+func decode(reader io.Reader) (*snapshot, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, errors.NewInvalid("failed to read snapshot: %s", err)
+		}
+		return nil, errors.NewInvalid("snapshot is empty")
+	}
+	header := &Header{}
+	if err := json.Unmarshal(scanner.Bytes(), header); err != nil || header.Format != Format {
+		return nil, errors.NewInvalid("not an onos-e2sub snapshot")
+	}
+	if header.Version < 1 || header.Version > Version {
+		return nil, errors.NewInvalid("unsupported snapshot version %d", header.Version)
+	}
+
+	snap := &snapshot{}
+	ids := make(map[Kind]map[string]bool)
+	line := 1
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		entry := &Entry{}
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			return nil, errors.NewInvalid("invalid entry at line %d: %s", line, err)
+		}
+		var id string
+		var err error
+		switch entry.Kind {
+		case KindSubscription:
+			sub := &subapi.Subscription{}
+			if err = json.Unmarshal(entry.Object, sub); err == nil && sub.ID == "" {
+				err = errors.NewInvalid("ID cannot be empty")
+			}
+			id = string(sub.ID)
+			snap.subs = append(snap.subs, sub)
+		case KindTask:
+			t := &taskapi.SubscriptionTask{}
+			if err = json.Unmarshal(entry.Object, t); err == nil && t.ID == "" {
+				err = errors.NewInvalid("ID cannot be empty")
+			}
+			id = string(t.ID)
+			snap.tasks = append(snap.tasks, t)
+		case KindEndpoint:
+			ep := &epapi.TerminationEndpoint{}
+			if err = json.Unmarshal(entry.Object, ep); err == nil && ep.ID == "" {
+				err = errors.NewInvalid("ID cannot be empty")
+			}
+			id = string(ep.ID)
+			snap.eps = append(snap.eps, ep)
+		case KindMetadata:
+			object := &metadataObject{}
+			if err = json.Unmarshal(entry.Object, object); err == nil && object.ID == "" {
+				err = errors.NewInvalid("ID cannot be empty")
+			}
+			object.Metadata.ID = object.ID
+			id = string(object.ID)
+			snap.metas = append(snap.metas, &object.Metadata)
+		case KindTermination:
+			object := &terminationObject{}
+			if err = json.Unmarshal(entry.Object, object); err == nil && object.ID == "" {
+				err = errors.NewInvalid("ID cannot be empty")
+			}
+			object.Termination.ID = object.ID
+			id = string(object.ID)
+			snap.terminations = append(snap.terminations, &object.Termination)
+		default:
+			err = errors.NewInvalid("unknown kind %s", entry.Kind)
+		}
+		if err != nil {
+			return nil, errors.NewInvalid("invalid entry at line %d: %s", line, err)
+		}
+
+		// Objects are restored in separate transactions, so repeated objects are rejected up front
+		if ids[entry.Kind] == nil {
+			ids[entry.Kind] = make(map[string]bool)
+		}
+		if ids[entry.Kind][id] {
+			return nil, errors.NewInvalid("invalid entry at line %d: %s %s is repeated", line, entry.Kind, id)
+		}
+		ids[entry.Kind][id] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.NewInvalid("failed to read snapshot: %s", err)
+	}
+
+	// A snapshot cut short is detected by its counts
+	for kind, count := range snap.counts() {
+		if header.Counts[kind] != count {
+			return nil, errors.NewInvalid("snapshot holds %d %s entries, expected %d", count, kind, header.Counts[kind])
+		}
+	}
+	return snap, nil
+}
@@ -0,0 +1,287 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	taskapi "github.com/onosproject/onos-api/go/onos/e2sub/task"
+	"github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	"github.com/onosproject/onos-e2sub/pkg/store/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/store/task"
+	"github.com/onosproject/onos-e2sub/pkg/store/termination"
+	"github.com/onosproject/onos-e2sub/pkg/store/txn"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func newStores(t *testing.T) Stores {
+	subStore, err := subscription.NewLocalStore()
+	assert.NoError(t, err)
+	taskStore, err := task.NewLocalStore()
+	assert.NoError(t, err)
+	endpointStore, err := endpoint.NewLocalStore()
+	assert.NoError(t, err)
+	metaStore, err := metadata.NewLocalStore()
+	assert.NoError(t, err)
+	termStore, err := termination.NewLocalStore()
+	assert.NoError(t, err)
+	txnStore, err := txn.NewLocalStore()
+	assert.NoError(t, err)
+	return Stores{
+		Subscriptions: subStore,
+		Tasks:         taskStore,
+		Endpoints:     endpointStore,
+		Metadata:      metaStore,
+		Terminations:  termStore,
+		Transactions:  txnStore,
+	}
+}
+
+func closeStores(stores Stores) {
+	stores.Subscriptions.Close()
+	stores.Tasks.Close()
+	stores.Endpoints.Close()
+	stores.Metadata.Close()
+	stores.Terminations.Close()
+	stores.Transactions.Close()
+}
+
+func TestExportImport(t *testing.T) {
+	source := newStores(t)
+	defer closeStores(source)
+
+	ctx := context.Background()
+	assert.NoError(t, source.Endpoints.Create(ctx, &epapi.TerminationEndpoint{ID: "e2t-1", IP: "10.0.0.1", Port: 5150}))
+	assert.NoError(t, source.Subscriptions.Create(ctx, &subapi.Subscription{
+		ID:    "sub-1",
+		AppID: "app-1",
+		Lifecycle: subapi.Lifecycle{
+			Status: subapi.Status_PENDING_DELETE,
+		},
+	}))
+	assert.NoError(t, source.Tasks.Create(ctx, &taskapi.SubscriptionTask{
+		ID:             "sub-1:e2t-1",
+		SubscriptionID: "sub-1",
+		EndpointID:     "e2t-1",
+	}))
+	assert.NoError(t, source.Metadata.Create(ctx, &metadata.Metadata{ID: "sub-1", Session: "session-1"}))
+	assert.NoError(t, source.Terminations.Create(ctx, &termination.Termination{ID: "e2t-1", Cordoned: true}))
+
+	buf := &bytes.Buffer{}
+	header, err := Export(ctx, source, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, Version, header.Version)
+	assert.Equal(t, 1, header.Counts[KindTask])
+	assert.Equal(t, 1, header.Counts[KindMetadata])
+	assert.Len(t, strings.Split(strings.TrimSpace(buf.String()), "\n"), 6)
+	snapshot := buf.String()
+
+	// Verify a snapshot is not imported into a deployment that is not empty
+	_, err = Import(ctx, source, strings.NewReader(snapshot), ImportOptions{})
+	assert.True(t, errors.IsConflict(err))
+
+	target := newStores(t)
+	defer closeStores(target)
+	imported, err := Import(ctx, target, strings.NewReader(snapshot), ImportOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, Imported{KindSubscription: 1, KindTask: 1, KindEndpoint: 1, KindMetadata: 1, KindTermination: 1}, imported)
+
+	sub, err := target.Subscriptions.Get(ctx, "sub-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "app-1", string(sub.AppID))
+	assert.Equal(t, subapi.Status_PENDING_DELETE, sub.Lifecycle.Status)
+	assert.NotEqual(t, subapi.Revision(0), sub.Revision)
+	tsk, err := target.Tasks.Get(ctx, "sub-1:e2t-1")
+	assert.NoError(t, err)
+	assert.Equal(t, epapi.ID("e2t-1"), tsk.EndpointID)
+	ep, err := target.Endpoints.Get(ctx, "e2t-1")
+	assert.NoError(t, err)
+	assert.Equal(t, epapi.IP("10.0.0.1"), ep.IP)
+	meta, err := target.Metadata.Get(ctx, "sub-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "session-1", meta.Session)
+	term, err := target.Terminations.Get(ctx, "e2t-1")
+	assert.NoError(t, err)
+	assert.True(t, term.Cordoned)
+}
+
+func TestImportSkip(t *testing.T) {
+	source := newStores(t)
+	defer closeStores(source)
+
+	ctx := context.Background()
+	assert.NoError(t, source.Endpoints.Create(ctx, &epapi.TerminationEndpoint{ID: "e2t-1"}))
+	assert.NoError(t, source.Subscriptions.Create(ctx, &subapi.Subscription{ID: "sub-1"}))
+	assert.NoError(t, source.Tasks.Create(ctx, &taskapi.SubscriptionTask{ID: "sub-1:e2t-1", SubscriptionID: "sub-1"}))
+	buf := &bytes.Buffer{}
+	_, err := Export(ctx, source, buf)
+	assert.NoError(t, err)
+
+	// Endpoints already registered in the target do not prevent an import skipping them
+	target := newStores(t)
+	defer closeStores(target)
+	assert.NoError(t, target.Endpoints.Create(ctx, &epapi.TerminationEndpoint{ID: "e2t-2"}))
+	imported, err := Import(ctx, target, bytes.NewReader(buf.Bytes()), ImportOptions{SkipTasks: true, SkipEndpoints: true})
+	assert.NoError(t, err)
+	assert.Equal(t, Imported{KindSubscription: 1, KindTask: 0, KindEndpoint: 0, KindMetadata: 0, KindTermination: 0}, imported)
+
+	tasks, err := target.Tasks.List(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, tasks)
+	_, err = target.Endpoints.Get(ctx, "e2t-1")
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestImportInvalid(t *testing.T) {
+	stores := newStores(t)
+	defer closeStores(stores)
+
+	ctx := context.Background()
+	header := `{"format":"onos-e2sub/snapshot","version":1,"counts":{"Subscription":1}}`
+	snapshots := []string{
+		``,
+		`{"format":"unknown","version":1}`,
+		`{"format":"onos-e2sub/snapshot","version":3}`,
+		header,
+		header + "\n" + `{"kind":"Unknown","object":{}}`,
+		header + "\n" + `{"kind":"Subscription","object":{}}`,
+		`{"format":"onos-e2sub/snapshot","version":1,"counts":{"Subscription":2}}` + "\n" + `{"kind":"Subscription","object":{"id":"sub-1"}}` + "\n" + `{"kind":"Subscription","object":{"id":"sub-1"}}`,
+	}
+	for _, snapshot := range snapshots {
+		_, err := Import(ctx, stores, strings.NewReader(snapshot), ImportOptions{})
+		assert.True(t, errors.IsInvalid(err), snapshot)
+	}
+
+	// Verify nothing is restored from an invalid snapshot
+	_, err := Import(ctx, stores, strings.NewReader(header+"\n"+`{"kind":"Subscription","object":{"id":"sub-1"}}`+"\n"+`{"kind":"Subscription","object":{"id":"sub-2"}}`), ImportOptions{})
+	assert.True(t, errors.IsInvalid(err))
+	subs, err := stores.Subscriptions.List(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, subs)
+}
+
+func TestImportRetry(t *testing.T) {
+	stores := newStores(t)
+	defer closeStores(stores)
+
+	// Verify the endpoints restored by a failed import are deleted so that it can be retried
+	ctx := context.Background()
+	header := `{"format":"onos-e2sub/snapshot","version":2,"counts":{"TerminationEndpoint":1,"Subscription":1}}`
+	endpoint := `{"kind":"TerminationEndpoint","object":{"id":"e2t-1"}}`
+	sub := `{"kind":"Subscription","object":{"id":"sub-1"}}`
+	failing := stores
+	failing.Subscriptions = &failingStore{Store: stores.Subscriptions, failing: "sub-1"}
+	_, err := Import(ctx, failing, strings.NewReader(header+"\n"+endpoint+"\n"+sub), ImportOptions{})
+	assert.Error(t, err)
+	eps, err := stores.Endpoints.List(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, eps)
+	subs, err := stores.Subscriptions.List(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, subs)
+
+	// Verify a snapshot of the first version, without metadata or terminations, is restored
+	header = `{"format":"onos-e2sub/snapshot","version":1,"counts":{"TerminationEndpoint":1,"Subscription":1}}`
+	imported, err := Import(ctx, stores, strings.NewReader(header+"\n"+endpoint+"\n"+sub), ImportOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, imported[KindSubscription])
+	assert.Equal(t, 1, imported[KindEndpoint])
+}
+
+// journal is a transaction store recording the number of operations of each journaled transaction
+type journal struct {
+	txn.Store
+	mu  sync.Mutex
+	ops []int
+}
+
+func (j *journal) Create(ctx context.Context, transaction *txn.Transaction) error {
+	j.mu.Lock()
+	j.ops = append(j.ops, len(transaction.Ops))
+	j.mu.Unlock()
+	return j.Store.Create(ctx, transaction)
+}
+
+// failingStore is a subscription store failing to create the given subscription, as if another
+// writer created it
+type failingStore struct {
+	subscription.Store
+	failing subapi.ID
+}
+
+func (s *failingStore) Create(ctx context.Context, sub *subapi.Subscription) error {
+	if sub.ID == s.failing {
+		return errors.NewAlreadyExists("subscription %s already exists", sub.ID)
+	}
+	return s.Store.Create(ctx, sub)
+}
+
+func TestImportChunks(t *testing.T) {
+	stores := newStores(t)
+	defer closeStores(stores)
+	ctx := context.Background()
+
+	// Write a snapshot holding more subscriptions, tasks and metadata than a transaction holds
+	const count = 2*chunkSize + 10
+	var snap bytes.Buffer
+	header := fmt.Sprintf(`{"format":"onos-e2sub/snapshot","version":2,"counts":{"Subscription":%d,"SubscriptionTask":%d,"Metadata":%d}}`, count, count, count)
+	snap.WriteString(header + "\n")
+	for i := 0; i < count; i++ {
+		snap.WriteString(fmt.Sprintf(`{"kind":"Subscription","object":{"id":"sub-%d"}}`+"\n", i))
+		snap.WriteString(fmt.Sprintf(`{"kind":"SubscriptionTask","object":{"id":"sub-%d:e2t-1","subscription_id":"sub-%d","endpoint_id":"e2t-1"}}`+"\n", i, i))
+		snap.WriteString(fmt.Sprintf(`{"kind":"Metadata","object":{"id":"sub-%d","zone":"zone-a"}}`+"\n", i))
+	}
+
+	// Verify the objects restored by earlier transactions are deleted if a later transaction fails
+	failing := stores
+	failing.Subscriptions = &failingStore{Store: stores.Subscriptions, failing: subapi.ID(fmt.Sprintf("sub-%d", count-1))}
+	_, err := Import(ctx, failing, bytes.NewReader(snap.Bytes()), ImportOptions{})
+	assert.Error(t, err)
+	subs, err := stores.Subscriptions.List(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, subs)
+	tasks, err := stores.Tasks.List(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, tasks)
+	metas, err := stores.Metadata.List(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, metas)
+
+	// Verify the retried import restores every object in transactions of at most chunkSize objects
+	journaled := &journal{Store: stores.Transactions}
+	stores.Transactions = journaled
+	imported, err := Import(ctx, stores, bytes.NewReader(snap.Bytes()), ImportOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, count, imported[KindSubscription])
+	assert.Greater(t, len(journaled.ops), 1)
+	total := 0
+	for _, ops := range journaled.ops {
+		assert.LessOrEqual(t, ops, chunkSize)
+		total += ops
+	}
+	assert.Equal(t, 3*count, total)
+
+	subs, err = stores.Subscriptions.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, subs, count)
+	tasks, err = stores.Tasks.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, count)
+	metas, err = stores.Metadata.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, metas, count)
+	transactions, err := stores.Transactions.List(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, transactions)
+}
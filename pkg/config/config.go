@@ -5,16 +5,332 @@
 package config
 
 import (
+	"time"
+
 	"github.com/onosproject/onos-lib-go/pkg/atomix"
 	configlib "github.com/onosproject/onos-lib-go/pkg/config"
 )
 
+const (
+	defaultOwnerGracePeriod     = 30 * time.Second
+	defaultSessionKeepAlive     = 10 * time.Second
+	defaultSessionTimeout       = 30 * time.Second
+	defaultSessionGracePeriod   = 30 * time.Second
+	defaultSessionTTL           = 5 * time.Minute
+	defaultDrainBatchSize       = 5
+	defaultDrainInterval        = time.Second
+	defaultHealthInterval       = 5 * time.Second
+	defaultHealthTimeout        = time.Second
+	defaultFailureThreshold     = 3
+	defaultSuccessThreshold     = 1
+	defaultDiscoveryPort        = 5150
+	defaultPodResyncPeriod      = 5 * time.Minute
+	defaultWatchBufferSize      = 1000
+	defaultWatchPolicy          = "disconnect"
+	defaultStoreDriver          = "atomix"
+	defaultStorePath            = "/var/lib/onos-e2sub/e2sub.db"
+	defaultTransactionTimeout   = time.Minute
+	defaultHistoryMaxRecords    = 100
+	defaultHistoryMaxAge        = 7 * 24 * time.Hour
+	defaultHistoryPruneInterval = time.Hour
+)
+
 var config *Config
 
 // Config is the onos-e2sub configuration
 type Config struct {
 	// Atomix is the Atomix configuration
 	Atomix atomix.Config `yaml:"atomix,omitempty"`
+	// Subscriptions is the subscription configuration
+	Subscriptions SubscriptionsConfig `yaml:"subscriptions,omitempty"`
+	// Sessions is the client session configuration
+	Sessions SessionsConfig `yaml:"sessions,omitempty"`
+	// Topo is the onos-topo configuration
+	Topo TopoConfig `yaml:"topo,omitempty"`
+	// Drain is the termination drain configuration
+	Drain DrainConfig `yaml:"drain,omitempty"`
+	// Health is the termination health probe configuration
+	Health HealthConfig `yaml:"health,omitempty"`
+	// Discovery is the termination discovery configuration
+	Discovery DiscoveryConfig `yaml:"discovery,omitempty"`
+	// Pods is the termination pod cache configuration
+	Pods PodsConfig `yaml:"pods,omitempty"`
+	// Watch is the client watch configuration
+	Watch WatchConfig `yaml:"watch,omitempty"`
+	// Store is the store backend configuration
+	Store StoreConfig `yaml:"store,omitempty"`
+	// History is the history retention configuration
+	History HistoryConfig `yaml:"history,omitempty"`
+}
+
+// HistoryConfig is the configuration of the retention of the history of subscriptions, tasks and endpoints
+type HistoryConfig struct {
+	// MaxRecords is the maximum number of records retained for each object
+	MaxRecords int `yaml:"maxRecords,omitempty"`
+	// MaxAge is the age after which records are pruned
+	MaxAge time.Duration `yaml:"maxAge,omitempty"`
+	// PruneInterval is the interval at which records older than the maximum age are pruned
+	PruneInterval time.Duration `yaml:"pruneInterval,omitempty"`
+}
+
+// GetMaxRecords gets the maximum number of records retained for each object
+func (c HistoryConfig) GetMaxRecords() int {
+	if c.MaxRecords == 0 {
+		return defaultHistoryMaxRecords
+	}
+	return c.MaxRecords
+}
+
+// GetMaxAge gets the age after which records are pruned
+func (c HistoryConfig) GetMaxAge() time.Duration {
+	if c.MaxAge == 0 {
+		return defaultHistoryMaxAge
+	}
+	return c.MaxAge
+}
+
+// GetPruneInterval gets the interval at which records are pruned
+func (c HistoryConfig) GetPruneInterval() time.Duration {
+	if c.PruneInterval == 0 {
+		return defaultHistoryPruneInterval
+	}
+	return c.PruneInterval
+}
+
+// StoreConfig is the configuration of the backend persisting the stores
+type StoreConfig struct {
+	// Driver is the store backend: atomix or embedded
+	Driver string `yaml:"driver,omitempty"`
+	// Path is the path of the embedded backend's database file
+	Path string `yaml:"path,omitempty"`
+	// TransactionTimeout is the time after which a transaction not yet applied by the instance
+	// committing it is recovered by another instance
+	TransactionTimeout time.Duration `yaml:"transactionTimeout,omitempty"`
+}
+
+// GetDriver gets the store backend
+func (c StoreConfig) GetDriver() string {
+	if c.Driver == "" {
+		return defaultStoreDriver
+	}
+	return c.Driver
+}
+
+// GetPath gets the path of the embedded backend's database file
+func (c StoreConfig) GetPath() string {
+	if c.Path == "" {
+		return defaultStorePath
+	}
+	return c.Path
+}
+
+// GetTransactionTimeout gets the time after which an unapplied transaction is recovered by another instance
+func (c StoreConfig) GetTransactionTimeout() time.Duration {
+	if c.TransactionTimeout == 0 {
+		return defaultTransactionTimeout
+	}
+	return c.TransactionTimeout
+}
+
+// WatchConfig is the configuration of the hubs fanning out store events to watching clients and
+// of the watches of the embedded store
+type WatchConfig struct {
+	// BufferSize is the number of events buffered for each watching client
+	BufferSize int `yaml:"bufferSize,omitempty"`
+	// Policy is the policy applied to a client whose buffer is full: block, drop or disconnect
+	Policy string `yaml:"policy,omitempty"`
+}
+
+// GetBufferSize gets the number of events buffered for each watching client
+func (c WatchConfig) GetBufferSize() int {
+	if c.BufferSize == 0 {
+		return defaultWatchBufferSize
+	}
+	return c.BufferSize
+}
+
+// GetPolicy gets the policy applied to a watching client whose buffer is full
+func (c WatchConfig) GetPolicy() string {
+	if c.Policy == "" {
+		return defaultWatchPolicy
+	}
+	return c.Policy
+}
+
+// PodsConfig is the configuration of the cache of termination pods
+type PodsConfig struct {
+	// Selector is the label selector of the termination pods
+	// Terminations whose pod does not match the selector are removed. If empty, all pods in the
+	// namespace are cached.
+	Selector string `yaml:"selector,omitempty"`
+	// ResyncPeriod is the interval at which all cached pods are reconciled
+	ResyncPeriod time.Duration `yaml:"resyncPeriod,omitempty"`
+}
+
+// GetResyncPeriod gets the pod cache resync period
+func (c PodsConfig) GetResyncPeriod() time.Duration {
+	if c.ResyncPeriod == 0 {
+		return defaultPodResyncPeriod
+	}
+	return c.ResyncPeriod
+}
+
+// DiscoveryConfig is the Kubernetes termination discovery configuration
+// If neither a service nor a selector is configured, terminations must register themselves.
+type DiscoveryConfig struct {
+	// Service is the name of the Service whose EndpointSlices are registered as terminations
+	Service string `yaml:"service,omitempty"`
+	// PortName is the name of the Service port serving the termination API; the first port if empty
+	PortName string `yaml:"portName,omitempty"`
+	// Selector is the label selector of the pods registered as terminations if no service is configured
+	Selector string `yaml:"selector,omitempty"`
+	// Port is the port on which pods selected by the selector serve the termination API
+	Port int `yaml:"port,omitempty"`
+}
+
+// GetPort gets the port of pods discovered by label selector
+func (c DiscoveryConfig) GetPort() int {
+	if c.Port == 0 {
+		return defaultDiscoveryPort
+	}
+	return c.Port
+}
+
+// HealthConfig is the termination health probe configuration
+type HealthConfig struct {
+	// Interval is the interval at which terminations are probed
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// Timeout is the time after which a probe fails
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// FailureThreshold is the number of consecutive failed probes after which a termination is unhealthy
+	FailureThreshold int `yaml:"failureThreshold,omitempty"`
+	// SuccessThreshold is the number of consecutive successful probes after which a termination is healthy
+	SuccessThreshold int `yaml:"successThreshold,omitempty"`
+}
+
+// GetInterval gets the health probe interval
+func (c HealthConfig) GetInterval() time.Duration {
+	if c.Interval == 0 {
+		return defaultHealthInterval
+	}
+	return c.Interval
+}
+
+// GetTimeout gets the health probe timeout
+func (c HealthConfig) GetTimeout() time.Duration {
+	if c.Timeout == 0 {
+		return defaultHealthTimeout
+	}
+	return c.Timeout
+}
+
+// GetFailureThreshold gets the health probe failure threshold
+func (c HealthConfig) GetFailureThreshold() int {
+	if c.FailureThreshold == 0 {
+		return defaultFailureThreshold
+	}
+	return c.FailureThreshold
+}
+
+// GetSuccessThreshold gets the health probe success threshold
+func (c HealthConfig) GetSuccessThreshold() int {
+	if c.SuccessThreshold == 0 {
+		return defaultSuccessThreshold
+	}
+	return c.SuccessThreshold
+}
+
+// DrainConfig is the termination drain configuration
+type DrainConfig struct {
+	// BatchSize is the maximum number of subscriptions migrating off a draining termination at once
+	BatchSize int `yaml:"batchSize,omitempty"`
+	// Interval is the minimum interval between batches of subscriptions migrating off a draining termination
+	Interval time.Duration `yaml:"interval,omitempty"`
+}
+
+// GetBatchSize gets the drain batch size
+func (c DrainConfig) GetBatchSize() int {
+	if c.BatchSize == 0 {
+		return defaultDrainBatchSize
+	}
+	return c.BatchSize
+}
+
+// GetInterval gets the drain interval
+func (c DrainConfig) GetInterval() time.Duration {
+	if c.Interval == 0 {
+		return defaultDrainInterval
+	}
+	return c.Interval
+}
+
+// TopoConfig is the onos-topo configuration
+type TopoConfig struct {
+	// Address is the address of the onos-topo service from which E2 node connectivity is tracked
+	// If the address is empty, subscriptions are placed regardless of the connectivity of their E2 node.
+	Address string `yaml:"address,omitempty"`
+}
+
+// SubscriptionsConfig is the subscription configuration
+type SubscriptionsConfig struct {
+	// OwnerGracePeriod is the time for which a subscription's owner pod may be missing before the subscription is deleted
+	OwnerGracePeriod time.Duration `yaml:"ownerGracePeriod,omitempty"`
+	// OwnerNamespaces are the namespaces in which owner pods are watched
+	// Subscriptions owned by pods in other namespaces are not deleted when their owner is missing. If
+	// empty, owner pods are watched in the onos-e2sub namespace.
+	OwnerNamespaces []string `yaml:"ownerNamespaces,omitempty"`
+}
+
+// GetOwnerGracePeriod gets the owner grace period
+func (c SubscriptionsConfig) GetOwnerGracePeriod() time.Duration {
+	if c.OwnerGracePeriod == 0 {
+		return defaultOwnerGracePeriod
+	}
+	return c.OwnerGracePeriod
+}
+
+// SessionsConfig is the client session configuration
+type SessionsConfig struct {
+	// KeepAlive is the interval at which open session streams renew their session
+	KeepAlive time.Duration `yaml:"keepAlive,omitempty"`
+	// Timeout is the time after which a session that has not been renewed is considered disconnected
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// GracePeriod is the time for which a session may be disconnected before its subscriptions are deleted
+	GracePeriod time.Duration `yaml:"gracePeriod,omitempty"`
+	// TTL is the time after which a session stream that has sent nothing to its client expires
+	TTL time.Duration `yaml:"ttl,omitempty"`
+}
+
+// GetKeepAlive gets the session keep-alive interval
+func (c SessionsConfig) GetKeepAlive() time.Duration {
+	if c.KeepAlive == 0 {
+		return defaultSessionKeepAlive
+	}
+	return c.KeepAlive
+}
+
+// GetTimeout gets the session timeout
+func (c SessionsConfig) GetTimeout() time.Duration {
+	if c.Timeout == 0 {
+		return defaultSessionTimeout
+	}
+	return c.Timeout
+}
+
+// GetGracePeriod gets the session grace period
+func (c SessionsConfig) GetGracePeriod() time.Duration {
+	if c.GracePeriod == 0 {
+		return defaultSessionGracePeriod
+	}
+	return c.GracePeriod
+}
+
+// GetTTL gets the session stream time-to-live
+func (c SessionsConfig) GetTTL() time.Duration {
+	if c.TTL == 0 {
+		return defaultSessionTTL
+	}
+	return c.TTL
 }
 
 // GetConfig gets the onos-e2sub configuration
@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package discovery
+
+import (
+	"context"
+	"time"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/store/history"
+	"github.com/onosproject/onos-e2sub/pkg/store/termination"
+	"github.com/onosproject/onos-lib-go/pkg/controller"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+)
+
+var log = logging.GetLogger("controller", "discovery")
+
+const defaultTimeout = 30 * time.Second
+
+// NewController returns a new termination discovery controller
+// The controller registers the terminations discovered by the source and removes discovered
+// terminations once they're no longer discovered. Terminations that registered themselves are
+// left untouched.
+func NewController(endpoints endpoint.Store, terminations termination.Store, source Source) *controller.Controller {
+	c := controller.NewController("Discovery")
+	c.Watch(&Watcher{
+		endpoints: endpoints,
+	})
+	c.Watch(&SourceWatcher{
+		source: source,
+	})
+	c.Reconcile(&Reconciler{
+		endpoints:    endpoints,
+		terminations: terminations,
+		source:       source,
+	})
+	return c
+}
+
+// Reconciler is a termination discovery reconciler
+type Reconciler struct {
+	endpoints    endpoint.Store
+	terminations termination.Store
+	source       Source
+}
+
+// Reconcile reconciles the registration of a discovered termination
+func (r *Reconciler) Reconcile(id controller.ID) (controller.Result, error) {
+	ctx, cancel := context.WithTimeout(history.WithCaller(context.Background(), "controller/discovery"), defaultTimeout)
+	defer cancel()
+
+	epID := id.Value.(epapi.ID)
+	discovered, err := r.source.Get(ctx, epID)
+	if err != nil {
+		log.Warnf("Failed to discover Endpoint %s: %s", epID, err)
+		return controller.Result{}, err
+	}
+
+	ep, err := r.endpoints.Get(ctx, epID)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return controller.Result{}, err
+		}
+		ep = nil
+	}
+
+	term, err := r.terminations.Get(ctx, epID)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return controller.Result{}, err
+		}
+		term = nil
+	}
+
+	if discovered == nil {
+		return r.reconcileRemoved(ctx, ep, term)
+	}
+	return r.reconcileDiscovered(ctx, discovered, ep, term)
+}
+
+// reconcileDiscovered registers a discovered termination or updates its address
+func (r *Reconciler) reconcileDiscovered(ctx context.Context, discovered *epapi.TerminationEndpoint, ep *epapi.TerminationEndpoint, term *termination.Termination) (controller.Result, error) {
+	// Mark the termination discovered before registering it so it's removed if it disappears
+	if term == nil {
+		term = &termination.Termination{
+			ID:         discovered.ID,
+			Discovered: true,
+		}
+		if err := r.terminations.Create(ctx, term); err != nil && !errors.IsAlreadyExists(err) {
+			log.Warnf("Failed to register discovered Endpoint %+v: %s", discovered, err)
+			return controller.Result{}, err
+		}
+	} else if !term.Discovered && ep == nil {
+		term.Discovered = true
+		if err := r.terminations.Update(ctx, term); err != nil {
+			log.Warnf("Failed to register discovered Endpoint %+v: %s", discovered, err)
+			return controller.Result{}, err
+		}
+	}
+
+	if ep == nil {
+		log.Infof("Registering discovered Endpoint %+v", discovered)
+		if err := r.endpoints.Create(ctx, discovered); err != nil && !errors.IsAlreadyExists(err) {
+			log.Warnf("Failed to register discovered Endpoint %+v: %s", discovered, err)
+			return controller.Result{}, err
+		}
+		return controller.Result{}, nil
+	}
+
+	if term == nil || !term.Discovered {
+		return controller.Result{}, nil
+	}
+
+	if ep.IP != discovered.IP || ep.Port != discovered.Port {
+		log.Infof("Updating discovered Endpoint %+v", discovered)
+		ep.IP = discovered.IP
+		ep.Port = discovered.Port
+		if err := r.endpoints.Update(ctx, ep); err != nil {
+			log.Warnf("Failed to update discovered Endpoint %+v: %s", discovered, err)
+			return controller.Result{}, err
+		}
+	}
+	return controller.Result{}, nil
+}
+
+// reconcileRemoved removes a discovered termination that is no longer discovered
+func (r *Reconciler) reconcileRemoved(ctx context.Context, ep *epapi.TerminationEndpoint, term *termination.Termination) (controller.Result, error) {
+	if term == nil || !term.Discovered {
+		return controller.Result{}, nil
+	}
+
+	if ep != nil {
+		log.Infof("Removing undiscovered Endpoint %+v", ep)
+		if err := r.endpoints.Delete(ctx, ep.ID); err != nil && !errors.IsNotFound(err) {
+			log.Warnf("Failed to remove undiscovered Endpoint %+v: %s", ep, err)
+			return controller.Result{}, err
+		}
+	}
+	if err := r.terminations.Delete(ctx, term.ID); err != nil && !errors.IsNotFound(err) {
+		log.Warnf("Failed to remove undiscovered Endpoint %s: %s", term.ID, err)
+		return controller.Result{}, err
+	}
+	return controller.Result{}, nil
+}
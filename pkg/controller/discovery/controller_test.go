@@ -0,0 +1,174 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package discovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	epstore "github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	termstore "github.com/onosproject/onos-e2sub/pkg/store/termination"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const namespace = "test"
+
+func newPod(name string, ip string, ready bool) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			UID:       types.UID(name),
+			Labels:    map[string]string{"app": "onos-e2t"},
+		},
+		Status: corev1.PodStatus{
+			PodIP: ip,
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: status},
+			},
+		},
+	}
+}
+
+func newSlice(name string, endpoints map[string]string) *discoveryv1beta1.EndpointSlice {
+	portName := "grpc"
+	port := int32(5150)
+	slice := &discoveryv1beta1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			UID:       types.UID(name),
+			Labels:    map[string]string{discoveryv1beta1.LabelServiceName: "onos-e2t"},
+		},
+		AddressType: discoveryv1beta1.AddressTypeIPv4,
+		Ports: []discoveryv1beta1.EndpointPort{
+			{Name: &portName, Port: &port},
+		},
+	}
+	for pod, ip := range endpoints {
+		slice.Endpoints = append(slice.Endpoints, discoveryv1beta1.Endpoint{
+			Addresses: []string{ip},
+			TargetRef: &corev1.ObjectReference{Kind: "Pod", Namespace: namespace, Name: pod},
+		})
+	}
+	return slice
+}
+
+// waitForEndpoint waits for the given endpoint to be registered at the given IP, or removed if the IP is empty
+func waitForEndpoint(t *testing.T, store epstore.Store, id epapi.ID, ip epapi.IP) {
+	t.Helper()
+	deadline := time.Now().Add(15 * time.Second)
+	for time.Now().Before(deadline) {
+		ep, err := store.Get(context.TODO(), id)
+		if (ip == "" && err != nil) || (err == nil && ep.IP == ip) {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("Endpoint %s not reconciled to %q", id, ip)
+}
+
+func newStores(t *testing.T) (epstore.Store, termstore.Store) {
+	endpointStore, err := epstore.NewLocalStore()
+	assert.NoError(t, err)
+	termStore, err := termstore.NewLocalStore()
+	assert.NoError(t, err)
+	return endpointStore, termStore
+}
+
+func TestPodDiscovery(t *testing.T) {
+	endpointStore, termStore := newStores(t)
+	defer endpointStore.Close()
+	defer termStore.Close()
+
+	client := fake.NewSimpleClientset()
+	source, err := NewPodSource(client, namespace, "app=onos-e2t", 5150)
+	assert.NoError(t, err)
+
+	// Register a termination that is not discovered
+	assert.NoError(t, endpointStore.Create(context.TODO(), &epapi.TerminationEndpoint{ID: "e2t-manual", IP: "10.0.0.9", Port: 5150}))
+
+	controller := NewController(endpointStore, termStore, source)
+	assert.NoError(t, controller.Start())
+	defer controller.Stop()
+
+	// Verify a ready pod is registered
+	pod, err := client.CoreV1().Pods(namespace).Create(newPod("e2t-1", "10.0.0.1", true))
+	assert.NoError(t, err)
+	waitForEndpoint(t, endpointStore, "e2t-1", "10.0.0.1")
+	ep, err := endpointStore.Get(context.TODO(), "e2t-1")
+	assert.NoError(t, err)
+	assert.Equal(t, epapi.Port(5150), ep.Port)
+	term, err := termStore.Get(context.TODO(), "e2t-1")
+	assert.NoError(t, err)
+	assert.True(t, term.Discovered)
+
+	// Verify a pod that is not ready is not registered
+	_, err = client.CoreV1().Pods(namespace).Create(newPod("e2t-2", "10.0.0.2", false))
+	assert.NoError(t, err)
+
+	// Verify a change to the pod's address updates the termination
+	pod.Status.PodIP = "10.0.0.3"
+	_, err = client.CoreV1().Pods(namespace).Update(pod)
+	assert.NoError(t, err)
+	waitForEndpoint(t, endpointStore, "e2t-1", "10.0.0.3")
+
+	// Verify a deleted pod is removed
+	assert.NoError(t, client.CoreV1().Pods(namespace).Delete("e2t-1", &metav1.DeleteOptions{}))
+	waitForEndpoint(t, endpointStore, "e2t-1", "")
+	_, err = termStore.Get(context.TODO(), "e2t-1")
+	assert.Error(t, err)
+
+	_, err = endpointStore.Get(context.TODO(), "e2t-2")
+	assert.Error(t, err)
+
+	// Verify the termination that was not discovered is left untouched
+	_, err = endpointStore.Get(context.TODO(), "e2t-manual")
+	assert.NoError(t, err)
+}
+
+func TestServiceDiscovery(t *testing.T) {
+	endpointStore, termStore := newStores(t)
+	defer endpointStore.Close()
+	defer termStore.Close()
+
+	client := fake.NewSimpleClientset()
+	source := NewServiceSource(client, namespace, "onos-e2t", "grpc")
+
+	controller := NewController(endpointStore, termStore, source)
+	assert.NoError(t, controller.Start())
+	defer controller.Stop()
+
+	// Verify the endpoints of the service are registered
+	slice, err := client.DiscoveryV1beta1().EndpointSlices(namespace).Create(newSlice("onos-e2t-abc", map[string]string{
+		"e2t-1": "10.0.0.1",
+		"e2t-2": "10.0.0.2",
+	}))
+	assert.NoError(t, err)
+	waitForEndpoint(t, endpointStore, "e2t-1", "10.0.0.1")
+	waitForEndpoint(t, endpointStore, "e2t-2", "10.0.0.2")
+
+	// Verify an endpoint removed from the slice is removed
+	slice.Endpoints = newSlice("onos-e2t-abc", map[string]string{"e2t-2": "10.0.0.2"}).Endpoints
+	_, err = client.DiscoveryV1beta1().EndpointSlices(namespace).Update(slice)
+	assert.NoError(t, err)
+	waitForEndpoint(t, endpointStore, "e2t-1", "")
+	waitForEndpoint(t, endpointStore, "e2t-2", "10.0.0.2")
+
+	// Verify the endpoints of a deleted slice are removed
+	assert.NoError(t, client.DiscoveryV1beta1().EndpointSlices(namespace).Delete("onos-e2t-abc", &metav1.DeleteOptions{}))
+	waitForEndpoint(t, endpointStore, "e2t-2", "")
+}
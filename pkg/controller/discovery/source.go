@@ -0,0 +1,237 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package discovery
+
+import (
+	"context"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// podIndex is the name of the index of EndpointSlices by the pods backing their endpoints
+const podIndex = "pod"
+
+// Source is a source of discovered termination endpoints
+// Terminations are discovered from a shared informer cache of the Kubernetes resources backing them.
+type Source interface {
+	// Get gets the discovered termination endpoint with the given ID from the cache
+	// If no termination is discovered with the given ID, nil is returned. If the cache has not synced,
+	// an Unavailable error is returned.
+	Get(ctx context.Context, id epapi.ID) (*epapi.TerminationEndpoint, error)
+
+	// Informer returns the shared informer of the Kubernetes resources from which terminations are discovered
+	Informer() cache.SharedIndexInformer
+
+	// IDs returns the IDs of the terminations discovered from the given cached object
+	IDs(obj interface{}) []epapi.ID
+}
+
+// NewServiceSource returns a Source discovering the ready endpoints of a Service's EndpointSlices
+func NewServiceSource(client kubernetes.Interface, namespace string, service string, portName string) Source {
+	selector := labels.Set{discoveryv1beta1.LabelServiceName: service}.String()
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.LabelSelector = selector
+				return client.DiscoveryV1beta1().EndpointSlices(namespace).List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.LabelSelector = selector
+				return client.DiscoveryV1beta1().EndpointSlices(namespace).Watch(options)
+			},
+		},
+		&discoveryv1beta1.EndpointSlice{},
+		0,
+		cache.Indexers{podIndex: indexSliceByPod},
+	)
+	return &serviceSource{
+		informer: informer,
+		service:  service,
+		portName: portName,
+	}
+}
+
+// serviceSource is a Source discovering terminations from EndpointSlices
+type serviceSource struct {
+	informer cache.SharedIndexInformer
+	service  string
+	portName string
+}
+
+func (s *serviceSource) Get(ctx context.Context, id epapi.ID) (*epapi.TerminationEndpoint, error) {
+	if !s.informer.HasSynced() {
+		return nil, errors.NewUnavailable("EndpointSlice cache has not synced")
+	}
+	slices, err := s.informer.GetIndexer().ByIndex(podIndex, string(id))
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range slices {
+		slice := obj.(*discoveryv1beta1.EndpointSlice)
+		port := s.getPort(slice)
+		if port == 0 {
+			continue
+		}
+		for _, endpoint := range slice.Endpoints {
+			if getPodName(endpoint) != string(id) || len(endpoint.Addresses) == 0 {
+				continue
+			}
+			if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue
+			}
+			return &epapi.TerminationEndpoint{
+				ID:   id,
+				IP:   epapi.IP(endpoint.Addresses[0]),
+				Port: epapi.Port(port),
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *serviceSource) Informer() cache.SharedIndexInformer {
+	return s.informer
+}
+
+func (s *serviceSource) IDs(obj interface{}) []epapi.ID {
+	slice, ok := obj.(*discoveryv1beta1.EndpointSlice)
+	if !ok || slice.Labels[discoveryv1beta1.LabelServiceName] != s.service {
+		return nil
+	}
+	ids := make([]epapi.ID, 0, len(slice.Endpoints))
+	for _, endpoint := range slice.Endpoints {
+		if name := getPodName(endpoint); name != "" {
+			ids = append(ids, epapi.ID(name))
+		}
+	}
+	return ids
+}
+
+// getPort returns the termination port of the given slice, or 0 if the slice does not serve it
+func (s *serviceSource) getPort(slice *discoveryv1beta1.EndpointSlice) int32 {
+	for _, port := range slice.Ports {
+		if port.Port == nil {
+			continue
+		}
+		if s.portName == "" || (port.Name != nil && *port.Name == s.portName) {
+			return *port.Port
+		}
+	}
+	return 0
+}
+
+// indexSliceByPod indexes an EndpointSlice by the names of the pods backing its endpoints
+func indexSliceByPod(obj interface{}) ([]string, error) {
+	slice, ok := obj.(*discoveryv1beta1.EndpointSlice)
+	if !ok {
+		return nil, nil
+	}
+	var names []string
+	for _, endpoint := range slice.Endpoints {
+		if name := getPodName(endpoint); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// getPodName returns the name of the pod backing a slice endpoint
+func getPodName(endpoint discoveryv1beta1.Endpoint) string {
+	if endpoint.TargetRef == nil || endpoint.TargetRef.Kind != "Pod" {
+		return ""
+	}
+	return endpoint.TargetRef.Name
+}
+
+// NewPodSource returns a Source discovering the ready pods matching a label selector
+func NewPodSource(client kubernetes.Interface, namespace string, selector string, port int) (Source, error) {
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		return nil, err
+	}
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.LabelSelector = parsed.String()
+				return client.CoreV1().Pods(namespace).List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.LabelSelector = parsed.String()
+				return client.CoreV1().Pods(namespace).Watch(options)
+			},
+		},
+		&corev1.Pod{},
+		0,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+	return &podSource{
+		informer: informer,
+		pods:     corelisters.NewPodLister(informer.GetIndexer()).Pods(namespace),
+		selector: parsed,
+		port:     port,
+	}, nil
+}
+
+// podSource is a Source discovering terminations from pods
+type podSource struct {
+	informer cache.SharedIndexInformer
+	pods     corelisters.PodNamespaceLister
+	selector labels.Selector
+	port     int
+}
+
+func (s *podSource) Get(ctx context.Context, id epapi.ID) (*epapi.TerminationEndpoint, error) {
+	if !s.informer.HasSynced() {
+		return nil, errors.NewUnavailable("pod cache has not synced")
+	}
+	pod, err := s.pods.Get(string(id))
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !s.selector.Matches(labels.Set(pod.Labels)) || pod.DeletionTimestamp != nil || pod.Status.PodIP == "" || !isPodReady(pod) {
+		return nil, nil
+	}
+	return &epapi.TerminationEndpoint{
+		ID:   id,
+		IP:   epapi.IP(pod.Status.PodIP),
+		Port: epapi.Port(s.port),
+	}, nil
+}
+
+func (s *podSource) Informer() cache.SharedIndexInformer {
+	return s.informer
+}
+
+func (s *podSource) IDs(obj interface{}) []epapi.ID {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil
+	}
+	return []epapi.ID{epapi.ID(pod.Name)}
+}
+
+// isPodReady returns whether the pod's Ready condition is true
+func isPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package discovery
+
+import (
+	"context"
+	"sync"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/controller/supervisor"
+	"github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	"github.com/onosproject/onos-lib-go/pkg/controller"
+	"k8s.io/client-go/tools/cache"
+)
+
+const queueSize = 100
+
+// Watcher is a termination endpoint watcher
+type Watcher struct {
+	endpoints endpoint.Store
+	cancel    context.CancelFunc
+	mu        sync.Mutex
+}
+
+// Start starts the endpoint watcher
+func (w *Watcher) Start(ch chan<- controller.ID) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	go func() {
+		defer close(ch)
+		supervisor.Watch(ctx, "discovery/endpoints", func(ctx context.Context, resync bool, ready func()) error {
+			endpointCh := make(chan epapi.Event, queueSize)
+			if err := w.endpoints.Watch(ctx, endpointCh, endpoint.WithReplay()); err != nil {
+				return err
+			}
+			ready()
+			for event := range endpointCh {
+				ch <- controller.NewID(event.Endpoint.ID)
+			}
+			return nil
+		})
+	}()
+	return nil
+}
+
+// Stop stops the endpoint watcher
+func (w *Watcher) Stop() {
+	w.mu.Lock()
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+	w.mu.Unlock()
+}
+
+var _ controller.Watcher = &Watcher{}
+
+// SourceWatcher is a watcher of the Kubernetes resources from which terminations are discovered
+// The watcher runs the source's shared informer and enqueues the terminations discovered from each
+// object that changes, both before and after the change, so that terminations removed from an object
+// are reconciled.
+type SourceWatcher struct {
+	source Source
+	cancel context.CancelFunc
+	mu     sync.Mutex
+}
+
+// Start starts the source watcher
+func (w *SourceWatcher) Start(ch chan<- controller.ID) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	enqueue := func(objs ...interface{}) {
+		ids := make(map[epapi.ID]bool)
+		for _, obj := range objs {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			for _, id := range w.source.IDs(obj) {
+				ids[id] = true
+			}
+		}
+		for id := range ids {
+			select {
+			case ch <- controller.NewID(id):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+	informer := w.source.Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			enqueue(obj)
+		},
+		UpdateFunc: func(old, obj interface{}) {
+			enqueue(old, obj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			enqueue(obj)
+		},
+	})
+
+	go func() {
+		defer close(ch)
+		supervisor.WatchInformer(ctx, "discovery/source", informer)
+	}()
+	return nil
+}
+
+// Stop stops the source watcher
+func (w *SourceWatcher) Stop() {
+	w.mu.Lock()
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+	w.mu.Unlock()
+}
+
+var _ controller.Watcher = &SourceWatcher{}
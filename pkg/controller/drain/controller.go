@@ -0,0 +1,183 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package drain
+
+import (
+	"context"
+	"time"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	taskapi "github.com/onosproject/onos-api/go/onos/e2sub/task"
+	"github.com/onosproject/onos-e2sub/pkg/controller/scheduler"
+	"github.com/onosproject/onos-e2sub/pkg/store/history"
+	"github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	"github.com/onosproject/onos-e2sub/pkg/store/task"
+	"github.com/onosproject/onos-e2sub/pkg/store/termination"
+	"github.com/onosproject/onos-e2sub/pkg/store/txn"
+	"github.com/onosproject/onos-lib-go/pkg/controller"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+)
+
+var log = logging.GetLogger("controller", "drain")
+
+const defaultTimeout = 30 * time.Second
+
+// NewController returns a new termination drain controller
+// The controller migrates the subscriptions off a draining termination by evicting at most
+// batchSize subscriptions at once, and at most one batch per interval. Each batch is evicted in
+// a single transaction.
+func NewController(terminations termination.Store, tasks task.Store, metadata metadata.Store, transactions txn.Store, batchSize int, interval time.Duration) *controller.Controller {
+	c := controller.NewController("Drain")
+	requeues := scheduler.NewScheduler()
+	c.Watch(requeues)
+	c.Watch(&Watcher{
+		terminations: terminations,
+	})
+	c.Watch(&TaskWatcher{
+		tasks: tasks,
+	})
+	c.Reconcile(&Reconciler{
+		terminations: terminations,
+		tasks:        tasks,
+		metadata:     metadata,
+		transactor: txn.NewTransactor(transactions, txn.Stores{
+			Tasks:    tasks,
+			Metadata: metadata,
+		}),
+		batchSize: batchSize,
+		interval:  interval,
+		scheduler: requeues,
+	})
+	return c
+}
+
+// Reconciler is a termination drain reconciler
+type Reconciler struct {
+	terminations termination.Store
+	tasks        task.Store
+	metadata     metadata.Store
+	transactor   *txn.Transactor
+	batchSize    int
+	interval     time.Duration
+	scheduler    *scheduler.Scheduler
+}
+
+// Reconcile reconciles the drain of a termination
+func (r *Reconciler) Reconcile(id controller.ID) (controller.Result, error) {
+	ctx, cancel := context.WithTimeout(history.WithCaller(context.Background(), "controller/drain"), defaultTimeout)
+	defer cancel()
+
+	term, err := r.terminations.Get(ctx, id.Value.(epapi.ID))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return controller.Result{}, nil
+		}
+		return controller.Result{}, err
+	}
+
+	if term.Drain == nil || !term.Drain.Completed.IsZero() {
+		return controller.Result{}, nil
+	}
+
+	log.Infof("Reconciling drain of Termination %+v", term)
+
+	// List the tasks remaining on the termination; closed tasks no longer use the termination
+	tasks, err := r.tasks.List(ctx)
+	if err != nil {
+		return controller.Result{}, err
+	}
+	remaining := make([]taskapi.SubscriptionTask, 0)
+	for _, task := range tasks {
+		if task.EndpointID == term.ID && !(task.Lifecycle.Phase == taskapi.Phase_CLOSE && task.Lifecycle.Status == taskapi.Status_COMPLETE) {
+			remaining = append(remaining, task)
+		}
+	}
+
+	// Once no tasks remain, the drain is complete
+	if len(remaining) == 0 {
+		log.Infof("Completed drain of Termination %s", term.ID)
+		term.Drain.Remaining = 0
+		term.Drain.Completed = time.Now()
+		if err := r.terminations.Update(ctx, term); err != nil {
+			log.Warnf("Failed to reconcile drain of Termination %s: %s", term.ID, err)
+			return controller.Result{}, err
+		}
+		return controller.Result{}, nil
+	}
+
+	// Record the drain progress
+	if term.Drain.Remaining != len(remaining) || term.Drain.Total < len(remaining) {
+		term.Drain.Remaining = len(remaining)
+		if term.Drain.Total < len(remaining) {
+			term.Drain.Total = len(remaining)
+		}
+		if err := r.terminations.Update(ctx, term); err != nil {
+			log.Warnf("Failed to reconcile drain of Termination %s: %s", term.ID, err)
+			return controller.Result{}, err
+		}
+	}
+
+	// Wait for the interval since the last batch was evicted
+	if next := term.Drain.Evicted.Add(r.interval); time.Now().Before(next) {
+		r.scheduler.RequeueAt(id, next)
+		return controller.Result{}, nil
+	}
+
+	// Count the subscriptions already being migrated and evict another batch
+	// Closing tasks belong to subscriptions already migrated, whose eviction was cleared once their
+	// task on another termination was opened.
+	pending := make([]*metadata.Metadata, 0)
+	migrating := 0
+	for _, task := range remaining {
+		if task.Lifecycle.Phase == taskapi.Phase_CLOSE {
+			migrating++
+			continue
+		}
+		meta, err := r.metadata.Get(ctx, task.SubscriptionID)
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				return controller.Result{}, err
+			}
+			meta = &metadata.Metadata{
+				ID: task.SubscriptionID,
+			}
+		}
+		if meta.Evicted == term.ID {
+			migrating++
+		} else {
+			pending = append(pending, meta)
+		}
+	}
+
+	tx := r.transactor.Begin()
+	evicted := 0
+	for _, meta := range pending {
+		if migrating+evicted >= r.batchSize {
+			break
+		}
+		log.Infof("Evicting Subscription %s from Termination %s", meta.ID, term.ID)
+		meta.Evicted = term.ID
+		if meta.Revision == 0 {
+			tx.CreateMetadata(meta)
+		} else {
+			tx.UpdateMetadata(meta)
+		}
+		evicted++
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Warnf("Failed to evict Subscriptions from Termination %s: %s", term.ID, err)
+		return controller.Result{}, err
+	}
+
+	if evicted > 0 {
+		term.Drain.Evicted = time.Now()
+		if err := r.terminations.Update(ctx, term); err != nil {
+			log.Warnf("Failed to reconcile drain of Termination %s: %s", term.ID, err)
+			return controller.Result{}, err
+		}
+	}
+	return controller.Result{}, nil
+}
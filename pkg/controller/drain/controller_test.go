@@ -0,0 +1,155 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package drain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	taskapi "github.com/onosproject/onos-api/go/onos/e2sub/task"
+	metastore "github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	taskstore "github.com/onosproject/onos-e2sub/pkg/store/task"
+	termstore "github.com/onosproject/onos-e2sub/pkg/store/termination"
+	txnstore "github.com/onosproject/onos-e2sub/pkg/store/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	batchSize = 2
+	interval  = 500 * time.Millisecond
+)
+
+func createTask(id string) *taskapi.SubscriptionTask {
+	return &taskapi.SubscriptionTask{
+		ID:             taskapi.ID(id),
+		SubscriptionID: subapi.ID(id),
+		EndpointID:     "e2t-1",
+		Lifecycle: taskapi.Lifecycle{
+			Phase:  taskapi.Phase_OPEN,
+			Status: taskapi.Status_COMPLETE,
+		},
+	}
+}
+
+func nextTermEvent(t *testing.T, ch chan termstore.Event) termstore.Event {
+	t.Helper()
+	var event termstore.Event
+	select {
+	case event = <-ch:
+		break
+	case <-time.After(15 * time.Second):
+		t.Error("Termination Event channel timed out")
+		break
+	}
+	return event
+}
+
+func countEvicted(t *testing.T, metaStore metastore.Store) int {
+	t.Helper()
+	return len(listEvicted(t, metaStore))
+}
+
+func listEvicted(t *testing.T, metaStore metastore.Store) []subapi.ID {
+	t.Helper()
+	metas, err := metaStore.List(context.TODO())
+	assert.NoError(t, err)
+	evicted := make([]subapi.ID, 0)
+	for _, meta := range metas {
+		if meta.Evicted == "e2t-1" {
+			evicted = append(evicted, meta.ID)
+		}
+	}
+	return evicted
+}
+
+func TestDrain(t *testing.T) {
+	termStore, err := termstore.NewLocalStore()
+	assert.NoError(t, err)
+	defer termStore.Close()
+
+	taskStore, err := taskstore.NewLocalStore()
+	assert.NoError(t, err)
+	defer taskStore.Close()
+
+	metaStore, err := metastore.NewLocalStore()
+	assert.NoError(t, err)
+	defer metaStore.Close()
+
+	txnStore, err := txnstore.NewLocalStore()
+	assert.NoError(t, err)
+	defer txnStore.Close()
+
+	for _, id := range []string{"sub-1", "sub-2", "sub-3"} {
+		assert.NoError(t, taskStore.Create(context.TODO(), createTask(id)))
+	}
+
+	controller := NewController(termStore, taskStore, metaStore, txnStore, batchSize, interval)
+	assert.NoError(t, controller.Start())
+	defer controller.Stop()
+
+	termCh := make(chan termstore.Event)
+	assert.NoError(t, termStore.Watch(context.TODO(), termCh))
+
+	term := &termstore.Termination{
+		ID:       epapi.ID("e2t-1"),
+		Cordoned: true,
+		Drain: &termstore.Drain{
+			Started: time.Now(),
+		},
+	}
+	assert.NoError(t, termStore.Create(context.TODO(), term))
+	nextTermEvent(t, termCh)
+
+	// Verify the progress is recorded and the first batch is evicted
+	for {
+		event := nextTermEvent(t, termCh)
+		if !event.Termination.Drain.Evicted.IsZero() {
+			assert.Equal(t, 3, event.Termination.Drain.Total)
+			assert.Equal(t, 3, event.Termination.Drain.Remaining)
+			break
+		}
+	}
+	assert.Equal(t, batchSize, countEvicted(t, metaStore))
+
+	// Verify no further subscriptions are evicted until the batch has migrated
+	time.Sleep(2 * interval)
+	assert.Equal(t, batchSize, countEvicted(t, metaStore))
+
+	// Migrate the first batch and verify the remaining subscription is evicted
+	migrated := make(map[subapi.ID]bool)
+	for _, id := range listEvicted(t, metaStore) {
+		assert.NoError(t, taskStore.Delete(context.TODO(), taskapi.ID(id)))
+		migrated[id] = true
+	}
+	for {
+		event := nextTermEvent(t, termCh)
+		if event.Termination.Drain.Remaining == 1 && countEvicted(t, metaStore) == 3 {
+			break
+		}
+	}
+
+	// Close the last task and verify the drain completes
+	var last subapi.ID
+	for _, id := range []subapi.ID{"sub-1", "sub-2", "sub-3"} {
+		if !migrated[id] {
+			last = id
+		}
+	}
+	task, err := taskStore.Get(context.TODO(), taskapi.ID(last))
+	assert.NoError(t, err)
+	task.Lifecycle.Phase = taskapi.Phase_CLOSE
+	assert.NoError(t, taskStore.Update(context.TODO(), task))
+	for {
+		event := nextTermEvent(t, termCh)
+		if !event.Termination.Drain.Completed.IsZero() {
+			assert.Equal(t, 0, event.Termination.Drain.Remaining)
+			assert.Equal(t, 3, event.Termination.Drain.Total)
+			break
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package drain
+
+import (
+	"context"
+	"sync"
+
+	taskapi "github.com/onosproject/onos-api/go/onos/e2sub/task"
+	"github.com/onosproject/onos-e2sub/pkg/controller/supervisor"
+	"github.com/onosproject/onos-e2sub/pkg/store/task"
+	"github.com/onosproject/onos-e2sub/pkg/store/termination"
+	"github.com/onosproject/onos-lib-go/pkg/controller"
+)
+
+const queueSize = 100
+
+// Watcher is a termination watcher
+type Watcher struct {
+	terminations termination.Store
+	cancel       context.CancelFunc
+	mu           sync.Mutex
+}
+
+// Start starts the termination watcher
+func (w *Watcher) Start(ch chan<- controller.ID) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	go func() {
+		defer close(ch)
+		supervisor.Watch(ctx, "drain/terminations", func(ctx context.Context, resync bool, ready func()) error {
+			termCh := make(chan termination.Event, queueSize)
+			if err := w.terminations.Watch(ctx, termCh, termination.WithReplay()); err != nil {
+				return err
+			}
+			ready()
+			for event := range termCh {
+				if event.Termination.Drain != nil {
+					ch <- controller.NewID(event.Termination.ID)
+				}
+			}
+			return nil
+		})
+	}()
+	return nil
+}
+
+// Stop stops the termination watcher
+func (w *Watcher) Stop() {
+	w.mu.Lock()
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+	w.mu.Unlock()
+}
+
+var _ controller.Watcher = &Watcher{}
+
+// TaskWatcher is a subscription task watcher
+type TaskWatcher struct {
+	tasks  task.Store
+	cancel context.CancelFunc
+	mu     sync.Mutex
+}
+
+// Start starts the task watcher
+func (w *TaskWatcher) Start(ch chan<- controller.ID) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	go func() {
+		defer close(ch)
+		supervisor.Watch(ctx, "drain/tasks", func(ctx context.Context, resync bool, ready func()) error {
+			var opts []task.WatchOption
+			if resync {
+				opts = append(opts, task.WithReplay())
+			}
+			taskCh := make(chan taskapi.Event, queueSize)
+			if err := w.tasks.Watch(ctx, taskCh, opts...); err != nil {
+				return err
+			}
+			ready()
+			for event := range taskCh {
+				ch <- controller.NewID(event.Task.EndpointID)
+			}
+			return nil
+		})
+	}()
+	return nil
+}
+
+// Stop stops the task watcher
+func (w *TaskWatcher) Stop() {
+	w.mu.Lock()
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+	w.mu.Unlock()
+}
+
+var _ controller.Watcher = &TaskWatcher{}
@@ -6,17 +6,20 @@ package endpoint
 
 import (
 	"context"
-
 	"time"
 
 	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
 
 	"github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/store/history"
 	"github.com/onosproject/onos-lib-go/pkg/controller"
-	"github.com/onosproject/onos-lib-go/pkg/env"
-	"k8s.io/apimachinery/pkg/api/errors"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 
 	"github.com/onosproject/onos-lib-go/pkg/logging"
 )
@@ -26,36 +29,63 @@ var log = logging.GetLogger("controller", "endpoint")
 const defaultTimeout = 30 * time.Second
 
 // NewController returns a new endpoint controller
-func NewController(endpoints endpoint.Store, client *kubernetes.Clientset) *controller.Controller {
+// The controller deletes endpoints whose pod no longer exists. Pods are read from a shared
+// informer cache of the pods in the namespace matching the given label selector, which is
+// resynced every resyncPeriod.
+func NewController(endpoints endpoint.Store, client kubernetes.Interface, namespace string, selector string, resyncPeriod time.Duration) *controller.Controller {
+	pods := newPodInformer(client, namespace, selector, resyncPeriod)
 	c := controller.NewController("Endpoint")
 	c.Watch(&Watcher{
+		name:      "endpoint/endpoints",
 		endpoints: endpoints,
 	})
 	c.Watch(&PodWatcher{
-		client:    client,
-		namespace: env.GetPodNamespace(),
-		endpoints: endpoints,
+		pods: pods,
 	})
 	c.Reconcile(&Reconciler{
-		namespace: env.GetPodNamespace(),
+		namespace: namespace,
 		endpoints: endpoints,
-		client:    client,
+		pods:      pods,
 	})
 	return c
 }
 
+// newPodInformer returns a shared informer for the pods in the namespace matching the selector
+func newPodInformer(client kubernetes.Interface, namespace string, selector string, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.LabelSelector = selector
+				return client.CoreV1().Pods(namespace).List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.LabelSelector = selector
+				return client.CoreV1().Pods(namespace).Watch(options)
+			},
+		},
+		&corev1.Pod{},
+		resyncPeriod,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+}
+
 // Reconciler is a endpoint reconciler
 type Reconciler struct {
 	namespace string
 	endpoints endpoint.Store
-	client    *kubernetes.Clientset
+	pods      cache.SharedIndexInformer
 }
 
 // Reconcile reconciles the state of a endpoint
 func (r *Reconciler) Reconcile(id controller.ID) (controller.Result, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	ctx, cancel := context.WithTimeout(history.WithCaller(context.Background(), "controller/endpoint"), defaultTimeout)
 	defer cancel()
 
+	// Pods missing from a cache that has not synced may still exist
+	if !r.pods.HasSynced() {
+		return controller.Result{}, errors.NewUnavailable("pod cache has not synced")
+	}
+
 	// Get the endpoint from the store
 	endpoint, err := r.endpoints.Get(ctx, id.Value.(epapi.ID))
 	if err != nil {
@@ -67,15 +97,15 @@ func (r *Reconciler) Reconcile(id controller.ID) (controller.Result, error) {
 
 	log.Infof("Reconciling Endpoint %+v", endpoint)
 
-	// Get the pod associated with the endpoint
-	_, err = r.client.CoreV1().Pods(r.namespace).Get(string(endpoint.ID), metav1.GetOptions{})
+	// Get the pod associated with the endpoint from the cache
+	_, exists, err := r.pods.GetStore().GetByKey(r.namespace + "/" + string(endpoint.ID))
 	if err != nil {
-		if !errors.IsNotFound(err) {
-			log.Warnf("Failed to reconcile Endpoint %+v: %s", endpoint, err)
-			return controller.Result{}, err
-		}
+		log.Warnf("Failed to reconcile Endpoint %+v: %s", endpoint, err)
+		return controller.Result{}, err
+	}
 
-		// If the pod not exist, delete the endpoint
+	// If the pod not exist, delete the endpoint
+	if !exists {
 		log.Infof("Deleting orphaned Endpoint %+v", endpoint)
 		err := r.endpoints.Delete(ctx, endpoint.ID)
 		if err != nil && !errors.IsNotFound(err) {
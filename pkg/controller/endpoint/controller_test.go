@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package endpoint
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	epstore "github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const namespace = "test"
+
+func newPod(name string, app string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{"app": app},
+		},
+	}
+}
+
+// waitForRemoved waits for the given endpoint to be removed from the store
+func waitForRemoved(t *testing.T, store epstore.Store, id epapi.ID) {
+	t.Helper()
+	deadline := time.Now().Add(15 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := store.Get(context.TODO(), id); err != nil {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("Endpoint %s not removed", id)
+}
+
+func TestOrphanedEndpoints(t *testing.T) {
+	endpointStore, err := epstore.NewLocalStore()
+	assert.NoError(t, err)
+	defer endpointStore.Close()
+
+	client := fake.NewSimpleClientset(
+		newPod("e2t-1", "onos-e2t"),
+		newPod("e2t-2", "onos-e2t"),
+		newPod("other", "other"),
+	)
+
+	for _, id := range []epapi.ID{"e2t-1", "e2t-2", "e2t-3", "other"} {
+		assert.NoError(t, endpointStore.Create(context.TODO(), &epapi.TerminationEndpoint{ID: id, IP: "10.0.0.1", Port: 5150}))
+	}
+
+	controller := NewController(endpointStore, client, namespace, "app=onos-e2t", time.Minute)
+	assert.NoError(t, controller.Start())
+	defer controller.Stop()
+
+	// Verify the endpoints without a pod matching the selector are removed
+	waitForRemoved(t, endpointStore, "e2t-3")
+	waitForRemoved(t, endpointStore, "other")
+	_, err = endpointStore.Get(context.TODO(), "e2t-1")
+	assert.NoError(t, err)
+	_, err = endpointStore.Get(context.TODO(), "e2t-2")
+	assert.NoError(t, err)
+
+	// Delete a pod and verify its endpoint is removed
+	assert.NoError(t, client.CoreV1().Pods(namespace).Delete("e2t-2", &metav1.DeleteOptions{}))
+	waitForRemoved(t, endpointStore, "e2t-2")
+	_, err = endpointStore.Get(context.TODO(), "e2t-1")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,185 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package endpoint
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/controller/scheduler"
+	"github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/store/history"
+	"github.com/onosproject/onos-e2sub/pkg/store/termination"
+	"github.com/onosproject/onos-lib-go/pkg/controller"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// Prober probes the health of a termination endpoint
+type Prober interface {
+	// Probe returns an error if the endpoint is not serving
+	Probe(ctx context.Context, ep *epapi.TerminationEndpoint) error
+}
+
+// DialFunc connects to the given address
+type DialFunc func(ctx context.Context, address string) (*grpc.ClientConn, error)
+
+// NewGRPCProber returns a Prober that probes endpoints with the standard gRPC health protocol
+// Endpoints that do not implement the health service are considered serving as long as they respond.
+func NewGRPCProber(dial DialFunc) Prober {
+	return &grpcProber{
+		dial: dial,
+	}
+}
+
+// grpcProber is a gRPC health protocol Prober
+type grpcProber struct {
+	dial DialFunc
+}
+
+func (p *grpcProber) Probe(ctx context.Context, ep *epapi.TerminationEndpoint) error {
+	conn, err := p.dial(ctx, fmt.Sprintf("%s:%d", ep.IP, ep.Port))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	response, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			return nil
+		}
+		return err
+	}
+	if response.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("health status is %s", response.Status)
+	}
+	return nil
+}
+
+// NewHealthController returns a new termination endpoint health controller
+// Each endpoint is probed every interval, and is marked unhealthy after failureThreshold
+// consecutive failed probes and healthy after successThreshold consecutive successful probes.
+func NewHealthController(endpoints endpoint.Store, terminations termination.Store, prober Prober,
+	interval time.Duration, timeout time.Duration, failureThreshold int, successThreshold int) *controller.Controller {
+	c := controller.NewController("Health")
+	requeues := scheduler.NewScheduler()
+	c.Watch(requeues)
+	c.Watch(&Watcher{
+		name:      "health/endpoints",
+		endpoints: endpoints,
+	})
+	c.Reconcile(&HealthReconciler{
+		endpoints:        endpoints,
+		terminations:     terminations,
+		prober:           prober,
+		interval:         interval,
+		timeout:          timeout,
+		failureThreshold: failureThreshold,
+		successThreshold: successThreshold,
+		scheduler:        requeues,
+	})
+	return c
+}
+
+// HealthReconciler is a termination endpoint health reconciler
+type HealthReconciler struct {
+	endpoints        endpoint.Store
+	terminations     termination.Store
+	prober           Prober
+	interval         time.Duration
+	timeout          time.Duration
+	failureThreshold int
+	successThreshold int
+	scheduler        *scheduler.Scheduler
+}
+
+// Reconcile probes an endpoint and records its health
+func (r *HealthReconciler) Reconcile(id controller.ID) (controller.Result, error) {
+	ctx, cancel := context.WithTimeout(history.WithCaller(context.Background(), "controller/health"), defaultTimeout)
+	defer cancel()
+
+	ep, err := r.endpoints.Get(ctx, id.Value.(epapi.ID))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return controller.Result{}, nil
+		}
+		return controller.Result{}, err
+	}
+
+	probeCtx, probeCancel := context.WithTimeout(ctx, r.timeout)
+	probeErr := r.prober.Probe(probeCtx, ep)
+	probeCancel()
+
+	term, err := r.terminations.Get(ctx, ep.ID)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return controller.Result{}, err
+		}
+		term = &termination.Termination{
+			ID: ep.ID,
+		}
+	}
+
+	if r.updateHealth(term, probeErr) {
+		if term.Revision == 0 {
+			err = r.terminations.Create(ctx, term)
+		} else {
+			err = r.terminations.Update(ctx, term)
+		}
+		if err != nil {
+			log.Warnf("Failed to update health of Endpoint %s: %s", ep.ID, err)
+			return controller.Result{}, err
+		}
+	}
+
+	r.scheduler.RequeueAt(id, time.Now().Add(r.interval))
+	return controller.Result{}, nil
+}
+
+// updateHealth applies the result of a probe to the termination's health
+// Consecutive probe counts are bounded by the thresholds, so the termination is only updated
+// while its health is changing.
+func (r *HealthReconciler) updateHealth(term *termination.Termination, probeErr error) bool {
+	health := termination.Health{}
+	if term.Health != nil {
+		health = *term.Health
+	}
+
+	if probeErr == nil {
+		health.Failures = 0
+		health.Reason = ""
+		if health.Successes < r.successThreshold {
+			health.Successes++
+		}
+		if health.Successes >= r.successThreshold && health.Status != termination.HealthHealthy {
+			log.Infof("Endpoint %s is healthy", term.ID)
+			health.Status = termination.HealthHealthy
+		}
+	} else {
+		health.Successes = 0
+		health.Reason = probeErr.Error()
+		if health.Failures < r.failureThreshold {
+			health.Failures++
+		}
+		if health.Failures >= r.failureThreshold && health.Status != termination.HealthUnhealthy {
+			log.Warnf("Endpoint %s is unhealthy: %s", term.ID, probeErr)
+			health.Status = termination.HealthUnhealthy
+		}
+	}
+
+	if term.Health != nil && health.Status == term.Health.Status && health.Failures == term.Health.Failures &&
+		health.Successes == term.Health.Successes && health.Reason == term.Health.Reason {
+		return false
+	}
+	health.Updated = time.Now()
+	term.Health = &health
+	return true
+}
@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package endpoint
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	epstore "github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	termstore "github.com/onosproject/onos-e2sub/pkg/store/termination"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func nextHealth(t *testing.T, ch chan termstore.Event) *termstore.Health {
+	t.Helper()
+	for {
+		select {
+		case event := <-ch:
+			if event.Termination.Health != nil && event.Termination.Health.Status != termstore.HealthUnknown {
+				return event.Termination.Health
+			}
+		case <-time.After(15 * time.Second):
+			t.Fatal("Termination Event channel timed out")
+		}
+	}
+}
+
+func TestHealthProbes(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	healthServer := health.NewServer()
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	defer server.Stop()
+
+	endpointStore, err := epstore.NewLocalStore()
+	assert.NoError(t, err)
+	defer endpointStore.Close()
+
+	termStore, err := termstore.NewLocalStore()
+	assert.NoError(t, err)
+	defer termStore.Close()
+
+	prober := NewGRPCProber(func(ctx context.Context, address string) (*grpc.ClientConn, error) {
+		return grpc.DialContext(ctx, address, grpc.WithInsecure(), grpc.WithBlock())
+	})
+	controller := NewHealthController(endpointStore, termStore, prober, 100*time.Millisecond, time.Second, 3, 2)
+	assert.NoError(t, controller.Start())
+	defer controller.Stop()
+
+	termCh := make(chan termstore.Event)
+	assert.NoError(t, termStore.Watch(context.TODO(), termCh))
+
+	addr := lis.Addr().(*net.TCPAddr)
+	err = endpointStore.Create(context.TODO(), &epapi.TerminationEndpoint{
+		ID:   "e2t-1",
+		IP:   epapi.IP(addr.IP.String()),
+		Port: epapi.Port(addr.Port),
+	})
+	assert.NoError(t, err)
+
+	// Verify the endpoint becomes healthy after the success threshold
+	health := nextHealth(t, termCh)
+	assert.Equal(t, termstore.HealthHealthy, health.Status)
+	assert.Equal(t, 2, health.Successes)
+
+	// Verify the endpoint becomes unhealthy after the failure threshold
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	health = nextHealth(t, termCh)
+	for health.Status == termstore.HealthHealthy {
+		assert.True(t, health.Failures < 3)
+		assert.NotEmpty(t, health.Reason)
+		health = nextHealth(t, termCh)
+	}
+	assert.Equal(t, termstore.HealthUnhealthy, health.Status)
+	assert.Equal(t, 3, health.Failures)
+
+	// Verify the endpoint becomes healthy again
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	health = nextHealth(t, termCh)
+	for health.Status == termstore.HealthUnhealthy {
+		health = nextHealth(t, termCh)
+	}
+	assert.Equal(t, termstore.HealthHealthy, health.Status)
+	assert.Empty(t, health.Reason)
+}
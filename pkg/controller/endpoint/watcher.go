@@ -9,17 +9,19 @@ import (
 	"sync"
 
 	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/controller/supervisor"
 	"github.com/onosproject/onos-e2sub/pkg/store/endpoint"
 	"github.com/onosproject/onos-lib-go/pkg/controller"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 )
 
 const queueSize = 100
 
 // Watcher is a endpoint watcher
+// The watch is supervised under the given name, which must be unique to the controller.
 type Watcher struct {
+	name      string
 	endpoints endpoint.Store
 	cancel    context.CancelFunc
 	mu        sync.Mutex
@@ -33,20 +35,23 @@ func (w *Watcher) Start(ch chan<- controller.ID) error {
 		return nil
 	}
 
-	endpointCh := make(chan epapi.Event, queueSize)
 	ctx, cancel := context.WithCancel(context.Background())
-	err := w.endpoints.Watch(ctx, endpointCh)
-	if err != nil {
-		cancel()
-		return err
-	}
 	w.cancel = cancel
 
 	go func() {
-		for request := range endpointCh {
-			ch <- controller.NewID(request.Endpoint.ID)
-		}
-		close(ch)
+		defer close(ch)
+		supervisor.Watch(ctx, w.name, func(ctx context.Context, resync bool, ready func()) error {
+			// Replay existing endpoints to clean up those orphaned while the controller was down
+			endpointCh := make(chan epapi.Event, queueSize)
+			if err := w.endpoints.Watch(ctx, endpointCh, endpoint.WithReplay()); err != nil {
+				return err
+			}
+			ready()
+			for request := range endpointCh {
+				ch <- controller.NewID(request.Endpoint.ID)
+			}
+			return nil
+		})
 	}()
 	return nil
 }
@@ -64,12 +69,11 @@ func (w *Watcher) Stop() {
 var _ controller.Watcher = &Watcher{}
 
 // PodWatcher is a pod watcher
+// The watcher runs the shared pod informer and enqueues the endpoint of each pod that changes.
 type PodWatcher struct {
-	client    *kubernetes.Clientset
-	namespace string
-	endpoints endpoint.Store
-	cancel    context.CancelFunc
-	mu        sync.Mutex
+	pods   cache.SharedIndexInformer
+	cancel context.CancelFunc
+	mu     sync.Mutex
 }
 
 // Start starts the pod watcher
@@ -81,29 +85,30 @@ func (w *PodWatcher) Start(ch chan<- controller.ID) error {
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
-	watch, err := w.client.CoreV1().Pods(w.namespace).Watch(metav1.ListOptions{})
-	if err != nil {
-		cancel()
-		return err
-	}
 	w.cancel = cancel
 
-	go func() {
-		<-ctx.Done()
-		watch.Stop()
-	}()
-
-	go func() {
-		for event := range watch.ResultChan() {
-			pod := event.Object.(*corev1.Pod)
-			endpoint, err := w.endpoints.Get(ctx, epapi.ID(pod.Name))
-			if err != nil {
-				log.Error(err)
-			} else {
-				ch <- controller.NewID(endpoint.ID)
+	enqueue := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		if pod, ok := obj.(*corev1.Pod); ok {
+			select {
+			case ch <- controller.NewID(epapi.ID(pod.Name)):
+			case <-ctx.Done():
 			}
 		}
-		close(ch)
+	}
+	w.pods.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueue,
+		UpdateFunc: func(_, obj interface{}) {
+			enqueue(obj)
+		},
+		DeleteFunc: enqueue,
+	})
+
+	go func() {
+		defer close(ch)
+		supervisor.WatchInformer(ctx, "endpoint/pods", w.pods)
 	}()
 	return nil
 }
@@ -0,0 +1,114 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package node
+
+import (
+	"context"
+	"time"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-e2sub/pkg/store/node"
+	"github.com/onosproject/onos-lib-go/pkg/controller"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+)
+
+var log = logging.GetLogger("controller", "node")
+
+const defaultTimeout = 30 * time.Second
+
+// NewController returns a new E2 node controller
+// The controller tracks the connectivity of the E2 nodes in onos-topo.
+func NewController(nodes node.Store, client topoapi.TopoClient) *controller.Controller {
+	c := controller.NewController("E2Node")
+	c.Watch(&TopoWatcher{
+		client: client,
+		nodes:  nodes,
+	})
+	c.Reconcile(&Reconciler{
+		nodes:  nodes,
+		client: client,
+	})
+	return c
+}
+
+// Reconciler is an E2 node reconciler
+type Reconciler struct {
+	nodes  node.Store
+	client topoapi.TopoClient
+}
+
+// Reconcile reconciles the connectivity of an E2 node
+func (r *Reconciler) Reconcile(id controller.ID) (controller.Result, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	nodeID := id.Value.(node.ID)
+	connected := false
+	res, err := r.client.Get(ctx, &topoapi.GetRequest{ID: topoapi.ID(nodeID)})
+	if err != nil {
+		err = errors.FromGRPC(err)
+		if !errors.IsNotFound(err) {
+			log.Warnf("Failed to reconcile E2 node %s: %s", nodeID, err)
+			return controller.Result{}, err
+		}
+	} else if res.Object != nil {
+		connected = isConnected(res.Object)
+	}
+
+	n, err := r.nodes.Get(ctx, nodeID)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Warnf("Failed to reconcile E2 node %s: %s", nodeID, err)
+			return controller.Result{}, err
+		}
+		n = &node.Node{
+			ID:        nodeID,
+			Connected: connected,
+			Updated:   time.Now(),
+		}
+		log.Infof("Adding E2 node %+v", n)
+		if err := r.nodes.Create(ctx, n); err != nil && !errors.IsAlreadyExists(err) {
+			log.Warnf("Failed to reconcile E2 node %s: %s", nodeID, err)
+			return controller.Result{}, err
+		}
+		return controller.Result{}, nil
+	}
+
+	if n.Connected != connected {
+		n.Connected = connected
+		n.Updated = time.Now()
+		log.Infof("Updating E2 node %+v", n)
+		if err := r.nodes.Update(ctx, n); err != nil {
+			log.Warnf("Failed to reconcile E2 node %s: %s", nodeID, err)
+			return controller.Result{}, err
+		}
+	}
+	return controller.Result{}, nil
+}
+
+// isE2Node returns whether the given topo object is an E2 node
+func isE2Node(object *topoapi.Object) bool {
+	return getE2APState(object) != nil
+}
+
+// isConnected returns whether the given topo object is a connected E2 node
+func isConnected(object *topoapi.Object) bool {
+	state := getE2APState(object)
+	return state != nil && state.ChannelState == topoapi.ChannelState_CONNECTED
+}
+
+// getE2APState returns the E2AP protocol state of the given topo object
+func getE2APState(object *topoapi.Object) *topoapi.ProtocolState {
+	if object.Type != topoapi.Object_ENTITY || object.GetEntity() == nil {
+		return nil
+	}
+	for _, state := range object.GetEntity().Protocols {
+		if state != nil && state.Protocol == topoapi.Protocol_E2AP {
+			return state
+		}
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package node
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+	nodestore "github.com/onosproject/onos-e2sub/pkg/store/node"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testTopo is a minimal onos-topo server
+type testTopo struct {
+	topoapi.UnimplementedTopoServer
+	objects  map[topoapi.ID]*topoapi.Object
+	watchers map[chan topoapi.Event]chan struct{}
+	mu       sync.Mutex
+}
+
+func (s *testTopo) Get(ctx context.Context, req *topoapi.GetRequest) (*topoapi.GetResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	object, ok := s.objects[req.ID]
+	if !ok {
+		return nil, errors.Status(errors.NewNotFound("object %s not found", req.ID)).Err()
+	}
+	return &topoapi.GetResponse{Object: object}, nil
+}
+
+func (s *testTopo) List(ctx context.Context, req *topoapi.ListRequest) (*topoapi.ListResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	objects := make([]topoapi.Object, 0, len(s.objects))
+	for _, object := range s.objects {
+		objects = append(objects, *object)
+	}
+	return &topoapi.ListResponse{Objects: objects}, nil
+}
+
+func (s *testTopo) Watch(req *topoapi.WatchRequest, server topoapi.Topo_WatchServer) error {
+	ch := make(chan topoapi.Event)
+	done := make(chan struct{})
+	s.mu.Lock()
+	s.watchers[ch] = done
+	s.mu.Unlock()
+	for {
+		select {
+		case event := <-ch:
+			if err := server.Send(&topoapi.WatchResponse{Event: event}); err != nil {
+				return err
+			}
+		case <-done:
+			return errors.Status(errors.NewUnavailable("watch closed")).Err()
+		case <-server.Context().Done():
+			return nil
+		}
+	}
+}
+
+func (s *testTopo) set(object *topoapi.Object) {
+	s.mu.Lock()
+	s.objects[object.ID] = object
+	watchers := make(map[chan topoapi.Event]chan struct{})
+	for ch, done := range s.watchers {
+		watchers[ch] = done
+	}
+	s.mu.Unlock()
+	for ch, done := range watchers {
+		select {
+		case ch <- topoapi.Event{Type: topoapi.EventType_UPDATED, Object: *object}:
+		case <-done:
+		}
+	}
+}
+
+// disconnect ends the open watch streams
+func (s *testTopo) disconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch, done := range s.watchers {
+		close(done)
+		delete(s.watchers, ch)
+	}
+}
+
+func (s *testTopo) watching() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.watchers) > 0
+}
+
+func newE2Node(id topoapi.ID, state topoapi.ChannelState) *topoapi.Object {
+	return &topoapi.Object{
+		ID:   id,
+		Type: topoapi.Object_ENTITY,
+		Obj: &topoapi.Object_Entity{
+			Entity: &topoapi.Entity{
+				Protocols: []*topoapi.ProtocolState{
+					{
+						Protocol:     topoapi.Protocol_E2AP,
+						ChannelState: state,
+					},
+				},
+			},
+		},
+	}
+}
+
+func newTopoClient(t *testing.T, topo *testTopo) topoapi.TopoClient {
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	topoapi.RegisterTopoServer(server, topo)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet", grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}), grpc.WithInsecure())
+	assert.NoError(t, err)
+	return topoapi.NewTopoClient(conn)
+}
+
+func nextNodeEvent(t *testing.T, ch chan nodestore.Event) nodestore.Event {
+	t.Helper()
+	var event nodestore.Event
+	select {
+	case event = <-ch:
+		break
+	case <-time.After(15 * time.Second):
+		t.Error("Node Event channel timed out")
+		break
+	}
+	return event
+}
+
+func TestNodeConnectivity(t *testing.T) {
+	nodeStore, err := nodestore.NewLocalStore()
+	assert.NoError(t, err)
+	defer nodeStore.Close()
+
+	topo := &testTopo{
+		objects:  make(map[topoapi.ID]*topoapi.Object),
+		watchers: make(map[chan topoapi.Event]chan struct{}),
+	}
+	cntrl := NewController(nodeStore, newTopoClient(t, topo))
+	assert.NoError(t, cntrl.Start())
+	defer cntrl.Stop()
+
+	nodeCh := make(chan nodestore.Event)
+	assert.NoError(t, nodeStore.Watch(context.TODO(), nodeCh))
+
+	// Wait for the controller to watch the topology
+	assert.Eventually(t, topo.watching, 5*time.Second, 10*time.Millisecond)
+
+	// Verify a connected E2 node is added
+	topo.set(newE2Node("e2node-1", topoapi.ChannelState_CONNECTED))
+	event := nextNodeEvent(t, nodeCh)
+	assert.Equal(t, nodestore.EventCreated, event.Type)
+	assert.Equal(t, nodestore.ID("e2node-1"), event.Node.ID)
+	assert.True(t, event.Node.Connected)
+
+	// Verify objects that are not E2 nodes are ignored, and a disconnected E2 node is updated
+	topo.set(&topoapi.Object{ID: "relation-1", Type: topoapi.Object_RELATION, Obj: &topoapi.Object_Relation{Relation: &topoapi.Relation{}}})
+	topo.set(newE2Node("e2node-1", topoapi.ChannelState_DISCONNECTED))
+	event = nextNodeEvent(t, nodeCh)
+	assert.Equal(t, nodestore.EventUpdated, event.Type)
+	assert.Equal(t, nodestore.ID("e2node-1"), event.Node.ID)
+	assert.False(t, event.Node.Connected)
+}
+
+func TestTopoReconnect(t *testing.T) {
+	nodeStore, err := nodestore.NewLocalStore()
+	assert.NoError(t, err)
+	defer nodeStore.Close()
+
+	topo := &testTopo{
+		objects:  make(map[topoapi.ID]*topoapi.Object),
+		watchers: make(map[chan topoapi.Event]chan struct{}),
+	}
+	topo.set(newE2Node("e2node-1", topoapi.ChannelState_CONNECTED))
+	topo.set(newE2Node("e2node-2", topoapi.ChannelState_CONNECTED))
+
+	nodeCh := make(chan nodestore.Event)
+	assert.NoError(t, nodeStore.Watch(context.TODO(), nodeCh))
+
+	cntrl := NewController(nodeStore, newTopoClient(t, topo))
+	assert.NoError(t, cntrl.Start())
+	defer cntrl.Stop()
+
+	// Verify the E2 nodes in onos-topo are listed once the watch is established
+	event := nextNodeEvent(t, nodeCh)
+	assert.Equal(t, nodestore.EventCreated, event.Type)
+	event = nextNodeEvent(t, nodeCh)
+	assert.Equal(t, nodestore.EventCreated, event.Type)
+	assert.Eventually(t, topo.watching, 5*time.Second, 10*time.Millisecond)
+
+	// Change the topology while the watch is down
+	topo.disconnect()
+	topo.mu.Lock()
+	topo.objects["e2node-1"] = newE2Node("e2node-1", topoapi.ChannelState_DISCONNECTED)
+	delete(topo.objects, "e2node-2")
+	topo.mu.Unlock()
+
+	// Verify the missed changes are reconciled once the watch is re-established
+	disconnected := make(map[nodestore.ID]bool)
+	for len(disconnected) < 2 {
+		event = nextNodeEvent(t, nodeCh)
+		if event.Type == nodestore.EventNone {
+			break
+		}
+		assert.Equal(t, nodestore.EventUpdated, event.Type)
+		assert.False(t, event.Node.Connected)
+		disconnected[event.Node.ID] = true
+	}
+	assert.True(t, disconnected["e2node-1"])
+	assert.True(t, disconnected["e2node-2"])
+}
@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package node
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-e2sub/pkg/controller/supervisor"
+	"github.com/onosproject/onos-e2sub/pkg/store/node"
+	"github.com/onosproject/onos-lib-go/pkg/controller"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// TopoWatcher is an onos-topo E2 node watcher
+// Whenever the watch is established, the E2 nodes in onos-topo and in the node store are listed
+// and enqueued, so that changes missed while the watch was down, including the removal of nodes
+// from onos-topo, are reconciled.
+type TopoWatcher struct {
+	client topoapi.TopoClient
+	nodes  node.Store
+	cancel context.CancelFunc
+	mu     sync.Mutex
+}
+
+// Start starts the topo watcher
+func (w *TopoWatcher) Start(ch chan<- controller.ID) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	go func() {
+		defer close(ch)
+		supervisor.Watch(ctx, "node/topo", func(ctx context.Context, resync bool, ready func()) error {
+			// List the nodes after starting the watch to ensure no changes are missed
+			stream, err := w.client.Watch(ctx, &topoapi.WatchRequest{Noreplay: true})
+			if err != nil {
+				return err
+			}
+			ids, err := w.list(ctx)
+			if err != nil {
+				return err
+			}
+			ready()
+			for _, id := range ids {
+				ch <- controller.NewID(id)
+			}
+
+			for {
+				res, err := stream.Recv()
+				if err == io.EOF || ctx.Err() != nil {
+					return nil
+				} else if err != nil {
+					return err
+				}
+				if isE2Node(&res.Event.Object) {
+					ch <- controller.NewID(node.ID(res.Event.Object.ID))
+				}
+			}
+		})
+	}()
+	return nil
+}
+
+// list lists the IDs of the E2 nodes in onos-topo and in the node store
+func (w *TopoWatcher) list(ctx context.Context) ([]node.ID, error) {
+	res, err := w.client.List(ctx, &topoapi.ListRequest{})
+	if err != nil {
+		return nil, errors.FromGRPC(err)
+	}
+	listed := make(map[node.ID]bool)
+	var ids []node.ID
+	for i := range res.Objects {
+		if isE2Node(&res.Objects[i]) {
+			id := node.ID(res.Objects[i].ID)
+			listed[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	nodes, err := w.nodes.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range nodes {
+		if !listed[n.ID] {
+			ids = append(ids, n.ID)
+		}
+	}
+	return ids, nil
+}
+
+// Stop stops the topo watcher
+func (w *TopoWatcher) Stop() {
+	w.mu.Lock()
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+	w.mu.Unlock()
+}
+
+var _ controller.Watcher = &TopoWatcher{}
@@ -0,0 +1,193 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package owner
+
+import (
+	"context"
+	"time"
+
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/controller/scheduler"
+	"github.com/onosproject/onos-e2sub/pkg/store/history"
+	"github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	"github.com/onosproject/onos-e2sub/pkg/store/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/store/txn"
+	"github.com/onosproject/onos-lib-go/pkg/controller"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+var log = logging.GetLogger("controller", "owner")
+
+const defaultTimeout = 30 * time.Second
+
+// NewController returns a new subscription owner controller
+// The controller deletes subscriptions whose owner pod has been missing for longer than the grace period.
+// Owner pods are read from a shared informer cache of each of the given namespaces; subscriptions owned
+// by pods in other namespaces are never deleted.
+func NewController(subs subscription.Store, metadata metadata.Store, transactions txn.Store, client kubernetes.Interface, namespaces []string, gracePeriod time.Duration) *controller.Controller {
+	informers := make(map[string]cache.SharedIndexInformer)
+	listers := make(map[string]corelisters.PodNamespaceLister)
+	for _, namespace := range namespaces {
+		informer := newPodInformer(client, namespace)
+		informers[namespace] = informer
+		listers[namespace] = corelisters.NewPodLister(informer.GetIndexer()).Pods(namespace)
+	}
+	owners := newIndex()
+
+	c := controller.NewController("Owner")
+	requeues := scheduler.NewScheduler()
+	c.Watch(requeues)
+	c.Watch(&Watcher{
+		metadata: metadata,
+		index:    owners,
+	})
+	c.Watch(&PodWatcher{
+		pods:  informers,
+		index: owners,
+	})
+	c.Reconcile(&Reconciler{
+		subs:     subs,
+		metadata: metadata,
+		transactor: txn.NewTransactor(transactions, txn.Stores{
+			Subscriptions: subs,
+			Metadata:      metadata,
+		}),
+		informers:   informers,
+		pods:        listers,
+		gracePeriod: gracePeriod,
+		scheduler:   requeues,
+	})
+	return c
+}
+
+// newPodInformer returns a shared informer for the pods in the namespace
+func newPodInformer(client kubernetes.Interface, namespace string) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return client.CoreV1().Pods(namespace).List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return client.CoreV1().Pods(namespace).Watch(options)
+			},
+		},
+		&corev1.Pod{},
+		0,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+}
+
+// Reconciler is a subscription owner reconciler
+type Reconciler struct {
+	subs        subscription.Store
+	metadata    metadata.Store
+	transactor  *txn.Transactor
+	informers   map[string]cache.SharedIndexInformer
+	pods        map[string]corelisters.PodNamespaceLister
+	gracePeriod time.Duration
+	scheduler   *scheduler.Scheduler
+}
+
+// Reconcile reconciles the owner of a subscription
+func (r *Reconciler) Reconcile(id controller.ID) (controller.Result, error) {
+	ctx, cancel := context.WithTimeout(history.WithCaller(context.Background(), "controller/owner"), defaultTimeout)
+	defer cancel()
+
+	meta, err := r.metadata.Get(ctx, id.Value.(subapi.ID))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return controller.Result{}, nil
+		}
+		return controller.Result{}, err
+	}
+
+	if meta.Owner == nil {
+		return controller.Result{}, nil
+	}
+
+	sub, err := r.subs.Get(ctx, meta.ID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return controller.Result{}, nil
+		}
+		return controller.Result{}, err
+	}
+
+	if sub.Lifecycle.Status != subapi.Status_ACTIVE {
+		return controller.Result{}, nil
+	}
+
+	// Pods in namespaces that are not watched cannot be found missing
+	pods, ok := r.pods[meta.Owner.Namespace]
+	if !ok {
+		log.Warnf("Not reconciling owner %s of Subscription %+v: namespace %s is not watched", meta.Owner, sub, meta.Owner.Namespace)
+		return controller.Result{}, nil
+	}
+
+	// Pods missing from a cache that has not synced may still exist
+	if !r.informers[meta.Owner.Namespace].HasSynced() {
+		return controller.Result{}, errors.NewUnavailable("pod cache has not synced")
+	}
+
+	log.Infof("Reconciling owner %s of Subscription %+v", meta.Owner, sub)
+
+	// Get the owner pod from the cache
+	_, err = pods.Get(meta.Owner.Name)
+	if err == nil {
+		// If the owner was previously lost and has returned, clear the lost time
+		if !meta.Owner.Lost.IsZero() {
+			log.Infof("Found owner %s of Subscription %+v", meta.Owner, sub)
+			meta.Owner.Lost = time.Time{}
+			tx := r.transactor.Begin()
+			tx.UpdateMetadata(meta)
+			if err := tx.Commit(ctx); err != nil {
+				log.Warnf("Failed to reconcile owner %s of Subscription %+v: %s", meta.Owner, sub, err)
+				return controller.Result{}, err
+			}
+		}
+		return controller.Result{}, nil
+	} else if !k8serrors.IsNotFound(err) {
+		log.Warnf("Failed to reconcile owner %s of Subscription %+v: %s", meta.Owner, sub, err)
+		return controller.Result{}, err
+	}
+
+	// If the owner was not already lost, record the time it was lost and wait for the grace period
+	if meta.Owner.Lost.IsZero() {
+		log.Infof("Lost owner %s of Subscription %+v", meta.Owner, sub)
+		meta.Owner.Lost = time.Now()
+		tx := r.transactor.Begin()
+		tx.UpdateMetadata(meta)
+		if err := tx.Commit(ctx); err != nil {
+			log.Warnf("Failed to reconcile owner %s of Subscription %+v: %s", meta.Owner, sub, err)
+			return controller.Result{}, err
+		}
+	}
+
+	expiry := meta.Owner.Lost.Add(r.gracePeriod)
+	if time.Now().Before(expiry) {
+		r.scheduler.RequeueAt(id, expiry)
+		return controller.Result{}, nil
+	}
+
+	// Once the grace period has expired, delete the orphaned subscription
+	log.Infof("Deleting orphaned Subscription %+v", sub)
+	sub.Lifecycle.Status = subapi.Status_PENDING_DELETE
+	tx := r.transactor.Begin()
+	tx.UpdateSubscription(sub)
+	if err := tx.Commit(ctx); err != nil {
+		log.Warnf("Failed to delete orphaned Subscription %+v: %s", sub, err)
+		return controller.Result{}, err
+	}
+	return controller.Result{}, nil
+}
@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package owner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	metastore "github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	substore "github.com/onosproject/onos-e2sub/pkg/store/subscription"
+	txnstore "github.com/onosproject/onos-e2sub/pkg/store/txn"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const gracePeriod = time.Second
+
+func createPod(namespace, name string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+	}
+}
+
+func createSubscription(subID string) subapi.Subscription {
+	return subapi.Subscription{
+		ID:    subapi.ID(subID),
+		AppID: "app1",
+		Details: &subapi.SubscriptionDetails{
+			E2NodeID: "e2node1",
+		},
+		Lifecycle: subapi.Lifecycle{Status: subapi.Status_ACTIVE},
+	}
+}
+
+func nextSubEvent(t *testing.T, ch chan subapi.Event) subapi.Event {
+	t.Helper()
+	var event subapi.Event
+	select {
+	case event = <-ch:
+		break
+	case <-time.After(15 * time.Second):
+		t.Error("Sub Event channel timed out")
+		break
+	}
+	return event
+}
+
+func TestOrphanedSubscription(t *testing.T) {
+	subStore, err := substore.NewLocalStore()
+	assert.NoError(t, err)
+	defer subStore.Close()
+
+	metaStore, err := metastore.NewLocalStore()
+	assert.NoError(t, err)
+	defer metaStore.Close()
+
+	txnStore, err := txnstore.NewLocalStore()
+	assert.NoError(t, err)
+	defer txnStore.Close()
+
+	client := fake.NewSimpleClientset(createPod("test", "xapp-1"), createPod("test", "xapp-2"))
+
+	cntrl := NewController(subStore, metaStore, txnStore, client, []string{"test"}, gracePeriod)
+	assert.NoError(t, cntrl.Start())
+	defer cntrl.Stop()
+
+	// Create subscriptions owned by each pod
+	for _, owner := range []string{"xapp-1", "xapp-2"} {
+		meta := &metastore.Metadata{
+			ID: subapi.ID(owner),
+			Owner: &metastore.Owner{
+				Namespace: "test",
+				Name:      owner,
+			},
+		}
+		assert.NoError(t, metaStore.Create(context.TODO(), meta))
+		sub := createSubscription(owner)
+		assert.NoError(t, subStore.Create(context.TODO(), &sub))
+	}
+
+	// Create a subscription owned by a pod in a namespace that is not watched
+	meta := &metastore.Metadata{
+		ID: "xapp-3",
+		Owner: &metastore.Owner{
+			Namespace: "other",
+			Name:      "xapp-3",
+		},
+	}
+	assert.NoError(t, metaStore.Create(context.TODO(), meta))
+	sub := createSubscription("xapp-3")
+	assert.NoError(t, subStore.Create(context.TODO(), &sub))
+
+	subCh := make(chan subapi.Event)
+	assert.NoError(t, subStore.Watch(context.TODO(), subCh))
+
+	// Delete the owner of the first subscription and verify it's deleted after the grace period
+	deleted := time.Now()
+	assert.NoError(t, client.CoreV1().Pods("test").Delete("xapp-1", &metav1.DeleteOptions{}))
+
+	event := nextSubEvent(t, subCh)
+	assert.Equal(t, subapi.EventType_UPDATED, event.Type)
+	assert.Equal(t, subapi.ID("xapp-1"), event.Subscription.ID)
+	assert.Equal(t, subapi.Status_PENDING_DELETE, event.Subscription.Lifecycle.Status)
+	assert.True(t, time.Since(deleted) >= gracePeriod)
+
+	// Delete the owner of the second subscription and recreate it within the grace period
+	assert.NoError(t, client.CoreV1().Pods("test").Delete("xapp-2", &metav1.DeleteOptions{}))
+	time.Sleep(gracePeriod / 2)
+	_, err = client.CoreV1().Pods("test").Create(createPod("test", "xapp-2"))
+	assert.NoError(t, err)
+
+	// Verify the second and third subscriptions are retained
+	select {
+	case event := <-subCh:
+		t.Errorf("unexpected event %+v", event)
+	case <-time.After(2 * gracePeriod):
+	}
+	for _, id := range []subapi.ID{"xapp-2", "xapp-3"} {
+		sub, err := subStore.Get(context.TODO(), id)
+		assert.NoError(t, err)
+		assert.Equal(t, subapi.Status_ACTIVE, sub.Lifecycle.Status)
+	}
+}
@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package owner
+
+import (
+	"sync"
+
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/store/metadata"
+)
+
+// newIndex returns a new owner index
+func newIndex() *index {
+	return &index{
+		subs:   make(map[string]map[subapi.ID]bool),
+		owners: make(map[subapi.ID]string),
+	}
+}
+
+// index indexes the subscriptions by the namespace/name of their owner pod
+type index struct {
+	subs   map[string]map[subapi.ID]bool
+	owners map[subapi.ID]string
+	mu     sync.RWMutex
+}
+
+// update indexes the owner of the given subscription metadata
+func (i *index) update(meta *metadata.Metadata) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.remove(meta.ID)
+	if meta.Owner == nil {
+		return
+	}
+	owner := meta.Owner.String()
+	subs, ok := i.subs[owner]
+	if !ok {
+		subs = make(map[subapi.ID]bool)
+		i.subs[owner] = subs
+	}
+	subs[meta.ID] = true
+	i.owners[meta.ID] = owner
+}
+
+// delete removes the given subscription from the index
+func (i *index) delete(id subapi.ID) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.remove(id)
+}
+
+// remove removes the given subscription from the index while the lock is held
+func (i *index) remove(id subapi.ID) {
+	owner, ok := i.owners[id]
+	if !ok {
+		return
+	}
+	delete(i.owners, id)
+	delete(i.subs[owner], id)
+	if len(i.subs[owner]) == 0 {
+		delete(i.subs, owner)
+	}
+}
+
+// reset removes all subscriptions from the index
+func (i *index) reset() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.subs = make(map[string]map[subapi.ID]bool)
+	i.owners = make(map[subapi.ID]string)
+}
+
+// get returns the IDs of the subscriptions owned by the given pod
+func (i *index) get(namespace, name string) []subapi.ID {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	subs := i.subs[namespace+"/"+name]
+	ids := make([]subapi.ID, 0, len(subs))
+	for id := range subs {
+		ids = append(ids, id)
+	}
+	return ids
+}
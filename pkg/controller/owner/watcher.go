@@ -0,0 +1,150 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package owner
+
+import (
+	"context"
+	"sync"
+
+	"github.com/onosproject/onos-e2sub/pkg/controller/supervisor"
+	"github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	"github.com/onosproject/onos-lib-go/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const queueSize = 100
+
+// Watcher is a subscription metadata watcher
+// The watcher maintains the index of subscriptions by owner from which the PodWatcher enqueues the
+// subscriptions owned by a pod.
+type Watcher struct {
+	metadata metadata.Store
+	index    *index
+	cancel   context.CancelFunc
+	mu       sync.Mutex
+}
+
+// Start starts the metadata watcher
+func (w *Watcher) Start(ch chan<- controller.ID) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	go func() {
+		defer close(ch)
+		supervisor.Watch(ctx, "owner/metadata", func(ctx context.Context, resync bool, ready func()) error {
+			metaCh := make(chan metadata.Event, queueSize)
+			if err := w.metadata.Watch(ctx, metaCh, metadata.WithReplay()); err != nil {
+				return err
+			}
+			// The replay rebuilds the index, dropping the metadata removed while the watch was down
+			w.index.reset()
+			ready()
+			for event := range metaCh {
+				if event.Type == metadata.EventRemoved {
+					w.index.delete(event.Metadata.ID)
+					continue
+				}
+				w.index.update(&event.Metadata)
+				if event.Metadata.Owner != nil {
+					ch <- controller.NewID(event.Metadata.ID)
+				}
+			}
+			return nil
+		})
+	}()
+	return nil
+}
+
+// Stop stops the metadata watcher
+func (w *Watcher) Stop() {
+	w.mu.Lock()
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+	w.mu.Unlock()
+}
+
+var _ controller.Watcher = &Watcher{}
+
+// PodWatcher is an owner pod watcher
+// The watcher runs the shared informers of the owner namespaces and enqueues the subscriptions
+// owned by each pod that changes.
+type PodWatcher struct {
+	pods   map[string]cache.SharedIndexInformer
+	index  *index
+	cancel context.CancelFunc
+	mu     sync.Mutex
+}
+
+// Start starts the pod watcher
+func (w *PodWatcher) Start(ch chan<- controller.ID) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	enqueue := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			return
+		}
+		for _, id := range w.index.get(pod.Namespace, pod.Name) {
+			select {
+			case ch <- controller.NewID(id):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	for namespace, pods := range w.pods {
+		pods.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: enqueue,
+			UpdateFunc: func(_, obj interface{}) {
+				enqueue(obj)
+			},
+			DeleteFunc: enqueue,
+		})
+		wg.Add(1)
+		go func(namespace string, pods cache.SharedIndexInformer) {
+			defer wg.Done()
+			supervisor.WatchInformer(ctx, "owner/pods/"+namespace, pods)
+		}(namespace, pods)
+	}
+
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+	return nil
+}
+
+// Stop stops the pod watcher
+func (w *PodWatcher) Stop() {
+	w.mu.Lock()
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+	w.mu.Unlock()
+}
+
+var _ controller.Watcher = &PodWatcher{}
@@ -0,0 +1,216 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package placement
+
+import (
+	"context"
+	"fmt"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	taskapi "github.com/onosproject/onos-api/go/onos/e2sub/task"
+	"github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	"github.com/onosproject/onos-e2sub/pkg/store/node"
+	"github.com/onosproject/onos-e2sub/pkg/store/termination"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// Tasks lists the subscription tasks determining the load of the endpoints
+// The task store is a source of tasks, as is a cache of the tasks maintained from a watch.
+type Tasks interface {
+	// List lists the subscription tasks
+	List(ctx context.Context) ([]taskapi.SubscriptionTask, error)
+}
+
+// NewPlacer returns a new Placer
+// If the E2 node store is nil, subscriptions are placed regardless of the connectivity of their E2 node.
+func NewPlacer(endpoints endpoint.Store, terminations termination.Store, tasks Tasks, nodes node.Store) *Placer {
+	return &Placer{
+		endpoints:    endpoints,
+		terminations: terminations,
+		tasks:        tasks,
+		nodes:        nodes,
+	}
+}
+
+// Placer decides the termination endpoint on which to place a subscription
+// The Placer only reads the stores, so a decision can be previewed without placing the subscription.
+type Placer struct {
+	endpoints    endpoint.Store
+	terminations termination.Store
+	tasks        Tasks
+	nodes        node.Store
+}
+
+// Decision is the placement decision for a subscription
+type Decision struct {
+	// Endpoint is the termination endpoint on which to place the subscription
+	// If the subscription cannot be placed, Endpoint is nil.
+	Endpoint *epapi.TerminationEndpoint
+	// Condition is the condition preventing the subscription from being placed
+	// An evicted subscription kept on its endpoint also has a condition, preventing its migration.
+	Condition metadata.Condition
+	// Reason is the reason for the condition
+	Reason string
+}
+
+// unplaced returns the decision for a subscription that cannot be placed
+func unplaced(condition metadata.Condition, reason string) *Decision {
+	return &Decision{
+		Condition: condition,
+		Reason:    reason,
+	}
+}
+
+// Place decides the termination endpoint on which to place the subscription
+// The subscription's E2 node must be connected. Endpoints are filtered by the subscription's E2 node,
+// service model and placement constraints, by their health, and by whether they're cordoned or have
+// spare capacity. The endpoint already hosting the subscription is preferred unless the subscription
+// has been evicted from it, then the least loaded endpoint. If no endpoint can accept the
+// subscription, the decision holds the condition preventing it from being placed; an evicted
+// subscription is then kept on its current endpoint.
+func (p *Placer) Place(ctx context.Context, sub *subapi.Subscription, meta *metadata.Metadata) (*Decision, error) {
+	// If the subscription's E2 node is not connected, it cannot be placed until the node connects
+	if p.nodes != nil {
+		n, err := p.nodes.Get(ctx, node.ID(sub.Details.E2NodeID))
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		if n == nil || !n.Connected {
+			return unplaced(metadata.ConditionWaitingForNode, fmt.Sprintf("E2 node %s is not connected", sub.Details.E2NodeID)), nil
+		}
+	}
+
+	endpoints, err := p.endpoints.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(endpoints) == 0 {
+		return unplaced(metadata.ConditionUnschedulable, "no termination endpoints are registered"), nil
+	}
+
+	terminations, err := p.terminations.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	terms := make(map[epapi.ID]*termination.Termination)
+	reportsNodes := false
+	for i, term := range terminations {
+		terms[term.ID] = &terminations[i]
+		if term.Nodes != nil {
+			reportsNodes = true
+		}
+	}
+	getTermination := func(id epapi.ID) *termination.Termination {
+		if term, ok := terms[id]; ok {
+			return term
+		}
+		return &termination.Termination{ID: id}
+	}
+
+	// If terminations report their E2 nodes, only consider the endpoints connected to the subscription's node
+	if reportsNodes {
+		connected := make([]epapi.TerminationEndpoint, 0, len(endpoints))
+		for _, ep := range endpoints {
+			if getTermination(ep.ID).HasNode(string(sub.Details.E2NodeID)) {
+				connected = append(connected, ep)
+			}
+		}
+		if len(connected) == 0 {
+			return unplaced(metadata.ConditionWaitingForNode, fmt.Sprintf("E2 node %s is not connected to any termination", sub.Details.E2NodeID)), nil
+		}
+		endpoints = connected
+	}
+
+	// Filter the endpoints by service model and placement constraints
+	var placement metadata.Placement
+	if meta != nil && meta.Placement != nil {
+		placement = *meta.Placement
+	}
+	compatible := make([]epapi.TerminationEndpoint, 0, len(endpoints))
+	for _, ep := range endpoints {
+		term := getTermination(ep.ID)
+		if term.SupportsServiceModel(sub.Details.ServiceModel) && term.Matches(placement.Zone, placement.Labels) {
+			compatible = append(compatible, ep)
+		}
+	}
+	if len(compatible) == 0 {
+		reason := fmt.Sprintf("no termination endpoint supports service model %s/%s", sub.Details.ServiceModel.Name, sub.Details.ServiceModel.Version)
+		if placement.Zone != "" || len(placement.Labels) > 0 {
+			reason += " and matches the subscription's placement constraints"
+		}
+		return unplaced(metadata.ConditionUnschedulable, reason), nil
+	}
+
+	// Unhealthy endpoints are excluded, migrating the subscriptions they host to healthy endpoints
+	healthy := make([]epapi.TerminationEndpoint, 0, len(compatible))
+	for _, ep := range compatible {
+		if getTermination(ep.ID).IsHealthy() {
+			healthy = append(healthy, ep)
+		}
+	}
+	if len(healthy) == 0 {
+		return unplaced(metadata.ConditionUnschedulable, "all compatible termination endpoints are unhealthy"), nil
+	}
+	compatible = healthy
+
+	// Count the open tasks on each endpoint to determine its load
+	tasks, err := p.tasks.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	load := make(map[epapi.ID]int)
+	hosts := make(map[epapi.ID]bool)
+	for _, task := range tasks {
+		// Closing tasks do not host the subscription, e.g. while it's migrated off their endpoint
+		if task.Lifecycle.Phase != taskapi.Phase_OPEN {
+			continue
+		}
+		if task.SubscriptionID == sub.ID {
+			hosts[task.EndpointID] = true
+		} else {
+			load[task.EndpointID]++
+		}
+	}
+
+	var evicted epapi.ID
+	if meta != nil {
+		evicted = meta.Evicted
+	}
+
+	var selected, host *epapi.TerminationEndpoint
+	for i, ep := range compatible {
+		term := getTermination(ep.ID)
+
+		// Keep the subscription on its current endpoint unless it's being migrated off a cordoned endpoint
+		if hosts[ep.ID] {
+			if !(term.Cordoned && evicted == ep.ID) {
+				return &Decision{Endpoint: &compatible[i]}, nil
+			}
+			host = &compatible[i]
+		}
+
+		// Cordoned endpoints and endpoints at capacity accept no new subscriptions
+		if term.Cordoned || (term.Capacity > 0 && load[ep.ID] >= term.Capacity) {
+			continue
+		}
+		if selected == nil || load[ep.ID] < load[selected.ID] || (load[ep.ID] == load[selected.ID] && ep.ID < selected.ID) {
+			selected = &compatible[i]
+		}
+	}
+	if selected == nil {
+		// A subscription evicted from its endpoint stays there until another endpoint can accept it
+		if host != nil {
+			return &Decision{
+				Endpoint:  host,
+				Condition: metadata.ConditionUnschedulable,
+				Reason:    fmt.Sprintf("no other termination endpoint can accept the subscription evicted from %s", host.ID),
+			}, nil
+		}
+		return unplaced(metadata.ConditionUnschedulable, "all compatible termination endpoints are cordoned or at capacity"), nil
+	}
+	return &Decision{Endpoint: selected}, nil
+}
@@ -0,0 +1,167 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package placement
+
+import (
+	"context"
+	"testing"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	taskapi "github.com/onosproject/onos-api/go/onos/e2sub/task"
+	"github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	"github.com/onosproject/onos-e2sub/pkg/store/node"
+	"github.com/onosproject/onos-e2sub/pkg/store/task"
+	"github.com/onosproject/onos-e2sub/pkg/store/termination"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlace(t *testing.T) {
+	endpoints, err := endpoint.NewLocalStore()
+	assert.NoError(t, err)
+	defer endpoints.Close()
+	terminations, err := termination.NewLocalStore()
+	assert.NoError(t, err)
+	defer terminations.Close()
+	tasks, err := task.NewLocalStore()
+	assert.NoError(t, err)
+	defer tasks.Close()
+	nodes, err := node.NewLocalStore()
+	assert.NoError(t, err)
+	defer nodes.Close()
+	placer := NewPlacer(endpoints, terminations, tasks, nodes)
+	ctx := context.Background()
+
+	sub := &subapi.Subscription{
+		ID:    "sub-1",
+		AppID: "app-1",
+		Details: &subapi.SubscriptionDetails{
+			E2NodeID:     "e2-1",
+			ServiceModel: subapi.ServiceModel{Name: "sm1", Version: "v1"},
+		},
+	}
+
+	// Verify the subscription is not placed until its E2 node is connected
+	decision, err := placer.Place(ctx, sub, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, decision.Endpoint)
+	assert.Equal(t, metadata.ConditionWaitingForNode, decision.Condition)
+	assert.NoError(t, nodes.Create(ctx, &node.Node{ID: "e2-1", Connected: true}))
+
+	decision, err = placer.Place(ctx, sub, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, metadata.ConditionUnschedulable, decision.Condition)
+	assert.NotEmpty(t, decision.Reason)
+
+	for _, id := range []epapi.ID{"e2t-1", "e2t-2"} {
+		assert.NoError(t, endpoints.Create(ctx, &epapi.TerminationEndpoint{ID: id, IP: "127.0.0.1", Port: 5150}))
+	}
+	assert.NoError(t, terminations.Create(ctx, &termination.Termination{ID: "e2t-1", Zone: "zone-a", Capacity: 1}))
+	assert.NoError(t, terminations.Create(ctx, &termination.Termination{ID: "e2t-2", Zone: "zone-b"}))
+
+	// Verify the subscription is placed on the least loaded endpoint
+	assert.NoError(t, tasks.Create(ctx, &taskapi.SubscriptionTask{ID: "sub-2:e2t-1", SubscriptionID: "sub-2", EndpointID: "e2t-1"}))
+	decision, err = placer.Place(ctx, sub, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, epapi.ID("e2t-2"), decision.Endpoint.ID)
+
+	// Verify the placement constraints are applied and capacity is respected
+	meta := &metadata.Metadata{ID: sub.ID, Placement: &metadata.Placement{Zone: "zone-a"}}
+	decision, err = placer.Place(ctx, sub, meta)
+	assert.NoError(t, err)
+	assert.Nil(t, decision.Endpoint)
+	assert.Equal(t, metadata.ConditionUnschedulable, decision.Condition)
+
+	// Verify placing a subscription does not write to the stores
+	list, err := tasks.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+}
+
+func TestPlaceMigrating(t *testing.T) {
+	endpoints, err := endpoint.NewLocalStore()
+	assert.NoError(t, err)
+	defer endpoints.Close()
+	terminations, err := termination.NewLocalStore()
+	assert.NoError(t, err)
+	defer terminations.Close()
+	tasks, err := task.NewLocalStore()
+	assert.NoError(t, err)
+	defer tasks.Close()
+	placer := NewPlacer(endpoints, terminations, tasks, nil)
+	ctx := context.Background()
+
+	sub := &subapi.Subscription{
+		ID:    "sub-1",
+		AppID: "app-1",
+		Details: &subapi.SubscriptionDetails{
+			E2NodeID:     "e2-1",
+			ServiceModel: subapi.ServiceModel{Name: "sm1", Version: "v1"},
+		},
+	}
+	for _, id := range []epapi.ID{"e2t-1", "e2t-2"} {
+		assert.NoError(t, endpoints.Create(ctx, &epapi.TerminationEndpoint{ID: id, IP: "127.0.0.1", Port: 5150}))
+	}
+	assert.NoError(t, tasks.Create(ctx, &taskapi.SubscriptionTask{ID: "sub-2:e2t-2", SubscriptionID: "sub-2", EndpointID: "e2t-2"}))
+
+	// The subscription has been migrated off e2t-1, whose task is still closing
+	closing := &taskapi.SubscriptionTask{ID: "sub-1:e2t-1", SubscriptionID: sub.ID, EndpointID: "e2t-1"}
+	closing.Lifecycle.Phase = taskapi.Phase_CLOSE
+	assert.NoError(t, tasks.Create(ctx, closing))
+	assert.NoError(t, tasks.Create(ctx, &taskapi.SubscriptionTask{ID: "sub-1:e2t-2", SubscriptionID: sub.ID, EndpointID: "e2t-2"}))
+
+	// Verify the subscription stays on the endpoint of its open task, although e2t-1 is less loaded
+	decision, err := placer.Place(ctx, sub, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, epapi.ID("e2t-2"), decision.Endpoint.ID)
+}
+
+func TestPlaceEvicted(t *testing.T) {
+	endpoints, err := endpoint.NewLocalStore()
+	assert.NoError(t, err)
+	defer endpoints.Close()
+	terminations, err := termination.NewLocalStore()
+	assert.NoError(t, err)
+	defer terminations.Close()
+	tasks, err := task.NewLocalStore()
+	assert.NoError(t, err)
+	defer tasks.Close()
+	placer := NewPlacer(endpoints, terminations, tasks, nil)
+	ctx := context.Background()
+
+	sub := &subapi.Subscription{
+		ID:    "sub-1",
+		AppID: "app-1",
+		Details: &subapi.SubscriptionDetails{
+			E2NodeID:     "e2-1",
+			ServiceModel: subapi.ServiceModel{Name: "sm1", Version: "v1"},
+		},
+	}
+	for _, id := range []epapi.ID{"e2t-1", "e2t-2"} {
+		assert.NoError(t, endpoints.Create(ctx, &epapi.TerminationEndpoint{ID: id, IP: "127.0.0.1", Port: 5150}))
+	}
+	assert.NoError(t, terminations.Create(ctx, &termination.Termination{ID: "e2t-1", Cordoned: true}))
+	term2 := &termination.Termination{ID: "e2t-2", Capacity: 1}
+	assert.NoError(t, terminations.Create(ctx, term2))
+	assert.NoError(t, tasks.Create(ctx, &taskapi.SubscriptionTask{ID: "sub-1:e2t-1", SubscriptionID: sub.ID, EndpointID: "e2t-1"}))
+	assert.NoError(t, tasks.Create(ctx, &taskapi.SubscriptionTask{ID: "sub-2:e2t-2", SubscriptionID: "sub-2", EndpointID: "e2t-2"}))
+
+	// Verify the evicted subscription stays on its endpoint while no other endpoint has capacity
+	meta := &metadata.Metadata{ID: sub.ID, Evicted: "e2t-1"}
+	decision, err := placer.Place(ctx, sub, meta)
+	assert.NoError(t, err)
+	assert.Equal(t, epapi.ID("e2t-1"), decision.Endpoint.ID)
+	assert.Equal(t, metadata.ConditionUnschedulable, decision.Condition)
+	assert.NotEmpty(t, decision.Reason)
+
+	// Verify the subscription is migrated once another endpoint has capacity
+	term2.Capacity = 2
+	assert.NoError(t, terminations.Update(ctx, term2))
+	decision, err = placer.Place(ctx, sub, meta)
+	assert.NoError(t, err)
+	assert.Equal(t, epapi.ID("e2t-2"), decision.Endpoint.ID)
+	assert.Equal(t, metadata.ConditionNone, decision.Condition)
+}
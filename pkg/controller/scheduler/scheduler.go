@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/controller"
+)
+
+// NewScheduler returns a new Scheduler
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		timers: make(map[interface{}]*timer),
+	}
+}
+
+// Scheduler is a controller Watcher that requeues object IDs at a requested time
+// Reconcilers use the Scheduler to be called again when time based state, e.g. an
+// activation window or a grace period, is due to change.
+type Scheduler struct {
+	ch     chan<- controller.ID
+	done   chan struct{}
+	timers map[interface{}]*timer
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+}
+
+// timer is a pending requeue
+type timer struct {
+	time  time.Time
+	timer *time.Timer
+}
+
+// Start starts the scheduler
+func (s *Scheduler) Start(ch chan<- controller.ID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ch != nil {
+		return nil
+	}
+	s.ch = ch
+	s.done = make(chan struct{})
+	for key, t := range s.timers {
+		s.schedule(key, t.time)
+	}
+	return nil
+}
+
+// RequeueAt requeues the given ID at the given time
+// If the ID is already scheduled to be requeued earlier, the earlier time is retained.
+func (s *Scheduler) RequeueAt(id controller.ID, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if pending, ok := s.timers[id.Value]; ok && !pending.time.After(t) {
+		return
+	}
+	s.schedule(id.Value, t)
+}
+
+// RequeueAfter requeues the given ID after the given duration
+func (s *Scheduler) RequeueAfter(id controller.ID, d time.Duration) {
+	s.RequeueAt(id, time.Now().Add(d))
+}
+
+// schedule schedules a requeue of the given key; must be called with the lock held
+func (s *Scheduler) schedule(key interface{}, t time.Time) {
+	if pending, ok := s.timers[key]; ok && pending.timer != nil {
+		pending.timer.Stop()
+	}
+	pending := &timer{
+		time: t,
+	}
+	if s.ch != nil {
+		pending.timer = time.AfterFunc(time.Until(t), func() {
+			s.fire(key, pending)
+		})
+	}
+	s.timers[key] = pending
+}
+
+// fire requeues the given key
+func (s *Scheduler) fire(key interface{}, pending *timer) {
+	s.mu.Lock()
+	if s.timers[key] != pending || s.ch == nil {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.timers, key)
+	ch, done := s.ch, s.done
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	defer s.wg.Done()
+	select {
+	case ch <- controller.NewID(key):
+	case <-done:
+	}
+}
+
+// Stop stops the scheduler
+// Pending requeues are retained and will be scheduled again if the scheduler is restarted.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if s.ch == nil {
+		s.mu.Unlock()
+		return
+	}
+	for _, t := range s.timers {
+		if t.timer != nil {
+			t.timer.Stop()
+			t.timer = nil
+		}
+	}
+	ch := s.ch
+	s.ch = nil
+	close(s.done)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	close(ch)
+}
+
+var _ controller.Watcher = &Scheduler{}
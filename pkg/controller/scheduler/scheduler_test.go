@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/controller"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler(t *testing.T) {
+	scheduler := NewScheduler()
+
+	// Requeues scheduled before the scheduler is started are retained
+	scheduler.RequeueAfter(controller.NewID("a"), 100*time.Millisecond)
+
+	ch := make(chan controller.ID)
+	assert.NoError(t, scheduler.Start(ch))
+
+	scheduler.RequeueAfter(controller.NewID("b"), 50*time.Millisecond)
+	assert.Equal(t, "b", nextID(t, ch).String())
+	assert.Equal(t, "a", nextID(t, ch).String())
+
+	// An earlier requeue replaces a later one
+	scheduler.RequeueAfter(controller.NewID("c"), time.Hour)
+	scheduler.RequeueAfter(controller.NewID("c"), 50*time.Millisecond)
+	assert.Equal(t, "c", nextID(t, ch).String())
+
+	// A later requeue does not replace an earlier one
+	scheduler.RequeueAfter(controller.NewID("d"), 50*time.Millisecond)
+	scheduler.RequeueAfter(controller.NewID("d"), time.Hour)
+	assert.Equal(t, "d", nextID(t, ch).String())
+
+	// Requeues in the past are delivered immediately
+	scheduler.RequeueAt(controller.NewID("e"), time.Now().Add(-time.Minute))
+	assert.Equal(t, "e", nextID(t, ch).String())
+
+	scheduler.RequeueAfter(controller.NewID("f"), time.Hour)
+	scheduler.Stop()
+	_, ok := <-ch
+	assert.False(t, ok)
+}
+
+func nextID(t *testing.T, ch chan controller.ID) controller.ID {
+	t.Helper()
+	select {
+	case id := <-ch:
+		return id
+	case <-time.After(5 * time.Second):
+		t.FailNow()
+	}
+	return controller.ID{}
+}
@@ -0,0 +1,136 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"context"
+	"time"
+
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/controller/scheduler"
+	"github.com/onosproject/onos-e2sub/pkg/store/history"
+	"github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	"github.com/onosproject/onos-e2sub/pkg/store/session"
+	"github.com/onosproject/onos-e2sub/pkg/store/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/store/txn"
+	"github.com/onosproject/onos-lib-go/pkg/controller"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+)
+
+var log = logging.GetLogger("controller", "session")
+
+const defaultTimeout = 30 * time.Second
+
+// NewController returns a new session controller
+// The controller deletes the subscriptions scoped to a session once the session has been
+// disconnected for longer than the grace period. The subscriptions of a session are deleted in a
+// single transaction.
+func NewController(sessions session.Store, subs subscription.Store, metadata metadata.Store, transactions txn.Store, timeout, gracePeriod time.Duration) *controller.Controller {
+	c := controller.NewController("Session")
+	requeues := scheduler.NewScheduler()
+	c.Watch(requeues)
+	c.Watch(&Watcher{
+		sessions: sessions,
+	})
+	c.Reconcile(&Reconciler{
+		sessions: sessions,
+		subs:     subs,
+		metadata: metadata,
+		transactor: txn.NewTransactor(transactions, txn.Stores{
+			Subscriptions: subs,
+			Metadata:      metadata,
+		}),
+		timeout:     timeout,
+		gracePeriod: gracePeriod,
+		scheduler:   requeues,
+	})
+	return c
+}
+
+// Reconciler is a session reconciler
+type Reconciler struct {
+	sessions    session.Store
+	subs        subscription.Store
+	metadata    metadata.Store
+	transactor  *txn.Transactor
+	timeout     time.Duration
+	gracePeriod time.Duration
+	scheduler   *scheduler.Scheduler
+}
+
+// Reconcile reconciles a session
+func (r *Reconciler) Reconcile(id controller.ID) (controller.Result, error) {
+	ctx, cancel := context.WithTimeout(history.WithCaller(context.Background(), "controller/session"), defaultTimeout)
+	defer cancel()
+
+	sess, err := r.sessions.Get(ctx, id.Value.(session.ID))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return controller.Result{}, nil
+		}
+		return controller.Result{}, err
+	}
+
+	// If the session is connected, verify its stream is still renewing it
+	if sess.Connected {
+		lapsed := sess.Renewed.Add(r.timeout)
+		if time.Now().Before(lapsed) {
+			r.scheduler.RequeueAt(id, lapsed)
+			return controller.Result{}, nil
+		}
+
+		log.Infof("Session %s keep-alive lapsed", sess.ID)
+		sess.Connected = false
+		sess.Disconnected = lapsed
+		if err := r.sessions.Update(ctx, sess); err != nil {
+			log.Warnf("Failed to reconcile Session %s: %s", sess.ID, err)
+			return controller.Result{}, err
+		}
+	}
+
+	// Wait for the grace period to allow the client to reclaim the session
+	expiry := sess.Disconnected.Add(r.gracePeriod)
+	if time.Now().Before(expiry) {
+		r.scheduler.RequeueAt(id, expiry)
+		return controller.Result{}, nil
+	}
+
+	// Once the grace period has expired, delete the session's subscriptions
+	log.Infof("Session %s expired", sess.ID)
+	metas, err := r.metadata.List(ctx)
+	if err != nil {
+		return controller.Result{}, err
+	}
+	tx := r.transactor.Begin()
+	for _, meta := range metas {
+		if meta.Session != string(sess.ID) {
+			continue
+		}
+		sub, err := r.subs.Get(ctx, meta.ID)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return controller.Result{}, err
+		}
+		if sub.Lifecycle.Status != subapi.Status_ACTIVE {
+			continue
+		}
+		log.Infof("Deleting Subscription %+v for expired Session %s", sub, sess.ID)
+		sub.Lifecycle.Status = subapi.Status_PENDING_DELETE
+		tx.UpdateSubscription(sub)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Warnf("Failed to delete Subscriptions for expired Session %s: %s", sess.ID, err)
+		return controller.Result{}, err
+	}
+
+	if err := r.sessions.Delete(ctx, sess.ID); err != nil && !errors.IsNotFound(err) {
+		log.Warnf("Failed to delete Session %s: %s", sess.ID, err)
+		return controller.Result{}, err
+	}
+	return controller.Result{}, nil
+}
@@ -0,0 +1,150 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	metastore "github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	sessionstore "github.com/onosproject/onos-e2sub/pkg/store/session"
+	substore "github.com/onosproject/onos-e2sub/pkg/store/subscription"
+	txnstore "github.com/onosproject/onos-e2sub/pkg/store/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	timeout     = 500 * time.Millisecond
+	gracePeriod = time.Second
+)
+
+func createSubscription(subID string) subapi.Subscription {
+	return subapi.Subscription{
+		ID:    subapi.ID(subID),
+		AppID: "app1",
+		Details: &subapi.SubscriptionDetails{
+			E2NodeID: "e2node1",
+		},
+		Lifecycle: subapi.Lifecycle{Status: subapi.Status_ACTIVE},
+	}
+}
+
+func nextSubEvent(t *testing.T, ch chan subapi.Event) subapi.Event {
+	t.Helper()
+	var event subapi.Event
+	select {
+	case event = <-ch:
+		break
+	case <-time.After(15 * time.Second):
+		t.Error("Sub Event channel timed out")
+		break
+	}
+	return event
+}
+
+func TestSessionSubscriptions(t *testing.T) {
+	sessionStore, err := sessionstore.NewLocalStore()
+	assert.NoError(t, err)
+	defer sessionStore.Close()
+
+	subStore, err := substore.NewLocalStore()
+	assert.NoError(t, err)
+	defer subStore.Close()
+
+	metaStore, err := metastore.NewLocalStore()
+	assert.NoError(t, err)
+	defer metaStore.Close()
+
+	txnStore, err := txnstore.NewLocalStore()
+	assert.NoError(t, err)
+	defer txnStore.Close()
+
+	cntrl := NewController(sessionStore, subStore, metaStore, txnStore, timeout, gracePeriod)
+	assert.NoError(t, cntrl.Start())
+	defer cntrl.Stop()
+
+	// Create a subscription scoped to each session
+	for _, id := range []string{"session-1", "session-2"} {
+		sess := &sessionstore.Session{
+			ID:        sessionstore.ID(id),
+			Stream:    "stream-1",
+			Connected: true,
+			Renewed:   time.Now(),
+		}
+		assert.NoError(t, sessionStore.Create(context.TODO(), sess))
+		meta := &metastore.Metadata{
+			ID:      subapi.ID(id),
+			Session: id,
+		}
+		assert.NoError(t, metaStore.Create(context.TODO(), meta))
+		sub := createSubscription(id)
+		assert.NoError(t, subStore.Create(context.TODO(), &sub))
+	}
+
+	subCh := make(chan subapi.Event)
+	assert.NoError(t, subStore.Watch(context.TODO(), subCh))
+
+	// Keep the second session alive until it's disconnected
+	stopCh := make(chan struct{})
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		ticker := time.NewTicker(timeout / 4)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				sess, err := sessionStore.Get(context.TODO(), "session-2")
+				if err == nil && sess.Connected {
+					sess.Renewed = time.Now()
+					_ = sessionStore.Update(context.TODO(), sess)
+				}
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+
+	// Verify the first session's subscription is deleted once its keep-alive and grace period lapse
+	started := time.Now()
+	event := nextSubEvent(t, subCh)
+	assert.Equal(t, subapi.EventType_UPDATED, event.Type)
+	assert.Equal(t, subapi.ID("session-1"), event.Subscription.ID)
+	assert.Equal(t, subapi.Status_PENDING_DELETE, event.Subscription.Lifecycle.Status)
+	assert.True(t, time.Since(started) >= gracePeriod)
+
+	assert.Eventually(t, func() bool {
+		_, err := sessionStore.Get(context.TODO(), "session-1")
+		return err != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Disconnect the second session and reclaim it within the grace period
+	close(stopCh)
+	<-doneCh
+	sess, err := sessionStore.Get(context.TODO(), "session-2")
+	assert.NoError(t, err)
+	sess.Connected = false
+	sess.Disconnected = time.Now()
+	assert.NoError(t, sessionStore.Update(context.TODO(), sess))
+	time.Sleep(gracePeriod / 2)
+	sess, err = sessionStore.Get(context.TODO(), "session-2")
+	assert.NoError(t, err)
+	sess.Stream = "stream-2"
+	sess.Connected = true
+	sess.Renewed = time.Now()
+	assert.NoError(t, sessionStore.Update(context.TODO(), sess))
+
+	// Verify the second session's subscription is retained past the original grace period
+	select {
+	case event := <-subCh:
+		t.Errorf("unexpected event %+v", event)
+	case <-time.After(gracePeriod):
+	}
+	sub, err := subStore.Get(context.TODO(), "session-2")
+	assert.NoError(t, err)
+	assert.Equal(t, subapi.Status_ACTIVE, sub.Lifecycle.Status)
+}
@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"context"
+	"sync"
+
+	"github.com/onosproject/onos-e2sub/pkg/controller/supervisor"
+	"github.com/onosproject/onos-e2sub/pkg/store/session"
+	"github.com/onosproject/onos-lib-go/pkg/controller"
+)
+
+const queueSize = 100
+
+// Watcher is a session watcher
+type Watcher struct {
+	sessions session.Store
+	cancel   context.CancelFunc
+	mu       sync.Mutex
+}
+
+// Start starts the session watcher
+func (w *Watcher) Start(ch chan<- controller.ID) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	go func() {
+		defer close(ch)
+		supervisor.Watch(ctx, "session/sessions", func(ctx context.Context, resync bool, ready func()) error {
+			sessionCh := make(chan session.Event, queueSize)
+			if err := w.sessions.Watch(ctx, sessionCh, session.WithReplay()); err != nil {
+				return err
+			}
+			ready()
+			for event := range sessionCh {
+				if event.Type != session.EventRemoved {
+					ch <- controller.NewID(event.Session.ID)
+				}
+			}
+			return nil
+		})
+	}()
+	return nil
+}
+
+// Stop stops the session watcher
+func (w *Watcher) Stop() {
+	w.mu.Lock()
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+	w.mu.Unlock()
+}
+
+var _ controller.Watcher = &Watcher{}
@@ -11,9 +11,16 @@ import (
 
 	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
 	taskapi "github.com/onosproject/onos-api/go/onos/e2sub/task"
+	"github.com/onosproject/onos-e2sub/pkg/controller/placement"
+	"github.com/onosproject/onos-e2sub/pkg/controller/scheduler"
 	"github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/store/history"
+	"github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	"github.com/onosproject/onos-e2sub/pkg/store/node"
 	"github.com/onosproject/onos-e2sub/pkg/store/subscription"
 	"github.com/onosproject/onos-e2sub/pkg/store/task"
+	"github.com/onosproject/onos-e2sub/pkg/store/termination"
+	"github.com/onosproject/onos-e2sub/pkg/store/txn"
 	"github.com/onosproject/onos-lib-go/pkg/controller"
 	"github.com/onosproject/onos-lib-go/pkg/errors"
 	"github.com/onosproject/onos-lib-go/pkg/logging"
@@ -24,43 +31,76 @@ var log = logging.GetLogger("controller", "subscription")
 const defaultTimeout = 30 * time.Second
 
 // NewController returns a new network controller
-func NewController(subs subscription.Store, endpoints endpoint.Store, tasks task.Store) *controller.Controller {
+// If the E2 node store is nil, subscriptions are placed regardless of the connectivity of their E2 node.
+// The changes to a subscription's tasks and metadata are committed in transactions journaled in the
+// given transaction store.
+func NewController(subs subscription.Store, endpoints endpoint.Store, tasks task.Store, metadata metadata.Store, nodes node.Store, terminations termination.Store, transactions txn.Store) *controller.Controller {
 	c := controller.NewController("Subscription")
+	requeues := scheduler.NewScheduler()
+	c.Watch(requeues)
+	affected := newIndex()
 	c.Watch(&Watcher{
-		subs: subs,
+		subs:  subs,
+		index: affected,
+	})
+	c.Watch(&MetadataWatcher{
+		metadata: metadata,
+		index:    affected,
 	})
 	c.Watch(&TerminationEndpointWatcher{
-		subs:      subs,
 		endpoints: endpoints,
+		index:     affected,
 	})
 	c.Watch(&TaskWatcher{
-		subs:  subs,
 		tasks: tasks,
+		index: affected,
+	})
+	c.Watch(&TerminationWatcher{
+		terminations: terminations,
+		index:        affected,
 	})
+	if nodes != nil {
+		c.Watch(&NodeWatcher{
+			nodes: nodes,
+			index: affected,
+		})
+	}
 	c.Reconcile(&Reconciler{
 		subs:      subs,
-		endpoints: endpoints,
 		tasks:     tasks,
+		metadata:  metadata,
+		index:     affected,
+		placer:    placement.NewPlacer(endpoints, terminations, affected, nodes),
+		scheduler: requeues,
+		transactor: txn.NewTransactor(transactions, txn.Stores{
+			Subscriptions: subs,
+			Tasks:         tasks,
+			Metadata:      metadata,
+		}),
 	})
 	return c
 }
 
 // Reconciler is a device change reconciler
 type Reconciler struct {
-	subs      subscription.Store
-	endpoints endpoint.Store
-	tasks     task.Store
+	subs       subscription.Store
+	tasks      task.Store
+	metadata   metadata.Store
+	index      *index
+	placer     *placement.Placer
+	scheduler  *scheduler.Scheduler
+	transactor *txn.Transactor
 }
 
 // Reconcile reconciles the state of a device change
 func (r *Reconciler) Reconcile(id controller.ID) (controller.Result, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	ctx, cancel := context.WithTimeout(history.WithCaller(context.Background(), "controller/subscription"), defaultTimeout)
 	defer cancel()
 
 	sub, err := r.subs.Get(ctx, id.Value.(subapi.ID))
 	if err != nil {
 		if errors.IsNotFound(err) {
-			return controller.Result{}, nil
+			return r.reconcileRemovedSubscription(ctx, id.Value.(subapi.ID))
 		}
 		return controller.Result{}, err
 	}
@@ -77,41 +117,123 @@ func (r *Reconciler) Reconcile(id controller.ID) (controller.Result, error) {
 }
 
 func (r *Reconciler) reconcileActiveSubscription(sub *subapi.Subscription) (controller.Result, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	ctx, cancel := context.WithTimeout(history.WithCaller(context.Background(), "controller/subscription"), defaultTimeout)
 	defer cancel()
 
-	// List the termination endpoints
-	endpoints, err := r.endpoints.List(ctx)
-	if err != nil {
+	meta, err := r.metadata.Get(ctx, sub.ID)
+	if err != nil && !errors.IsNotFound(err) {
 		log.Warnf("Failed to reconcile Subscription %+v: %s", sub, err)
 		return controller.Result{}, err
 	}
 
-	// Get the first termination endpoint
-	if len(endpoints) == 0 {
-		log.Warnf("No endpoints found for Subscription %+v", sub)
-		return controller.Result{}, nil
+	// If the subscription is scheduled, requeue it for the next window transition and
+	// close its tasks while outside the activation window
+	if meta != nil && meta.Schedule != nil {
+		active, next := meta.Schedule.Window(time.Now())
+		if !next.IsZero() {
+			r.scheduler.RequeueAt(controller.NewID(sub.ID), next)
+		}
+		if !active {
+			return r.reconcileInactiveSubscription(ctx, r.transactor.Begin(), sub)
+		}
 	}
 
-	// TODO: Use mastership to support multiple endpoints
-	endpoint := &endpoints[0]
+	// Select a termination endpoint that can accept the subscription, closing the subscription's
+	// tasks while it cannot be placed, e.g. until its E2 node connects
+	decision, err := r.placer.Place(ctx, sub, meta)
+	if err != nil {
+		log.Warnf("Failed to reconcile Subscription %+v: %s", sub, err)
+		return controller.Result{}, err
+	}
+	if decision.Endpoint == nil {
+		log.Infof("Unable to place Subscription %+v: %s", sub, decision.Reason)
+		tx := r.transactor.Begin()
+		r.setCondition(tx, meta, sub.ID, decision.Condition, decision.Reason)
+		return r.reconcileInactiveSubscription(ctx, tx, sub)
+	}
+	endpoint := decision.Endpoint
 
-	// If a subscription task was not found, create one
+	subTasks, err := r.listSubscriptionTasks(ctx, sub.ID)
+	if err != nil {
+		log.Warnf("Failed to reconcile Subscription %+v: %s", sub, err)
+		return controller.Result{}, err
+	}
+
+	// Assign the subscription to the endpoint and migrate it off other endpoints, e.g. after its
+	// E2 node re-homed, in a single transaction
+	// Once the subscription is assigned to another endpoint, its eviction is complete
+	tx := r.transactor.Begin()
+	migrated := meta != nil && meta.Evicted != "" && meta.Evicted != endpoint.ID
+	if migrated {
+		log.Infof("Migrated Subscription %+v off TerminationEndpoint %s", sub, meta.Evicted)
+		meta.Evicted = ""
+	}
+	if !r.setCondition(tx, meta, sub.ID, decision.Condition, decision.Reason) && migrated {
+		tx.UpdateMetadata(meta)
+	}
 	taskID := taskapi.ID(fmt.Sprintf("%s:%s", sub.ID, endpoint.ID))
-	_, err = r.tasks.Get(ctx, taskID)
-	if errors.IsNotFound(err) {
+	var assigned *taskapi.SubscriptionTask
+	for i, task := range subTasks {
+		if task.ID == taskID {
+			assigned = &subTasks[i]
+		}
+	}
+	if assigned == nil {
 		log.Infof("Assigning Subscription %+v to TerminationEndpoint %+v", sub, endpoint)
-		task := &taskapi.SubscriptionTask{
-			ID:             taskapi.ID(fmt.Sprintf("%s:%s", sub.ID, endpoint.ID)),
+		tx.CreateTask(&taskapi.SubscriptionTask{
+			ID:             taskID,
 			SubscriptionID: sub.ID,
 			EndpointID:     endpoint.ID,
+		})
+	} else if assigned.Lifecycle.Phase == taskapi.Phase_CLOSE {
+		// If the task was closed while the subscription could not be placed, reopen it
+		log.Infof("Opening SubscriptionTask %+v", assigned)
+		assigned.Lifecycle.Phase = taskapi.Phase_OPEN
+		assigned.Lifecycle.Status = taskapi.Status_PENDING
+		assigned.Lifecycle.Failure = nil
+		tx.UpdateTask(assigned)
+	}
+	for _, task := range subTasks {
+		if task.ID == taskID {
+			continue
 		}
-		err := r.tasks.Create(ctx, task)
-		if err != nil && !errors.IsAlreadyExists(err) {
-			log.Warnf("Failed to assign Subscription %+v to TerminationEndpoint %+v: %s", sub, endpoint, err)
-			return controller.Result{}, err
+		updateTask := task
+		if task.Lifecycle.Phase != taskapi.Phase_CLOSE {
+			log.Infof("Closing SubscriptionTask %+v", task)
+			updateTask.Lifecycle.Phase = taskapi.Phase_CLOSE
+			updateTask.Lifecycle.Status = taskapi.Status_PENDING
+			tx.UpdateTask(&updateTask)
+		} else if task.Lifecycle.Status == taskapi.Status_COMPLETE {
+			log.Infof("Deleting SubscriptionTask %+v", task)
+			tx.DeleteTask(&updateTask)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Warnf("Failed to reconcile Subscription %+v: %s", sub, err)
+		return controller.Result{}, err
+	}
+	return controller.Result{}, nil
+}
+
+// reconcileInactiveSubscription closes the subscription's tasks along with the changes already in the transaction
+func (r *Reconciler) reconcileInactiveSubscription(ctx context.Context, tx *txn.Txn, sub *subapi.Subscription) (controller.Result, error) {
+	subTasks, err := r.listSubscriptionTasks(ctx, sub.ID)
+	if err != nil {
+		log.Warnf("Failed to reconcile Subscription %+v: %s", sub, err)
+		return controller.Result{}, err
+	}
+
+	// Close the subscription tasks but retain them to be reopened once the subscription is placeable again
+	for _, task := range subTasks {
+		if task.Lifecycle.Phase != taskapi.Phase_CLOSE {
+			log.Infof("Closing SubscriptionTask %+v", task)
+			task.Lifecycle.Phase = taskapi.Phase_CLOSE
+			task.Lifecycle.Status = taskapi.Status_PENDING
+			updateTask := task
+			tx.UpdateTask(&updateTask)
 		}
-	} else if err != nil {
+	}
+	if err := tx.Commit(ctx); err != nil {
 		log.Warnf("Failed to reconcile Subscription %+v: %s", sub, err)
 		return controller.Result{}, err
 	}
@@ -119,56 +241,129 @@ func (r *Reconciler) reconcileActiveSubscription(sub *subapi.Subscription) (cont
 }
 
 func (r *Reconciler) reconcileDeletedSubscription(sub *subapi.Subscription) (controller.Result, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	ctx, cancel := context.WithTimeout(history.WithCaller(context.Background(), "controller/subscription"), defaultTimeout)
 	defer cancel()
 
 	// List the subscription tasks
-	tasks, err := r.tasks.List(ctx)
+	subTasks, err := r.listSubscriptionTasks(ctx, sub.ID)
 	if err != nil {
 		log.Warnf("Failed to reconcile Subscription %+v: %s", sub, err)
 		return controller.Result{}, err
 	}
 
-	// Filter the subscription tasks by this subscription
-	subTasks := make([]taskapi.SubscriptionTask, 0, len(tasks))
-	for _, task := range tasks {
-		if task.SubscriptionID == sub.ID {
-			subTasks = append(subTasks, task)
+	// Ensure all subscription tasks are marked closed
+	tx := r.transactor.Begin()
+	closed := true
+	for _, task := range subTasks {
+		if task.Lifecycle.Phase != taskapi.Phase_CLOSE {
+			log.Infof("Closing SubscriptionTask %+v", task)
+			task.Lifecycle.Phase = taskapi.Phase_CLOSE
+			task.Lifecycle.Status = taskapi.Status_PENDING
+			updateTask := task
+			tx.UpdateTask(&updateTask)
+			closed = false
+		} else if task.Lifecycle.Status != taskapi.Status_COMPLETE {
+			closed = false
 		}
 	}
 
-	// If the subscription tasks are empty, delete the subscription
-	if len(subTasks) == 0 {
-		log.Infof("Deleting Subscription %+v", sub)
-		err := r.subs.Delete(ctx, sub.ID)
+	// Once all subscription tasks have completed closing, delete them along with the subscription
+	// and its metadata in a single transaction
+	if closed {
+		meta, err := r.metadata.Get(ctx, sub.ID)
 		if err != nil && !errors.IsNotFound(err) {
 			log.Warnf("Failed to reconcile Subscription %+v: %s", sub, err)
 			return controller.Result{}, err
 		}
+		for _, task := range subTasks {
+			log.Infof("Deleting SubscriptionTask %+v", task)
+			deleteTask := task
+			tx.DeleteTask(&deleteTask)
+		}
+		log.Infof("Deleting Subscription %+v", sub)
+		tx.DeleteSubscription(sub)
+		if meta != nil {
+			tx.DeleteMetadata(meta)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Warnf("Failed to reconcile Subscription %+v: %s", sub, err)
+		return controller.Result{}, err
+	}
+	return controller.Result{}, nil
+}
+
+// reconcileRemovedSubscription closes and deletes the tasks left for a subscription that no longer exists
+// Tasks are left when a subscription is removed after the controller assigned it, e.g. when the
+// transaction that added it in an atomic batch is rolled back.
+func (r *Reconciler) reconcileRemovedSubscription(ctx context.Context, id subapi.ID) (controller.Result, error) {
+	subTasks, err := r.listSubscriptionTasks(ctx, id)
+	if err != nil {
+		log.Warnf("Failed to reconcile removed Subscription %s: %s", id, err)
+		return controller.Result{}, err
+	}
+	if len(subTasks) == 0 {
 		return controller.Result{}, nil
 	}
 
-	// Ensure all subscription tasks are marked closed and delete tasks already closed
+	tx := r.transactor.Begin()
 	for _, task := range subTasks {
+		updateTask := task
 		if task.Lifecycle.Phase != taskapi.Phase_CLOSE {
-			log.Infof("Closing SubscriptionTask %+v", task)
-			task.Lifecycle.Phase = taskapi.Phase_CLOSE
-			task.Lifecycle.Status = taskapi.Status_PENDING
-			updateTask := task
-			err := r.tasks.Update(ctx, &updateTask)
-			if err != nil {
-				log.Warnf("Failed to reconcile Subscription %+v: %s", sub, err)
-				return controller.Result{}, err
-			}
+			log.Infof("Closing SubscriptionTask %+v of removed Subscription %s", task, id)
+			updateTask.Lifecycle.Phase = taskapi.Phase_CLOSE
+			updateTask.Lifecycle.Status = taskapi.Status_PENDING
+			tx.UpdateTask(&updateTask)
+		} else if task.Lifecycle.Status == taskapi.Status_COMPLETE {
+			log.Infof("Deleting SubscriptionTask %+v of removed Subscription %s", task, id)
+			tx.DeleteTask(&updateTask)
 		}
-		if task.Lifecycle.Phase == taskapi.Phase_CLOSE && task.Lifecycle.Status == taskapi.Status_COMPLETE {
-			log.Infof("Deleting SubscriptionTask %+v", task)
-			err = r.tasks.Delete(ctx, task.ID)
-			if err != nil && !errors.IsNotFound(err) {
-				log.Warnf("Failed to reconcile Subscription %+v: %s", sub, err)
-				return controller.Result{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Warnf("Failed to reconcile removed Subscription %s: %s", id, err)
+		return controller.Result{}, err
+	}
+	return controller.Result{}, nil
+}
+
+// setCondition records the condition preventing the subscription from being placed in its metadata,
+// returning whether the metadata was written in the transaction
+func (r *Reconciler) setCondition(tx *txn.Txn, meta *metadata.Metadata, id subapi.ID, condition metadata.Condition, reason string) bool {
+	if meta == nil {
+		if condition != metadata.ConditionNone {
+			tx.CreateMetadata(&metadata.Metadata{
+				ID:        id,
+				Condition: condition,
+				Reason:    reason,
+			})
+			return true
+		}
+		return false
+	}
+	if meta.Condition == condition && meta.Reason == reason {
+		return false
+	}
+	meta.Condition = condition
+	meta.Reason = reason
+	tx.UpdateMetadata(meta)
+	return true
+}
+
+// listSubscriptionTasks lists the tasks for the given subscription
+// The tasks are found in the index and read from the store, so that they're changed at their
+// current revisions.
+func (r *Reconciler) listSubscriptionTasks(ctx context.Context, id subapi.ID) ([]taskapi.SubscriptionTask, error) {
+	ids := r.index.getTasks(id)
+	subTasks := make([]taskapi.SubscriptionTask, 0, len(ids))
+	for _, taskID := range ids {
+		task, err := r.tasks.Get(ctx, taskID)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
 			}
+			return nil, err
 		}
+		subTasks = append(subTasks, *task)
 	}
-	return controller.Result{}, nil
+	return subTasks, nil
 }
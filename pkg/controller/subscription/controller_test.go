@@ -13,8 +13,12 @@ import (
 	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
 	taskapi "github.com/onosproject/onos-api/go/onos/e2sub/task"
 	epstore "github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	metastore "github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	nodestore "github.com/onosproject/onos-e2sub/pkg/store/node"
 	substore "github.com/onosproject/onos-e2sub/pkg/store/subscription"
 	taskstore "github.com/onosproject/onos-e2sub/pkg/store/task"
+	termstore "github.com/onosproject/onos-e2sub/pkg/store/termination"
+	"github.com/onosproject/onos-e2sub/pkg/store/txn"
 
 	"github.com/onosproject/onos-lib-go/pkg/controller"
 	"github.com/stretchr/testify/assert"
@@ -25,6 +29,9 @@ type testController struct {
 	subStore  substore.Store
 	epStore   epstore.Store
 	taskStore taskstore.Store
+	metaStore metastore.Store
+	termStore termstore.Store
+	txnStore  txn.Store
 }
 
 func createController(t *testing.T) testController {
@@ -37,7 +44,16 @@ func createController(t *testing.T) testController {
 	taskStore, err := taskstore.NewLocalStore()
 	assert.NoError(t, err)
 
-	cntrl := NewController(subStore, epStore, taskStore)
+	metaStore, err := metastore.NewLocalStore()
+	assert.NoError(t, err)
+
+	termStore, err := termstore.NewLocalStore()
+	assert.NoError(t, err)
+
+	txnStore, err := txn.NewLocalStore()
+	assert.NoError(t, err)
+
+	cntrl := NewController(subStore, epStore, taskStore, metaStore, nil, termStore, txnStore)
 	assert.NotNil(t, cntrl)
 
 	return testController{
@@ -45,6 +61,9 @@ func createController(t *testing.T) testController {
 		subStore:  subStore,
 		epStore:   epStore,
 		taskStore: taskStore,
+		metaStore: metaStore,
+		termStore: termStore,
+		txnStore:  txnStore,
 	}
 }
 
@@ -53,6 +72,9 @@ func destroyController(t *testing.T, c testController) {
 	assert.NoError(t, c.subStore.Close())
 	assert.NoError(t, c.epStore.Close())
 	assert.NoError(t, c.taskStore.Close())
+	assert.NoError(t, c.metaStore.Close())
+	assert.NoError(t, c.termStore.Close())
+	assert.NoError(t, c.txnStore.Close())
 }
 
 func checkTask(t *testing.T, task taskapi.SubscriptionTask, taskID taskapi.ID, subID subapi.ID, epID epapi.ID) {
@@ -219,3 +241,404 @@ func TestDeleteSubscription(t *testing.T) {
 	close(subCh)
 	destroyController(t, c)
 }
+
+func TestScheduledSubscription(t *testing.T) {
+	// Set up a controller to test
+	const (
+		subID  = "sub3"
+		epID   = "ep3"
+		taskID = taskapi.ID(subID + ":" + epID)
+	)
+	c := createController(t)
+	assert.NoError(t, c.cntrl.Start())
+
+	// Make an end point
+	ep := createEP(epID)
+	assert.NoError(t, c.epStore.Create(context.Background(), &ep))
+
+	// Watch for task events
+	taskCh := make(chan taskapi.Event)
+	assert.NoError(t, c.taskStore.Watch(context.TODO(), taskCh))
+
+	// Make a subscription scheduled to be active in the near future
+	now := time.Now()
+	meta := &metastore.Metadata{
+		ID: subID,
+		Schedule: &metastore.Schedule{
+			Start: now.Add(time.Second),
+			End:   now.Add(3 * time.Second),
+		},
+	}
+	assert.NoError(t, c.metaStore.Create(context.TODO(), meta))
+	sub := createSubscription(subID, epID)
+	assert.NoError(t, c.subStore.Create(context.TODO(), &sub))
+
+	// Verify the task is created once the window opens
+	event, task := nextTaskEvent(t, taskCh)
+	checkTask(t, task, taskID, subID, epID)
+	checkEvent(t, event, taskapi.EventType_CREATED, task)
+	assert.False(t, time.Now().Before(meta.Schedule.Start))
+
+	// Verify the task is closed once the window closes
+	event, task = nextTaskEvent(t, taskCh)
+	checkTask(t, task, taskID, subID, epID)
+	checkEvent(t, event, taskapi.EventType_UPDATED, task)
+	assert.Equal(t, taskapi.Phase_CLOSE, task.Lifecycle.Phase)
+	assert.False(t, time.Now().Before(meta.Schedule.End))
+
+	// Clean up
+	close(taskCh)
+	destroyController(t, c)
+}
+
+func TestWaitingForNode(t *testing.T) {
+	// Set up a controller that tracks E2 node connectivity
+	const (
+		subID  = "sub4"
+		epID   = "ep4"
+		nodeID = "e2node4"
+		taskID = taskapi.ID(subID + ":" + epID)
+	)
+	nodeStore, err := nodestore.NewLocalStore()
+	assert.NoError(t, err)
+	defer nodeStore.Close()
+
+	c := createController(t)
+	c.cntrl = NewController(c.subStore, c.epStore, c.taskStore, c.metaStore, nodeStore, c.termStore, c.txnStore)
+	assert.NoError(t, c.cntrl.Start())
+
+	// Make an end point
+	ep := createEP(epID)
+	assert.NoError(t, c.epStore.Create(context.Background(), &ep))
+
+	// Watch for task and metadata events
+	taskCh := make(chan taskapi.Event)
+	assert.NoError(t, c.taskStore.Watch(context.TODO(), taskCh))
+	metaCh := make(chan metastore.Event)
+	assert.NoError(t, c.metaStore.Watch(context.TODO(), metaCh))
+
+	// Make a subscription for an unknown E2 node and verify it's waiting for the node
+	sub := createSubscription(subID, nodeID)
+	assert.NoError(t, c.subStore.Create(context.TODO(), &sub))
+	metaEvent := nextMetaEvent(t, metaCh)
+	assert.Equal(t, metastore.ConditionWaitingForNode, metaEvent.Metadata.Condition)
+
+	// Connect the E2 node and verify the task is created
+	node := &nodestore.Node{
+		ID:        nodeID,
+		Connected: true,
+	}
+	assert.NoError(t, nodeStore.Create(context.TODO(), node))
+	event, task := nextTaskEvent(t, taskCh)
+	checkTask(t, task, taskID, subID, epID)
+	checkEvent(t, event, taskapi.EventType_CREATED, task)
+	metaEvent = nextMetaEvent(t, metaCh)
+	assert.Equal(t, metastore.ConditionNone, metaEvent.Metadata.Condition)
+
+	// Disconnect the E2 node and verify the task is closed
+	node.Connected = false
+	assert.NoError(t, nodeStore.Update(context.TODO(), node))
+	event, task = nextTaskEvent(t, taskCh)
+	checkEvent(t, event, taskapi.EventType_UPDATED, task)
+	assert.Equal(t, taskapi.Phase_CLOSE, task.Lifecycle.Phase)
+	metaEvent = nextMetaEvent(t, metaCh)
+	assert.Equal(t, metastore.ConditionWaitingForNode, metaEvent.Metadata.Condition)
+
+	// Reconnect the E2 node and verify the task is reopened
+	node.Connected = true
+	assert.NoError(t, nodeStore.Update(context.TODO(), node))
+	for {
+		event, task = nextTaskEvent(t, taskCh)
+		if task.Lifecycle.Phase == taskapi.Phase_OPEN || t.Failed() {
+			break
+		}
+	}
+	checkEvent(t, event, taskapi.EventType_UPDATED, task)
+
+	// Clean up
+	close(taskCh)
+	destroyController(t, c)
+}
+
+func nextMetaEvent(t *testing.T, ch chan metastore.Event) metastore.Event {
+	t.Helper()
+	var event metastore.Event
+	select {
+	case event = <-ch:
+		break
+	case <-time.After(15 * time.Second):
+		t.Error("Metadata Event channel timed out")
+		break
+	}
+	return event
+}
+
+func TestNodePlacement(t *testing.T) {
+	// Set up a controller to test
+	const (
+		subID   = "sub5"
+		epID1   = "ep5a"
+		epID2   = "ep5b"
+		nodeID  = "e2node5"
+		taskID1 = taskapi.ID(subID + ":" + epID1)
+		taskID2 = taskapi.ID(subID + ":" + epID2)
+	)
+	c := createController(t)
+	assert.NoError(t, c.cntrl.Start())
+
+	// Make two end points, the first connected to the E2 node
+	ep1 := createEP(epID1)
+	assert.NoError(t, c.epStore.Create(context.Background(), &ep1))
+	ep2 := createEP(epID2)
+	assert.NoError(t, c.epStore.Create(context.Background(), &ep2))
+	term1 := &termstore.Termination{ID: epID1, Nodes: []string{nodeID}}
+	assert.NoError(t, c.termStore.Create(context.Background(), term1))
+	term2 := &termstore.Termination{ID: epID2, Nodes: []string{}}
+	assert.NoError(t, c.termStore.Create(context.Background(), term2))
+
+	// Watch for task events
+	taskCh := make(chan taskapi.Event)
+	assert.NoError(t, c.taskStore.Watch(context.TODO(), taskCh))
+
+	// Make a subscription and verify the task is placed on the node's end point
+	sub := createSubscription(subID, nodeID)
+	assert.NoError(t, c.subStore.Create(context.TODO(), &sub))
+	event, task := nextTaskEvent(t, taskCh)
+	checkTask(t, task, taskID1, subID, epID1)
+	checkEvent(t, event, taskapi.EventType_CREATED, task)
+
+	// Re-home the E2 node to the second end point
+	term2.Nodes = []string{nodeID}
+	assert.NoError(t, c.termStore.Update(context.Background(), term2))
+	term1.Nodes = []string{}
+	assert.NoError(t, c.termStore.Update(context.Background(), term1))
+
+	// Verify a task is created on the new end point and the old task is closed
+	var created, closed bool
+	for !(created && closed) && !t.Failed() {
+		event, task = nextTaskEvent(t, taskCh)
+		switch task.ID {
+		case taskID2:
+			if event.Type == taskapi.EventType_CREATED {
+				created = true
+			}
+		case taskID1:
+			if task.Lifecycle.Phase == taskapi.Phase_CLOSE {
+				closed = true
+			}
+		}
+	}
+
+	// Complete closing the old task and verify it's deleted
+	oldTask, err := c.taskStore.Get(context.TODO(), taskID1)
+	assert.NoError(t, err)
+	oldTask.Lifecycle.Status = taskapi.Status_COMPLETE
+	assert.NoError(t, c.taskStore.Update(context.TODO(), oldTask))
+	for !t.Failed() {
+		event, task = nextTaskEvent(t, taskCh)
+		if event.Type == taskapi.EventType_REMOVED {
+			assert.Equal(t, taskID1, task.ID)
+			break
+		}
+	}
+
+	// Clean up
+	close(taskCh)
+	destroyController(t, c)
+}
+
+func TestCapacityPlacement(t *testing.T) {
+	// Set up a controller to test
+	const (
+		epID1 = "ep6a"
+		epID2 = "ep6b"
+	)
+	c := createController(t)
+	assert.NoError(t, c.cntrl.Start())
+
+	// Make an end point supporting sm1 with capacity for one task, and an end point supporting sm2
+	ep1 := createEP(epID1)
+	assert.NoError(t, c.epStore.Create(context.Background(), &ep1))
+	ep2 := createEP(epID2)
+	assert.NoError(t, c.epStore.Create(context.Background(), &ep2))
+	term1 := &termstore.Termination{ID: epID1, ServiceModels: []termstore.ServiceModel{{Name: "sm1"}}, Capacity: 1}
+	assert.NoError(t, c.termStore.Create(context.Background(), term1))
+	term2 := &termstore.Termination{ID: epID2, ServiceModels: []termstore.ServiceModel{{Name: "sm2", Version: "v1"}}}
+	assert.NoError(t, c.termStore.Create(context.Background(), term2))
+
+	// Watch for task and metadata events
+	taskCh := make(chan taskapi.Event)
+	assert.NoError(t, c.taskStore.Watch(context.TODO(), taskCh))
+	metaCh := make(chan metastore.Event)
+	assert.NoError(t, c.metaStore.Watch(context.TODO(), metaCh))
+
+	// Verify the first sm1 subscription is placed on the sm1 end point
+	sub1 := createSubscription("sub6a", "e2node6")
+	sub1.Details.ServiceModel = subapi.ServiceModel{Name: "sm1", Version: "v2"}
+	assert.NoError(t, c.subStore.Create(context.TODO(), &sub1))
+	event, task := nextTaskEvent(t, taskCh)
+	checkTask(t, task, "sub6a:"+epID1, "sub6a", epID1)
+	checkEvent(t, event, taskapi.EventType_CREATED, task)
+
+	// Verify the second sm1 subscription is unschedulable while the end point is at capacity
+	sub2 := createSubscription("sub6b", "e2node6")
+	sub2.Details.ServiceModel = subapi.ServiceModel{Name: "sm1", Version: "v2"}
+	assert.NoError(t, c.subStore.Create(context.TODO(), &sub2))
+	metaEvent := nextMetaEvent(t, metaCh)
+	assert.Equal(t, subapi.ID("sub6b"), metaEvent.Metadata.ID)
+	assert.Equal(t, metastore.ConditionUnschedulable, metaEvent.Metadata.Condition)
+
+	// Verify a subscription for an unsupported service model version is unschedulable
+	sub3 := createSubscription("sub6c", "e2node6")
+	sub3.Details.ServiceModel = subapi.ServiceModel{Name: "sm2", Version: "v2"}
+	assert.NoError(t, c.subStore.Create(context.TODO(), &sub3))
+	metaEvent = nextMetaEvent(t, metaCh)
+	assert.Equal(t, subapi.ID("sub6c"), metaEvent.Metadata.ID)
+	assert.Equal(t, metastore.ConditionUnschedulable, metaEvent.Metadata.Condition)
+
+	// Increase the capacity of the sm1 end point and verify the second subscription is placed
+	term1.Capacity = 2
+	assert.NoError(t, c.termStore.Update(context.Background(), term1))
+	event, task = nextTaskEvent(t, taskCh)
+	checkTask(t, task, "sub6b:"+epID1, "sub6b", epID1)
+	checkEvent(t, event, taskapi.EventType_CREATED, task)
+
+	// Clean up
+	close(taskCh)
+	destroyController(t, c)
+}
+
+func TestCordonedPlacement(t *testing.T) {
+	// Set up a controller to test
+	const (
+		epID1 = "ep7a"
+		epID2 = "ep7b"
+	)
+	c := createController(t)
+	assert.NoError(t, c.cntrl.Start())
+
+	ep1 := createEP(epID1)
+	assert.NoError(t, c.epStore.Create(context.Background(), &ep1))
+	ep2 := createEP(epID2)
+	assert.NoError(t, c.epStore.Create(context.Background(), &ep2))
+
+	// Watch for task events
+	taskCh := make(chan taskapi.Event)
+	assert.NoError(t, c.taskStore.Watch(context.TODO(), taskCh))
+
+	// Verify the subscription is placed on the first end point
+	sub := createSubscription("sub7", "e2node7")
+	assert.NoError(t, c.subStore.Create(context.TODO(), &sub))
+	event, task := nextTaskEvent(t, taskCh)
+	checkTask(t, task, "sub7:"+epID1, "sub7", epID1)
+	checkEvent(t, event, taskapi.EventType_CREATED, task)
+
+	// Cordon the first end point and verify the subscription is not moved
+	term1 := &termstore.Termination{ID: epID1, Cordoned: true}
+	assert.NoError(t, c.termStore.Create(context.Background(), term1))
+	select {
+	case event := <-taskCh:
+		t.Errorf("Unexpected task event %+v", event)
+	case <-time.After(time.Second):
+	}
+
+	// Evict the subscription and verify it's migrated to the second end point
+	meta := &metastore.Metadata{ID: "sub7", Evicted: epID1}
+	assert.NoError(t, c.metaStore.Create(context.Background(), meta))
+	event, task = nextTaskEvent(t, taskCh)
+	checkTask(t, task, "sub7:"+epID2, "sub7", epID2)
+	checkEvent(t, event, taskapi.EventType_CREATED, task)
+
+	event, task = nextTaskEvent(t, taskCh)
+	assert.Equal(t, taskapi.ID("sub7:"+epID1), task.ID)
+	assert.Equal(t, taskapi.EventType_UPDATED, event.Type)
+	assert.Equal(t, taskapi.Phase_CLOSE, task.Lifecycle.Phase)
+
+	// Verify the eviction is cleared once the subscription is migrated
+	meta, err := c.metaStore.Get(context.Background(), "sub7")
+	assert.NoError(t, err)
+	assert.Equal(t, epapi.ID(""), meta.Evicted)
+
+	// Clean up
+	close(taskCh)
+	destroyController(t, c)
+}
+
+func TestUnhealthyPlacement(t *testing.T) {
+	// Set up a controller to test
+	const (
+		epID1 = "ep8a"
+		epID2 = "ep8b"
+	)
+	c := createController(t)
+	assert.NoError(t, c.cntrl.Start())
+
+	ep1 := createEP(epID1)
+	assert.NoError(t, c.epStore.Create(context.Background(), &ep1))
+	ep2 := createEP(epID2)
+	assert.NoError(t, c.epStore.Create(context.Background(), &ep2))
+
+	// Watch for task events
+	taskCh := make(chan taskapi.Event)
+	assert.NoError(t, c.taskStore.Watch(context.TODO(), taskCh))
+
+	// Verify the subscription is placed on the first end point
+	sub := createSubscription("sub8", "e2node8")
+	assert.NoError(t, c.subStore.Create(context.TODO(), &sub))
+	event, task := nextTaskEvent(t, taskCh)
+	checkTask(t, task, "sub8:"+epID1, "sub8", epID1)
+	checkEvent(t, event, taskapi.EventType_CREATED, task)
+
+	// Mark the first end point unhealthy and verify the subscription is moved to the second end point
+	term1 := &termstore.Termination{ID: epID1, Health: &termstore.Health{Status: termstore.HealthUnhealthy}}
+	assert.NoError(t, c.termStore.Create(context.Background(), term1))
+	event, task = nextTaskEvent(t, taskCh)
+	checkTask(t, task, "sub8:"+epID2, "sub8", epID2)
+	checkEvent(t, event, taskapi.EventType_CREATED, task)
+
+	event, task = nextTaskEvent(t, taskCh)
+	assert.Equal(t, taskapi.ID("sub8:"+epID1), task.ID)
+	assert.Equal(t, taskapi.EventType_UPDATED, event.Type)
+	assert.Equal(t, taskapi.Phase_CLOSE, task.Lifecycle.Phase)
+
+	// Clean up
+	close(taskCh)
+	destroyController(t, c)
+}
+
+func TestEndpointAddressChange(t *testing.T) {
+	// Set up a controller to test
+	const epID = "ep9"
+	c := createController(t)
+	assert.NoError(t, c.cntrl.Start())
+
+	ep := createEP(epID)
+	assert.NoError(t, c.epStore.Create(context.Background(), &ep))
+
+	// Watch for task events
+	taskCh := make(chan taskapi.Event)
+	assert.NoError(t, c.taskStore.Watch(context.TODO(), taskCh))
+
+	sub := createSubscription("sub9", "e2node9")
+	assert.NoError(t, c.subStore.Create(context.TODO(), &sub))
+	event, task := nextTaskEvent(t, taskCh)
+	checkTask(t, task, "sub9:"+epID, "sub9", epID)
+	checkEvent(t, event, taskapi.EventType_CREATED, task)
+
+	// Change the end point's address and verify the task is not reassigned
+	ep.IP = "10.10.10.10"
+	assert.NoError(t, c.epStore.Update(context.Background(), &ep))
+	select {
+	case event := <-taskCh:
+		t.Errorf("Unexpected task event %+v", event)
+	case <-time.After(time.Second):
+	}
+
+	task2, err := c.taskStore.Get(context.TODO(), "sub9:"+epID)
+	assert.NoError(t, err)
+	assert.Equal(t, task.Revision, task2.Revision)
+
+	// Clean up
+	close(taskCh)
+	destroyController(t, c)
+}
@@ -0,0 +1,257 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package subscription
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	taskapi "github.com/onosproject/onos-api/go/onos/e2sub/task"
+	"github.com/onosproject/onos-e2sub/pkg/controller/placement"
+	"github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	"github.com/onosproject/onos-e2sub/pkg/store/node"
+)
+
+// newIndex returns a new subscription index
+func newIndex() *index {
+	i := &index{}
+	i.resetSubscriptions()
+	i.resetTasks()
+	i.resetMetadata()
+	return i
+}
+
+// index indexes the subscriptions by their E2 node, by the endpoints hosting their tasks and by
+// their placement condition, and indexes the tasks by their subscription
+// The index is maintained from the events of the subscription, task and metadata watchers, and is
+// read by the other watchers to enqueue only the subscriptions affected by an event. The reconciler
+// reads the tasks of a subscription, and the placer the load of the endpoints, from the index rather
+// than listing every task.
+type index struct {
+	subNodes   map[subapi.ID]node.ID
+	nodeSubs   map[node.ID]map[subapi.ID]bool
+	tasks      map[taskapi.ID]taskapi.SubscriptionTask
+	endpoints  map[epapi.ID]map[taskapi.ID]subapi.ID
+	subTasks   map[subapi.ID]map[taskapi.ID]bool
+	conditions map[subapi.ID]metadata.Condition
+	mu         sync.RWMutex
+}
+
+// updateSubscription indexes the E2 node of a subscription
+func (i *index) updateSubscription(sub *subapi.Subscription) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.removeSubscription(sub.ID)
+	nodeID := node.ID(sub.GetDetails().GetE2NodeID())
+	i.subNodes[sub.ID] = nodeID
+	subs, ok := i.nodeSubs[nodeID]
+	if !ok {
+		subs = make(map[subapi.ID]bool)
+		i.nodeSubs[nodeID] = subs
+	}
+	subs[sub.ID] = true
+}
+
+// deleteSubscription removes a subscription from the index of E2 nodes
+func (i *index) deleteSubscription(id subapi.ID) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.removeSubscription(id)
+}
+
+func (i *index) removeSubscription(id subapi.ID) {
+	nodeID, ok := i.subNodes[id]
+	if !ok {
+		return
+	}
+	delete(i.subNodes, id)
+	delete(i.nodeSubs[nodeID], id)
+	if len(i.nodeSubs[nodeID]) == 0 {
+		delete(i.nodeSubs, nodeID)
+	}
+}
+
+// resetSubscriptions clears the index of E2 nodes
+func (i *index) resetSubscriptions() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.subNodes = make(map[subapi.ID]node.ID)
+	i.nodeSubs = make(map[node.ID]map[subapi.ID]bool)
+}
+
+// updateTask indexes the endpoint and subscription of a subscription task
+func (i *index) updateTask(task *taskapi.SubscriptionTask) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.removeTask(task.ID)
+	i.tasks[task.ID] = *task
+	tasks, ok := i.endpoints[task.EndpointID]
+	if !ok {
+		tasks = make(map[taskapi.ID]subapi.ID)
+		i.endpoints[task.EndpointID] = tasks
+	}
+	tasks[task.ID] = task.SubscriptionID
+	subTasks, ok := i.subTasks[task.SubscriptionID]
+	if !ok {
+		subTasks = make(map[taskapi.ID]bool)
+		i.subTasks[task.SubscriptionID] = subTasks
+	}
+	subTasks[task.ID] = true
+}
+
+// deleteTask removes a subscription task from the index of endpoints
+func (i *index) deleteTask(id taskapi.ID) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.removeTask(id)
+}
+
+func (i *index) removeTask(id taskapi.ID) {
+	task, ok := i.tasks[id]
+	if !ok {
+		return
+	}
+	delete(i.tasks, id)
+	delete(i.endpoints[task.EndpointID], id)
+	if len(i.endpoints[task.EndpointID]) == 0 {
+		delete(i.endpoints, task.EndpointID)
+	}
+	delete(i.subTasks[task.SubscriptionID], id)
+	if len(i.subTasks[task.SubscriptionID]) == 0 {
+		delete(i.subTasks, task.SubscriptionID)
+	}
+}
+
+// resetTasks clears the index of endpoints
+func (i *index) resetTasks() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.tasks = make(map[taskapi.ID]taskapi.SubscriptionTask)
+	i.endpoints = make(map[epapi.ID]map[taskapi.ID]subapi.ID)
+	i.subTasks = make(map[subapi.ID]map[taskapi.ID]bool)
+}
+
+// updateMetadata indexes the placement condition of a subscription
+func (i *index) updateMetadata(meta *metadata.Metadata) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if meta.Condition == metadata.ConditionNone {
+		delete(i.conditions, meta.ID)
+	} else {
+		i.conditions[meta.ID] = meta.Condition
+	}
+}
+
+// deleteMetadata removes a subscription from the index of conditions
+func (i *index) deleteMetadata(id subapi.ID) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.conditions, id)
+}
+
+// resetMetadata clears the index of conditions
+func (i *index) resetMetadata() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.conditions = make(map[subapi.ID]metadata.Condition)
+}
+
+// getByNode returns the IDs of the subscriptions to the given E2 node
+func (i *index) getByNode(id node.ID) []subapi.ID {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	ids := make([]subapi.ID, 0, len(i.nodeSubs[id]))
+	for subID := range i.nodeSubs[id] {
+		ids = append(ids, subID)
+	}
+	return ids
+}
+
+// getByEndpoint returns the IDs of the subscriptions with tasks on the given endpoint
+func (i *index) getByEndpoint(id epapi.ID) []subapi.ID {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	ids := make([]subapi.ID, 0, len(i.endpoints[id]))
+	for _, subID := range i.endpoints[id] {
+		ids = append(ids, subID)
+	}
+	return ids
+}
+
+// getTasks returns the IDs of the tasks of the given subscription
+func (i *index) getTasks(id subapi.ID) []taskapi.ID {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	ids := make([]taskapi.ID, 0, len(i.subTasks[id]))
+	for taskID := range i.subTasks[id] {
+		ids = append(ids, taskID)
+	}
+	sort.Slice(ids, func(j, k int) bool {
+		return ids[j] < ids[k]
+	})
+	return ids
+}
+
+// List lists the indexed tasks
+func (i *index) List(ctx context.Context) ([]taskapi.SubscriptionTask, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	tasks := make([]taskapi.SubscriptionTask, 0, len(i.tasks))
+	for _, task := range i.tasks {
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// getEndpoints returns the IDs of the endpoints hosting subscription tasks
+func (i *index) getEndpoints() []epapi.ID {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	ids := make([]epapi.ID, 0, len(i.endpoints))
+	for id := range i.endpoints {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// getUnplaced returns the IDs of the subscriptions that could not be placed
+// If conditions are given, only the subscriptions with one of the conditions are returned.
+func (i *index) getUnplaced(conditions ...metadata.Condition) []subapi.ID {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	var ids []subapi.ID
+	for id, condition := range i.conditions {
+		if len(conditions) == 0 || hasCondition(conditions, condition) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// getAll returns the IDs of all indexed subscriptions
+func (i *index) getAll() []subapi.ID {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	ids := make([]subapi.ID, 0, len(i.subNodes))
+	for id := range i.subNodes {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// hasCondition returns whether the condition is one of the given conditions
+func hasCondition(conditions []metadata.Condition, condition metadata.Condition) bool {
+	for _, c := range conditions {
+		if c == condition {
+			return true
+		}
+	}
+	return false
+}
+
+var _ placement.Tasks = &index{}
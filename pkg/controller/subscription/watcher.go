@@ -6,22 +6,30 @@ package subscription
 
 import (
 	"context"
+	"reflect"
 	"sync"
+	"time"
 
 	regapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
 	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
 	taskapi "github.com/onosproject/onos-api/go/onos/e2sub/task"
+	"github.com/onosproject/onos-e2sub/pkg/controller/supervisor"
 	"github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	"github.com/onosproject/onos-e2sub/pkg/store/node"
 	"github.com/onosproject/onos-e2sub/pkg/store/subscription"
 	"github.com/onosproject/onos-e2sub/pkg/store/task"
+	"github.com/onosproject/onos-e2sub/pkg/store/termination"
 	"github.com/onosproject/onos-lib-go/pkg/controller"
 )
 
 const queueSize = 100
 
 // Watcher is a subscription watcher
+// The watcher indexes the E2 node of each subscription.
 type Watcher struct {
 	subs   subscription.Store
+	index  *index
 	cancel context.CancelFunc
 	mu     sync.Mutex
 }
@@ -34,20 +42,29 @@ func (w *Watcher) Start(ch chan<- controller.ID) error {
 		return nil
 	}
 
-	subCh := make(chan subapi.Event, queueSize)
 	ctx, cancel := context.WithCancel(context.Background())
-	err := w.subs.Watch(ctx, subCh)
-	if err != nil {
-		cancel()
-		return err
-	}
 	w.cancel = cancel
 
 	go func() {
-		for request := range subCh {
-			ch <- controller.NewID(request.Subscription.ID)
-		}
-		close(ch)
+		defer close(ch)
+		supervisor.Watch(ctx, "subscription/subscriptions", func(ctx context.Context, resync bool, ready func()) error {
+			// The replay rebuilds the index, dropping the subscriptions removed while the watch was down
+			subCh := make(chan subapi.Event, queueSize)
+			if err := w.subs.Watch(ctx, subCh, subscription.WithReplay()); err != nil {
+				return err
+			}
+			w.index.resetSubscriptions()
+			ready()
+			for event := range subCh {
+				if event.Type == subapi.EventType_REMOVED {
+					w.index.deleteSubscription(event.Subscription.ID)
+				} else {
+					w.index.updateSubscription(&event.Subscription)
+				}
+				ch <- controller.NewID(event.Subscription.ID)
+			}
+			return nil
+		})
 	}()
 	return nil
 }
@@ -64,10 +81,73 @@ func (w *Watcher) Stop() {
 
 var _ controller.Watcher = &Watcher{}
 
+// MetadataWatcher is a subscription metadata watcher
+// The watcher indexes the placement condition of each subscription.
+type MetadataWatcher struct {
+	metadata metadata.Store
+	index    *index
+	cancel   context.CancelFunc
+	mu       sync.Mutex
+}
+
+// Start starts the metadata watcher
+func (w *MetadataWatcher) Start(ch chan<- controller.ID) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	go func() {
+		defer close(ch)
+		supervisor.Watch(ctx, "subscription/metadata", func(ctx context.Context, resync bool, ready func()) error {
+			// The replay rebuilds the index, dropping the metadata removed while the watch was down
+			metaCh := make(chan metadata.Event, queueSize)
+			if err := w.metadata.Watch(ctx, metaCh, metadata.WithReplay()); err != nil {
+				return err
+			}
+			w.index.resetMetadata()
+			ready()
+			for event := range metaCh {
+				if event.Type == metadata.EventRemoved {
+					w.index.deleteMetadata(event.Metadata.ID)
+					continue
+				}
+				w.index.updateMetadata(&event.Metadata)
+				ch <- controller.NewID(event.Metadata.ID)
+			}
+			return nil
+		})
+	}()
+	return nil
+}
+
+// Stop stops the metadata watcher
+func (w *MetadataWatcher) Stop() {
+	w.mu.Lock()
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+	w.mu.Unlock()
+}
+
+var _ controller.Watcher = &MetadataWatcher{}
+
+// debounce is the window within which the events enqueueing many subscriptions, e.g. every
+// subscription that could not be placed, are coalesced
+const debounce = 100 * time.Millisecond
+
 // TerminationEndpointWatcher is a termination endpoint watcher
+// Rather than requeueing every subscription on each endpoint event, the watcher enqueues only the
+// subscriptions an event can affect: those with tasks on a removed endpoint, and those that could not
+// be placed when an endpoint is added. Bursts of events are coalesced within debounce.
 type TerminationEndpointWatcher struct {
-	subs      subscription.Store
 	endpoints endpoint.Store
+	index     *index
 	cancel    context.CancelFunc
 	mu        sync.Mutex
 }
@@ -80,29 +160,93 @@ func (w *TerminationEndpointWatcher) Start(ch chan<- controller.ID) error {
 		return nil
 	}
 
-	endpointCh := make(chan regapi.Event, queueSize)
 	ctx, cancel := context.WithCancel(context.Background())
-	err := w.endpoints.Watch(ctx, endpointCh)
-	if err != nil {
-		cancel()
-		return err
-	}
 	w.cancel = cancel
 
 	go func() {
-		for range endpointCh {
-			subs, err := w.subs.List(ctx)
-			if err == nil {
-				for _, sub := range subs {
-					ch <- controller.NewID(sub.ID)
+		defer close(ch)
+		supervisor.Watch(ctx, "subscription/endpoints", func(ctx context.Context, resync bool, ready func()) error {
+			endpointCh := make(chan regapi.Event, queueSize)
+			if err := w.endpoints.Watch(ctx, endpointCh); err != nil {
+				return err
+			}
+			ready()
+			if resync {
+				w.resync(ctx, ch)
+			}
+
+			added := false
+			removed := make(map[regapi.ID]bool)
+			var flush <-chan time.Time
+			for {
+				select {
+				case event, ok := <-endpointCh:
+					if !ok {
+						return nil
+					}
+					switch event.Type {
+					case regapi.EventType_ADDED:
+						added = true
+					case regapi.EventType_REMOVED:
+						removed[event.Endpoint.ID] = true
+					default:
+						// Tasks are assigned to endpoints by ID, so changes to an endpoint's address do not affect placement
+						continue
+					}
+					if flush == nil {
+						flush = time.After(debounce)
+					}
+				case <-flush:
+					w.enqueueAffected(added, removed, ch)
+					added = false
+					removed = make(map[regapi.ID]bool)
+					flush = nil
 				}
 			}
-		}
-		close(ch)
+		})
 	}()
 	return nil
 }
 
+// resync enqueues the subscriptions affected by endpoint events missed while the watch was down
+// Since the missed events are unknown, every endpoint hosting tasks that's no longer registered is
+// treated as removed, and an endpoint is assumed to have been added.
+func (w *TerminationEndpointWatcher) resync(ctx context.Context, ch chan<- controller.ID) {
+	endpoints, err := w.endpoints.List(ctx)
+	if err != nil {
+		log.Warnf("Failed to list termination endpoints: %s", err)
+		return
+	}
+	registered := make(map[regapi.ID]bool)
+	for _, ep := range endpoints {
+		registered[ep.ID] = true
+	}
+	removed := make(map[regapi.ID]bool)
+	for _, id := range w.index.getEndpoints() {
+		if !registered[id] {
+			removed[id] = true
+		}
+	}
+	w.enqueueAffected(true, removed, ch)
+}
+
+// enqueueAffected enqueues the subscriptions with tasks on the removed endpoints and, if an endpoint
+// was added, the subscriptions that could not be placed
+func (w *TerminationEndpointWatcher) enqueueAffected(added bool, removed map[regapi.ID]bool, ch chan<- controller.ID) {
+	ids := make(map[subapi.ID]bool)
+	for id := range removed {
+		for _, subID := range w.index.getByEndpoint(id) {
+			ids[subID] = true
+		}
+	}
+	if added {
+		for _, subID := range w.index.getUnplaced() {
+			ids[subID] = true
+		}
+	}
+	enqueue(ids, ch)
+}
+
 // Stop stops the channel watcher
 func (w *TerminationEndpointWatcher) Stop() {
 	w.mu.Lock()
@@ -115,10 +259,13 @@ func (w *TerminationEndpointWatcher) Stop() {
 
 var _ controller.Watcher = &TerminationEndpointWatcher{}
 
-// TaskWatcher is a termination endpoint watcher
+// TaskWatcher is a subscription task watcher
+// The watcher indexes the endpoint and subscription of each task, and enqueues the subscription of
+// each changed task. Since removing a task may free capacity, the subscriptions that are unschedulable
+// are enqueued once per burst of removals, coalesced within debounce.
 type TaskWatcher struct {
-	subs   subscription.Store
 	tasks  task.Store
+	index  *index
 	cancel context.CancelFunc
 	mu     sync.Mutex
 }
@@ -131,23 +278,45 @@ func (w *TaskWatcher) Start(ch chan<- controller.ID) error {
 		return nil
 	}
 
-	taskCh := make(chan taskapi.Event, queueSize)
 	ctx, cancel := context.WithCancel(context.Background())
-	err := w.tasks.Watch(ctx, taskCh)
-	if err != nil {
-		cancel()
-		return err
-	}
 	w.cancel = cancel
 
 	go func() {
-		for event := range taskCh {
-			sub, err := w.subs.Get(ctx, event.Task.SubscriptionID)
-			if err == nil {
-				ch <- controller.NewID(sub.ID)
+		defer close(ch)
+		supervisor.Watch(ctx, "subscription/tasks", func(ctx context.Context, resync bool, ready func()) error {
+			// The replay rebuilds the index, dropping the tasks removed while the watch was down
+			taskCh := make(chan taskapi.Event, queueSize)
+			if err := w.tasks.Watch(ctx, taskCh, task.WithReplay()); err != nil {
+				return err
 			}
-		}
-		close(ch)
+			w.index.resetTasks()
+			ready()
+			var flush <-chan time.Time
+			for {
+				select {
+				case event, ok := <-taskCh:
+					if !ok {
+						return nil
+					}
+					if event.Type == taskapi.EventType_REMOVED {
+						w.index.deleteTask(event.Task.ID)
+						if flush == nil {
+							flush = time.After(debounce)
+						}
+					} else {
+						w.index.updateTask(&event.Task)
+					}
+					ch <- controller.NewID(event.Task.SubscriptionID)
+				case <-flush:
+					ids := make(map[subapi.ID]bool)
+					for _, id := range w.index.getUnplaced(metadata.ConditionUnschedulable) {
+						ids[id] = true
+					}
+					enqueue(ids, ch)
+					flush = nil
+				}
+			}
+		})
 	}()
 	return nil
 }
@@ -163,3 +332,230 @@ func (w *TaskWatcher) Stop() {
 }
 
 var _ controller.Watcher = &TaskWatcher{}
+
+// placementState is the state of a termination used to place subscriptions
+// Terminations are rewritten by health probes, drains and node reports; only changes to this state
+// affect the placement of subscriptions.
+type placementState struct {
+	Nodes         []string
+	Labels        map[string]string
+	Zone          string
+	ServiceModels []termination.ServiceModel
+	Capacity      int
+	Cordoned      bool
+	Healthy       bool
+}
+
+// newPlacementState returns the placement state of the given termination
+func newPlacementState(term *termination.Termination) *placementState {
+	return &placementState{
+		Nodes:         term.Nodes,
+		Labels:        term.Labels,
+		Zone:          term.Zone,
+		ServiceModels: term.ServiceModels,
+		Capacity:      term.Capacity,
+		Cordoned:      term.Cordoned,
+		Healthy:       term.IsHealthy(),
+	}
+}
+
+// TerminationWatcher is a termination state watcher
+// The watcher tracks the placement state of each termination and, when it changes, enqueues the
+// subscriptions with tasks on the termination and the subscriptions that could not be placed.
+// Bursts of changes are coalesced within debounce.
+type TerminationWatcher struct {
+	terminations termination.Store
+	index        *index
+	states       map[regapi.ID]*placementState
+	cancel       context.CancelFunc
+	mu           sync.Mutex
+}
+
+// Start starts the termination watcher
+func (w *TerminationWatcher) Start(ch chan<- controller.ID) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.states = make(map[regapi.ID]*placementState)
+
+	go func() {
+		defer close(ch)
+		supervisor.Watch(ctx, "subscription/terminations", func(ctx context.Context, resync bool, ready func()) error {
+			// List the terminations after starting the watch to ensure no changes are missed
+			termCh := make(chan termination.Event, queueSize)
+			if err := w.terminations.Watch(ctx, termCh); err != nil {
+				return err
+			}
+			terms, err := w.terminations.List(ctx)
+			if err != nil {
+				return err
+			}
+			ready()
+
+			// Compare the listed terminations to the states last seen to find the changes missed while
+			// the watch was down
+			states := make(map[regapi.ID]*placementState)
+			for i := range terms {
+				states[terms[i].ID] = newPlacementState(&terms[i])
+			}
+			ids := make(map[subapi.ID]bool)
+			if resync {
+				for id := range w.states {
+					if _, ok := states[id]; !ok {
+						w.update(id, nil, ids)
+					}
+				}
+				for id, state := range states {
+					w.update(id, state, ids)
+				}
+			}
+			w.states = states
+			enqueue(ids, ch)
+
+			ids = make(map[subapi.ID]bool)
+			var flush <-chan time.Time
+			for {
+				select {
+				case event, ok := <-termCh:
+					if !ok {
+						return nil
+					}
+					if event.Type == termination.EventRemoved {
+						w.update(event.Termination.ID, nil, ids)
+					} else {
+						w.update(event.Termination.ID, newPlacementState(&event.Termination), ids)
+					}
+					if len(ids) > 0 && flush == nil {
+						flush = time.After(debounce)
+					}
+				case <-flush:
+					enqueue(ids, ch)
+					ids = make(map[subapi.ID]bool)
+					flush = nil
+				}
+			}
+		})
+	}()
+	return nil
+}
+
+// update records the placement state of a termination, or its removal if the state is nil, and adds
+// the subscriptions affected by the change to ids
+func (w *TerminationWatcher) update(id regapi.ID, state *placementState, ids map[subapi.ID]bool) {
+	prev, ok := w.states[id]
+	if ok && state != nil && reflect.DeepEqual(prev, state) {
+		return
+	}
+	if !ok && state == nil {
+		return
+	}
+
+	// Whether any termination reports its E2 nodes changes which endpoints every subscription may use
+	reported := w.reportsNodes()
+	if state == nil {
+		delete(w.states, id)
+	} else {
+		w.states[id] = state
+	}
+	if w.reportsNodes() != reported {
+		for _, subID := range w.index.getAll() {
+			ids[subID] = true
+		}
+		return
+	}
+
+	for _, subID := range w.index.getByEndpoint(id) {
+		ids[subID] = true
+	}
+	for _, subID := range w.index.getUnplaced() {
+		ids[subID] = true
+	}
+}
+
+// reportsNodes returns whether any termination reports its E2 nodes
+func (w *TerminationWatcher) reportsNodes() bool {
+	for _, state := range w.states {
+		if state.Nodes != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Stop stops the termination watcher
+func (w *TerminationWatcher) Stop() {
+	w.mu.Lock()
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+	w.mu.Unlock()
+}
+
+var _ controller.Watcher = &TerminationWatcher{}
+
+// NodeWatcher is an E2 node watcher
+type NodeWatcher struct {
+	nodes  node.Store
+	index  *index
+	cancel context.CancelFunc
+	mu     sync.Mutex
+}
+
+// Start starts the E2 node watcher
+func (w *NodeWatcher) Start(ch chan<- controller.ID) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	go func() {
+		defer close(ch)
+		supervisor.Watch(ctx, "subscription/nodes", func(ctx context.Context, resync bool, ready func()) error {
+			var opts []node.WatchOption
+			if resync {
+				opts = append(opts, node.WithReplay())
+			}
+			nodeCh := make(chan node.Event, queueSize)
+			if err := w.nodes.Watch(ctx, nodeCh, opts...); err != nil {
+				return err
+			}
+			ready()
+			for event := range nodeCh {
+				for _, id := range w.index.getByNode(event.Node.ID) {
+					ch <- controller.NewID(id)
+				}
+			}
+			return nil
+		})
+	}()
+	return nil
+}
+
+// Stop stops the E2 node watcher
+func (w *NodeWatcher) Stop() {
+	w.mu.Lock()
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+	w.mu.Unlock()
+}
+
+var _ controller.Watcher = &NodeWatcher{}
+
+// enqueue enqueues the given subscriptions
+func enqueue(ids map[subapi.ID]bool, ch chan<- controller.ID) {
+	for id := range ids {
+		ch <- controller.NewID(id)
+	}
+}
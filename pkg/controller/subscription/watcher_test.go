@@ -0,0 +1,172 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package subscription
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	regapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	taskapi "github.com/onosproject/onos-api/go/onos/e2sub/task"
+	epstore "github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	metastore "github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	taskstore "github.com/onosproject/onos-e2sub/pkg/store/task"
+	termstore "github.com/onosproject/onos-e2sub/pkg/store/termination"
+	"github.com/onosproject/onos-lib-go/pkg/controller"
+	"github.com/stretchr/testify/assert"
+)
+
+// nextIDs returns the IDs enqueued until no more IDs are enqueued for a second
+func nextIDs(ch chan controller.ID) []subapi.ID {
+	var ids []subapi.ID
+	for {
+		select {
+		case id := <-ch:
+			ids = append(ids, id.Value.(subapi.ID))
+		case <-time.After(time.Second):
+			return ids
+		}
+	}
+}
+
+func TestTerminationEndpointWatcher(t *testing.T) {
+	epStore, err := epstore.NewLocalStore()
+	assert.NoError(t, err)
+	defer epStore.Close()
+
+	ctx := context.TODO()
+	for _, id := range []string{"e2t-1", "e2t-2"} {
+		ep := createEP(id)
+		assert.NoError(t, epStore.Create(ctx, &ep))
+	}
+	affected := newIndex()
+	affected.updateTask(&taskapi.SubscriptionTask{ID: "sub-1:e2t-1", SubscriptionID: "sub-1", EndpointID: "e2t-1"})
+	affected.updateTask(&taskapi.SubscriptionTask{ID: "sub-2:e2t-2", SubscriptionID: "sub-2", EndpointID: "e2t-2"})
+	affected.updateMetadata(&metastore.Metadata{ID: "sub-3", Condition: metastore.ConditionUnschedulable})
+	affected.updateMetadata(&metastore.Metadata{ID: "sub-4"})
+
+	watcher := &TerminationEndpointWatcher{
+		endpoints: epStore,
+		index:     affected,
+	}
+	ch := make(chan controller.ID)
+	assert.NoError(t, watcher.Start(ch))
+	defer watcher.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	// Verify removing an endpoint only enqueues the subscriptions with tasks on it
+	assert.NoError(t, epStore.Delete(ctx, "e2t-1"))
+	assert.Equal(t, []subapi.ID{"sub-1"}, nextIDs(ch))
+
+	// Verify adding an endpoint only enqueues the subscriptions that could not be placed
+	ep := createEP("e2t-3")
+	assert.NoError(t, epStore.Create(ctx, &ep))
+	assert.Equal(t, []subapi.ID{"sub-3"}, nextIDs(ch))
+
+	// Verify a burst of events is coalesced
+	ep = createEP("e2t-4")
+	assert.NoError(t, epStore.Delete(ctx, "e2t-2"))
+	assert.NoError(t, epStore.Create(ctx, &ep))
+	assert.NoError(t, epStore.Delete(ctx, "e2t-3"))
+	ep = createEP("e2t-5")
+	assert.NoError(t, epStore.Create(ctx, &ep))
+	assert.ElementsMatch(t, []subapi.ID{"sub-2", "sub-3"}, nextIDs(ch))
+}
+
+func TestTerminationWatcher(t *testing.T) {
+	termStore, err := termstore.NewLocalStore()
+	assert.NoError(t, err)
+	defer termStore.Close()
+
+	ctx := context.TODO()
+	for _, id := range []regapi.ID{"e2t-1", "e2t-2"} {
+		assert.NoError(t, termStore.Create(ctx, &termstore.Termination{ID: id}))
+	}
+	affected := newIndex()
+	affected.updateSubscription(&subapi.Subscription{ID: "sub-1", Details: &subapi.SubscriptionDetails{E2NodeID: "e2node-1"}})
+	affected.updateSubscription(&subapi.Subscription{ID: "sub-2", Details: &subapi.SubscriptionDetails{E2NodeID: "e2node-1"}})
+	affected.updateSubscription(&subapi.Subscription{ID: "sub-3", Details: &subapi.SubscriptionDetails{E2NodeID: "e2node-1"}})
+	affected.updateTask(&taskapi.SubscriptionTask{ID: "sub-1:e2t-1", SubscriptionID: "sub-1", EndpointID: "e2t-1"})
+	affected.updateTask(&taskapi.SubscriptionTask{ID: "sub-2:e2t-2", SubscriptionID: "sub-2", EndpointID: "e2t-2"})
+	affected.updateMetadata(&metastore.Metadata{ID: "sub-3", Condition: metastore.ConditionUnschedulable})
+
+	watcher := &TerminationWatcher{
+		terminations: termStore,
+		index:        affected,
+	}
+	ch := make(chan controller.ID)
+	assert.NoError(t, watcher.Start(ch))
+	defer watcher.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	// Verify rewrites that do not change the termination's placement state enqueue no subscriptions
+	term, err := termStore.Get(ctx, "e2t-1")
+	assert.NoError(t, err)
+	term.Health = &termstore.Health{Status: termstore.HealthHealthy, Successes: 3}
+	assert.NoError(t, termStore.Update(ctx, term))
+	term.Drain = &termstore.Drain{Total: 1, Remaining: 1}
+	assert.NoError(t, termStore.Update(ctx, term))
+	assert.Empty(t, nextIDs(ch))
+
+	// Verify cordoning a termination enqueues the subscriptions on it and those that could not be placed
+	term.Cordoned = true
+	assert.NoError(t, termStore.Update(ctx, term))
+	assert.ElementsMatch(t, []subapi.ID{"sub-1", "sub-3"}, nextIDs(ch))
+
+	// Verify a termination that starts reporting its E2 nodes enqueues every subscription
+	term, err = termStore.Get(ctx, "e2t-2")
+	assert.NoError(t, err)
+	term.Nodes = []string{"e2node-1"}
+	assert.NoError(t, termStore.Update(ctx, term))
+	assert.ElementsMatch(t, []subapi.ID{"sub-1", "sub-2", "sub-3"}, nextIDs(ch))
+
+	// Verify a burst of changes is coalesced
+	for capacity := 1; capacity <= 3; capacity++ {
+		term.Capacity = capacity
+		assert.NoError(t, termStore.Update(ctx, term))
+	}
+	assert.ElementsMatch(t, []subapi.ID{"sub-2", "sub-3"}, nextIDs(ch))
+
+	// Verify removing a termination enqueues the subscriptions on it and those that could not be placed
+	assert.NoError(t, termStore.Delete(ctx, "e2t-1"))
+	assert.ElementsMatch(t, []subapi.ID{"sub-1", "sub-3"}, nextIDs(ch))
+}
+
+func TestTaskWatcher(t *testing.T) {
+	taskStore, err := taskstore.NewLocalStore()
+	assert.NoError(t, err)
+	defer taskStore.Close()
+
+	ctx := context.TODO()
+	for _, id := range []subapi.ID{"sub-1", "sub-2"} {
+		assert.NoError(t, taskStore.Create(ctx, &taskapi.SubscriptionTask{ID: taskapi.ID(id) + ":e2t-1", SubscriptionID: id, EndpointID: "e2t-1"}))
+	}
+	affected := newIndex()
+	affected.updateMetadata(&metastore.Metadata{ID: "sub-3", Condition: metastore.ConditionUnschedulable})
+	affected.updateMetadata(&metastore.Metadata{ID: "sub-4", Condition: metastore.ConditionWaitingForNode})
+
+	watcher := &TaskWatcher{
+		tasks: taskStore,
+		index: affected,
+	}
+	ch := make(chan controller.ID)
+	assert.NoError(t, watcher.Start(ch))
+	defer watcher.Stop()
+
+	// Verify the replayed tasks are indexed by subscription and enqueue their subscriptions
+	assert.ElementsMatch(t, []subapi.ID{"sub-1", "sub-2"}, nextIDs(ch))
+	assert.Equal(t, []taskapi.ID{"sub-1:e2t-1"}, affected.getTasks("sub-1"))
+	tasks, err := affected.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 2)
+
+	// Verify a burst of removals enqueues their subscriptions and, once, the unschedulable subscriptions
+	assert.NoError(t, taskStore.Delete(ctx, "sub-1:e2t-1"))
+	assert.NoError(t, taskStore.Delete(ctx, "sub-2:e2t-1"))
+	assert.ElementsMatch(t, []subapi.ID{"sub-1", "sub-2", "sub-3"}, nextIDs(ch))
+	assert.Empty(t, affected.getTasks("sub-1"))
+}
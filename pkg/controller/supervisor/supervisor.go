@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package supervisor
+
+import (
+	"context"
+	"expvar"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"k8s.io/client-go/tools/cache"
+)
+
+var log = logging.GetLogger("controller", "supervisor")
+
+const (
+	initialBackoff = 100 * time.Millisecond
+	maxBackoff     = 30 * time.Second
+)
+
+func init() {
+	expvar.Publish("watches", expvar.Func(func() interface{} {
+		return List()
+	}))
+}
+
+// WatchFunc establishes a watch and processes its events until the watch's stream ends
+// resync indicates the watch is being re-established and must replay the state it watches to
+// recover the events missed while it was down. The function calls ready once the watch is
+// established, and returns an error if the watch cannot be established.
+type WatchFunc func(ctx context.Context, resync bool, ready func()) error
+
+// Status is the status of a supervised watch
+type Status struct {
+	// Name is the name of the watch
+	Name string `json:"name"`
+	// Established indicates whether the watch is currently established
+	Established bool `json:"established"`
+	// Since is the time at which the watch was last established or lost
+	Since time.Time `json:"since"`
+	// Restarts is the number of times the watch has been re-established
+	Restarts int `json:"restarts"`
+	// Failures is the number of failed attempts to establish the watch
+	Failures int `json:"failures"`
+	// Error is the error with which the watch was last lost
+	Error string `json:"error,omitempty"`
+}
+
+var (
+	watches   = make(map[*Status]bool)
+	watchesMu sync.RWMutex
+)
+
+// Watch maintains a watch until the given context is canceled
+// Whenever the watch cannot be established or its stream ends, it's re-established with
+// exponential backoff.
+func Watch(ctx context.Context, name string, f WatchFunc) {
+	status := &Status{
+		Name:  name,
+		Since: time.Now(),
+	}
+	watchesMu.Lock()
+	watches[status] = true
+	watchesMu.Unlock()
+	defer func() {
+		watchesMu.Lock()
+		delete(watches, status)
+		watchesMu.Unlock()
+	}()
+
+	backoff := initialBackoff
+	established := false
+	for {
+		watchCtx, cancel := context.WithCancel(ctx)
+		resync := established
+		err := f(watchCtx, resync, func() {
+			watchesMu.Lock()
+			if established {
+				status.Restarts++
+			}
+			status.Established = true
+			status.Since = time.Now()
+			watchesMu.Unlock()
+			established = true
+			backoff = initialBackoff
+		})
+		cancel()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		watchesMu.Lock()
+		if status.Established {
+			status.Established = false
+			status.Since = time.Now()
+		} else {
+			status.Failures++
+		}
+		if err != nil {
+			status.Error = err.Error()
+		} else {
+			status.Error = "watch stream closed"
+		}
+		reason := status.Error
+		watchesMu.Unlock()
+
+		log.Warnf("Watch %s lost: %s; retrying in %s", name, reason, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// WatchInformer runs a shared informer until the given context is canceled
+// The informer re-lists and re-watches its resources itself, so the watch is reported established once
+// the informer's cache has synced and is not restarted.
+func WatchInformer(ctx context.Context, name string, informer cache.SharedInformer) {
+	Watch(ctx, name, func(ctx context.Context, resync bool, ready func()) error {
+		go informer.Run(ctx.Done())
+		if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+			return ctx.Err()
+		}
+		ready()
+		<-ctx.Done()
+		return nil
+	})
+}
+
+// List lists the statuses of the supervised watches
+func List() []Status {
+	watchesMu.RLock()
+	statuses := make([]Status, 0, len(watches))
+	for status := range watches {
+		statuses = append(statuses, *status)
+	}
+	watchesMu.RUnlock()
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// Ready returns whether all supervised watches are established
+func Ready() bool {
+	watchesMu.RLock()
+	defer watchesMu.RUnlock()
+	for status := range watches {
+		if !status.Established {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	attempts := make(chan bool)
+	streams := make(chan chan struct{})
+	attempt := 0
+	go Watch(ctx, "test", func(ctx context.Context, resync bool, ready func()) error {
+		attempt++
+		attempts <- resync
+
+		// Fail to establish the watch on the first attempt
+		if attempt == 1 {
+			return errors.New("unavailable")
+		}
+		ready()
+		stream := make(chan struct{})
+		streams <- stream
+		select {
+		case <-stream:
+		case <-ctx.Done():
+		}
+		return nil
+	})
+
+	// Verify the watch is retried after failing to establish
+	assert.False(t, <-attempts)
+	assert.False(t, <-attempts)
+	stream := <-streams
+	assert.True(t, Ready())
+	statuses := List()
+	assert.Len(t, statuses, 1)
+	assert.Equal(t, "test", statuses[0].Name)
+	assert.True(t, statuses[0].Established)
+	assert.Equal(t, 1, statuses[0].Failures)
+	assert.Equal(t, 0, statuses[0].Restarts)
+
+	// Close the stream and verify the watch is re-established with a resync
+	close(stream)
+	assert.True(t, <-attempts)
+	<-streams
+	statuses = List()
+	assert.Len(t, statuses, 1)
+	assert.True(t, statuses[0].Established)
+	assert.Equal(t, 1, statuses[0].Restarts)
+	assert.Equal(t, "watch stream closed", statuses[0].Error)
+
+	// Verify the watch is removed once the context is canceled
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(List()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Len(t, List(), 0)
+}
+
+func TestReady(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	attempts := make(chan struct{}, 10)
+	go Watch(ctx, "unavailable", func(ctx context.Context, resync bool, ready func()) error {
+		attempts <- struct{}{}
+		return errors.New("unavailable")
+	})
+
+	<-attempts
+	<-attempts
+	assert.False(t, Ready())
+	statuses := List()
+	assert.Len(t, statuses, 1)
+	assert.False(t, statuses[0].Established)
+	assert.Equal(t, "unavailable", statuses[0].Error)
+}
+
+func TestWatchInformer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	listed := make(chan struct{})
+	informer := cache.NewSharedInformer(&cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			<-listed
+			return &corev1.PodList{}, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return watch.NewFake(), nil
+		},
+	}, &corev1.Pod{}, 0)
+
+	done := make(chan struct{})
+	go func() {
+		WatchInformer(ctx, "informer", informer)
+		close(done)
+	}()
+
+	// Verify the watch is not established until the informer's cache has synced
+	time.Sleep(100 * time.Millisecond)
+	status := getStatus("informer")
+	assert.NotNil(t, status)
+	assert.False(t, status.Established)
+
+	close(listed)
+	deadline := time.Now().Add(5 * time.Second)
+	for !getStatus("informer").Established && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, getStatus("informer").Established)
+
+	cancel()
+	<-done
+	assert.Nil(t, getStatus("informer"))
+}
+
+func getStatus(name string) *Status {
+	for _, status := range List() {
+		if status.Name == name {
+			return &status
+		}
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package health
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	_map "github.com/atomix/go-client/pkg/client/map"
+	"github.com/onosproject/onos-e2sub/pkg/controller/supervisor"
+	"github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+)
+
+var log = logging.GetLogger("health")
+
+// Check checks the health of a component, returning an error if the component is unhealthy
+type Check func(ctx context.Context) error
+
+// Status is the result of a check
+type Status struct {
+	// Name is the name of the check
+	Name string `json:"name"`
+	// Required indicates whether the replica is only ready while the check passes
+	Required bool `json:"required"`
+	// Healthy indicates whether the check passed
+	Healthy bool `json:"healthy"`
+	// Error is the reason the check failed
+	Error string `json:"error,omitempty"`
+}
+
+// Report is the result of the checks of a Checker
+type Report struct {
+	// Ready indicates whether all the required checks passed
+	Ready bool `json:"ready"`
+	// Checks are the results of the checks ordered by name
+	Checks []Status `json:"checks"`
+}
+
+// NewChecker returns a new Checker failing checks that take longer than the given timeout
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+		checks:  make(map[string]check),
+	}
+}
+
+// Checker aggregates the checks of the components of a replica to determine whether it's ready
+type Checker struct {
+	timeout time.Duration
+	checks  map[string]check
+	mu      sync.RWMutex
+}
+
+// check is a registered check
+type check struct {
+	required bool
+	check    Check
+}
+
+// Register registers a named check
+// A check that is not required is reported but does not affect whether the replica is ready.
+func (c *Checker) Register(name string, required bool, f Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check{
+		required: required,
+		check:    f,
+	}
+}
+
+// Check runs the checks concurrently and reports their results
+func (c *Checker) Check(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	c.mu.RLock()
+	statuses := make([]Status, 0, len(c.checks))
+	checks := make([]Check, 0, len(c.checks))
+	for name, check := range c.checks {
+		statuses = append(statuses, Status{Name: name, Required: check.required})
+		checks = append(checks, check.check)
+	}
+	c.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := checks[i](ctx); err != nil {
+				statuses[i].Error = err.Error()
+			} else {
+				statuses[i].Healthy = true
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	report := Report{
+		Ready:  true,
+		Checks: statuses,
+	}
+	for _, status := range statuses {
+		if !status.Healthy {
+			if status.Required {
+				report.Ready = false
+			}
+			log.Debugf("Health check %s failed: %s", status.Name, status.Error)
+		}
+	}
+	return report
+}
+
+// NewStartup returns a new Startup awaiting the given components
+func NewStartup(components ...string) *Startup {
+	pending := make(map[string]bool)
+	for _, component := range components {
+		pending[component] = true
+	}
+	return &Startup{
+		pending: pending,
+	}
+}
+
+// Startup tracks the components of a replica that have yet to start
+type Startup struct {
+	pending map[string]bool
+	mu      sync.RWMutex
+}
+
+// Started records that the component has started
+func (s *Startup) Started(component string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, component)
+}
+
+// Check fails until all the components have started
+func (s *Startup) Check(ctx context.Context) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.pending) == 0 {
+		return nil
+	}
+	pending := make([]string, 0, len(s.pending))
+	for component := range s.pending {
+		pending = append(pending, component)
+	}
+	sort.Strings(pending)
+	return errors.NewUnavailable("waiting for %s to start", strings.Join(pending, ", "))
+}
+
+// Store returns a check that the store backend is reachable through the given map
+func Store(m _map.Map) Check {
+	return func(ctx context.Context) error {
+		if _, err := m.Len(ctx); err != nil {
+			return errors.FromAtomix(err)
+		}
+		return nil
+	}
+}
+
+// Watches checks that the controllers' supervised watches are established
+func Watches(ctx context.Context) error {
+	var lost []string
+	for _, status := range supervisor.List() {
+		if !status.Established {
+			lost = append(lost, status.Name)
+		}
+	}
+	if len(lost) > 0 {
+		return errors.NewUnavailable("watches %s are not established", strings.Join(lost, ", "))
+	}
+	return nil
+}
+
+// Endpoints returns a check that termination endpoints are registered
+func Endpoints(endpoints endpoint.Store) Check {
+	return func(ctx context.Context) error {
+		eps, err := endpoints.List(ctx)
+		if err != nil {
+			return err
+		}
+		if len(eps) == 0 {
+			return errors.NewUnavailable("no termination endpoints are registered")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package health
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker(t *testing.T) {
+	endpoints, err := endpoint.NewLocalStore()
+	assert.NoError(t, err)
+	defer endpoints.Close()
+
+	startup := NewStartup("controllers", "northbound")
+	checker := NewChecker(100 * time.Millisecond)
+	checker.Register("startup", true, startup.Check)
+	checker.Register("endpoints", false, Endpoints(endpoints))
+	checker.Register("watches", true, Watches)
+
+	// Verify the replica is not ready until its components have started
+	report := checker.Check(context.Background())
+	assert.False(t, report.Ready)
+	assert.Len(t, report.Checks, 3)
+	assert.Equal(t, "endpoints", report.Checks[0].Name)
+	assert.Equal(t, "startup", report.Checks[1].Name)
+	assert.False(t, report.Checks[1].Healthy)
+	assert.Contains(t, report.Checks[1].Error, "controllers, northbound")
+
+	// Verify checks that are not required do not affect readiness
+	startup.Started("controllers")
+	startup.Started("northbound")
+	report = checker.Check(context.Background())
+	assert.True(t, report.Ready)
+	assert.False(t, report.Checks[0].Healthy)
+	assert.True(t, report.Checks[1].Healthy)
+
+	assert.NoError(t, endpoints.Create(context.Background(), &epapi.TerminationEndpoint{ID: "e2t-1", IP: "127.0.0.1", Port: 5150}))
+	report = checker.Check(context.Background())
+	assert.True(t, report.Checks[0].Healthy)
+
+	// Verify a check that does not complete in time fails
+	checker.Register("store", true, func(ctx context.Context) error {
+		<-ctx.Done()
+		return errors.NewTimeout("store did not respond")
+	})
+	report = checker.Check(context.Background())
+	assert.False(t, report.Ready)
+	assert.Equal(t, "store", report.Checks[2].Name)
+	assert.False(t, report.Checks[2].Healthy)
+}
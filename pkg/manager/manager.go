@@ -5,29 +5,66 @@
 package manager
 
 import (
+	"context"
+	"time"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+	e2subconfig "github.com/onosproject/onos-e2sub/pkg/config"
+	discoveryctrl "github.com/onosproject/onos-e2sub/pkg/controller/discovery"
+	drainctrl "github.com/onosproject/onos-e2sub/pkg/controller/drain"
 	endpointctrl "github.com/onosproject/onos-e2sub/pkg/controller/endpoint"
+	nodectrl "github.com/onosproject/onos-e2sub/pkg/controller/node"
+	ownerctrl "github.com/onosproject/onos-e2sub/pkg/controller/owner"
+	"github.com/onosproject/onos-e2sub/pkg/controller/placement"
+	sessionctrl "github.com/onosproject/onos-e2sub/pkg/controller/session"
 	subctrl "github.com/onosproject/onos-e2sub/pkg/controller/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/health"
+	"github.com/onosproject/onos-e2sub/pkg/northbound/admin"
 	"github.com/onosproject/onos-e2sub/pkg/northbound/endpoint"
+	healthsvc "github.com/onosproject/onos-e2sub/pkg/northbound/health"
 	"github.com/onosproject/onos-e2sub/pkg/northbound/subscription"
 	"github.com/onosproject/onos-e2sub/pkg/northbound/task"
+	"github.com/onosproject/onos-e2sub/pkg/store/driver"
 	regstore "github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/store/history"
+	metastore "github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	nodestore "github.com/onosproject/onos-e2sub/pkg/store/node"
+	"github.com/onosproject/onos-e2sub/pkg/store/quarantine"
+	sessionstore "github.com/onosproject/onos-e2sub/pkg/store/session"
 	substore "github.com/onosproject/onos-e2sub/pkg/store/subscription"
 	taskstore "github.com/onosproject/onos-e2sub/pkg/store/task"
+	termstore "github.com/onosproject/onos-e2sub/pkg/store/termination"
+	"github.com/onosproject/onos-e2sub/pkg/store/txn"
+	"github.com/onosproject/onos-e2sub/pkg/store/watch"
+	"github.com/onosproject/onos-lib-go/pkg/env"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
 	"github.com/onosproject/onos-lib-go/pkg/logging"
 	"github.com/onosproject/onos-lib-go/pkg/northbound"
+	"github.com/onosproject/onos-lib-go/pkg/southbound"
+	"google.golang.org/grpc"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
 
 var log = logging.GetLogger("manager")
 
+const (
+	// healthCheckTimeout is the time after which a health check fails
+	healthCheckTimeout = 5 * time.Second
+	// healthCheckInterval is the interval at which the gRPC health status is refreshed
+	healthCheckInterval = 5 * time.Second
+)
+
 // Config is a manager configuration
 type Config struct {
-	CAPath   string
-	KeyPath  string
-	CertPath string
-	GRPCPort int
-	E2Port   int
+	CAPath     string
+	KeyPath    string
+	CertPath   string
+	GRPCPort   int
+	E2Port     int
+	AdminHost  string
+	AdminPort  int
+	HealthPort int
 }
 
 // NewManager creates a new manager
@@ -85,37 +122,239 @@ func (m *Manager) startNorthboundServer() error {
 		return err
 	}
 
+	metaStore, err := metastore.NewAtomixStore()
+	if err != nil {
+		return err
+	}
+
+	sessionStore, err := sessionstore.NewAtomixStore()
+	if err != nil {
+		return err
+	}
+
+	termStore, err := termstore.NewAtomixStore()
+	if err != nil {
+		return err
+	}
+
+	quarantineStore, err := quarantine.NewAtomixStore()
+	if err != nil {
+		return err
+	}
+
+	e2subConfig, err := e2subconfig.GetConfig()
+	if err != nil {
+		return err
+	}
+
+	// Outside Kubernetes, e.g. a standalone binary using the embedded store, the controllers
+	// tracking termination pods and subscription owners are not started
+	var kubeClient kubernetes.Interface
 	config, err := rest.InClusterConfig()
+	switch {
+	case err == rest.ErrNotInCluster:
+		log.Warn("Not running in Kubernetes: termination pods and subscription owners are not tracked")
+		if e2subConfig.Discovery.Service != "" || e2subConfig.Discovery.Selector != "" {
+			return errors.NewInvalid("termination discovery requires Kubernetes")
+		}
+	case err != nil:
+		return err
+	default:
+		kubeClient, err = kubernetes.NewForConfig(config)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Record the mutations of subscriptions, tasks and endpoints in their history
+	historyStore, err := history.NewAtomixStore(history.Retention{
+		MaxRecords: e2subConfig.History.GetMaxRecords(),
+		MaxAge:     e2subConfig.History.GetMaxAge(),
+	})
 	if err != nil {
 		return err
 	}
+	history.StartPruning(historyStore, e2subConfig.History.GetMaxAge(), e2subConfig.History.GetPruneInterval())
+	endpointStore = history.NewEndpointStore(endpointStore, historyStore)
+	subStore = history.NewSubscriptionStore(subStore, historyStore)
+	taskStore = history.NewTaskStore(taskStore, historyStore)
 
-	kubeClient, err := kubernetes.NewForConfig(config)
+	// If onos-topo is configured, the connectivity of E2 nodes is tracked in the node store
+	var nodeStore nodestore.Store
+	if e2subConfig.Topo.Address != "" {
+		nodeStore, err = nodestore.NewAtomixStore()
+		if err != nil {
+			return err
+		}
+	}
+
+	txnStore, err := txn.NewAtomixStore()
 	if err != nil {
 		return err
 	}
 
-	endpointController := endpointctrl.NewController(endpointStore, kubeClient)
-	err = endpointController.Start()
+	// The replica is ready once its controllers and northbound API have started, while the store
+	// backend is reachable and the controllers' watches are established
+	healthMap, err := driver.GetMap(context.Background(), "health")
 	if err != nil {
 		return err
 	}
+	startup := health.NewStartup("controllers", "northbound")
+	checker := health.NewChecker(healthCheckTimeout)
+	checker.Register("startup", true, startup.Check)
+	checker.Register("store", true, health.Store(healthMap))
+	checker.Register("watches", true, health.Watches)
+	checker.Register("endpoints", false, health.Endpoints(endpointStore))
 
-	subController := subctrl.NewController(subStore, endpointStore, taskStore)
+	watchPolicy, err := watch.ParsePolicy(e2subConfig.Watch.GetPolicy())
+	if err != nil {
+		return err
+	}
+	watchBufferSize := e2subConfig.Watch.GetBufferSize()
+
+	placer := placement.NewPlacer(endpointStore, termStore, taskStore, nodeStore)
+	subService := subscription.NewService(subStore, metaStore, sessionStore, txnStore, placer,
+		e2subConfig.Sessions.GetKeepAlive(), e2subConfig.Sessions.GetTTL(), watchBufferSize, watchPolicy)
+
+	// Start the admin server before the controllers so that liveness is reported while they start
+	adminServer := admin.NewServer(m.Config.AdminHost, m.Config.AdminPort, subStore, taskStore, endpointStore, termStore, metaStore, txnStore, historyStore,
+		quarantineStore, subService.Server(), checker)
+	adminCh := make(chan error)
+	go func() {
+		err := adminServer.Serve(func(started string) {
+			log.Info("Started admin server on ", started)
+			close(adminCh)
+		})
+		if err != nil {
+			adminCh <- err
+		}
+	}()
+	if err := <-adminCh; err != nil {
+		return err
+	}
+	healthCh := make(chan error)
+	go func() {
+		err := adminServer.ServeHealth(m.Config.HealthPort, func(started string) {
+			log.Info("Started health server on ", started)
+			close(healthCh)
+		})
+		if err != nil {
+			healthCh <- err
+		}
+	}()
+	if err := <-healthCh; err != nil {
+		return err
+	}
+
+	if kubeClient != nil {
+		endpointController := endpointctrl.NewController(endpointStore, kubeClient, env.GetPodNamespace(),
+			e2subConfig.Pods.Selector, e2subConfig.Pods.GetResyncPeriod())
+		err = endpointController.Start()
+		if err != nil {
+			return err
+		}
+	}
+
+	// If Kubernetes discovery is configured, register terminations from a Service or from labelled pods
+	var source discoveryctrl.Source
+	if e2subConfig.Discovery.Service != "" {
+		source = discoveryctrl.NewServiceSource(kubeClient, env.GetPodNamespace(), e2subConfig.Discovery.Service, e2subConfig.Discovery.PortName)
+	} else if e2subConfig.Discovery.Selector != "" {
+		source, err = discoveryctrl.NewPodSource(kubeClient, env.GetPodNamespace(), e2subConfig.Discovery.Selector, e2subConfig.Discovery.GetPort())
+		if err != nil {
+			return err
+		}
+	}
+	if source != nil {
+		discoveryController := discoveryctrl.NewController(endpointStore, termStore, source)
+		err = discoveryController.Start()
+		if err != nil {
+			return err
+		}
+	}
+
+	prober := endpointctrl.NewGRPCProber(func(ctx context.Context, address string) (*grpc.ClientConn, error) {
+		return southbound.Connect(ctx, address, m.Config.CertPath, m.Config.KeyPath, grpc.WithBlock())
+	})
+	healthController := endpointctrl.NewHealthController(endpointStore, termStore, prober,
+		e2subConfig.Health.GetInterval(), e2subConfig.Health.GetTimeout(),
+		e2subConfig.Health.GetFailureThreshold(), e2subConfig.Health.GetSuccessThreshold())
+	err = healthController.Start()
+	if err != nil {
+		return err
+	}
+
+	// If onos-topo is configured, track the connectivity of E2 nodes
+	if e2subConfig.Topo.Address != "" {
+		conn, err := southbound.Connect(context.Background(), e2subConfig.Topo.Address, m.Config.CertPath, m.Config.KeyPath)
+		if err != nil {
+			return err
+		}
+
+		nodeController := nodectrl.NewController(nodeStore, topoapi.NewTopoClient(conn))
+		err = nodeController.Start()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Complete the transactions interrupted by a crash before reconciling subscriptions, and keep
+	// completing those abandoned by other instances
+	transactor := txn.NewTransactor(txnStore, txn.Stores{
+		Subscriptions: subStore,
+		Tasks:         taskStore,
+		Metadata:      metaStore,
+	})
+	recoveryCtx := history.WithCaller(context.Background(), "txn/recovery")
+	if _, err := transactor.Recover(recoveryCtx, e2subConfig.Store.GetTransactionTimeout()); err != nil {
+		return err
+	}
+	transactor.StartRecovery(recoveryCtx, e2subConfig.Store.GetTransactionTimeout())
+
+	subController := subctrl.NewController(subStore, endpointStore, taskStore, metaStore, nodeStore, termStore, txnStore)
 	err = subController.Start()
 	if err != nil {
 		return err
 	}
 
+	if kubeClient != nil {
+		ownerNamespaces := e2subConfig.Subscriptions.OwnerNamespaces
+		if len(ownerNamespaces) == 0 {
+			ownerNamespaces = []string{env.GetPodNamespace()}
+		}
+		ownerController := ownerctrl.NewController(subStore, metaStore, txnStore, kubeClient, ownerNamespaces, e2subConfig.Subscriptions.GetOwnerGracePeriod())
+		err = ownerController.Start()
+		if err != nil {
+			return err
+		}
+	}
+
+	sessionController := sessionctrl.NewController(sessionStore, subStore, metaStore, txnStore,
+		e2subConfig.Sessions.GetTimeout(), e2subConfig.Sessions.GetGracePeriod())
+	err = sessionController.Start()
+	if err != nil {
+		return err
+	}
+
+	drainController := drainctrl.NewController(termStore, taskStore, metaStore, txnStore,
+		e2subConfig.Drain.GetBatchSize(), e2subConfig.Drain.GetInterval())
+	err = drainController.Start()
+	if err != nil {
+		return err
+	}
+	startup.Started("controllers")
+
 	s.AddService(logging.Service{})
-	s.AddService(endpoint.NewService(endpointStore))
-	s.AddService(subscription.NewService(subStore))
-	s.AddService(task.NewService(taskStore))
+	s.AddService(healthsvc.NewService(checker, healthCheckInterval))
+	s.AddService(endpoint.NewService(endpointStore, termStore, watchBufferSize, watchPolicy))
+	s.AddService(subService)
+	s.AddService(task.NewService(taskStore, watchBufferSize, watchPolicy))
 
 	doneCh := make(chan error)
 	go func() {
 		err := s.Serve(func(started string) {
 			log.Info("Started NBI on ", started)
+			startup.Started("northbound")
 			close(doneCh)
 		})
 		if err != nil {
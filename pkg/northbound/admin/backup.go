@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package admin
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/onosproject/onos-e2sub/pkg/backup"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// handleBackup serves a snapshot of the subscriptions, tasks, endpoints, subscription metadata and termination state
+func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Buffer the snapshot so a failed export is reported as an error rather than a truncated file
+	file := fmt.Sprintf("e2sub-%s.jsonl", time.Now().UTC().Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file))
+	writer := &bufferedResponse{ResponseWriter: w}
+	if _, err := backup.Export(r.Context(), s.backupStores(), writer); err != nil {
+		w.Header().Del("Content-Disposition")
+		writeError(w, err)
+		return
+	}
+	writer.flush()
+}
+
+// handleRestore restores a snapshot into an empty deployment
+func (s *Server) handleRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	opts := backup.ImportOptions{}
+	var err error
+	if opts.SkipTasks, err = parseBool(r, "skipTasks"); err != nil {
+		writeError(w, err)
+		return
+	}
+	if opts.SkipEndpoints, err = parseBool(r, "skipEndpoints"); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	imported, err := backup.Import(r.Context(), s.backupStores(), bufio.NewReader(r.Body), opts)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, imported)
+}
+
+func (s *Server) backupStores() backup.Stores {
+	return backup.Stores{
+		Subscriptions: s.subscriptions,
+		Tasks:         s.tasks,
+		Endpoints:     s.endpoints,
+		Metadata:      s.metadata,
+		Terminations:  s.terminations,
+		Transactions:  s.transactions,
+	}
+}
+
+// parseBool parses an optional boolean query parameter
+func parseBool(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.NewInvalid("invalid %s %s", name, value)
+	}
+	return b, nil
+}
+
+// bufferedResponse buffers a response body until flushed
+type bufferedResponse struct {
+	http.ResponseWriter
+	buf []byte
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.buf = append(b.buf, p...)
+	return len(p), nil
+}
+
+func (b *bufferedResponse) flush() {
+	b.ResponseWriter.WriteHeader(http.StatusOK)
+	if _, err := b.ResponseWriter.Write(b.buf); err != nil {
+		log.Warnf("Failed to write response: %s", err)
+	}
+}
@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/backup"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackupRestore(t *testing.T) {
+	s, endpointStore, termStore := newTestServer(t)
+	defer endpointStore.Close()
+	defer termStore.Close()
+
+	err := endpointStore.Create(context.TODO(), &epapi.TerminationEndpoint{ID: "e2t-1"})
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, basePath+"/backup", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	snapshot := w.Body.Bytes()
+
+	// Verify endpoints already registered prevent restoring the snapshot's endpoints
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, basePath+"/restore", bytes.NewReader(snapshot)))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, basePath+"/restore?skipEndpoints=true", bytes.NewReader(snapshot)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	imported := backup.Imported{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &imported))
+	assert.Equal(t, 0, imported[backup.KindEndpoint])
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, basePath+"/restore?skipTasks=maybe", bytes.NewReader(snapshot)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, basePath+"/restore", bytes.NewReader([]byte("{}"))))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, basePath+"/backup", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
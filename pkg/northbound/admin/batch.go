@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	subnb "github.com/onosproject/onos-e2sub/pkg/northbound/subscription"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	grpcmd "google.golang.org/grpc/metadata"
+)
+
+// metadataPrefix is the prefix of the request headers passed to the subscription service as request metadata
+const metadataPrefix = "e2sub-"
+
+// Batcher adds and removes batches of subscriptions
+type Batcher interface {
+	// AddSubscriptions adds a batch of subscriptions
+	AddSubscriptions(ctx context.Context, req *subnb.AddSubscriptionsRequest) (*subnb.AddSubscriptionsResponse, error)
+
+	// RemoveSubscriptions removes a batch of subscriptions
+	RemoveSubscriptions(ctx context.Context, req *subnb.RemoveSubscriptionsRequest) (*subnb.RemoveSubscriptionsResponse, error)
+}
+
+// handleBatchAdd adds a batch of subscriptions
+func (s *Server) handleBatchAdd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	req := &subnb.AddSubscriptionsRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeError(w, errors.NewInvalid("invalid request: %s", err))
+		return
+	}
+	res, err := s.batcher.AddSubscriptions(withRequestMetadata(r), req)
+	if err != nil {
+		writeError(w, errors.FromGRPC(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// handleBatchRemove removes a batch of subscriptions
+func (s *Server) handleBatchRemove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	req := &subnb.RemoveSubscriptionsRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeError(w, errors.NewInvalid("invalid request: %s", err))
+		return
+	}
+	res, err := s.batcher.RemoveSubscriptions(withRequestMetadata(r), req)
+	if err != nil {
+		writeError(w, errors.FromGRPC(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// withRequestMetadata returns the request context with the e2sub- request headers as gRPC request metadata
+func withRequestMetadata(r *http.Request) context.Context {
+	md := grpcmd.MD{}
+	for name, values := range r.Header {
+		key := strings.ToLower(name)
+		if strings.HasPrefix(key, metadataPrefix) {
+			md.Append(key, values...)
+		}
+	}
+	return grpcmd.NewIncomingContext(r.Context(), md)
+}
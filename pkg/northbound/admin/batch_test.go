@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	subnb "github.com/onosproject/onos-e2sub/pkg/northbound/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/store/history"
+	"github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	"github.com/onosproject/onos-e2sub/pkg/store/session"
+	"github.com/onosproject/onos-e2sub/pkg/store/txn"
+	"github.com/onosproject/onos-e2sub/pkg/store/watch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatch(t *testing.T) {
+	s, endpointStore, termStore := newTestServer(t)
+	defer endpointStore.Close()
+	defer termStore.Close()
+
+	metaStore, err := metadata.NewLocalStore()
+	assert.NoError(t, err)
+	sessionStore, err := session.NewLocalStore()
+	assert.NoError(t, err)
+	txnStore, err := txn.NewLocalStore()
+	assert.NoError(t, err)
+	s.subscriptions = history.NewSubscriptionStore(s.subscriptions, s.history)
+	s.batcher = subnb.NewService(s.subscriptions, metaStore, sessionStore, txnStore, nil, 0, 0, 0, watch.PolicyBlock).Server()
+
+	// Verify the e2sub- request headers are applied to each subscription in the batch
+	body := `{"subscriptions": [{"id": "sub-1", "app_id": "app-1", "details": {"e2_node_id": "node-1"}}, {"id": "sub-2"}]}`
+	r := httptest.NewRequest(http.MethodPost, basePath+"/subscriptions:batchAdd", strings.NewReader(body))
+	r.Header.Set(subnb.ZoneKey, "zone-a")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	added := &subnb.AddSubscriptionsResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), added))
+	assert.Len(t, added.Results, 2)
+	assert.Equal(t, "OK", added.Results[0].Code)
+	assert.Equal(t, "InvalidArgument", added.Results[1].Code)
+	meta, err := metaStore.Get(context.TODO(), "sub-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "zone-a", meta.Placement.Zone)
+
+	// Verify the subscriptions added through the admin API are recorded as such
+	timeline, err := s.history.GetTimeline(context.TODO(), "sub-1")
+	assert.NoError(t, err)
+	assert.Len(t, timeline, 1)
+	assert.Equal(t, "admin/batchAdd", timeline[0].Caller)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, basePath+"/subscriptions:batchAdd", strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, basePath+"/subscriptions:batchRemove",
+		strings.NewReader(`{"ids": ["sub-1", "sub-2"], "atomic": true}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	removed := &subnb.RemoveSubscriptionsResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), removed))
+	assert.Equal(t, "FailedPrecondition", removed.Results[0].Code)
+	assert.Equal(t, "NotFound", removed.Results[1].Code)
+	sub, err := s.subscriptions.Get(context.TODO(), "sub-1")
+	assert.NoError(t, err)
+	assert.Equal(t, subapi.Status_ACTIVE, sub.Lifecycle.Status)
+}
@@ -0,0 +1,48 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onosproject/onos-e2sub/pkg/health"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth(t *testing.T) {
+	s, endpointStore, termStore := newTestServer(t)
+	defer endpointStore.Close()
+	defer termStore.Close()
+
+	startup := health.NewStartup("controllers")
+	s.health.Register("startup", true, startup.Check)
+	s.health.Register("endpoints", false, health.Endpoints(endpointStore))
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Verify the replica is not ready until the controllers have started
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	report := &health.Report{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), report))
+	assert.False(t, report.Ready)
+	assert.Len(t, report.Checks, 2)
+
+	// Verify the missing endpoints are reported without affecting readiness
+	startup.Started("controllers")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	report = &health.Report{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), report))
+	assert.True(t, report.Ready)
+	assert.False(t, report.Checks[0].Healthy)
+}
@@ -0,0 +1,32 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package admin
+
+import (
+	"net/http"
+	"strings"
+
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+)
+
+// handleSubscription serves the timeline of a subscription
+func (s *Server) handleSubscription(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, basePath+"/subscriptions/"), "/"), "/")
+	if len(path) != 2 || path[0] == "" || path[1] != "timeline" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	timeline, err := s.history.GetTimeline(r.Context(), subapi.ID(path[0]))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, timeline)
+}
@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onosproject/onos-e2sub/pkg/store/history"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeline(t *testing.T) {
+	s, endpointStore, termStore := newTestServer(t)
+	defer endpointStore.Close()
+	defer termStore.Close()
+	defer s.history.Close()
+
+	err := s.history.Append(context.TODO(), &history.Record{
+		Kind:           history.KindSubscription,
+		ObjectID:       "sub-1",
+		SubscriptionID: "sub-1",
+		Operation:      history.OperationCreated,
+	})
+	assert.NoError(t, err)
+	err = s.history.Append(context.TODO(), &history.Record{
+		Kind:           history.KindTask,
+		ObjectID:       "task-1",
+		SubscriptionID: "sub-1",
+		Operation:      history.OperationCreated,
+	})
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, basePath+"/subscriptions/sub-1/timeline", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var timeline []history.Record
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &timeline))
+	assert.Len(t, timeline, 2)
+	assert.Equal(t, "sub-1", timeline[0].ObjectID)
+	assert.Equal(t, "task-1", timeline[1].ObjectID)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, basePath+"/subscriptions/sub-1", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, basePath+"/subscriptions/sub-1/timeline", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
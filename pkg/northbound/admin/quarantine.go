@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/onosproject/onos-e2sub/pkg/store/quarantine"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// RestoreRequest is a request to restore a quarantined entry
+type RestoreRequest struct {
+	// Value is the repaired value to restore in place of the quarantined value, if any
+	Value []byte `json:"value,omitempty"`
+}
+
+// handleQuarantines serves the quarantined entries
+func (s *Server) handleQuarantines(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	entries, err := s.quarantine.List(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// handleQuarantine serves a quarantined entry and its restore and purge operations
+// Entry IDs are the entry's map and key, which may itself contain slashes.
+func (s *Server) handleQuarantine(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, basePath+"/quarantine/")
+	if id == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	entry, err := s.quarantine.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, entry)
+	case http.MethodPost:
+		request := &RestoreRequest{}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+				writeError(w, errors.NewInvalid("invalid restore request: %s", err))
+				return
+			}
+		}
+		if err := quarantine.Restore(r.Context(), s.quarantine, id, request.Value); err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, entry)
+	case http.MethodDelete:
+		log.Infof("Purging quarantined %s entry %s", entry.Map, entry.Key)
+		if err := s.quarantine.Delete(r.Context(), id); err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, entry)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
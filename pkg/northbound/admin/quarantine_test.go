@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/store/quarantine"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuarantine(t *testing.T) {
+	s, endpointStore, termStore := newTestServer(t)
+	defer endpointStore.Close()
+	defer termStore.Close()
+
+	ctx := context.TODO()
+	assert.NoError(t, s.quarantine.Put(ctx, &quarantine.Entry{Map: "subscriptions", Key: "sub-1", Value: []byte("corrupt")}))
+	assert.NoError(t, s.quarantine.Put(ctx, &quarantine.Entry{Map: "subscription-tasks", Key: "sub-1:e2t-1", Value: []byte("corrupt")}))
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, basePath+"/quarantine", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var entries []quarantine.Entry
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+	assert.Len(t, entries, 2)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, basePath+"/quarantine/subscriptions/sub-1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Verify the entry cannot be restored until it is repaired
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, basePath+"/quarantine/subscriptions/sub-1", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	value, err := proto.Marshal(&subapi.Subscription{AppID: "app-1"})
+	assert.NoError(t, err)
+	body, err := json.Marshal(&RestoreRequest{Value: value})
+	assert.NoError(t, err)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, basePath+"/quarantine/subscriptions/sub-1", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	sub, err := s.subscriptions.Get(ctx, "sub-1")
+	assert.NoError(t, err)
+	assert.Equal(t, subapi.AppID("app-1"), sub.AppID)
+
+	// Verify a quarantined entry can be purged
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, basePath+"/quarantine/subscription-tasks/sub-1:e2t-1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, basePath+"/quarantine", nil))
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+	assert.Empty(t, entries)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, basePath+"/quarantine/subscriptions/sub-1", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPut, basePath+"/quarantine", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
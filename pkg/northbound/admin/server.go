@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package admin
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/onosproject/onos-e2sub/pkg/health"
+	"github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/store/history"
+	"github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	"github.com/onosproject/onos-e2sub/pkg/store/quarantine"
+	"github.com/onosproject/onos-e2sub/pkg/store/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/store/task"
+	"github.com/onosproject/onos-e2sub/pkg/store/termination"
+	"github.com/onosproject/onos-e2sub/pkg/store/txn"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+)
+
+var log = logging.GetLogger("northbound", "admin")
+
+// basePath is the base path of the admin API
+const basePath = "/api/v1"
+
+// NewServer creates a new admin server listening on the given host and port
+// The admin API is unauthenticated, so the host should be a loopback address unless access to
+// the port is otherwise restricted.
+func NewServer(host string, port int, subscriptions subscription.Store, tasks task.Store, endpoints endpoint.Store,
+	terminations termination.Store, metadata metadata.Store, transactions txn.Store, history history.Store, quarantine quarantine.Store,
+	batcher Batcher, checker *health.Checker) *Server {
+	s := &Server{
+		host:          host,
+		port:          port,
+		subscriptions: subscriptions,
+		tasks:         tasks,
+		endpoints:     endpoints,
+		terminations:  terminations,
+		metadata:      metadata,
+		transactions:  transactions,
+		history:       history,
+		quarantine:    quarantine,
+		batcher:       batcher,
+		health:        checker,
+		mux:           http.NewServeMux(),
+	}
+	s.handle(basePath+"/terminations", "admin/terminations", s.handleTerminations)
+	s.handle(basePath+"/terminations/", "admin/terminations", s.handleTermination)
+	s.handle(basePath+"/subscriptions/", "admin/subscriptions", s.handleSubscription)
+	s.handle(basePath+"/subscriptions:batchAdd", "admin/batchAdd", s.handleBatchAdd)
+	s.handle(basePath+"/subscriptions:batchRemove", "admin/batchRemove", s.handleBatchRemove)
+	s.handle(basePath+"/watches", "admin/watches", s.handleWatches)
+	s.handle(basePath+"/backup", "admin/backup", s.handleBackup)
+	s.handle(basePath+"/restore", "admin/restore", s.handleRestore)
+	s.handle(basePath+"/quarantine", "admin/quarantine", s.handleQuarantines)
+	s.handle(basePath+"/quarantine/", "admin/quarantine", s.handleQuarantine)
+	s.mux.HandleFunc("/healthz", s.handleLiveness)
+	s.mux.HandleFunc("/readyz", s.handleReadiness)
+	s.mux.Handle("/debug/vars", expvar.Handler())
+	return s
+}
+
+// Server is an HTTP/JSON server for onos-e2sub administrative operations
+type Server struct {
+	host          string
+	port          int
+	subscriptions subscription.Store
+	tasks         task.Store
+	endpoints     endpoint.Store
+	terminations  termination.Store
+	metadata      metadata.Store
+	transactions  txn.Store
+	history       history.Store
+	quarantine    quarantine.Store
+	batcher       Batcher
+	health        *health.Checker
+	mux           *http.ServeMux
+}
+
+// handle registers the handler of an admin API path
+// The store mutations performed by the handler are recorded in the history as made by the given caller.
+func (s *Server) handle(pattern string, caller string, handler http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r.WithContext(history.WithCaller(r.Context(), caller)))
+	})
+}
+
+// ServeHTTP serves an admin API request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Serve starts serving the admin API, calling started once the server is listening
+func (s *Server) Serve(started func(string)) error {
+	return serve(net.JoinHostPort(s.host, strconv.Itoa(s.port)), s, started)
+}
+
+// ServeHealth starts serving only the health checks on the given port of all interfaces, calling
+// started once the server is listening, so that they can be probed while the admin API is only
+// reachable locally
+func (s *Server) ServeHealth(port int, started func(string)) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handleLiveness)
+	mux.HandleFunc("/readyz", s.handleReadiness)
+	return serve(fmt.Sprintf(":%d", port), mux, started)
+}
+
+// serve serves HTTP requests on the given address
+func serve(address string, handler http.Handler, started func(string)) error {
+	lis, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	started(lis.Addr().String())
+	return http.Serve(lis, handler)
+}
+
+// writeJSON writes the given value to the response as JSON
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Warnf("Failed to write response: %s", err)
+	}
+}
+
+// writeError writes the given error to the response with the matching HTTP status
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.IsNotFound(err):
+		status = http.StatusNotFound
+	case errors.IsInvalid(err):
+		status = http.StatusBadRequest
+	case errors.IsConflict(err), errors.IsAlreadyExists(err):
+		status = http.StatusConflict
+	case errors.IsUnavailable(err):
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
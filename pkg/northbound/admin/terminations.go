@@ -0,0 +1,221 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package admin
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/store/termination"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// TerminationStatus is the administrative status of a termination endpoint
+type TerminationStatus struct {
+	ID       epapi.ID           `json:"id"`
+	IP       epapi.IP           `json:"ip"`
+	Port     epapi.Port         `json:"port"`
+	Cordoned bool               `json:"cordoned"`
+	Drain    *termination.Drain `json:"drain,omitempty"`
+	// Drained indicates the termination has been drained and is safe to stop
+	Drained bool `json:"drained"`
+	// Health is the health of the termination observed by probes
+	Health *termination.Health `json:"health,omitempty"`
+}
+
+// handleTerminations serves the termination collection
+func (s *Server) handleTerminations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	eps, err := s.endpoints.List(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	statuses := make([]TerminationStatus, 0, len(eps))
+	for _, ep := range eps {
+		status, err := s.getTerminationStatus(r.Context(), ep.ID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		statuses = append(statuses, *status)
+	}
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+// handleTermination serves a termination and its cordon, uncordon and drain operations
+func (s *Server) handleTermination(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, basePath+"/terminations/"), "/"), "/")
+	id := epapi.ID(path[0])
+	if id == "" || len(path) > 2 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if len(path) == 1 {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		status, err := s.getTerminationStatus(r.Context(), id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var update func(*termination.Termination)
+	switch path[1] {
+	case "cordon":
+		update = func(term *termination.Termination) {
+			term.Cordoned = true
+		}
+	case "uncordon":
+		update = func(term *termination.Termination) {
+			term.Cordoned = false
+			term.Drain = nil
+		}
+	case "drain":
+		update = func(term *termination.Termination) {
+			term.Cordoned = true
+			if term.Drain == nil || !term.Drain.Completed.IsZero() {
+				term.Drain = &termination.Drain{
+					Started: time.Now(),
+				}
+			}
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	log.Infof("Received %s request for Termination %s", path[1], id)
+	if err := s.updateTermination(r.Context(), id, update); err != nil {
+		log.Warnf("Failed to %s Termination %s: %s", path[1], id, err)
+		writeError(w, err)
+		return
+	}
+
+	// Once uncordoned, the subscriptions evicted from the termination but not yet migrated stay on it
+	if path[1] == "uncordon" {
+		if err := s.clearEvictions(r.Context(), id); err != nil {
+			log.Warnf("Failed to %s Termination %s: %s", path[1], id, err)
+			writeError(w, err)
+			return
+		}
+	}
+	status, err := s.getTerminationStatus(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// clearEvictions clears the eviction of the subscriptions evicted from the given termination
+// Leaving them marked would count them as migrating, or evict them at once, if the termination is drained again.
+func (s *Server) clearEvictions(ctx context.Context, id epapi.ID) error {
+	metas, err := s.metadata.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, meta := range metas {
+		if meta.Evicted != id {
+			continue
+		}
+		for {
+			meta.Evicted = ""
+			err := s.metadata.Update(ctx, &meta)
+			if err == nil || errors.IsNotFound(err) {
+				break
+			} else if !errors.IsConflict(err) {
+				return err
+			}
+			log.Debugf("Retrying update of Metadata %s: %s", meta.ID, err)
+			update, err := s.metadata.Get(ctx, meta.ID)
+			if err != nil {
+				if errors.IsNotFound(err) {
+					break
+				}
+				return err
+			}
+			if update.Evicted != id {
+				break
+			}
+			meta = *update
+		}
+	}
+	return nil
+}
+
+// updateTermination applies the given update to the state of a registered termination
+// The state is read and updated again if another writer, e.g. the drain controller, changes it concurrently.
+func (s *Server) updateTermination(ctx context.Context, id epapi.ID, update func(*termination.Termination)) error {
+	if _, err := s.endpoints.Get(ctx, id); err != nil {
+		return err
+	}
+
+	for {
+		term, err := s.terminations.Get(ctx, id)
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				return err
+			}
+			term = &termination.Termination{
+				ID: id,
+			}
+			update(term)
+			err = s.terminations.Create(ctx, term)
+		} else {
+			update(term)
+			err = s.terminations.Update(ctx, term)
+		}
+		if err == nil {
+			return nil
+		} else if !errors.IsConflict(err) && !errors.IsAlreadyExists(err) && !errors.IsNotFound(err) {
+			return err
+		}
+		log.Debugf("Retrying update of Termination %s: %s", id, err)
+	}
+}
+
+// getTerminationStatus gets the administrative status of a termination
+func (s *Server) getTerminationStatus(ctx context.Context, id epapi.ID) (*TerminationStatus, error) {
+	ep, err := s.endpoints.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &TerminationStatus{
+		ID:   ep.ID,
+		IP:   ep.IP,
+		Port: ep.Port,
+	}
+	term, err := s.terminations.Get(ctx, id)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if term != nil {
+		status.Cordoned = term.Cordoned
+		status.Drain = term.Drain
+		status.Drained = term.Drain != nil && !term.Drain.Completed.IsZero()
+		status.Health = term.Health
+	}
+	return status, nil
+}
@@ -0,0 +1,155 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/health"
+	"github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/store/history"
+	"github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	"github.com/onosproject/onos-e2sub/pkg/store/quarantine"
+	"github.com/onosproject/onos-e2sub/pkg/store/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/store/task"
+	"github.com/onosproject/onos-e2sub/pkg/store/termination"
+	"github.com/onosproject/onos-e2sub/pkg/store/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T) (*Server, endpoint.Store, termination.Store) {
+	endpointStore, err := endpoint.NewLocalStore()
+	assert.NoError(t, err)
+	termStore, err := termination.NewLocalStore()
+	assert.NoError(t, err)
+	historyStore, err := history.NewLocalStore()
+	assert.NoError(t, err)
+	subStore, err := subscription.NewLocalStore()
+	assert.NoError(t, err)
+	taskStore, err := task.NewLocalStore()
+	assert.NoError(t, err)
+	quarantineStore, err := quarantine.NewLocalStore()
+	assert.NoError(t, err)
+	metaStore, err := metadata.NewLocalStore()
+	assert.NoError(t, err)
+	txnStore, err := txn.NewLocalStore()
+	assert.NoError(t, err)
+	return NewServer("localhost", 0, subStore, taskStore, endpointStore, termStore, metaStore, txnStore, historyStore, quarantineStore, nil,
+		health.NewChecker(time.Second)), endpointStore, termStore
+}
+
+func doRequest(t *testing.T, s *Server, method string, path string) (int, *TerminationStatus) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(method, basePath+path, nil))
+	if w.Code != http.StatusOK {
+		return w.Code, nil
+	}
+	status := &TerminationStatus{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), status))
+	return w.Code, status
+}
+
+func TestCordonDrain(t *testing.T) {
+	s, endpointStore, termStore := newTestServer(t)
+	defer endpointStore.Close()
+	defer termStore.Close()
+
+	code, _ := doRequest(t, s, http.MethodPost, "/terminations/e2t-1/cordon")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	err := endpointStore.Create(context.TODO(), &epapi.TerminationEndpoint{
+		ID:   "e2t-1",
+		IP:   "127.0.0.1",
+		Port: 5150,
+	})
+	assert.NoError(t, err)
+
+	code, status := doRequest(t, s, http.MethodGet, "/terminations/e2t-1")
+	assert.Equal(t, http.StatusOK, code)
+	assert.False(t, status.Cordoned)
+	assert.Nil(t, status.Drain)
+
+	code, status = doRequest(t, s, http.MethodPost, "/terminations/e2t-1/cordon")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, status.Cordoned)
+	assert.Nil(t, status.Drain)
+
+	code, status = doRequest(t, s, http.MethodPost, "/terminations/e2t-1/drain")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, status.Cordoned)
+	assert.NotNil(t, status.Drain)
+	assert.False(t, status.Drained)
+
+	// Draining again does not restart an active drain
+	started := status.Drain.Started
+	code, status = doRequest(t, s, http.MethodPost, "/terminations/e2t-1/drain")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, started.Equal(status.Drain.Started))
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, basePath+"/terminations", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var statuses []TerminationStatus
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &statuses))
+	assert.Len(t, statuses, 1)
+	assert.True(t, statuses[0].Cordoned)
+
+	// Verify uncordoning clears the evictions from the termination
+	assert.NoError(t, s.metadata.Create(context.TODO(), &metadata.Metadata{ID: "sub-1", Evicted: "e2t-1"}))
+	assert.NoError(t, s.metadata.Create(context.TODO(), &metadata.Metadata{ID: "sub-2", Evicted: "e2t-2"}))
+	code, status = doRequest(t, s, http.MethodPost, "/terminations/e2t-1/uncordon")
+	assert.Equal(t, http.StatusOK, code)
+	assert.False(t, status.Cordoned)
+	assert.Nil(t, status.Drain)
+	meta, err := s.metadata.Get(context.TODO(), "sub-1")
+	assert.NoError(t, err)
+	assert.Equal(t, epapi.ID(""), meta.Evicted)
+	meta, err = s.metadata.Get(context.TODO(), "sub-2")
+	assert.NoError(t, err)
+	assert.Equal(t, epapi.ID("e2t-2"), meta.Evicted)
+
+	code, _ = doRequest(t, s, http.MethodPost, "/terminations/e2t-1/unknown")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = doRequest(t, s, http.MethodGet, "/terminations/e2t-1/drain")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+}
+
+func TestConcurrentCordon(t *testing.T) {
+	s, endpointStore, termStore := newTestServer(t)
+	defer endpointStore.Close()
+	defer termStore.Close()
+
+	err := endpointStore.Create(context.TODO(), &epapi.TerminationEndpoint{
+		ID:   "e2t-1",
+		IP:   "127.0.0.1",
+		Port: 5150,
+	})
+	assert.NoError(t, err)
+
+	// Verify concurrent updates of a termination are retried rather than failing with a conflict
+	var wg sync.WaitGroup
+	codes := make([]int, 10)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, basePath+"/terminations/e2t-1/cordon", nil))
+			codes[i] = w.Code
+		}(i)
+	}
+	wg.Wait()
+	for _, code := range codes {
+		assert.Equal(t, http.StatusOK, code)
+	}
+}
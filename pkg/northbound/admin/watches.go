@@ -0,0 +1,26 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package admin
+
+import (
+	"net/http"
+
+	"github.com/onosproject/onos-e2sub/pkg/controller/supervisor"
+)
+
+// handleWatches serves the statuses of the controllers' watches
+// The response status is 503 if any watch is not established.
+func (s *Server) handleWatches(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	status := http.StatusOK
+	if !supervisor.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, supervisor.List())
+}
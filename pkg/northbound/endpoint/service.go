@@ -6,43 +6,114 @@ package endpoint
 
 import (
 	"context"
+	"strconv"
+	"strings"
 
 	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
 
+	"github.com/onosproject/onos-e2sub/pkg/northbound/labels"
 	store "github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/store/termination"
+	"github.com/onosproject/onos-e2sub/pkg/store/watch"
 	"github.com/onosproject/onos-lib-go/pkg/errors"
 	"github.com/onosproject/onos-lib-go/pkg/logging"
 	"github.com/onosproject/onos-lib-go/pkg/northbound"
 	"google.golang.org/grpc"
+	grpcmd "google.golang.org/grpc/metadata"
 )
 
 var log = logging.GetLogger("northbound", "endpoint")
 
+const (
+	// NodesKey is the request metadata key for the comma separated E2 nodes connected to a termination
+	NodesKey = "e2sub-e2nodes"
+	// LabelsKey is the request metadata key for the comma separated key=value labels of a termination
+	LabelsKey = "e2sub-labels"
+	// ZoneKey is the request metadata key for the zone of a termination
+	ZoneKey = "e2sub-zone"
+	// ServiceModelsKey is the request metadata key for the comma separated name[/version] service models supported by a termination
+	ServiceModelsKey = "e2sub-service-models"
+	// CapacityKey is the request metadata key for the maximum number of subscription tasks a termination accepts
+	CapacityKey = "e2sub-capacity"
+	// HealthKey is the response metadata key for the health status of a termination
+	HealthKey = "e2sub-health"
+	// HealthReasonKey is the response metadata key for the reason the last health probe of a termination failed
+	HealthReasonKey = "e2sub-health-reason"
+	// WatchHealthKey is the request metadata key indicating a termination watch reflects termination health
+	WatchHealthKey = "e2sub-watch-health"
+)
+
 // NewService creates a new registry service
-func NewService(store store.Store) northbound.Service {
+// Termination watches are fanned out from a single endpoint store watch, and the health of the
+// terminations from a single termination store watch, buffering bufferSize events for each client
+// and applying the given policy to clients whose buffer is full.
+func NewService(store store.Store, terminations termination.Store, bufferSize int, policy watch.Policy) northbound.Service {
 	return &Service{
-		store: store,
+		store:        store,
+		terminations: terminations,
+		bufferSize:   bufferSize,
+		policy:       policy,
 	}
 }
 
 // Service is a Service implementation for subscription service.
 type Service struct {
-	store store.Store
+	store        store.Store
+	terminations termination.Store
+	bufferSize   int
+	policy       watch.Policy
 }
 
 // Register registers the Service with the gRPC server.
 func (s *Service) Register(r *grpc.Server) {
 	server := &Server{
-		endPointStore: s.store,
+		endPointStore:    s.store,
+		terminationStore: s.terminations,
+		events:           watch.NewHub("terminations", s.openWatch, s.bufferSize, s.policy),
+		health:           watch.NewHub("termination-health", s.openHealthWatch, s.bufferSize, s.policy),
 	}
 	epapi.RegisterE2RegistryServiceServer(r, server)
 }
 
+// openWatch opens the termination endpoint store watch shared by all WatchTerminations streams
+func (s *Service) openWatch(ctx context.Context, ch chan<- interface{}) error {
+	endpointCh := make(chan epapi.Event)
+	if err := s.store.Watch(ctx, endpointCh); err != nil {
+		return err
+	}
+	go func() {
+		defer close(ch)
+		for event := range endpointCh {
+			ch <- event
+		}
+	}()
+	return nil
+}
+
+// openHealthWatch opens the termination store watch shared by all WatchTerminations streams
+// reflecting termination health
+func (s *Service) openHealthWatch(ctx context.Context, ch chan<- interface{}) error {
+	termCh := make(chan termination.Event)
+	if err := s.terminations.Watch(ctx, termCh); err != nil {
+		return err
+	}
+	go func() {
+		defer close(ch)
+		for event := range termCh {
+			ch <- event
+		}
+	}()
+	return nil
+}
+
 var _ northbound.Service = &Service{}
 
 // Server implements the gRPC service for managing of subscriptions
 type Server struct {
-	endPointStore store.Store
+	endPointStore    store.Store
+	terminationStore termination.Store
+	events           *watch.Hub
+	health           *watch.Hub
 }
 
 // E2RegistryClientFactory : Default E2RegistryClientFactory creation.
@@ -56,14 +127,45 @@ func CreateE2RegistryClient(cc *grpc.ClientConn) epapi.E2RegistryServiceClient {
 }
 
 // AddTermination adds an E2 end-point
+// If the end-point carries a revision, the registered end-point is updated if its revision matches.
 func (s *Server) AddTermination(ctx context.Context, req *epapi.AddTerminationRequest) (*epapi.AddTerminationResponse, error) {
 	log.Infof("Received AddTerminationRequest %+v", req)
 	ep := req.Endpoint
-	err := s.endPointStore.Create(ctx, ep)
+	report, err := getReport(ctx)
 	if err != nil {
 		log.Warnf("AddTerminationRequest %+v failed: %v", req, err)
 		return nil, errors.Status(err).Err()
 	}
+	// A termination with a revision updates the registered termination, e.g. to change its address
+	if ep.Revision != 0 {
+		err = s.endPointStore.Update(ctx, ep)
+	} else {
+		err = s.endPointStore.Create(ctx, ep)
+	}
+	if err != nil {
+		// A termination that reports its state re-registers to report changes to its state, and a
+		// discovered termination may register itself
+		if !errors.IsAlreadyExists(err) || (report == nil && !s.isDiscovered(ctx, ep.ID)) {
+			log.Warnf("AddTerminationRequest %+v failed: %v", req, err)
+			return nil, errors.Status(err).Err()
+		}
+		existing, err := s.endPointStore.Get(ctx, ep.ID)
+		if err != nil {
+			log.Warnf("AddTerminationRequest %+v failed: %v", req, err)
+			return nil, errors.Status(err).Err()
+		}
+		if existing.IP != ep.IP || existing.Port != ep.Port {
+			err := errors.NewAlreadyExists("termination %s already exists", ep.ID)
+			log.Warnf("AddTerminationRequest %+v failed: %v", req, err)
+			return nil, errors.Status(err).Err()
+		}
+	}
+	if report != nil {
+		if err := s.reportTermination(ctx, ep.ID, report); err != nil {
+			log.Warnf("AddTerminationRequest %+v failed: %v", req, err)
+			return nil, errors.Status(err).Err()
+		}
+	}
 	res := &epapi.AddTerminationResponse{}
 	log.Infof("Sending AddTerminationResponse %+v", res)
 	return res, nil
@@ -77,6 +179,20 @@ func (s *Server) GetTermination(ctx context.Context, req *epapi.GetTerminationRe
 		log.Warnf("GetTerminatonRequest %+v failed: %v", req, err)
 		return nil, errors.Status(err).Err()
 	}
+
+	// Return the state reported by the termination in the response header
+	term, err := s.terminationStore.Get(ctx, req.ID)
+	if err != nil && !errors.IsNotFound(err) {
+		log.Warnf("GetTerminatonRequest %+v failed: %v", req, err)
+		return nil, errors.Status(err).Err()
+	}
+	if term != nil {
+		if err := grpc.SetHeader(ctx, getHeader(term)); err != nil {
+			log.Warnf("GetTerminatonRequest %+v failed: %v", req, err)
+			return nil, err
+		}
+	}
+
 	res := &epapi.GetTerminationResponse{
 		Endpoint: ep,
 	}
@@ -92,6 +208,11 @@ func (s *Server) RemoveTermination(ctx context.Context, req *epapi.RemoveTermina
 		log.Warnf("RemoveTerminationRequest %+v failed: %v", req, err)
 		return nil, errors.Status(err).Err()
 	}
+	err = s.terminationStore.Delete(ctx, req.ID)
+	if err != nil && !errors.IsNotFound(err) {
+		log.Warnf("RemoveTerminationRequest %+v failed: %v", req, err)
+		return nil, errors.Status(err).Err()
+	}
 	res := &epapi.RemoveTerminationResponse{}
 	log.Infof("Sending RemoveTerminationResponse %+v", res)
 	return res, nil
@@ -114,34 +235,343 @@ func (s *Server) ListTerminations(ctx context.Context, req *epapi.ListTerminatio
 }
 
 // WatchTerminations streams termination end-point changes
+// If the request metadata sets WatchHealthKey, unhealthy terminations are streamed as removed and
+// streamed as added again once healthy. If events were dropped for a slow client, an event with an
+// empty end-point marks the gap.
 func (s *Server) WatchTerminations(req *epapi.WatchTerminationsRequest, server epapi.E2RegistryService_WatchTerminationsServer) error {
 	log.Infof("Received WatchTerminationsRequest %+v", req)
-	var watchOpts []store.WatchOption
-	if !req.Noreplay {
-		watchOpts = append(watchOpts, store.WithReplay())
+
+	// Subscribe before listing the existing end-points to ensure no changes are missed
+	sub, err := s.events.Subscribe(server.Context())
+	if err != nil {
+		log.Warnf("WatchTerminationsRequest %+v failed: %v", req, err)
+		return errors.Status(err).Err()
 	}
 
+	// Replay the existing end-points ahead of the subscriber's events
 	ch := make(chan epapi.Event)
-	if err := s.endPointStore.Watch(server.Context(), ch, watchOpts...); err != nil {
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(ch)
+		if !req.Noreplay {
+			endpoints, err := s.endPointStore.List(server.Context())
+			if err != nil {
+				errCh <- err
+				return
+			}
+			for _, ep := range endpoints {
+				select {
+				case ch <- epapi.Event{Type: epapi.EventType_NONE, Endpoint: ep}:
+				case <-server.Context().Done():
+					return
+				}
+			}
+		}
+		for event := range sub.Events() {
+			var epEvent epapi.Event
+			if event.Gap {
+				epEvent = epapi.Event{Type: epapi.EventType_NONE}
+			} else {
+				epEvent = event.Value.(epapi.Event)
+			}
+			select {
+			case ch <- epEvent:
+			case <-server.Context().Done():
+				return
+			}
+		}
+		if err := sub.Err(); err != nil {
+			errCh <- err
+		}
+	}()
+
+	if !isWatchHealth(server.Context()) {
+		err = s.Stream(server, ch)
+	} else {
+		healthSub, subErr := s.health.Subscribe(server.Context())
+		if subErr != nil {
+			log.Warnf("WatchTerminationsRequest %+v failed: %v", req, subErr)
+			return errors.Status(subErr).Err()
+		}
+		err = s.streamHealth(server, ch, healthSub)
+	}
+	if err != nil {
+		return err
+	}
+	select {
+	case err := <-errCh:
 		log.Warnf("WatchTerminationsRequest %+v failed: %v", req, err)
 		return errors.Status(err).Err()
+	default:
+		return nil
 	}
-
-	return s.Stream(server, ch)
 }
 
 // Stream is the ongoing stream for WatchTerminations request
 func (s *Server) Stream(server epapi.E2RegistryService_WatchTerminationsServer, ch chan epapi.Event) error {
 	for event := range ch {
-		res := &epapi.WatchTerminationsResponse{
-			Event: event,
+		if err := s.send(server, event); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		log.Infof("Sending WatchTerminationsResponse %+v", res)
-		if err := server.Send(res); err != nil {
-			log.Warnf("WatchTerminationsResponse %+v failed: %v", res, err)
-			return err
+// streamHealth streams termination end-point changes filtered by the health of the terminations
+// If termination events were dropped for a slow client, the health of the streamed end-points is
+// read again from the store.
+func (s *Server) streamHealth(server epapi.E2RegistryService_WatchTerminationsServer, ch chan epapi.Event, healthSub *watch.Subscriber) error {
+	endpoints := make(map[epapi.ID]epapi.TerminationEndpoint)
+	unhealthy := make(map[epapi.ID]bool)
+	for {
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			id := event.Endpoint.ID
+			switch {
+			case id == "":
+				// Gap markers carry no end-point and are forwarded as is
+			case event.Type == epapi.EventType_REMOVED:
+				_, visible := endpoints[id]
+				delete(endpoints, id)
+				if !visible || unhealthy[id] {
+					continue
+				}
+			default:
+				endpoints[id] = event.Endpoint
+				term, err := s.terminationStore.Get(server.Context(), id)
+				if err != nil && !errors.IsNotFound(err) {
+					log.Warnf("WatchTerminationsResponse for %s failed: %v", id, err)
+					return errors.Status(err).Err()
+				}
+				unhealthy[id] = term != nil && !term.IsHealthy()
+				if unhealthy[id] {
+					continue
+				}
+			}
+			if err := s.send(server, event); err != nil {
+				return err
+			}
+		case event, ok := <-healthSub.Events():
+			if !ok {
+				if err := healthSub.Err(); err != nil {
+					log.Warnf("WatchTerminationsResponse failed: %v", err)
+					return errors.Status(err).Err()
+				}
+				return nil
+			}
+			if event.Gap {
+				for id := range endpoints {
+					term, err := s.terminationStore.Get(server.Context(), id)
+					if err != nil && !errors.IsNotFound(err) {
+						log.Warnf("WatchTerminationsResponse for %s failed: %v", id, err)
+						return errors.Status(err).Err()
+					}
+					if err := s.sendHealth(server, endpoints, unhealthy, id, term != nil && !term.IsHealthy()); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			termEvent := event.Value.(termination.Event)
+			isUnhealthy := termEvent.Type != termination.EventRemoved && !termEvent.Termination.IsHealthy()
+			if err := s.sendHealth(server, endpoints, unhealthy, termEvent.Termination.ID, isUnhealthy); err != nil {
+				return err
+			}
 		}
 	}
+}
+
+// sendHealth streams a change to the health of a termination as the removal or addition of its end-point
+func (s *Server) sendHealth(server epapi.E2RegistryService_WatchTerminationsServer, endpoints map[epapi.ID]epapi.TerminationEndpoint,
+	unhealthy map[epapi.ID]bool, id epapi.ID, isUnhealthy bool) error {
+	if isUnhealthy == unhealthy[id] {
+		return nil
+	}
+	unhealthy[id] = isUnhealthy
+	ep, ok := endpoints[id]
+	if !ok {
+		return nil
+	}
+	eventType := epapi.EventType_ADDED
+	if isUnhealthy {
+		eventType = epapi.EventType_REMOVED
+	}
+	return s.send(server, epapi.Event{Type: eventType, Endpoint: ep})
+}
+
+// send sends a WatchTerminations event
+func (s *Server) send(server epapi.E2RegistryService_WatchTerminationsServer, event epapi.Event) error {
+	res := &epapi.WatchTerminationsResponse{
+		Event: event,
+	}
+
+	log.Infof("Sending WatchTerminationsResponse %+v", res)
+	if err := server.Send(res); err != nil {
+		log.Warnf("WatchTerminationsResponse %+v failed: %v", res, err)
+		return err
+	}
 	return nil
 }
+
+// isWatchHealth returns whether the request metadata asks for a watch reflecting termination health
+func isWatchHealth(ctx context.Context) bool {
+	md, ok := grpcmd.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	values := md.Get(WatchHealthKey)
+	if len(values) == 0 {
+		return false
+	}
+	watchHealth, err := strconv.ParseBool(values[0])
+	return err == nil && watchHealth
+}
+
+// isDiscovered returns whether the given termination was registered by Kubernetes discovery
+func (s *Server) isDiscovered(ctx context.Context, id epapi.ID) bool {
+	term, err := s.terminationStore.Get(ctx, id)
+	return err == nil && term.Discovered
+}
+
+// reportTermination applies the state reported by the given termination
+func (s *Server) reportTermination(ctx context.Context, id epapi.ID, report func(*termination.Termination)) error {
+	// The termination state is also written by the controllers, so the report is applied to the
+	// latest state until it is written without conflict
+	for {
+		term, err := s.terminationStore.Get(ctx, id)
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				return err
+			}
+			term = &termination.Termination{
+				ID: id,
+			}
+			report(term)
+			err = s.terminationStore.Create(ctx, term)
+		} else {
+			report(term)
+			err = s.terminationStore.Update(ctx, term)
+		}
+		if err == nil {
+			return nil
+		} else if !errors.IsConflict(err) && !errors.IsAlreadyExists(err) && !errors.IsNotFound(err) {
+			return err
+		}
+		log.Debugf("Retrying report of Termination %s: %s", id, err)
+	}
+}
+
+// getReport reads the state reported by a termination from the request metadata
+// Only the state present in the request metadata is applied. If the termination does not report
+// any state, nil is returned.
+func getReport(ctx context.Context) (func(*termination.Termination), error) {
+	md, ok := grpcmd.FromIncomingContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+
+	var reports []func(*termination.Termination)
+	if values := md.Get(NodesKey); len(values) > 0 {
+		nodes := splitValues(values)
+		reports = append(reports, func(term *termination.Termination) {
+			term.Nodes = nodes
+		})
+	}
+	if values := md.Get(LabelsKey); len(values) > 0 {
+		parsed, err := labels.Parse(values)
+		if err != nil {
+			return nil, errors.NewInvalid("invalid %s: %s", LabelsKey, err)
+		}
+		reports = append(reports, func(term *termination.Termination) {
+			term.Labels = parsed
+		})
+	}
+	if values := md.Get(ZoneKey); len(values) > 0 {
+		zone := values[0]
+		reports = append(reports, func(term *termination.Termination) {
+			term.Zone = zone
+		})
+	}
+	if values := md.Get(ServiceModelsKey); len(values) > 0 {
+		models := make([]termination.ServiceModel, 0)
+		for _, value := range splitValues(values) {
+			model := termination.ServiceModel{Name: value}
+			if i := strings.Index(value, "/"); i >= 0 {
+				model.Name = value[:i]
+				model.Version = value[i+1:]
+			}
+			if model.Name == "" {
+				return nil, errors.NewInvalid("invalid %s: %s", ServiceModelsKey, value)
+			}
+			models = append(models, model)
+		}
+		reports = append(reports, func(term *termination.Termination) {
+			term.ServiceModels = models
+		})
+	}
+	if values := md.Get(CapacityKey); len(values) > 0 {
+		capacity, err := strconv.Atoi(values[0])
+		if err != nil || capacity < 0 {
+			return nil, errors.NewInvalid("invalid %s: %s", CapacityKey, values[0])
+		}
+		reports = append(reports, func(term *termination.Termination) {
+			term.Capacity = capacity
+		})
+	}
+
+	if len(reports) == 0 {
+		return nil, nil
+	}
+	return func(term *termination.Termination) {
+		for _, report := range reports {
+			report(term)
+		}
+	}, nil
+}
+
+// getHeader returns the response header for the given termination state
+func getHeader(term *termination.Termination) grpcmd.MD {
+	md := grpcmd.MD{}
+	if term.Nodes != nil {
+		md.Set(NodesKey, strings.Join(term.Nodes, ","))
+	}
+	if len(term.Labels) > 0 {
+		md.Set(LabelsKey, labels.Format(term.Labels))
+	}
+	if term.Zone != "" {
+		md.Set(ZoneKey, term.Zone)
+	}
+	if len(term.ServiceModels) > 0 {
+		models := make([]string, 0, len(term.ServiceModels))
+		for _, model := range term.ServiceModels {
+			models = append(models, model.String())
+		}
+		md.Set(ServiceModelsKey, strings.Join(models, ","))
+	}
+	if term.Capacity > 0 {
+		md.Set(CapacityKey, strconv.Itoa(term.Capacity))
+	}
+	if term.Health != nil && term.Health.Status != termination.HealthUnknown {
+		md.Set(HealthKey, string(term.Health.Status))
+	}
+	if term.Health != nil && term.Health.Reason != "" {
+		md.Set(HealthReasonKey, term.Health.Reason)
+	}
+	return md
+}
+
+// splitValues splits comma separated metadata values
+func splitValues(values []string) []string {
+	split := make([]string, 0)
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				split = append(split, item)
+			}
+		}
+	}
+	return split
+}
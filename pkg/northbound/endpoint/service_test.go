@@ -7,19 +7,25 @@ package endpoint
 import (
 	"context"
 	"net"
+	"strconv"
 	"sync"
 	"testing"
 
 	regapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
 	store "github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/store/termination"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
 	"github.com/onosproject/onos-lib-go/pkg/northbound"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
+	grpcmd "google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/test/bufconn"
 )
 
 var lis *bufconn.Listener
 
+var service *Service
+
 func bufDialer(context.Context, string) (net.Conn, error) {
 	return lis.Dial()
 }
@@ -29,8 +35,13 @@ func newTestService() (northbound.Service, error) {
 	if err != nil {
 		return nil, err
 	}
+	termStore, err := termination.NewLocalStore()
+	if err != nil {
+		return nil, err
+	}
 	return &Service{
-		store: endPointStore,
+		store:        endPointStore,
+		terminations: termStore,
 	}, nil
 }
 
@@ -39,6 +50,7 @@ func createServerConnection(t *testing.T) *grpc.ClientConn {
 	s, err := newTestService()
 	assert.NoError(t, err)
 	assert.NotNil(t, s)
+	service = s.(*Service)
 	server := grpc.NewServer()
 	s.Register(server)
 
@@ -166,3 +178,289 @@ func TestBadRemove(t *testing.T) {
 	_, err := client.RemoveTermination(context.Background(), &regapi.RemoveTerminationRequest{})
 	assert.Error(t, err)
 }
+
+func TestReportNodes(t *testing.T) {
+	conn := createServerConnection(t)
+	client := regapi.NewE2RegistryServiceClient(conn)
+
+	ep := &regapi.TerminationEndpoint{
+		ID: "1", IP: "10.10.10.1", Port: 111,
+	}
+
+	// Register a termination with its connected E2 nodes
+	ctx := grpcmd.AppendToOutgoingContext(context.Background(), NodesKey, "e2node-1, e2node-2")
+	_, err := client.AddTermination(ctx, &regapi.AddTerminationRequest{
+		Endpoint: ep,
+	})
+	assert.NoError(t, err)
+
+	var header grpcmd.MD
+	_, err = client.GetTermination(context.Background(), &regapi.GetTerminationRequest{
+		ID: "1",
+	}, grpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e2node-1,e2node-2"}, header.Get(NodesKey))
+
+	// Verify re-registering the termination updates its E2 nodes
+	ctx = grpcmd.AppendToOutgoingContext(context.Background(), NodesKey, "e2node-2")
+	_, err = client.AddTermination(ctx, &regapi.AddTerminationRequest{
+		Endpoint: ep,
+	})
+	assert.NoError(t, err)
+
+	_, err = client.GetTermination(context.Background(), &regapi.GetTerminationRequest{
+		ID: "1",
+	}, grpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e2node-2"}, header.Get(NodesKey))
+
+	// Verify re-registering a termination at another address fails
+	_, err = client.AddTermination(ctx, &regapi.AddTerminationRequest{
+		Endpoint: &regapi.TerminationEndpoint{
+			ID: "1", IP: "10.10.10.2", Port: 111,
+		},
+	})
+	assert.Error(t, err)
+
+	// Verify re-registering without reporting E2 nodes fails
+	_, err = client.AddTermination(context.Background(), &regapi.AddTerminationRequest{
+		Endpoint: ep,
+	})
+	assert.Error(t, err)
+}
+
+func TestReportCapabilities(t *testing.T) {
+	conn := createServerConnection(t)
+	client := regapi.NewE2RegistryServiceClient(conn)
+
+	ep := &regapi.TerminationEndpoint{
+		ID: "1", IP: "10.10.10.1", Port: 111,
+	}
+
+	// Verify an invalid capacity is rejected
+	ctx := grpcmd.AppendToOutgoingContext(context.Background(), CapacityKey, "-1")
+	_, err := client.AddTermination(ctx, &regapi.AddTerminationRequest{
+		Endpoint: ep,
+	})
+	assert.Error(t, err)
+
+	// Register a termination with its capabilities
+	ctx = grpcmd.AppendToOutgoingContext(context.Background(),
+		LabelsKey, "tier=edge,rack=r1",
+		ZoneKey, "zone-a",
+		ServiceModelsKey, "kpm/v1,rc",
+		CapacityKey, "10")
+	_, err = client.AddTermination(ctx, &regapi.AddTerminationRequest{
+		Endpoint: ep,
+	})
+	assert.NoError(t, err)
+
+	var header grpcmd.MD
+	_, err = client.GetTermination(context.Background(), &regapi.GetTerminationRequest{
+		ID: "1",
+	}, grpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rack=r1,tier=edge"}, header.Get(LabelsKey))
+	assert.Equal(t, []string{"zone-a"}, header.Get(ZoneKey))
+	assert.Equal(t, []string{"kpm/v1,rc"}, header.Get(ServiceModelsKey))
+	assert.Equal(t, []string{"10"}, header.Get(CapacityKey))
+	assert.Empty(t, header.Get(NodesKey))
+
+	// Verify re-registering the termination only updates the reported state
+	ctx = grpcmd.AppendToOutgoingContext(context.Background(), CapacityKey, "20")
+	_, err = client.AddTermination(ctx, &regapi.AddTerminationRequest{
+		Endpoint: ep,
+	})
+	assert.NoError(t, err)
+
+	_, err = client.GetTermination(context.Background(), &regapi.GetTerminationRequest{
+		ID: "1",
+	}, grpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"zone-a"}, header.Get(ZoneKey))
+	assert.Equal(t, []string{"20"}, header.Get(CapacityKey))
+}
+
+func TestConcurrentReports(t *testing.T) {
+	conn := createServerConnection(t)
+	client := regapi.NewE2RegistryServiceClient(conn)
+
+	ep := &regapi.TerminationEndpoint{
+		ID: "1", IP: "10.10.10.1", Port: 111,
+	}
+	ctx := grpcmd.AppendToOutgoingContext(context.Background(), CapacityKey, "10")
+	_, err := client.AddTermination(ctx, &regapi.AddTerminationRequest{
+		Endpoint: ep,
+	})
+	assert.NoError(t, err)
+
+	// Verify concurrent reports, and writes by the controllers, are all applied
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx := grpcmd.AppendToOutgoingContext(context.Background(), CapacityKey, strconv.Itoa(20+i))
+			_, err := client.AddTermination(ctx, &regapi.AddTerminationRequest{
+				Endpoint: ep,
+			})
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			term, err := service.terminations.Get(context.Background(), "1")
+			assert.NoError(t, err)
+			term.Health = &termination.Health{Status: termination.HealthUnhealthy}
+			if err = service.terminations.Update(context.Background(), term); !errors.IsConflict(err) {
+				assert.NoError(t, err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	term, err := service.terminations.Get(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.True(t, term.Capacity >= 20)
+	assert.Equal(t, termination.HealthUnhealthy, term.Health.Status)
+}
+
+func setHealth(t *testing.T, id regapi.ID, status termination.HealthStatus) {
+	term, err := service.terminations.Get(context.Background(), id)
+	if err != nil {
+		term = &termination.Termination{ID: id, Health: &termination.Health{Status: status}}
+		assert.NoError(t, service.terminations.Create(context.Background(), term))
+		return
+	}
+	term.Health = &termination.Health{Status: status}
+	assert.NoError(t, service.terminations.Update(context.Background(), term))
+}
+
+func TestWatchHealth(t *testing.T) {
+	conn := createServerConnection(t)
+	client := regapi.NewE2RegistryServiceClient(conn)
+
+	_, err := client.AddTermination(context.Background(), &regapi.AddTerminationRequest{
+		Endpoint: &regapi.TerminationEndpoint{
+			ID: "1", IP: "10.10.10.1", Port: 111,
+		},
+	})
+	assert.NoError(t, err)
+	_, err = client.AddTermination(context.Background(), &regapi.AddTerminationRequest{
+		Endpoint: &regapi.TerminationEndpoint{
+			ID: "2", IP: "10.10.10.2", Port: 222,
+		},
+	})
+	assert.NoError(t, err)
+	setHealth(t, "2", termination.HealthUnhealthy)
+
+	// Verify the health is returned in the response header
+	var header grpcmd.MD
+	_, err = client.GetTermination(context.Background(), &regapi.GetTerminationRequest{ID: "2"}, grpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{string(termination.HealthUnhealthy)}, header.Get(HealthKey))
+
+	// Verify the unhealthy termination is not replayed
+	ctx := grpcmd.AppendToOutgoingContext(context.Background(), WatchHealthKey, "true")
+	res, err := client.WatchTerminations(ctx, &regapi.WatchTerminationsRequest{})
+	assert.NoError(t, err)
+	wr, err := res.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, regapi.EventType_NONE, wr.Event.Type)
+	assert.Equal(t, regapi.ID("1"), wr.Event.Endpoint.ID)
+
+	// Verify health changes are fanned out to every health watch
+	other, err := client.WatchTerminations(ctx, &regapi.WatchTerminationsRequest{})
+	assert.NoError(t, err)
+	wr, err = other.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, regapi.ID("1"), wr.Event.Endpoint.ID)
+
+	// Verify a termination becoming unhealthy is removed
+	setHealth(t, "1", termination.HealthUnhealthy)
+	wr, err = res.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, regapi.EventType_REMOVED, wr.Event.Type)
+	assert.Equal(t, regapi.ID("1"), wr.Event.Endpoint.ID)
+	wr, err = other.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, regapi.EventType_REMOVED, wr.Event.Type)
+	assert.Equal(t, regapi.ID("1"), wr.Event.Endpoint.ID)
+
+	// Verify a termination becoming healthy is added
+	setHealth(t, "2", termination.HealthHealthy)
+	wr, err = res.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, regapi.EventType_ADDED, wr.Event.Type)
+	assert.Equal(t, regapi.ID("2"), wr.Event.Endpoint.ID)
+	assert.Equal(t, regapi.IP("10.10.10.2"), wr.Event.Endpoint.IP)
+}
+
+func TestUpdateTermination(t *testing.T) {
+	conn := createServerConnection(t)
+	client := regapi.NewE2RegistryServiceClient(conn)
+
+	_, err := client.AddTermination(context.Background(), &regapi.AddTerminationRequest{
+		Endpoint: &regapi.TerminationEndpoint{
+			ID: "1", IP: "10.10.10.1", Port: 111,
+		},
+	})
+	assert.NoError(t, err)
+
+	res, err := client.GetTermination(context.Background(), &regapi.GetTerminationRequest{ID: "1"})
+	assert.NoError(t, err)
+	revision := res.Endpoint.Revision
+	assert.NotEqual(t, regapi.Revision(0), revision)
+
+	// Verify a termination can change its address using its revision
+	_, err = client.AddTermination(context.Background(), &regapi.AddTerminationRequest{
+		Endpoint: &regapi.TerminationEndpoint{
+			ID: "1", Revision: revision, IP: "10.10.10.2", Port: 111,
+		},
+	})
+	assert.NoError(t, err)
+
+	res, err = client.GetTermination(context.Background(), &regapi.GetTerminationRequest{ID: "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, regapi.IP("10.10.10.2"), res.Endpoint.IP)
+	assert.NotEqual(t, revision, res.Endpoint.Revision)
+
+	// Verify an update with a stale revision fails
+	_, err = client.AddTermination(context.Background(), &regapi.AddTerminationRequest{
+		Endpoint: &regapi.TerminationEndpoint{
+			ID: "1", Revision: revision, IP: "10.10.10.3", Port: 111,
+		},
+	})
+	assert.Error(t, err)
+	assert.True(t, errors.IsConflict(errors.FromGRPC(err)))
+}
+
+func TestDiscoveredRegistration(t *testing.T) {
+	conn := createServerConnection(t)
+	client := regapi.NewE2RegistryServiceClient(conn)
+
+	ep := &regapi.TerminationEndpoint{
+		ID: "1", IP: "10.10.10.1", Port: 111,
+	}
+	assert.NoError(t, service.store.Create(context.Background(), ep))
+	assert.NoError(t, service.terminations.Create(context.Background(), &termination.Termination{ID: "1", Discovered: true}))
+
+	// Verify a discovered termination can register itself
+	_, err := client.AddTermination(context.Background(), &regapi.AddTerminationRequest{
+		Endpoint: &regapi.TerminationEndpoint{
+			ID: "1", IP: "10.10.10.1", Port: 111,
+		},
+	})
+	assert.NoError(t, err)
+
+	// Verify a discovered termination cannot register itself at another address
+	_, err = client.AddTermination(context.Background(), &regapi.AddTerminationRequest{
+		Endpoint: &regapi.TerminationEndpoint{
+			ID: "1", IP: "10.10.10.2", Port: 111,
+		},
+	})
+	assert.Error(t, err)
+}
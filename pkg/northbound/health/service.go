@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package health
+
+import (
+	"context"
+	"time"
+
+	"github.com/onosproject/onos-e2sub/pkg/health"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"github.com/onosproject/onos-lib-go/pkg/northbound"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthapi "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+var log = logging.GetLogger("northbound", "health")
+
+// NewService creates a new gRPC health service
+// The serving status of the server is refreshed from the checker at the given interval: the server
+// is serving while the replica is ready.
+func NewService(checker *health.Checker, interval time.Duration) *Service {
+	return &Service{
+		checker:  checker,
+		interval: interval,
+		server:   grpchealth.NewServer(),
+	}
+}
+
+// Service is a Service implementation for the gRPC health service.
+type Service struct {
+	checker  *health.Checker
+	interval time.Duration
+	server   *grpchealth.Server
+	status   healthapi.HealthCheckResponse_ServingStatus
+}
+
+// Register registers the Service with the gRPC server.
+func (s *Service) Register(r *grpc.Server) {
+	healthapi.RegisterHealthServer(r, s.server)
+	s.update()
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.update()
+		}
+	}()
+}
+
+// update sets the serving status from the result of the checks
+func (s *Service) update() {
+	status := healthapi.HealthCheckResponse_SERVING
+	if report := s.checker.Check(context.Background()); !report.Ready {
+		status = healthapi.HealthCheckResponse_NOT_SERVING
+	}
+	if status == s.status {
+		return
+	}
+	log.Infof("Setting serving status %s", status)
+	s.status = status
+	s.server.SetServingStatus("", status)
+}
+
+var _ northbound.Service = &Service{}
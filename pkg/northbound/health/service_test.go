@@ -0,0 +1,51 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package health
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-e2sub/pkg/health"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	healthapi "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestHealth(t *testing.T) {
+	startup := health.NewStartup("northbound")
+	checker := health.NewChecker(time.Second)
+	checker.Register("startup", true, startup.Check)
+
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	NewService(checker, 10*time.Millisecond).Register(server)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	defer server.Stop()
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet", grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}))
+	assert.NoError(t, err)
+	defer conn.Close()
+	client := healthapi.NewHealthClient(conn)
+
+	// Verify the server is not serving until the replica is ready
+	res, err := client.Check(context.Background(), &healthapi.HealthCheckRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, healthapi.HealthCheckResponse_NOT_SERVING, res.Status)
+
+	startup.Started("northbound")
+	assert.Eventually(t, func() bool {
+		res, err := client.Check(context.Background(), &healthapi.HealthCheckRequest{})
+		return err == nil && res.Status == healthapi.HealthCheckResponse_SERVING
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package labels
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Parse parses comma separated key=value labels from the given request metadata values
+func Parse(values []string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, value := range values {
+		for _, label := range strings.Split(value, ",") {
+			if label = strings.TrimSpace(label); label == "" {
+				continue
+			}
+			i := strings.Index(label, "=")
+			if i <= 0 {
+				return nil, fmt.Errorf("invalid label %s", label)
+			}
+			labels[strings.TrimSpace(label[:i])] = strings.TrimSpace(label[i+1:])
+		}
+	}
+	return labels, nil
+}
+
+// Format formats the given labels as comma separated key=value pairs sorted by key
+func Format(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+labels[key])
+	}
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,23 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package labels
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabels(t *testing.T) {
+	labels, err := Parse([]string{"tier=edge, rack=r1", "site=a"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"tier": "edge", "rack": "r1", "site": "a"}, labels)
+	assert.Equal(t, "rack=r1,site=a,tier=edge", Format(labels))
+
+	_, err = Parse([]string{"tier"})
+	assert.Error(t, err)
+	_, err = Parse([]string{"=edge"})
+	assert.Error(t, err)
+}
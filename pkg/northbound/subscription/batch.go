@@ -0,0 +1,385 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package subscription
+
+import (
+	"context"
+	"sync"
+
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+const (
+	// MaxBatchSize is the maximum number of subscriptions added or removed in a batch
+	MaxBatchSize = 1000
+	// batchConcurrency is the number of subscriptions of a batch written concurrently
+	batchConcurrency = 16
+)
+
+// AddSubscriptionsRequest is a request to add a batch of subscriptions
+type AddSubscriptionsRequest struct {
+	// Subscriptions are the subscriptions to add
+	Subscriptions []subapi.Subscription `json:"subscriptions"`
+	// Atomic adds all of the subscriptions or, if any of them cannot be added, none of them
+	Atomic bool `json:"atomic,omitempty"`
+}
+
+// AddSubscriptionsResponse is the response to an AddSubscriptionsRequest
+type AddSubscriptionsResponse struct {
+	// Results are the results for the subscriptions in the order in which they were requested
+	Results []Result `json:"results"`
+}
+
+// RemoveSubscriptionsRequest is a request to remove a batch of subscriptions
+type RemoveSubscriptionsRequest struct {
+	// IDs are the IDs of the subscriptions to remove
+	IDs []subapi.ID `json:"ids"`
+	// Atomic removes all of the subscriptions or, if any of them cannot be removed, none of them
+	Atomic bool `json:"atomic,omitempty"`
+}
+
+// RemoveSubscriptionsResponse is the response to a RemoveSubscriptionsRequest
+type RemoveSubscriptionsResponse struct {
+	// Results are the results for the subscriptions in the order in which they were requested
+	Results []Result `json:"results"`
+}
+
+// Result is the result of adding or removing a subscription in a batch
+type Result struct {
+	// ID is the subscription ID
+	ID subapi.ID `json:"id"`
+	// Code is the name of the gRPC status code of the operation, e.g. OK or AlreadyExists
+	Code string `json:"code"`
+	// Message is the reason the operation failed
+	Message string `json:"message,omitempty"`
+	// Subscription is the added subscription
+	Subscription *subapi.Subscription `json:"subscription,omitempty"`
+}
+
+// newResult returns the result of an operation on a subscription
+func newResult(id subapi.ID, err error) Result {
+	status := errors.Status(err)
+	return Result{
+		ID:      id,
+		Code:    status.Code().String(),
+		Message: status.Message(),
+	}
+}
+
+// AddSubscriptions adds a batch of subscriptions
+// The request metadata apply to each subscription in the batch. The subscriptions are validated as a
+// whole before any is added, and the result of adding each is returned. In atomic mode, the
+// subscriptions are added in a single transaction only if all of them can be added.
+func (s *Server) AddSubscriptions(ctx context.Context, req *AddSubscriptionsRequest) (*AddSubscriptionsResponse, error) {
+	log.Infof("Received AddSubscriptionsRequest with %d subscriptions", len(req.Subscriptions))
+	if err := validateBatchSize(len(req.Subscriptions)); err != nil {
+		log.Warnf("AddSubscriptionsRequest failed: %v", err)
+		return nil, errors.Status(err).Err()
+	}
+
+	// The request metadata are shared by the batch, so are verified once
+	meta, err := getMetadata(ctx, "")
+	if err != nil {
+		log.Warnf("AddSubscriptionsRequest failed: %v", err)
+		return nil, errors.Status(err).Err()
+	}
+	if err := s.checkSession(ctx, meta); err != nil {
+		log.Warnf("AddSubscriptionsRequest failed: %v", err)
+		return nil, errors.Status(err).Err()
+	}
+
+	subs := req.Subscriptions
+	metas := make([]*metadata.Metadata, len(subs))
+	errs := make([]error, len(subs))
+	added := make(map[subapi.ID]bool)
+	for i := range subs {
+		if err := validateSubscription(&subs[i]); err != nil {
+			errs[i] = err
+			continue
+		}
+		if err := assignID(&subs[i]); err != nil {
+			errs[i] = err
+			continue
+		}
+		if added[subs[i].ID] {
+			errs[i] = errors.NewInvalid("subscription %s is repeated in the batch", subs[i].ID)
+			continue
+		}
+		added[subs[i].ID] = true
+		if meta != nil {
+			subMeta := *meta
+			subMeta.ID = subs[i].ID
+			metas[i] = &subMeta
+		}
+	}
+
+	if req.Atomic {
+		s.addSubscriptionsAtomically(ctx, subs, metas, errs)
+	} else {
+		forEach(len(subs), func(i int) {
+			if errs[i] == nil {
+				errs[i] = s.addSubscription(ctx, &subs[i], metas[i])
+			}
+		})
+	}
+
+	res := &AddSubscriptionsResponse{
+		Results: make([]Result, len(subs)),
+	}
+	failed := 0
+	for i := range subs {
+		res.Results[i] = newResult(subs[i].ID, errs[i])
+		if errs[i] == nil {
+			res.Results[i].Subscription = &subs[i]
+		} else {
+			failed++
+		}
+	}
+	log.Infof("Sending AddSubscriptionsResponse: added %d of %d subscriptions", len(subs)-failed, len(subs))
+	return res, nil
+}
+
+// addSubscriptionsAtomically adds the subscriptions and their metadata in a single transaction
+func (s *Server) addSubscriptionsAtomically(ctx context.Context, subs []subapi.Subscription, metas []*metadata.Metadata, errs []error) {
+	if s.transactor == nil {
+		abortBatch(subs2IDs(subs), errs, errors.NewNotSupported("atomic batches are not supported"))
+		return
+	}
+
+	// Check whether each subscription exists so that failures are reported for each subscription
+	// and subscriptions added by a retried batch are not added again. The subscriptions are read
+	// with a single List rather than one by one.
+	existing := make([]*subapi.Subscription, len(subs))
+	if !hasFailed(errs) {
+		current, err := s.listSubscriptions(ctx)
+		if err != nil {
+			abortBatch(subs2IDs(subs), errs, err)
+			return
+		}
+		currentMetas, err := s.listMetadata(ctx)
+		if err != nil {
+			abortBatch(subs2IDs(subs), errs, err)
+			return
+		}
+		for i := range subs {
+			if sub, ok := current[subs[i].ID]; ok {
+				if errs[i] = checkExistingSubscription(sub, currentMetas[subs[i].ID], &subs[i], metas[i]); errs[i] == nil {
+					existing[i] = sub
+				}
+			}
+		}
+	}
+	if hasFailed(errs) {
+		abortBatch(subs2IDs(subs), errs, nil)
+		return
+	}
+
+	// Create the subscription metadata before the subscriptions to ensure it's
+	// available when the subscriptions are reconciled
+	tx := s.transactor.Begin()
+	for i := range subs {
+		if existing[i] != nil {
+			continue
+		}
+		if metas[i] != nil {
+			tx.CreateMetadata(metas[i])
+		}
+		tx.CreateSubscription(&subs[i])
+	}
+	// A transaction is applied entirely or not at all, so if it fails none of the subscriptions
+	// were added
+	if err := tx.Commit(ctx); err != nil {
+		abortBatch(subs2IDs(subs), errs, err)
+		return
+	}
+
+	// Return the subscriptions at the revisions at which they were created
+	current, err := s.listSubscriptions(ctx)
+	if err != nil {
+		return
+	}
+	for i := range subs {
+		if sub, ok := current[subs[i].ID]; ok {
+			subs[i] = *sub
+		}
+	}
+}
+
+// listSubscriptions returns the current subscriptions by ID
+func (s *Server) listSubscriptions(ctx context.Context) (map[subapi.ID]*subapi.Subscription, error) {
+	subs, err := s.subscriptionStore.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[subapi.ID]*subapi.Subscription, len(subs))
+	for i := range subs {
+		current[subs[i].ID] = &subs[i]
+	}
+	return current, nil
+}
+
+// listMetadata returns the current subscription metadata by subscription ID
+func (s *Server) listMetadata(ctx context.Context) (map[subapi.ID]*metadata.Metadata, error) {
+	metas, err := s.metadataStore.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[subapi.ID]*metadata.Metadata, len(metas))
+	for i := range metas {
+		current[metas[i].ID] = &metas[i]
+	}
+	return current, nil
+}
+
+// RemoveSubscriptions removes a batch of subscriptions
+// The subscriptions are validated as a whole before any is removed, and the result of removing each is
+// returned. In atomic mode, the subscriptions are removed in a single transaction only if all of them
+// can be removed.
+func (s *Server) RemoveSubscriptions(ctx context.Context, req *RemoveSubscriptionsRequest) (*RemoveSubscriptionsResponse, error) {
+	log.Infof("Received RemoveSubscriptionsRequest with %d subscriptions", len(req.IDs))
+	if err := validateBatchSize(len(req.IDs)); err != nil {
+		log.Warnf("RemoveSubscriptionsRequest failed: %v", err)
+		return nil, errors.Status(err).Err()
+	}
+
+	ids := req.IDs
+	errs := make([]error, len(ids))
+	removed := make(map[subapi.ID]bool)
+	for i, id := range ids {
+		if id == "" {
+			errs[i] = errors.NewInvalid("subscription ID is required")
+			continue
+		}
+		if removed[id] {
+			errs[i] = errors.NewInvalid("subscription %s is repeated in the batch", id)
+			continue
+		}
+		removed[id] = true
+	}
+
+	if req.Atomic {
+		s.removeSubscriptionsAtomically(ctx, ids, errs)
+	} else {
+		forEach(len(ids), func(i int) {
+			if errs[i] == nil {
+				errs[i] = s.removeSubscription(ctx, ids[i])
+			}
+		})
+	}
+
+	res := &RemoveSubscriptionsResponse{
+		Results: make([]Result, len(ids)),
+	}
+	failed := 0
+	for i, id := range ids {
+		res.Results[i] = newResult(id, errs[i])
+		if errs[i] != nil {
+			failed++
+		}
+	}
+	log.Infof("Sending RemoveSubscriptionsResponse: removed %d of %d subscriptions", len(ids)-failed, len(ids))
+	return res, nil
+}
+
+// removeSubscriptionsAtomically marks the subscriptions for deletion in a single transaction
+func (s *Server) removeSubscriptionsAtomically(ctx context.Context, ids []subapi.ID, errs []error) {
+	if s.transactor == nil {
+		abortBatch(ids, errs, errors.NewNotSupported("atomic batches are not supported"))
+		return
+	}
+
+	subs := make([]*subapi.Subscription, len(ids))
+	if !hasFailed(errs) {
+		current, err := s.listSubscriptions(ctx)
+		if err != nil {
+			abortBatch(ids, errs, err)
+			return
+		}
+		for i, id := range ids {
+			if sub, ok := current[id]; ok {
+				subs[i] = sub
+			} else {
+				errs[i] = errors.NewNotFound("subscription %s not found", id)
+			}
+		}
+	}
+	if hasFailed(errs) {
+		abortBatch(ids, errs, nil)
+		return
+	}
+
+	tx := s.transactor.Begin()
+	for _, sub := range subs {
+		sub.Lifecycle.Status = subapi.Status_PENDING_DELETE
+		tx.UpdateSubscription(sub)
+	}
+	// A transaction is applied entirely or not at all, so if it fails none of the subscriptions
+	// were removed
+	if err := tx.Commit(ctx); err != nil {
+		abortBatch(ids, errs, err)
+	}
+}
+
+// validateBatchSize verifies the number of subscriptions in a batch
+func validateBatchSize(n int) error {
+	if n == 0 {
+		return errors.NewInvalid("at least one subscription is required")
+	}
+	if n > MaxBatchSize {
+		return errors.NewInvalid("at most %d subscriptions can be changed in a batch", MaxBatchSize)
+	}
+	return nil
+}
+
+// hasFailed returns whether the operation on any subscription of a batch failed
+func hasFailed(errs []error) bool {
+	for _, err := range errs {
+		if err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// abortBatch fails the subscriptions of an atomic batch that have not failed
+// If err is nil, they fail because other subscriptions in the batch failed.
+func abortBatch(ids []subapi.ID, errs []error, err error) {
+	for i, id := range ids {
+		if errs[i] != nil {
+			continue
+		}
+		if err != nil {
+			errs[i] = err
+		} else {
+			errs[i] = errors.NewConflict("subscription %s was not changed because other subscriptions in the batch failed", id)
+		}
+	}
+}
+
+func subs2IDs(subs []subapi.Subscription) []subapi.ID {
+	ids := make([]subapi.ID, len(subs))
+	for i, sub := range subs {
+		ids[i] = sub.ID
+	}
+	return ids
+}
+
+// forEach calls f for each index of a batch, running up to batchConcurrency calls at once
+func forEach(n int, f func(i int)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, batchConcurrency)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			f(i)
+		}(i)
+	}
+	wg.Wait()
+}
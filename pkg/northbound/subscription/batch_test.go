@@ -0,0 +1,292 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package subscription
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	taskapi "github.com/onosproject/onos-api/go/onos/e2sub/task"
+	subctrl "github.com/onosproject/onos-e2sub/pkg/controller/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	"github.com/onosproject/onos-e2sub/pkg/store/session"
+	store "github.com/onosproject/onos-e2sub/pkg/store/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/store/task"
+	"github.com/onosproject/onos-e2sub/pkg/store/termination"
+	"github.com/onosproject/onos-e2sub/pkg/store/txn"
+	"github.com/onosproject/onos-e2sub/pkg/store/watch"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	grpcmd "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func newTestBatchServer(t *testing.T) (*Server, store.Store, metadata.Store) {
+	subStore, err := store.NewLocalStore()
+	assert.NoError(t, err)
+	metaStore, err := metadata.NewLocalStore()
+	assert.NoError(t, err)
+	sessionStore, err := session.NewLocalStore()
+	assert.NoError(t, err)
+	txnStore, err := txn.NewLocalStore()
+	assert.NoError(t, err)
+	service := NewService(subStore, metaStore, sessionStore, txnStore, nil, time.Second, 0, 0, watch.PolicyBlock)
+	return service.Server(), subStore, metaStore
+}
+
+func newBatchSubscription(id subapi.ID) subapi.Subscription {
+	return subapi.Subscription{
+		ID:      id,
+		AppID:   "foo",
+		Details: &subapi.SubscriptionDetails{E2NodeID: "bar"},
+	}
+}
+
+func TestAddSubscriptions(t *testing.T) {
+	server, subStore, metaStore := newTestBatchServer(t)
+	ctx := grpcmd.NewIncomingContext(context.Background(), grpcmd.Pairs(ZoneKey, "zone-a"))
+
+	_, err := server.AddSubscriptions(ctx, &AddSubscriptionsRequest{})
+	assert.Error(t, err)
+
+	// Verify valid subscriptions are added and invalid subscriptions are reported
+	res, err := server.AddSubscriptions(ctx, &AddSubscriptionsRequest{
+		Subscriptions: []subapi.Subscription{
+			newBatchSubscription("1"),
+			{ID: "2"},
+			newBatchSubscription("1"),
+			newBatchSubscription("3"),
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, res.Results, 4)
+	assert.Equal(t, codes.OK.String(), res.Results[0].Code)
+	assert.NotNil(t, res.Results[0].Subscription)
+	assert.Equal(t, codes.InvalidArgument.String(), res.Results[1].Code)
+	assert.Equal(t, codes.InvalidArgument.String(), res.Results[2].Code)
+	assert.Equal(t, codes.OK.String(), res.Results[3].Code)
+	meta, err := metaStore.Get(context.Background(), "3")
+	assert.NoError(t, err)
+	assert.Equal(t, "zone-a", meta.Placement.Zone)
+
+	// Verify an atomic batch is not added if any subscription cannot be added
+	changed := newBatchSubscription("3")
+	changed.Details.E2NodeID = "baz"
+	res, err = server.AddSubscriptions(ctx, &AddSubscriptionsRequest{
+		Subscriptions: []subapi.Subscription{newBatchSubscription("4"), changed},
+		Atomic:        true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, codes.FailedPrecondition.String(), res.Results[0].Code)
+	assert.Equal(t, codes.FailedPrecondition.String(), res.Results[1].Code)
+	assert.Contains(t, res.Results[1].Message, "different")
+	_, err = subStore.Get(context.Background(), "4")
+	assert.Error(t, err)
+
+	// Verify subscriptions already added by a retried batch are returned rather than added again
+	res, err = server.AddSubscriptions(ctx, &AddSubscriptionsRequest{
+		Subscriptions: []subapi.Subscription{newBatchSubscription("4"), newBatchSubscription("5"), newBatchSubscription("3")},
+		Atomic:        true,
+	})
+	assert.NoError(t, err)
+	for _, result := range res.Results {
+		assert.Equal(t, codes.OK.String(), result.Code)
+		assert.NotZero(t, result.Subscription.Revision)
+	}
+	subs, err := subStore.List(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, subs, 4)
+	_, err = metaStore.Get(context.Background(), "5")
+	assert.NoError(t, err)
+}
+
+func TestRemoveSubscriptions(t *testing.T) {
+	server, subStore, _ := newTestBatchServer(t)
+	ctx := context.Background()
+	for _, id := range []subapi.ID{"1", "2", "3"} {
+		sub := newBatchSubscription(id)
+		assert.NoError(t, subStore.Create(ctx, &sub))
+	}
+
+	// Verify an atomic batch is not removed if any subscription cannot be removed
+	res, err := server.RemoveSubscriptions(ctx, &RemoveSubscriptionsRequest{
+		IDs:    []subapi.ID{"1", "4"},
+		Atomic: true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, codes.FailedPrecondition.String(), res.Results[0].Code)
+	assert.Equal(t, codes.NotFound.String(), res.Results[1].Code)
+	sub, err := subStore.Get(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, subapi.Status_ACTIVE, sub.Lifecycle.Status)
+
+	res, err = server.RemoveSubscriptions(ctx, &RemoveSubscriptionsRequest{
+		IDs:    []subapi.ID{"1", "2"},
+		Atomic: true,
+	})
+	assert.NoError(t, err)
+	for _, result := range res.Results {
+		assert.Equal(t, codes.OK.String(), result.Code)
+	}
+
+	// Verify the subscriptions that can be removed are removed
+	res, err = server.RemoveSubscriptions(ctx, &RemoveSubscriptionsRequest{
+		IDs: []subapi.ID{"3", "4"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, codes.OK.String(), res.Results[0].Code)
+	assert.Equal(t, codes.NotFound.String(), res.Results[1].Code)
+
+	subs, err := subStore.List(ctx)
+	assert.NoError(t, err)
+	for _, sub := range subs {
+		assert.Equal(t, subapi.Status_PENDING_DELETE, sub.Lifecycle.Status)
+	}
+}
+
+func TestBatchService(t *testing.T) {
+	subStore, err := store.NewLocalStore()
+	assert.NoError(t, err)
+	metaStore, err := metadata.NewLocalStore()
+	assert.NoError(t, err)
+	sessionStore, err := session.NewLocalStore()
+	assert.NoError(t, err)
+	txnStore, err := txn.NewLocalStore()
+	assert.NoError(t, err)
+	conn := serve(t, NewService(subStore, metaStore, sessionStore, txnStore, nil, time.Second, 0, 0, watch.PolicyBlock))
+	client := NewBatchServiceClient(conn)
+
+	// Verify the batch operations are served by the gRPC server, with the request metadata applied
+	ctx := grpcmd.AppendToOutgoingContext(context.Background(), ZoneKey, "zone-a")
+	addRes, err := client.AddSubscriptions(ctx, &AddSubscriptionsRequest{
+		Subscriptions: []subapi.Subscription{newBatchSubscription("1"), newBatchSubscription("2")},
+		Atomic:        true,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, addRes.Results, 2)
+	for _, result := range addRes.Results {
+		assert.Equal(t, codes.OK.String(), result.Code)
+		assert.NotZero(t, result.Subscription.Revision)
+	}
+	meta, err := metaStore.Get(context.Background(), "2")
+	assert.NoError(t, err)
+	assert.Equal(t, "zone-a", meta.Placement.Zone)
+
+	removeRes, err := client.RemoveSubscriptions(context.Background(), &RemoveSubscriptionsRequest{
+		IDs: []subapi.ID{"1", "3"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, codes.OK.String(), removeRes.Results[0].Code)
+	assert.Equal(t, codes.NotFound.String(), removeRes.Results[1].Code)
+
+	// Verify request errors are returned as gRPC errors
+	_, err = client.AddSubscriptions(context.Background(), &AddSubscriptionsRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// conflictingStore is a subscription store in which another writer adds a subscription while it's
+// being added, once the controller has assigned a task to the given subscription
+type conflictingStore struct {
+	store.Store
+	tasks       task.Store
+	conflicting subapi.ID
+	assigned    subapi.ID
+}
+
+func (s *conflictingStore) Create(ctx context.Context, sub *subapi.Subscription) error {
+	if sub.ID == s.conflicting {
+		deadline := time.Now().Add(5 * time.Second)
+		for !s.hasTask(ctx) && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		other := newBatchSubscription(sub.ID)
+		other.AppID = "other"
+		if err := s.Store.Create(ctx, &other); err != nil {
+			return err
+		}
+	}
+	return s.Store.Create(ctx, sub)
+}
+
+func (s *conflictingStore) hasTask(ctx context.Context) bool {
+	tasks, err := s.tasks.List(ctx)
+	if err != nil {
+		return false
+	}
+	for _, task := range tasks {
+		if task.SubscriptionID == s.assigned {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAtomicBatchRollback(t *testing.T) {
+	subStore, err := store.NewLocalStore()
+	assert.NoError(t, err)
+	metaStore, err := metadata.NewLocalStore()
+	assert.NoError(t, err)
+	sessionStore, err := session.NewLocalStore()
+	assert.NoError(t, err)
+	txnStore, err := txn.NewLocalStore()
+	assert.NoError(t, err)
+	epStore, err := endpoint.NewLocalStore()
+	assert.NoError(t, err)
+	taskStore, err := task.NewLocalStore()
+	assert.NoError(t, err)
+	termStore, err := termination.NewLocalStore()
+	assert.NoError(t, err)
+	ctx := context.Background()
+	assert.NoError(t, epStore.Create(ctx, &epapi.TerminationEndpoint{ID: "e2t-1", IP: "127.0.0.1", Port: 5150}))
+
+	controller := subctrl.NewController(subStore, epStore, taskStore, metaStore, nil, termStore, txnStore)
+	assert.NoError(t, controller.Start())
+	defer controller.Stop()
+
+	// Complete the closing of tasks as a termination would
+	taskCh := make(chan taskapi.Event)
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	assert.NoError(t, taskStore.Watch(watchCtx, taskCh))
+	go func() {
+		for event := range taskCh {
+			task := event.Task
+			if event.Type != taskapi.EventType_REMOVED && task.Lifecycle.Phase == taskapi.Phase_CLOSE && task.Lifecycle.Status == taskapi.Status_PENDING {
+				task.Lifecycle.Status = taskapi.Status_COMPLETE
+				_ = taskStore.Update(ctx, &task)
+			}
+		}
+	}()
+
+	// Add an atomic batch whose last subscription is added by another writer after the controller
+	// assigned the first subscription, rolling back the batch
+	conflicting := &conflictingStore{
+		Store:       subStore,
+		tasks:       taskStore,
+		conflicting: "2",
+		assigned:    "1",
+	}
+	server := NewService(conflicting, metaStore, sessionStore, txnStore, nil, time.Second, 0, 0, watch.PolicyBlock).Server()
+	res, err := server.AddSubscriptions(ctx, &AddSubscriptionsRequest{
+		Subscriptions: []subapi.Subscription{newBatchSubscription("1"), newBatchSubscription("2")},
+		Atomic:        true,
+	})
+	assert.NoError(t, err)
+	for _, result := range res.Results {
+		assert.Equal(t, codes.FailedPrecondition.String(), result.Code)
+		assert.Contains(t, result.Message, "rolled back")
+	}
+	_, err = subStore.Get(ctx, "1")
+	assert.Error(t, err)
+
+	// Verify the tasks the controller created for the rolled back subscription are closed and removed
+	assert.Eventually(t, func() bool {
+		return !conflicting.hasTask(ctx)
+	}, 10*time.Second, 10*time.Millisecond)
+}
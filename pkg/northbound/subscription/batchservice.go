@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package subscription
+
+import (
+	"context"
+	"encoding/json"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+// The batch operations are not part of the E2 subscription API, so the batch service is not
+// generated from a protobuf definition. Its messages are the JSON batch requests and responses,
+// exchanged with the "json" gRPC content subtype, so the service is served by the northbound gRPC
+// server alongside the E2SubscriptionService, under its TLS configuration.
+
+// BatchServiceName is the full name of the gRPC batch service
+const BatchServiceName = "onos.e2sub.subscription.E2SubscriptionBatchService"
+
+// codecName is the name of the codec and the content subtype of the batch service messages
+const codecName = "json"
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// jsonCodec is a gRPC codec encoding messages as JSON
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return codecName
+}
+
+// BatchServiceServer is the server API for the batch service
+type BatchServiceServer interface {
+	// AddSubscriptions adds a batch of subscriptions
+	AddSubscriptions(ctx context.Context, req *AddSubscriptionsRequest) (*AddSubscriptionsResponse, error)
+
+	// RemoveSubscriptions removes a batch of subscriptions
+	RemoveSubscriptions(ctx context.Context, req *RemoveSubscriptionsRequest) (*RemoveSubscriptionsResponse, error)
+}
+
+// RegisterBatchServiceServer registers the batch service with the gRPC server
+func RegisterBatchServiceServer(s *grpc.Server, srv BatchServiceServer) {
+	s.RegisterService(&batchServiceDesc, srv)
+}
+
+var batchServiceDesc = grpc.ServiceDesc{
+	ServiceName: BatchServiceName,
+	HandlerType: (*BatchServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddSubscriptions",
+			Handler:    addSubscriptionsHandler,
+		},
+		{
+			MethodName: "RemoveSubscriptions",
+			Handler:    removeSubscriptionsHandler,
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+func addSubscriptionsHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := &AddSubscriptionsRequest{}
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BatchServiceServer).AddSubscriptions(ctx, req)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/" + BatchServiceName + "/AddSubscriptions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BatchServiceServer).AddSubscriptions(ctx, req.(*AddSubscriptionsRequest))
+	}
+	return interceptor(ctx, req, info, handler)
+}
+
+func removeSubscriptionsHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := &RemoveSubscriptionsRequest{}
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BatchServiceServer).RemoveSubscriptions(ctx, req)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/" + BatchServiceName + "/RemoveSubscriptions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BatchServiceServer).RemoveSubscriptions(ctx, req.(*RemoveSubscriptionsRequest))
+	}
+	return interceptor(ctx, req, info, handler)
+}
+
+// BatchServiceClient is the client API for the batch service
+type BatchServiceClient interface {
+	// AddSubscriptions adds a batch of subscriptions
+	AddSubscriptions(ctx context.Context, req *AddSubscriptionsRequest, opts ...grpc.CallOption) (*AddSubscriptionsResponse, error)
+
+	// RemoveSubscriptions removes a batch of subscriptions
+	RemoveSubscriptions(ctx context.Context, req *RemoveSubscriptionsRequest, opts ...grpc.CallOption) (*RemoveSubscriptionsResponse, error)
+}
+
+// NewBatchServiceClient returns a client of the batch service served on the given connection
+func NewBatchServiceClient(conn *grpc.ClientConn) BatchServiceClient {
+	return &batchServiceClient{
+		conn: conn,
+	}
+}
+
+type batchServiceClient struct {
+	conn *grpc.ClientConn
+}
+
+func (c *batchServiceClient) AddSubscriptions(ctx context.Context, req *AddSubscriptionsRequest, opts ...grpc.CallOption) (*AddSubscriptionsResponse, error) {
+	res := &AddSubscriptionsResponse{}
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(codecName)}, opts...)
+	if err := c.conn.Invoke(ctx, "/"+BatchServiceName+"/AddSubscriptions", req, res, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *batchServiceClient) RemoveSubscriptions(ctx context.Context, req *RemoveSubscriptionsRequest, opts ...grpc.CallOption) (*RemoveSubscriptionsResponse, error) {
+	res := &RemoveSubscriptionsResponse{}
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(codecName)}, opts...)
+	if err := c.conn.Invoke(ctx, "/"+BatchServiceName+"/RemoveSubscriptions", req, res, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+var _ BatchServiceServer = &Server{}
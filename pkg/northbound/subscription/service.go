@@ -5,36 +5,141 @@
 package subscription
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/gogo/protobuf/proto"
 	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/controller/placement"
+	"github.com/onosproject/onos-e2sub/pkg/northbound/labels"
+	"github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	"github.com/onosproject/onos-e2sub/pkg/store/session"
 	store "github.com/onosproject/onos-e2sub/pkg/store/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/store/txn"
+	"github.com/onosproject/onos-e2sub/pkg/store/watch"
+	"github.com/onosproject/onos-lib-go/pkg/env"
 	"github.com/onosproject/onos-lib-go/pkg/errors"
 	"github.com/onosproject/onos-lib-go/pkg/logging"
 	"github.com/onosproject/onos-lib-go/pkg/northbound"
 	"google.golang.org/grpc"
+	grpcmd "google.golang.org/grpc/metadata"
 )
 
 var log = logging.GetLogger("northbound", "subscription")
 
+const (
+	// ScheduleStartKey is the request metadata key for the RFC 3339 start time of a subscription's activation window
+	ScheduleStartKey = "e2sub-schedule-start"
+	// ScheduleEndKey is the request metadata key for the RFC 3339 end time of a subscription's activation window
+	ScheduleEndKey = "e2sub-schedule-end"
+	// SchedulePeriodKey is the request metadata key for the recurrence period of a subscription's activation window
+	SchedulePeriodKey = "e2sub-schedule-period"
+	// OwnerKey is the request metadata key for the [namespace/]name of the pod that owns a subscription
+	OwnerKey = "e2sub-owner"
+	// SessionKey is the request metadata key for the token of the client session a subscription is scoped to
+	SessionKey = "e2sub-session"
+	// ZoneKey is the request metadata key for the zone of the termination endpoints a subscription may be placed on
+	ZoneKey = "e2sub-zone"
+	// LabelsKey is the request metadata key for the comma separated key=value labels of the termination endpoints
+	// a subscription may be placed on
+	LabelsKey = "e2sub-labels"
+	// ConditionKey is the response metadata key for the condition preventing a subscription from being placed
+	ConditionKey = "e2sub-condition"
+	// ReasonKey is the response metadata key for the reason for a subscription's condition
+	ReasonKey = "e2sub-reason"
+	// DryRunKey is the request metadata key requesting a subscription be admitted and placed without being added
+	DryRunKey = "e2sub-dry-run"
+	// EndpointKey is the response metadata key for the termination endpoint a dry run would place a subscription on
+	EndpointKey = "e2sub-endpoint"
+)
+
 // NewService creates a new subscription service
-func NewService(store store.Store) northbound.Service {
+// Subscription watches are fanned out from a single store watch, buffering bufferSize events for each
+// client and applying the given policy to clients whose buffer is full. Batches of subscriptions are
+// added and removed atomically in transactions journaled in the given transaction store. Dry runs of
+// AddSubscription are placed by the given placer. Session streams renew their session every keepAlive
+// and expire once they have sent nothing to their client for the given ttl.
+func NewService(store store.Store, metadata metadata.Store, sessions session.Store, transactions txn.Store, placer *placement.Placer,
+	keepAlive time.Duration, ttl time.Duration, bufferSize int, policy watch.Policy) *Service {
 	return &Service{
-		store: store,
+		store:        store,
+		metadata:     metadata,
+		sessions:     sessions,
+		transactions: transactions,
+		placer:       placer,
+		keepAlive:    keepAlive,
+		ttl:          ttl,
+		bufferSize:   bufferSize,
+		policy:       policy,
 	}
 }
 
 // Service is a Service implementation for subscription service.
 type Service struct {
-	store store.Store
+	store        store.Store
+	metadata     metadata.Store
+	sessions     session.Store
+	transactions txn.Store
+	placer       *placement.Placer
+	keepAlive    time.Duration
+	ttl          time.Duration
+	bufferSize   int
+	policy       watch.Policy
+	server       *Server
+	serverOnce   sync.Once
+}
+
+// Server returns the server implementing the service
+// The server also serves the batch operations that are not part of the E2 subscription API.
+func (s *Service) Server() *Server {
+	s.serverOnce.Do(func() {
+		s.server = &Server{
+			subscriptionStore: s.store,
+			metadataStore:     s.metadata,
+			sessionStore:      s.sessions,
+			placer:            s.placer,
+			keepAlive:         s.keepAlive,
+			ttl:               s.ttl,
+			events:            watch.NewHub("subscriptions", s.openWatch, s.bufferSize, s.policy),
+		}
+		if s.transactions != nil {
+			s.server.transactor = txn.NewTransactor(s.transactions, txn.Stores{
+				Subscriptions: s.store,
+				Metadata:      s.metadata,
+			})
+		}
+	})
+	return s.server
 }
 
 // Register registers the Service with the gRPC server.
 func (s *Service) Register(r *grpc.Server) {
-	server := &Server{
-		subscriptionStore: s.store,
+	subapi.RegisterE2SubscriptionServiceServer(r, s.Server())
+	RegisterBatchServiceServer(r, s.Server())
+}
+
+// openWatch opens the subscription store watch shared by all WatchSubscriptions streams
+func (s *Service) openWatch(ctx context.Context, ch chan<- interface{}) error {
+	subCh := make(chan subapi.Event)
+	if err := s.store.Watch(ctx, subCh); err != nil {
+		return err
 	}
-	subapi.RegisterE2SubscriptionServiceServer(r, server)
+	go func() {
+		defer close(ch)
+		for event := range subCh {
+			ch <- event
+		}
+	}()
+	return nil
 }
 
 var _ northbound.Service = &Service{}
@@ -42,27 +147,57 @@ var _ northbound.Service = &Service{}
 // Server implements the gRPC service for managing of subscriptions
 type Server struct {
 	subscriptionStore store.Store
+	metadataStore     metadata.Store
+	sessionStore      session.Store
+	transactor        *txn.Transactor
+	placer            *placement.Placer
+	keepAlive         time.Duration
+	ttl               time.Duration
+	events            *watch.Hub
 }
 
 // AddSubscription adds a subscription
 func (s *Server) AddSubscription(ctx context.Context, req *subapi.AddSubscriptionRequest) (*subapi.AddSubscriptionResponse, error) {
 	log.Infof("Received AddSubscriptionRequest %+v", req)
 	sub := req.Subscription
-	if sub.ID == "" {
-		return nil, errors.NewInvalid("subscription ID is required")
+	if err := validateSubscription(sub); err != nil {
+		return nil, err
 	}
-	if sub.AppID == "" {
-		return nil, errors.NewInvalid("subscription AppID is required")
+	if err := assignID(sub); err != nil {
+		log.Warnf("AddSubscriptionRequest %+v failed: %v", req, err)
+		return nil, errors.Status(err).Err()
+	}
+
+	meta, err := getMetadata(ctx, sub.ID)
+	if err != nil {
+		log.Warnf("AddSubscriptionRequest %+v failed: %v", req, err)
+		return nil, errors.Status(err).Err()
 	}
-	if sub.Details.E2NodeID == "" {
-		return nil, errors.NewInvalid("subscription E2NodeID is required")
+
+	if err := s.checkSession(ctx, meta); err != nil {
+		log.Warnf("AddSubscriptionRequest %+v failed: %v", req, err)
+		return nil, errors.Status(err).Err()
 	}
 
-	err := s.subscriptionStore.Create(ctx, sub)
+	dryRun, err := getDryRun(ctx)
 	if err != nil {
 		log.Warnf("AddSubscriptionRequest %+v failed: %v", req, err)
 		return nil, errors.Status(err).Err()
 	}
+	if dryRun {
+		res, err := s.previewSubscription(ctx, sub, meta)
+		if err != nil {
+			log.Warnf("AddSubscriptionRequest %+v failed: %v", req, err)
+			return nil, errors.Status(err).Err()
+		}
+		log.Infof("Sending AddSubscriptionResponse %+v for dry run", res)
+		return res, nil
+	}
+
+	if err := s.addSubscription(ctx, sub, meta); err != nil {
+		log.Warnf("AddSubscriptionRequest %+v failed: %v", req, err)
+		return nil, errors.Status(err).Err()
+	}
 	res := &subapi.AddSubscriptionResponse{
 		Subscription: sub,
 	}
@@ -70,6 +205,207 @@ func (s *Server) AddSubscription(ctx context.Context, req *subapi.AddSubscriptio
 	return res, nil
 }
 
+// validateSubscription verifies the subscription has the fields required to add it
+func validateSubscription(sub *subapi.Subscription) error {
+	if sub == nil {
+		return errors.NewInvalid("subscription is required")
+	}
+	if sub.AppID == "" {
+		return errors.NewInvalid("subscription AppID is required")
+	}
+	if sub.GetDetails().GetE2NodeID() == "" {
+		return errors.NewInvalid("subscription E2NodeID is required")
+	}
+	return nil
+}
+
+// assignID derives the ID of a subscription added without one
+func assignID(sub *subapi.Subscription) error {
+	if sub.ID != "" {
+		return nil
+	}
+	id, err := DeriveID(sub.AppID, sub.Details)
+	if err != nil {
+		return err
+	}
+	sub.ID = id
+	return nil
+}
+
+// equalDetails returns whether the canonical encodings of the subscription details are equal
+func equalDetails(details1, details2 *subapi.SubscriptionDetails) bool {
+	bytes1, err1 := proto.Marshal(details1)
+	bytes2, err2 := proto.Marshal(details2)
+	return err1 == nil && err2 == nil && bytes.Equal(bytes1, bytes2)
+}
+
+// DeriveID returns the ID assigned to a subscription added without one
+// The ID is the AppID suffixed with a hash of the canonical encoding of the subscription details, so
+// the same subscription is always assigned the same ID.
+func DeriveID(appID subapi.AppID, details *subapi.SubscriptionDetails) (subapi.ID, error) {
+	encoded, err := proto.Marshal(details)
+	if err != nil {
+		return "", errors.NewInvalid("invalid subscription details: %s", err)
+	}
+	hash := sha256.Sum256(encoded)
+	return subapi.ID(fmt.Sprintf("%s-%s", appID, hex.EncodeToString(hash[:8]))), nil
+}
+
+// checkSession verifies the session the subscription is scoped to is known
+func (s *Server) checkSession(ctx context.Context, meta *metadata.Metadata) error {
+	if meta == nil || meta.Session == "" {
+		return nil
+	}
+	if _, err := s.sessionStore.Get(ctx, session.ID(meta.Session)); err != nil {
+		if errors.IsNotFound(err) {
+			return errors.NewInvalid("unknown session %s", meta.Session)
+		}
+		return err
+	}
+	return nil
+}
+
+// addSubscription creates the subscription and its metadata
+// If the subscription was already added, e.g. by a request being retried, sub is set to the existing subscription.
+func (s *Server) addSubscription(ctx context.Context, sub *subapi.Subscription, meta *metadata.Metadata) error {
+	err := s.createSubscription(ctx, sub, meta)
+	if !errors.IsAlreadyExists(err) {
+		return err
+	}
+	existing, _, getErr := s.getExistingSubscription(ctx, sub, meta)
+	if getErr != nil {
+		return getErr
+	} else if existing == nil {
+		return err
+	}
+	*sub = *existing
+	return nil
+}
+
+// createSubscription creates the subscription and its metadata
+func (s *Server) createSubscription(ctx context.Context, sub *subapi.Subscription, meta *metadata.Metadata) error {
+	// Create the subscription metadata before the subscription to ensure it's
+	// available when the subscription is reconciled
+	if meta != nil {
+		if err := s.metadataStore.Create(ctx, meta); err != nil {
+			return err
+		}
+	}
+
+	if err := s.subscriptionStore.Create(ctx, sub); err != nil {
+		if meta != nil {
+			_ = s.metadataStore.Delete(ctx, meta.ID)
+		}
+		return err
+	}
+	return nil
+}
+
+// getExistingSubscription returns the subscription with the ID of sub and its metadata if it exists
+// A subscription with the same ID is only the same subscription if its AppID, details and the schedule,
+// owner, session and placement requested for it are equal; otherwise a Conflict error is returned.
+func (s *Server) getExistingSubscription(ctx context.Context, sub *subapi.Subscription, meta *metadata.Metadata) (*subapi.Subscription, *metadata.Metadata, error) {
+	existing, err := s.subscriptionStore.Get(ctx, sub.ID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	existingMeta, err := s.metadataStore.Get(ctx, sub.ID)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, nil, err
+		}
+		existingMeta = nil
+	}
+	if err := checkExistingSubscription(existing, existingMeta, sub, meta); err != nil {
+		return nil, nil, err
+	}
+	return existing, existingMeta, nil
+}
+
+// checkExistingSubscription verifies that an existing subscription is the subscription sub
+func checkExistingSubscription(existing *subapi.Subscription, existingMeta *metadata.Metadata, sub *subapi.Subscription, meta *metadata.Metadata) error {
+	if existing.AppID != sub.AppID || !equalDetails(existing.Details, sub.Details) {
+		return errors.NewConflict("subscription %s already exists with a different AppID or details", sub.ID)
+	}
+	if !equalRequest(existingMeta, meta) {
+		return errors.NewConflict("subscription %s already exists with a different schedule, owner, session or placement", sub.ID)
+	}
+	if existing.Lifecycle.Status == subapi.Status_PENDING_DELETE {
+		return errors.NewConflict("subscription %s is being removed", sub.ID)
+	}
+	return nil
+}
+
+// equalRequest returns whether two subscription metadata were requested with the same schedule, owner,
+// session and placement. The state onos-e2sub records in the metadata, e.g. the time the owner was lost
+// or the placement condition, is ignored.
+func equalRequest(meta1, meta2 *metadata.Metadata) bool {
+	bytes1, err1 := json.Marshal(getRequest(meta1))
+	bytes2, err2 := json.Marshal(getRequest(meta2))
+	return err1 == nil && err2 == nil && bytes.Equal(bytes1, bytes2)
+}
+
+// getRequest returns the part of subscription metadata that is set by the request adding the subscription
+func getRequest(meta *metadata.Metadata) *metadata.Metadata {
+	request := &metadata.Metadata{}
+	if meta == nil {
+		return request
+	}
+	request.Schedule = meta.Schedule
+	request.Session = meta.Session
+	request.Placement = meta.Placement
+	if meta.Owner != nil {
+		request.Owner = &metadata.Owner{
+			Namespace: meta.Owner.Namespace,
+			Name:      meta.Owner.Name,
+		}
+	}
+	return request
+}
+
+// previewSubscription decides where the subscription would be placed without adding it
+// The termination endpoint the subscription would be placed on, or the condition preventing it from
+// being placed, is returned in the response header. If the subscription already exists, the existing
+// subscription is returned along with its placement.
+func (s *Server) previewSubscription(ctx context.Context, sub *subapi.Subscription, meta *metadata.Metadata) (*subapi.AddSubscriptionResponse, error) {
+	if s.placer == nil {
+		return nil, errors.NewNotSupported("dry runs are not supported")
+	}
+
+	existing, existingMeta, err := s.getExistingSubscription(ctx, sub, meta)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		sub = existing
+		meta = existingMeta
+	}
+
+	decision, err := s.placer.Place(ctx, sub, meta)
+	if err != nil {
+		return nil, err
+	}
+	preview := &metadata.Metadata{ID: sub.ID}
+	if meta != nil {
+		*preview = *meta
+	}
+	preview.Condition = decision.Condition
+	preview.Reason = decision.Reason
+	md := getHeader(preview)
+	if decision.Endpoint != nil {
+		md.Set(EndpointKey, string(decision.Endpoint.ID))
+	}
+	if err := grpc.SetHeader(ctx, md); err != nil {
+		return nil, err
+	}
+	return &subapi.AddSubscriptionResponse{
+		Subscription: sub,
+	}, nil
+}
+
 // GetSubscription retrieves information about a specific subscription in the list of existing subscriptions
 func (s *Server) GetSubscription(ctx context.Context, req *subapi.GetSubscriptionRequest) (*subapi.GetSubscriptionResponse, error) {
 	log.Infof("Received GetSubscriptionRequest %+v", req)
@@ -78,6 +414,19 @@ func (s *Server) GetSubscription(ctx context.Context, req *subapi.GetSubscriptio
 		log.Warnf("GetSubscriptionRequest %+v failed: %v", req, err)
 		return nil, errors.Status(err).Err()
 	}
+
+	// Return the subscription's metadata in the response header
+	meta, err := s.metadataStore.Get(ctx, req.ID)
+	if err != nil && !errors.IsNotFound(err) {
+		log.Warnf("GetSubscriptionRequest %+v failed: %v", req, err)
+		return nil, errors.Status(err).Err()
+	}
+	if meta != nil {
+		if err := grpc.SetHeader(ctx, getHeader(meta)); err != nil {
+			log.Warnf("GetSubscriptionRequest %+v failed: %v", req, err)
+			return nil, err
+		}
+	}
 	res := &subapi.GetSubscriptionResponse{
 		Subscription: sub,
 	}
@@ -88,14 +437,7 @@ func (s *Server) GetSubscription(ctx context.Context, req *subapi.GetSubscriptio
 // RemoveSubscription removes a subscription
 func (s *Server) RemoveSubscription(ctx context.Context, req *subapi.RemoveSubscriptionRequest) (*subapi.RemoveSubscriptionResponse, error) {
 	log.Infof("Received RemoveSubscriptionRequest %+v", req)
-	sub, err := s.subscriptionStore.Get(ctx, req.ID)
-	if err != nil {
-		log.Warnf("RemoveSubscriptionRequest %+v failed: %v", req, err)
-		return nil, errors.Status(err).Err()
-	}
-	sub.Lifecycle.Status = subapi.Status_PENDING_DELETE
-	err = s.subscriptionStore.Update(ctx, sub)
-	if err != nil {
+	if err := s.removeSubscription(ctx, req.ID); err != nil {
 		log.Warnf("RemoveSubscriptionRequest %+v failed: %v", req, err)
 		return nil, errors.Status(err).Err()
 	}
@@ -104,6 +446,16 @@ func (s *Server) RemoveSubscription(ctx context.Context, req *subapi.RemoveSubsc
 	return res, nil
 }
 
+// removeSubscription marks the subscription for deletion
+func (s *Server) removeSubscription(ctx context.Context, id subapi.ID) error {
+	sub, err := s.subscriptionStore.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	sub.Lifecycle.Status = subapi.Status_PENDING_DELETE
+	return s.subscriptionStore.Update(ctx, sub)
+}
+
 // ListSubscriptions returns the list of current existing subscriptions
 func (s *Server) ListSubscriptions(ctx context.Context, req *subapi.ListSubscriptionsRequest) (*subapi.ListSubscriptionsResponse, error) {
 	log.Infof("Received ListSubscriptionsRequest %+v", req)
@@ -131,36 +483,412 @@ func (s *Server) ListSubscriptions(ctx context.Context, req *subapi.ListSubscrip
 // WatchTerminations streams termination end-point changes
 func (s *Server) WatchSubscriptions(req *subapi.WatchSubscriptionsRequest, server subapi.E2SubscriptionService_WatchSubscriptionsServer) error {
 	log.Infof("Received WatchTerminationsRequest %+v", req)
-	var watchOpts []store.WatchOption
-	if !req.Noreplay {
-		watchOpts = append(watchOpts, store.WithReplay())
+
+	// If a session was requested, hold the session open for the lifetime of the stream
+	var stream *sessionStream
+	if md, ok := grpcmd.FromIncomingContext(server.Context()); ok {
+		if values := md.Get(SessionKey); len(values) > 0 {
+			stream = newSessionStream(server)
+			defer stream.cancel()
+			id, err := s.openSession(stream, session.ID(values[0]))
+			if err != nil {
+				log.Warnf("WatchTerminationsRequest %+v failed: %v", req, err)
+				return errors.Status(err).Err()
+			}
+			if err := server.SendHeader(grpcmd.Pairs(SessionKey, string(id))); err != nil {
+				log.Warnf("WatchTerminationsRequest %+v failed: %v", req, err)
+				return err
+			}
+			server = stream
+		}
 	}
 
-	ch := make(chan subapi.Event)
-	if err := s.subscriptionStore.Watch(server.Context(), ch, watchOpts...); err != nil {
+	// Subscribe before listing the existing subscriptions to ensure no changes are missed
+	sub, err := s.events.Subscribe(server.Context())
+	if err != nil {
 		log.Warnf("WatchTerminationsRequest %+v failed: %v", req, err)
 		return errors.Status(err).Err()
 	}
 
-	return s.Stream(server, ch)
+	if !req.Noreplay {
+		subs, err := s.subscriptionStore.List(server.Context())
+		if err != nil {
+			log.Warnf("WatchTerminationsRequest %+v failed: %v", req, err)
+			return errors.Status(err).Err()
+		}
+		for _, subscription := range subs {
+			if err := s.send(server, subapi.Event{Type: subapi.EventType_NONE, Subscription: subscription}); err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.Stream(server, sub); err != nil {
+		return err
+	}
+	if stream != nil && stream.isExpired() {
+		return errors.Status(errors.NewUnavailable("session stream expired")).Err()
+	}
+	return nil
 }
 
 // Stream is the ongoing stream for WatchSubscriptions request
-func (s *Server) Stream(server subapi.E2SubscriptionService_WatchSubscriptionsServer, ch chan subapi.Event) error {
-	for event := range ch {
-		if event.Type == subapi.EventType_UPDATED {
+// If events were dropped for a slow client, an event with an empty subscription marks the gap.
+func (s *Server) Stream(server subapi.E2SubscriptionService_WatchSubscriptionsServer, sub *watch.Subscriber) error {
+	for event := range sub.Events() {
+		if event.Gap {
+			if err := s.send(server, subapi.Event{Type: subapi.EventType_NONE}); err != nil {
+				return err
+			}
 			continue
 		}
 
-		res := &subapi.WatchSubscriptionsResponse{
-			Event: event,
+		subEvent := event.Value.(subapi.Event)
+		if subEvent.Type == subapi.EventType_UPDATED {
+			continue
 		}
-
-		log.Infof("Sending WatchSubscriptionsResponse %+v", res)
-		if err := server.Send(res); err != nil {
-			log.Warnf("WatchSubscriptionsResponse %+v failed: %v", res, err)
+		if err := s.send(server, subEvent); err != nil {
 			return err
 		}
 	}
+	if err := sub.Err(); err != nil {
+		log.Warnf("WatchSubscriptions stream failed: %v", err)
+		return errors.Status(err).Err()
+	}
 	return nil
 }
+
+// send sends a WatchSubscriptions event
+func (s *Server) send(server subapi.E2SubscriptionService_WatchSubscriptionsServer, event subapi.Event) error {
+	res := &subapi.WatchSubscriptionsResponse{
+		Event: event,
+	}
+
+	log.Infof("Sending WatchSubscriptionsResponse %+v", res)
+	if err := server.Send(res); err != nil {
+		log.Warnf("WatchSubscriptionsResponse %+v failed: %v", res, err)
+		return err
+	}
+	return nil
+}
+
+// openSession opens or reclaims the session with the given token for the given stream
+// If the token is empty, a new session token is generated.
+func (s *Server) openSession(stream *sessionStream, id session.ID) (session.ID, error) {
+	ctx := stream.Context()
+	if id == "" {
+		id = session.ID(newToken())
+	}
+	token := newToken()
+
+	// Retry conflicts with the session's previous stream, which may still be disconnecting
+	for {
+		sess, err := s.sessionStore.Get(ctx, id)
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				return "", err
+			}
+			sess = &session.Session{
+				ID:        id,
+				Stream:    token,
+				Connected: true,
+				Renewed:   time.Now(),
+			}
+			err = s.sessionStore.Create(ctx, sess)
+		} else {
+			sess.Stream = token
+			sess.Connected = true
+			sess.Renewed = time.Now()
+			sess.Disconnected = time.Time{}
+			err = s.sessionStore.Update(ctx, sess)
+		}
+		if err == nil {
+			break
+		} else if !errors.IsConflict(err) && !errors.IsAlreadyExists(err) && !errors.IsNotFound(err) {
+			return "", err
+		}
+	}
+	log.Infof("Opened Session %s", id)
+
+	go s.holdSession(stream, id, token)
+	return id, nil
+}
+
+// holdSession renews the session until the stream's context is done, then marks the session disconnected
+// A stream that has sent nothing for the session TTL, e.g. on a half-open connection, is expired and
+// closed so that the session is no longer renewed.
+func (s *Server) holdSession(stream *sessionStream, id session.ID, token string) {
+	ticker := time.NewTicker(s.keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if s.ttl > 0 && time.Since(stream.lastActive()) >= s.ttl {
+				log.Warnf("Session %s stream expired after %s", id, s.ttl)
+				stream.expire()
+				continue
+			}
+			if !s.updateSession(context.Background(), id, token, func(sess *session.Session) {
+				sess.Renewed = time.Now()
+			}) {
+				return
+			}
+		case <-stream.Context().Done():
+			s.updateSession(context.Background(), id, token, func(sess *session.Session) {
+				sess.Connected = false
+				sess.Disconnected = time.Now()
+			})
+			log.Infof("Disconnected Session %s", id)
+			return
+		}
+	}
+}
+
+// updateSession updates the session if it's still held by the given stream
+func (s *Server) updateSession(ctx context.Context, id session.ID, stream string, f func(*session.Session)) bool {
+	sess, err := s.sessionStore.Get(ctx, id)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Warnf("Failed to update Session %s: %v", id, err)
+		}
+		return false
+	}
+	if sess.Stream != stream || !sess.Connected {
+		return false
+	}
+	f(sess)
+	if err := s.sessionStore.Update(ctx, sess); err != nil {
+		log.Warnf("Failed to update Session %s: %v", id, err)
+	}
+	return true
+}
+
+// newSessionStream returns a session stream wrapping the given WatchSubscriptions stream
+func newSessionStream(server subapi.E2SubscriptionService_WatchSubscriptionsServer) *sessionStream {
+	ctx, cancel := context.WithCancel(server.Context())
+	return &sessionStream{
+		E2SubscriptionService_WatchSubscriptionsServer: server,
+		ctx:    ctx,
+		cancel: cancel,
+		active: time.Now(),
+	}
+}
+
+// sessionStream is a WatchSubscriptions stream holding a session
+// The stream records the time of its last send, and its context is cancelled when it expires.
+type sessionStream struct {
+	subapi.E2SubscriptionService_WatchSubscriptionsServer
+	ctx     context.Context
+	cancel  context.CancelFunc
+	mu      sync.RWMutex
+	active  time.Time
+	expired bool
+}
+
+// Context returns the stream context, which is done when the stream ends or expires
+func (s *sessionStream) Context() context.Context {
+	return s.ctx
+}
+
+// Send sends a response to the client and records the stream's activity
+func (s *sessionStream) Send(res *subapi.WatchSubscriptionsResponse) error {
+	if err := s.E2SubscriptionService_WatchSubscriptionsServer.Send(res); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.active = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// lastActive returns the time of the stream's last send
+func (s *sessionStream) lastActive() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.active
+}
+
+// expire expires the stream, cancelling its context
+func (s *sessionStream) expire() {
+	s.mu.Lock()
+	s.expired = true
+	s.mu.Unlock()
+	s.cancel()
+}
+
+// isExpired returns whether the stream expired
+func (s *sessionStream) isExpired() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.expired
+}
+
+// newToken returns a new random token
+func newToken() string {
+	bytes := make([]byte, 16)
+	_, _ = rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+// getMetadata reads the subscription metadata from the request metadata
+func getMetadata(ctx context.Context, id subapi.ID) (*metadata.Metadata, error) {
+	md, ok := grpcmd.FromIncomingContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+
+	schedule, err := getSchedule(md)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := getOwner(md)
+	if err != nil {
+		return nil, err
+	}
+	var token string
+	if values := md.Get(SessionKey); len(values) > 0 {
+		if values[0] == "" {
+			return nil, errors.NewInvalid("invalid %s: session token is required", SessionKey)
+		}
+		token = values[0]
+	}
+	placement, err := getPlacement(md)
+	if err != nil {
+		return nil, err
+	}
+	if schedule == nil && owner == nil && token == "" && placement == nil {
+		return nil, nil
+	}
+	return &metadata.Metadata{
+		ID:        id,
+		Schedule:  schedule,
+		Owner:     owner,
+		Session:   token,
+		Placement: placement,
+	}, nil
+}
+
+// getSchedule reads the subscription schedule from the request metadata
+func getSchedule(md grpcmd.MD) (*metadata.Schedule, error) {
+	start, end, period := md.Get(ScheduleStartKey), md.Get(ScheduleEndKey), md.Get(SchedulePeriodKey)
+	if len(start) == 0 && len(end) == 0 && len(period) == 0 {
+		return nil, nil
+	}
+
+	schedule := &metadata.Schedule{}
+	if len(start) > 0 {
+		t, err := time.Parse(time.RFC3339, start[0])
+		if err != nil {
+			return nil, errors.NewInvalid("invalid %s: %s", ScheduleStartKey, err)
+		}
+		schedule.Start = t
+	}
+	if len(end) > 0 {
+		t, err := time.Parse(time.RFC3339, end[0])
+		if err != nil {
+			return nil, errors.NewInvalid("invalid %s: %s", ScheduleEndKey, err)
+		}
+		schedule.End = t
+	}
+	if len(period) > 0 {
+		d, err := time.ParseDuration(period[0])
+		if err != nil {
+			return nil, errors.NewInvalid("invalid %s: %s", SchedulePeriodKey, err)
+		}
+		schedule.Period = d
+	}
+	if err := schedule.Validate(); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// getDryRun reads whether the request is a dry run from the request metadata
+func getDryRun(ctx context.Context) (bool, error) {
+	md, ok := grpcmd.FromIncomingContext(ctx)
+	if !ok {
+		return false, nil
+	}
+	values := md.Get(DryRunKey)
+	if len(values) == 0 {
+		return false, nil
+	}
+	dryRun, err := strconv.ParseBool(values[0])
+	if err != nil {
+		return false, errors.NewInvalid("invalid %s: %s", DryRunKey, values[0])
+	}
+	return dryRun, nil
+}
+
+// getOwner reads the subscription owner from the request metadata
+func getOwner(md grpcmd.MD) (*metadata.Owner, error) {
+	values := md.Get(OwnerKey)
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	owner := &metadata.Owner{
+		Namespace: env.GetPodNamespace(),
+		Name:      values[0],
+	}
+	if i := strings.Index(values[0], "/"); i >= 0 {
+		owner.Namespace = values[0][:i]
+		owner.Name = values[0][i+1:]
+	}
+	if owner.Namespace == "" || owner.Name == "" {
+		return nil, errors.NewInvalid("invalid %s: %s", OwnerKey, values[0])
+	}
+	return owner, nil
+}
+
+// getPlacement reads the subscription placement constraints from the request metadata
+func getPlacement(md grpcmd.MD) (*metadata.Placement, error) {
+	zone, values := md.Get(ZoneKey), md.Get(LabelsKey)
+	if len(zone) == 0 && len(values) == 0 {
+		return nil, nil
+	}
+
+	placement := &metadata.Placement{}
+	if len(zone) > 0 {
+		placement.Zone = zone[0]
+	}
+	if len(values) > 0 {
+		parsed, err := labels.Parse(values)
+		if err != nil {
+			return nil, errors.NewInvalid("invalid %s: %s", LabelsKey, err)
+		}
+		placement.Labels = parsed
+	}
+	return placement, nil
+}
+
+// getHeader returns the response header for the given subscription metadata
+func getHeader(meta *metadata.Metadata) grpcmd.MD {
+	md := grpcmd.MD{}
+	if meta.Schedule != nil {
+		if !meta.Schedule.Start.IsZero() {
+			md.Set(ScheduleStartKey, meta.Schedule.Start.Format(time.RFC3339))
+		}
+		if !meta.Schedule.End.IsZero() {
+			md.Set(ScheduleEndKey, meta.Schedule.End.Format(time.RFC3339))
+		}
+		if meta.Schedule.Period != 0 {
+			md.Set(SchedulePeriodKey, meta.Schedule.Period.String())
+		}
+	}
+	if meta.Owner != nil {
+		md.Set(OwnerKey, meta.Owner.String())
+	}
+	if meta.Placement != nil {
+		if meta.Placement.Zone != "" {
+			md.Set(ZoneKey, meta.Placement.Zone)
+		}
+		if len(meta.Placement.Labels) > 0 {
+			md.Set(LabelsKey, labels.Format(meta.Placement.Labels))
+		}
+	}
+	if meta.Condition != metadata.ConditionNone {
+		md.Set(ConditionKey, string(meta.Condition))
+		md.Set(ReasonKey, meta.Reason)
+	}
+	return md
+}
@@ -10,12 +10,23 @@ import (
 	"net"
 	"sync"
 	"testing"
+	"time"
 
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
 	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/controller/placement"
+	"github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	"github.com/onosproject/onos-e2sub/pkg/store/session"
 	store "github.com/onosproject/onos-e2sub/pkg/store/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/store/task"
+	"github.com/onosproject/onos-e2sub/pkg/store/termination"
+	"github.com/onosproject/onos-e2sub/pkg/store/watch"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
 	"github.com/onosproject/onos-lib-go/pkg/northbound"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
+	grpcmd "google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/test/bufconn"
 )
 
@@ -26,20 +37,35 @@ func bufDialer(context.Context, string) (net.Conn, error) {
 }
 
 func newTestService() (northbound.Service, error) {
-	endPointStore, err := store.NewLocalStore()
+	subStore, err := store.NewLocalStore()
+	if err != nil {
+		return nil, err
+	}
+	metaStore, err := metadata.NewLocalStore()
+	if err != nil {
+		return nil, err
+	}
+	sessionStore, err := session.NewLocalStore()
 	if err != nil {
 		return nil, err
 	}
 	return &Service{
-		store: endPointStore,
+		store:     subStore,
+		metadata:  metaStore,
+		sessions:  sessionStore,
+		keepAlive: time.Second,
 	}, nil
 }
 
 func createServerConnection(t *testing.T) *grpc.ClientConn {
-	lis = bufconn.Listen(1024 * 1024)
 	s, err := newTestService()
 	assert.NoError(t, err)
 	assert.NotNil(t, s)
+	return serve(t, s)
+}
+
+func serve(t *testing.T, s northbound.Service) *grpc.ClientConn {
+	lis = bufconn.Listen(1024 * 1024)
 	server := grpc.NewServer()
 	s.Register(server)
 
@@ -167,6 +193,116 @@ func TestBadAdd(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestIdempotentAdd(t *testing.T) {
+	conn := createServerConnection(t)
+	client := subapi.NewE2SubscriptionServiceClient(conn)
+
+	details := &subapi.SubscriptionDetails{E2NodeID: "bar", ServiceModel: subapi.ServiceModel{
+		Name:    "sm1",
+		Version: "v1",
+	}}
+
+	// Verify a subscription added without an ID is assigned one derived from its AppID and details
+	res, err := client.AddSubscription(context.Background(), &subapi.AddSubscriptionRequest{
+		Subscription: &subapi.Subscription{AppID: "foo", Details: details},
+	})
+	assert.NoError(t, err)
+	id, err := DeriveID("foo", details)
+	assert.NoError(t, err)
+	assert.Equal(t, id, res.Subscription.ID)
+	revision := res.Subscription.Revision
+
+	// Verify a retried request returns the existing subscription
+	res, err = client.AddSubscription(context.Background(), &subapi.AddSubscriptionRequest{
+		Subscription: &subapi.Subscription{AppID: "foo", Details: details},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, id, res.Subscription.ID)
+	assert.Equal(t, revision, res.Subscription.Revision)
+
+	// Verify a request with the same ID and different details conflicts with the existing subscription
+	_, err = client.AddSubscription(context.Background(), &subapi.AddSubscriptionRequest{
+		Subscription: &subapi.Subscription{ID: id, AppID: "foo", Details: &subapi.SubscriptionDetails{E2NodeID: "baz"}},
+	})
+	assert.True(t, errors.IsConflict(errors.FromGRPC(err)))
+
+	other, err := DeriveID("foo", &subapi.SubscriptionDetails{E2NodeID: "baz"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, id, other)
+}
+
+func TestDryRunAdd(t *testing.T) {
+	subStore, err := store.NewLocalStore()
+	assert.NoError(t, err)
+	metaStore, err := metadata.NewLocalStore()
+	assert.NoError(t, err)
+	sessionStore, err := session.NewLocalStore()
+	assert.NoError(t, err)
+	epStore, err := endpoint.NewLocalStore()
+	assert.NoError(t, err)
+	termStore, err := termination.NewLocalStore()
+	assert.NoError(t, err)
+	taskStore, err := task.NewLocalStore()
+	assert.NoError(t, err)
+	placer := placement.NewPlacer(epStore, termStore, taskStore, nil)
+	conn := serve(t, NewService(subStore, metaStore, sessionStore, nil, placer, time.Second, 0, 0, watch.PolicyBlock))
+	client := subapi.NewE2SubscriptionServiceClient(conn)
+
+	sub := &subapi.Subscription{
+		AppID: "foo", Details: &subapi.SubscriptionDetails{E2NodeID: "bar", ServiceModel: subapi.ServiceModel{
+			Name:    "sm1",
+			Version: "v1",
+		}},
+	}
+
+	// Verify the condition preventing the subscription from being placed is returned
+	var header grpcmd.MD
+	ctx := grpcmd.AppendToOutgoingContext(context.Background(), DryRunKey, "true")
+	res, err := client.AddSubscription(ctx, &subapi.AddSubscriptionRequest{
+		Subscription: sub,
+	}, grpc.Header(&header))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, res.Subscription.ID)
+	assert.Equal(t, []string{string(metadata.ConditionUnschedulable)}, header.Get(ConditionKey))
+	assert.Empty(t, header.Get(EndpointKey))
+
+	assert.NoError(t, epStore.Create(context.Background(), &epapi.TerminationEndpoint{ID: "e2t-1", IP: "127.0.0.1", Port: 5150}))
+	assert.NoError(t, termStore.Create(context.Background(), &termination.Termination{ID: "e2t-1", Zone: "zone-a"}))
+
+	// Verify the endpoint the subscription would be placed on is returned
+	ctx = grpcmd.AppendToOutgoingContext(context.Background(), DryRunKey, "true", ZoneKey, "zone-a")
+	_, err = client.AddSubscription(ctx, &subapi.AddSubscriptionRequest{
+		Subscription: sub,
+	}, grpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e2t-1"}, header.Get(EndpointKey))
+	assert.Equal(t, []string{"zone-a"}, header.Get(ZoneKey))
+	assert.Empty(t, header.Get(ConditionKey))
+
+	// Verify the request is validated
+	ctx = grpcmd.AppendToOutgoingContext(context.Background(), DryRunKey, "true", ZoneKey, "zone-a", LabelsKey, "tier")
+	_, err = client.AddSubscription(ctx, &subapi.AddSubscriptionRequest{
+		Subscription: sub,
+	})
+	assert.Error(t, err)
+	ctx = grpcmd.AppendToOutgoingContext(context.Background(), DryRunKey, "maybe")
+	_, err = client.AddSubscription(ctx, &subapi.AddSubscriptionRequest{
+		Subscription: sub,
+	})
+	assert.Error(t, err)
+
+	// Verify nothing was written
+	subs, err := subStore.List(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, subs)
+	metas, err := metaStore.List(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, metas)
+	tasks, err := taskStore.List(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, tasks)
+}
+
 func TestBadRemove(t *testing.T) {
 	conn := createServerConnection(t)
 	client := subapi.NewE2SubscriptionServiceClient(conn)
@@ -174,3 +310,214 @@ func TestBadRemove(t *testing.T) {
 	_, err := client.RemoveSubscription(context.Background(), &subapi.RemoveSubscriptionRequest{})
 	assert.Error(t, err)
 }
+
+func TestScheduledAdd(t *testing.T) {
+	conn := createServerConnection(t)
+	client := subapi.NewE2SubscriptionServiceClient(conn)
+
+	sub := &subapi.Subscription{
+		ID: "1", AppID: "foo", Details: &subapi.SubscriptionDetails{E2NodeID: "bar", ServiceModel: subapi.ServiceModel{
+			Name:    "sm1",
+			Version: "v1",
+		}},
+	}
+
+	// Verify an invalid schedule is rejected
+	ctx := grpcmd.AppendToOutgoingContext(context.Background(),
+		ScheduleStartKey, "2020-12-01T09:00:00Z",
+		ScheduleEndKey, "2020-12-01T08:00:00Z")
+	_, err := client.AddSubscription(ctx, &subapi.AddSubscriptionRequest{
+		Subscription: sub,
+	})
+	assert.Error(t, err)
+
+	ctx = grpcmd.AppendToOutgoingContext(context.Background(),
+		ScheduleStartKey, "2020-12-01T08:00:00Z",
+		ScheduleEndKey, "2020-12-01T09:00:00Z",
+		SchedulePeriodKey, "24h")
+	_, err = client.AddSubscription(ctx, &subapi.AddSubscriptionRequest{
+		Subscription: sub,
+	})
+	assert.NoError(t, err)
+
+	// Verify the schedule is returned in the response header
+	var header grpcmd.MD
+	_, err = client.GetSubscription(context.Background(), &subapi.GetSubscriptionRequest{
+		ID: "1",
+	}, grpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2020-12-01T08:00:00Z"}, header.Get(ScheduleStartKey))
+	assert.Equal(t, []string{"2020-12-01T09:00:00Z"}, header.Get(ScheduleEndKey))
+	assert.Equal(t, []string{"24h0m0s"}, header.Get(SchedulePeriodKey))
+}
+
+func TestOwnedAdd(t *testing.T) {
+	conn := createServerConnection(t)
+	client := subapi.NewE2SubscriptionServiceClient(conn)
+
+	sub := &subapi.Subscription{
+		ID: "1", AppID: "foo", Details: &subapi.SubscriptionDetails{E2NodeID: "bar", ServiceModel: subapi.ServiceModel{
+			Name:    "sm1",
+			Version: "v1",
+		}},
+	}
+
+	// Verify an invalid owner is rejected
+	ctx := grpcmd.AppendToOutgoingContext(context.Background(), OwnerKey, "default/")
+	_, err := client.AddSubscription(ctx, &subapi.AddSubscriptionRequest{
+		Subscription: sub,
+	})
+	assert.Error(t, err)
+
+	ctx = grpcmd.AppendToOutgoingContext(context.Background(), OwnerKey, "default/xapp-1")
+	_, err = client.AddSubscription(ctx, &subapi.AddSubscriptionRequest{
+		Subscription: sub,
+	})
+	assert.NoError(t, err)
+
+	// Verify the owner is returned in the response header
+	var header grpcmd.MD
+	_, err = client.GetSubscription(context.Background(), &subapi.GetSubscriptionRequest{
+		ID: "1",
+	}, grpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"default/xapp-1"}, header.Get(OwnerKey))
+
+	// Verify a retried request is only idempotent if it requests the same owner
+	_, err = client.AddSubscription(ctx, &subapi.AddSubscriptionRequest{
+		Subscription: sub,
+	})
+	assert.NoError(t, err)
+	_, err = client.AddSubscription(grpcmd.AppendToOutgoingContext(context.Background(), OwnerKey, "default/xapp-2"), &subapi.AddSubscriptionRequest{
+		Subscription: sub,
+	})
+	assert.True(t, errors.IsConflict(errors.FromGRPC(err)))
+	_, err = client.AddSubscription(context.Background(), &subapi.AddSubscriptionRequest{
+		Subscription: sub,
+	})
+	assert.True(t, errors.IsConflict(errors.FromGRPC(err)))
+}
+
+func TestSessionAdd(t *testing.T) {
+	conn := createServerConnection(t)
+	client := subapi.NewE2SubscriptionServiceClient(conn)
+
+	sub := &subapi.Subscription{
+		ID: "1", AppID: "foo", Details: &subapi.SubscriptionDetails{E2NodeID: "bar", ServiceModel: subapi.ServiceModel{
+			Name:    "sm1",
+			Version: "v1",
+		}},
+	}
+
+	// Verify an unknown session is rejected
+	ctx := grpcmd.AppendToOutgoingContext(context.Background(), SessionKey, "unknown")
+	_, err := client.AddSubscription(ctx, &subapi.AddSubscriptionRequest{
+		Subscription: sub,
+	})
+	assert.Error(t, err)
+
+	// Open a new session and verify the session token is returned in the response header
+	watchCtx, cancel := context.WithCancel(grpcmd.AppendToOutgoingContext(context.Background(), SessionKey, ""))
+	defer cancel()
+	stream, err := client.WatchSubscriptions(watchCtx, &subapi.WatchSubscriptionsRequest{})
+	assert.NoError(t, err)
+	header, err := stream.Header()
+	assert.NoError(t, err)
+	tokens := header.Get(SessionKey)
+	assert.Len(t, tokens, 1)
+	assert.NotEqual(t, "", tokens[0])
+
+	// Add a subscription scoped to the session
+	ctx = grpcmd.AppendToOutgoingContext(context.Background(), SessionKey, tokens[0])
+	_, err = client.AddSubscription(ctx, &subapi.AddSubscriptionRequest{
+		Subscription: sub,
+	})
+	assert.NoError(t, err)
+
+	// Reclaim the session from a new stream
+	cancel()
+	watchCtx, cancel = context.WithCancel(grpcmd.AppendToOutgoingContext(context.Background(), SessionKey, tokens[0]))
+	defer cancel()
+	stream, err = client.WatchSubscriptions(watchCtx, &subapi.WatchSubscriptionsRequest{})
+	assert.NoError(t, err)
+	header, err = stream.Header()
+	assert.NoError(t, err)
+	assert.Equal(t, tokens, header.Get(SessionKey))
+}
+
+func TestSessionExpiry(t *testing.T) {
+	subStore, err := store.NewLocalStore()
+	assert.NoError(t, err)
+	metaStore, err := metadata.NewLocalStore()
+	assert.NoError(t, err)
+	sessionStore, err := session.NewLocalStore()
+	assert.NoError(t, err)
+	conn := serve(t, NewService(subStore, metaStore, sessionStore, nil, nil, 10*time.Millisecond, 200*time.Millisecond, 0, watch.PolicyBlock))
+	client := subapi.NewE2SubscriptionServiceClient(conn)
+
+	// Open a session on a stream that stays quiet, e.g. as on a half-open connection
+	quietCtx, quietCancel := context.WithCancel(grpcmd.AppendToOutgoingContext(context.Background(), SessionKey, ""))
+	defer quietCancel()
+	quiet, err := client.WatchSubscriptions(quietCtx, &subapi.WatchSubscriptionsRequest{Noreplay: true})
+	assert.NoError(t, err)
+	header, err := quiet.Header()
+	assert.NoError(t, err)
+	id := session.ID(header.Get(SessionKey)[0])
+	sess, err := sessionStore.Get(context.Background(), id)
+	assert.NoError(t, err)
+	assert.True(t, sess.Connected)
+
+	// Verify the quiet stream expires and its session is disconnected
+	_, err = quiet.Recv()
+	assert.True(t, errors.IsUnavailable(errors.FromGRPC(err)))
+	assert.Eventually(t, func() bool {
+		sess, err := sessionStore.Get(context.Background(), id)
+		return err == nil && !sess.Connected && !sess.Disconnected.IsZero()
+	}, time.Second, 10*time.Millisecond)
+
+	// Verify the session can be reclaimed from a new stream
+	ctx, cancel := context.WithCancel(grpcmd.AppendToOutgoingContext(context.Background(), SessionKey, string(id)))
+	defer cancel()
+	stream, err := client.WatchSubscriptions(ctx, &subapi.WatchSubscriptionsRequest{Noreplay: true})
+	assert.NoError(t, err)
+	header, err = stream.Header()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{string(id)}, header.Get(SessionKey))
+	sess, err = sessionStore.Get(context.Background(), id)
+	assert.NoError(t, err)
+	assert.True(t, sess.Connected)
+}
+
+func TestPlacementAdd(t *testing.T) {
+	conn := createServerConnection(t)
+	client := subapi.NewE2SubscriptionServiceClient(conn)
+
+	sub := &subapi.Subscription{
+		ID: "1", AppID: "foo", Details: &subapi.SubscriptionDetails{E2NodeID: "bar", ServiceModel: subapi.ServiceModel{
+			Name:    "sm1",
+			Version: "v1",
+		}},
+	}
+
+	// Verify invalid labels are rejected
+	ctx := grpcmd.AppendToOutgoingContext(context.Background(), LabelsKey, "tier")
+	_, err := client.AddSubscription(ctx, &subapi.AddSubscriptionRequest{
+		Subscription: sub,
+	})
+	assert.Error(t, err)
+
+	ctx = grpcmd.AppendToOutgoingContext(context.Background(), ZoneKey, "zone-a", LabelsKey, "tier=edge")
+	_, err = client.AddSubscription(ctx, &subapi.AddSubscriptionRequest{
+		Subscription: sub,
+	})
+	assert.NoError(t, err)
+
+	// Verify the placement constraints are returned in the response header
+	var header grpcmd.MD
+	_, err = client.GetSubscription(context.Background(), &subapi.GetSubscriptionRequest{
+		ID: "1",
+	}, grpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"zone-a"}, header.Get(ZoneKey))
+	assert.Equal(t, []string{"tier=edge"}, header.Get(LabelsKey))
+}
@@ -9,6 +9,7 @@ import (
 
 	taskapi "github.com/onosproject/onos-api/go/onos/e2sub/task"
 	store "github.com/onosproject/onos-e2sub/pkg/store/task"
+	"github.com/onosproject/onos-e2sub/pkg/store/watch"
 	"github.com/onosproject/onos-lib-go/pkg/errors"
 	"github.com/onosproject/onos-lib-go/pkg/logging"
 	"github.com/onosproject/onos-lib-go/pkg/northbound"
@@ -18,30 +19,53 @@ import (
 var log = logging.GetLogger("northbound", "task")
 
 // NewService creates a new subscription service
-func NewService(store store.Store) northbound.Service {
+// Task watches are fanned out from a single store watch, buffering bufferSize events for each
+// client and applying the given policy to clients whose buffer is full.
+func NewService(store store.Store, bufferSize int, policy watch.Policy) northbound.Service {
 	return &Service{
-		store: store,
+		store:      store,
+		bufferSize: bufferSize,
+		policy:     policy,
 	}
 }
 
 // Service is a Service implementation for subscription service.
 type Service struct {
-	store store.Store
+	store      store.Store
+	bufferSize int
+	policy     watch.Policy
 }
 
 // Register registers the Service with the gRPC server.
 func (s *Service) Register(r *grpc.Server) {
 	server := &Server{
-		store: s.store,
+		store:  s.store,
+		events: watch.NewHub("tasks", s.openWatch, s.bufferSize, s.policy),
 	}
 	taskapi.RegisterE2SubscriptionTaskServiceServer(r, server)
 }
 
+// openWatch opens the task store watch shared by all WatchSubscriptionTasks streams
+func (s *Service) openWatch(ctx context.Context, ch chan<- interface{}) error {
+	taskCh := make(chan taskapi.Event)
+	if err := s.store.Watch(ctx, taskCh); err != nil {
+		return err
+	}
+	go func() {
+		defer close(ch)
+		for event := range taskCh {
+			ch <- event
+		}
+	}()
+	return nil
+}
+
 var _ northbound.Service = &Service{}
 
 // Server implements the gRPC service for managing of subscriptions
 type Server struct {
-	store store.Store
+	store  store.Store
+	events *watch.Hub
 }
 
 func (s *Server) GetSubscriptionTask(ctx context.Context, req *taskapi.GetSubscriptionTaskRequest) (*taskapi.GetSubscriptionTaskResponse, error) {
@@ -84,37 +108,71 @@ func (s *Server) ListSubscriptionTasks(ctx context.Context, req *taskapi.ListSub
 	return res, nil
 }
 
+// WatchSubscriptionTasks streams subscription task changes
+// If events were dropped for a slow client, an event with an empty task marks the gap.
 func (s *Server) WatchSubscriptionTasks(req *taskapi.WatchSubscriptionTasksRequest, server taskapi.E2SubscriptionTaskService_WatchSubscriptionTasksServer) error {
 	log.Infof("Received WatchSubscriptionTasksRequest %+v", req)
-	var watchOpts []store.WatchOption
-	if !req.Noreplay {
-		watchOpts = append(watchOpts, store.WithReplay())
-	}
 
-	ch := make(chan taskapi.Event)
-	if err := s.store.Watch(server.Context(), ch, watchOpts...); err != nil {
+	// Subscribe before listing the existing tasks to ensure no changes are missed
+	sub, err := s.events.Subscribe(server.Context())
+	if err != nil {
 		log.Warnf("WatchSubscriptionTasksRequest %+v failed: %v", req, err)
 		return errors.Status(err).Err()
 	}
 
-	for event := range ch {
-		if req.SubscriptionID != "" && event.Task.SubscriptionID != req.SubscriptionID {
-			continue
+	if !req.Noreplay {
+		tasks, err := s.store.List(server.Context())
+		if err != nil {
+			log.Warnf("WatchSubscriptionTasksRequest %+v failed: %v", req, err)
+			return errors.Status(err).Err()
 		}
-		if req.EndpointID != "" && event.Task.EndpointID != req.EndpointID {
-			continue
+		for _, task := range tasks {
+			if err := s.send(req, server, taskapi.Event{Type: taskapi.EventType_NONE, Task: task}); err != nil {
+				return err
+			}
 		}
+	}
 
-		res := &taskapi.WatchSubscriptionTasksResponse{
-			Event: event,
+	for event := range sub.Events() {
+		if event.Gap {
+			res := &taskapi.WatchSubscriptionTasksResponse{
+				Event: taskapi.Event{Type: taskapi.EventType_NONE},
+			}
+			if err := server.Send(res); err != nil {
+				log.Warnf("WatchSubscriptionTasksResponse %+v failed: %v", res, err)
+				return err
+			}
+			continue
 		}
-
-		log.Infof("Sending WatchSubscriptionTasksResponse %+v", res)
-		if err := server.Send(res); err != nil {
-			log.Warnf("WatchSubscriptionTasksResponse %+v failed: %v", res, err)
+		if err := s.send(req, server, event.Value.(taskapi.Event)); err != nil {
 			return err
 		}
 	}
+	if err := sub.Err(); err != nil {
+		log.Warnf("WatchSubscriptionTasksRequest %+v failed: %v", req, err)
+		return errors.Status(err).Err()
+	}
+	return nil
+}
+
+// send sends a WatchSubscriptionTasks event if it matches the request's filters
+func (s *Server) send(req *taskapi.WatchSubscriptionTasksRequest, server taskapi.E2SubscriptionTaskService_WatchSubscriptionTasksServer, event taskapi.Event) error {
+	if req.SubscriptionID != "" && event.Task.SubscriptionID != req.SubscriptionID {
+		return nil
+	}
+	if req.EndpointID != "" && event.Task.EndpointID != req.EndpointID {
+		return nil
+	}
+
+	res := &taskapi.WatchSubscriptionTasksResponse{
+		Event: event,
+	}
+
+	log.Infof("Sending WatchSubscriptionTasksResponse %+v", res)
+	if err := server.Send(res); err != nil {
+		log.Warnf("WatchSubscriptionTasksResponse %+v failed: %v", res, err)
+		return err
+	}
 	return nil
 }
 
@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"context"
+
+	_map "github.com/atomix/go-client/pkg/client/map"
+	"github.com/onosproject/onos-e2sub/pkg/config"
+	"github.com/onosproject/onos-e2sub/pkg/store/watch"
+	"github.com/onosproject/onos-lib-go/pkg/atomix"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+)
+
+var log = logging.GetLogger("store", "driver")
+
+const (
+	// Atomix persists the stores in an Atomix consensus database
+	Atomix = "atomix"
+	// Embedded persists the stores in a database file embedded in onos-e2sub
+	Embedded = "embedded"
+)
+
+// GetMap returns the named map from the store backend selected by the onos-e2sub configuration
+func GetMap(ctx context.Context, name string) (_map.Map, error) {
+	ricConfig, err := config.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	switch ricConfig.Store.GetDriver() {
+	case Atomix:
+		database, err := atomix.GetDatabase(ricConfig.Atomix, ricConfig.Atomix.GetDatabase(atomix.DatabaseTypeConsensus))
+		if err != nil {
+			return nil, err
+		}
+		return database.GetMap(ctx, name)
+	case Embedded:
+		policy, err := watch.ParsePolicy(ricConfig.Watch.GetPolicy())
+		if err != nil {
+			return nil, err
+		}
+		database, err := OpenDatabase(ricConfig.Store.GetPath(), ricConfig.Watch.GetBufferSize(), policy)
+		if err != nil {
+			return nil, err
+		}
+		// The map holds its own reference to the database
+		defer database.Close()
+		return database.GetMap(ctx, name)
+	default:
+		return nil, errors.NewInvalid("unknown store driver %s", ricConfig.Store.Driver)
+	}
+}
@@ -0,0 +1,597 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	aerrors "github.com/atomix/go-client/pkg/client/errors"
+	_map "github.com/atomix/go-client/pkg/client/map"
+	"github.com/atomix/go-client/pkg/client/primitive"
+	"github.com/onosproject/onos-e2sub/pkg/store/watch"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// openTimeout is the time to wait for another process to release the database file
+	openTimeout = 5 * time.Second
+	// defaultWatchBufferSize is the default number of events queued for each watch
+	defaultWatchBufferSize = 1000
+)
+
+// databases are the embedded databases open in the process, by path
+var (
+	databases   = make(map[string]*Database)
+	databasesMu sync.Mutex
+)
+
+// OpenDatabase opens the embedded database at the given path, creating it if necessary
+// A database is shared by all its users in the process, and each must close it once done. Up to
+// watchBufferSize events are queued for each map watch, beyond which the given policy is applied.
+// If no buffer size or policy is given, 1000 events are queued and overflowing watches are ended.
+// The buffer size and policy of the first user to open the database apply.
+func OpenDatabase(path string, watchBufferSize int, watchPolicy watch.Policy) (*Database, error) {
+	databasesMu.Lock()
+	defer databasesMu.Unlock()
+	if database, ok := databases[path]; ok {
+		database.refs++
+		return database, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, errors.NewUnavailable("failed to create database %s: %s", path, err)
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, errors.NewUnavailable("failed to open database %s: %s", path, err)
+	}
+	log.Infof("Opened embedded database %s", path)
+
+	if watchBufferSize <= 0 {
+		watchBufferSize = defaultWatchBufferSize
+	}
+	if watchPolicy == "" {
+		watchPolicy = watch.PolicyDisconnect
+	}
+	database := &Database{
+		path:            path,
+		db:              db,
+		refs:            1,
+		watchers:        make(map[string]map[*watcher]bool),
+		watchBufferSize: watchBufferSize,
+		watchPolicy:     watchPolicy,
+	}
+	databases[path] = database
+	return database, nil
+}
+
+// Database is an embedded database persisting maps in a single file
+// Each map is a bucket of records holding the entries' values and versions. Mutations and the
+// events they produce are serialized so that watchers see the events in the order of the mutations.
+type Database struct {
+	path            string
+	db              *bolt.DB
+	refs            int
+	watchers        map[string]map[*watcher]bool
+	watchBufferSize int
+	watchPolicy     watch.Policy
+	mu              sync.Mutex
+}
+
+// GetMap returns the named map, creating it if necessary
+// The map holds a reference to the database until it is closed.
+func (d *Database) GetMap(ctx context.Context, name string) (_map.Map, error) {
+	err := d.db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(name))
+		return err
+	})
+	if err != nil {
+		return nil, errors.NewUnavailable("failed to create map %s: %s", name, err)
+	}
+
+	databasesMu.Lock()
+	d.refs++
+	databasesMu.Unlock()
+	return &embeddedMap{
+		database: d,
+		name:     name,
+		closed:   make(chan struct{}),
+	}, nil
+}
+
+// Close releases a reference to the database, closing it once no references remain
+func (d *Database) Close() error {
+	databasesMu.Lock()
+	defer databasesMu.Unlock()
+	d.refs--
+	if d.refs > 0 {
+		return nil
+	}
+	delete(databases, d.path)
+	log.Infof("Closing embedded database %s", d.path)
+	return d.db.Close()
+}
+
+// notify queues an event for the watchers of the named map
+// The caller must hold the database lock.
+func (d *Database) notify(name string, event *_map.Event) {
+	for w := range d.watchers[name] {
+		if w.key == "" || w.key == event.Entry.Key {
+			w.push(event)
+		}
+	}
+}
+
+// record is the persisted form of a map entry
+type record struct {
+	Version uint64    `json:"version"`
+	Value   []byte    `json:"value"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+func (r *record) entry(key string) *_map.Entry {
+	return &_map.Entry{
+		Version: _map.Version(r.Version),
+		Key:     key,
+		Value:   r.Value,
+		Created: r.Created,
+		Updated: r.Updated,
+	}
+}
+
+// embeddedMap is a map persisted in an embedded database
+// It returns the same errors as an Atomix map so that the stores behave the same on either backend.
+type embeddedMap struct {
+	database *Database
+	name     string
+	closed   chan struct{}
+	once     sync.Once
+}
+
+func (m *embeddedMap) Name() primitive.Name {
+	return primitive.Name{
+		Namespace: Embedded,
+		Database:  m.database.path,
+		Name:      m.name,
+	}
+}
+
+func (m *embeddedMap) Put(ctx context.Context, key string, value []byte, opts ..._map.PutOption) (*_map.Entry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	ifNotSet, version, err := getPutOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	m.database.mu.Lock()
+	defer m.database.mu.Unlock()
+	event := &_map.Event{}
+	err = m.update(func(bucket *bolt.Bucket) error {
+		prev, err := getRecord(bucket, key)
+		if err != nil {
+			return err
+		}
+		if prev != nil && ifNotSet {
+			return aerrors.New(aerrors.AlreadyExists, "key %s already exists", key)
+		}
+		if version != 0 {
+			// Atomix rejects a versioned put of a missing key as an existing one
+			if prev == nil {
+				return aerrors.New(aerrors.AlreadyExists, "key %s already exists", key)
+			}
+			if prev.Version != uint64(version) {
+				return aerrors.New(aerrors.Conflict, "request version %d does not match stored entry version %d", version, prev.Version)
+			}
+		}
+
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		rec := &record{
+			Version: seq,
+			Value:   value,
+			Created: now,
+			Updated: now,
+		}
+		event.Type = _map.EventInserted
+		if prev != nil {
+			rec.Created = prev.Created
+			event.Type = _map.EventUpdated
+		}
+		if err := putRecord(bucket, key, rec); err != nil {
+			return err
+		}
+		event.Entry = rec.entry(key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.database.notify(m.name, event)
+	return event.Entry, nil
+}
+
+func (m *embeddedMap) Get(ctx context.Context, key string, opts ..._map.GetOption) (*_map.Entry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	def, hasDefault, err := getDefault(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry *_map.Entry
+	err = m.view(func(bucket *bolt.Bucket) error {
+		rec, err := getRecord(bucket, key)
+		if err != nil {
+			return err
+		}
+		if rec == nil {
+			if hasDefault {
+				entry = &_map.Entry{Key: key, Value: def}
+				return nil
+			}
+			return aerrors.New(aerrors.NotFound, "key %s not found", key)
+		}
+		entry = rec.entry(key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (m *embeddedMap) Remove(ctx context.Context, key string, opts ..._map.RemoveOption) (*_map.Entry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	version, err := getRemoveOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	m.database.mu.Lock()
+	defer m.database.mu.Unlock()
+	event := &_map.Event{Type: _map.EventRemoved}
+	err = m.update(func(bucket *bolt.Bucket) error {
+		prev, err := getRecord(bucket, key)
+		if err != nil {
+			return err
+		}
+		if prev == nil {
+			return aerrors.New(aerrors.NotFound, "key %s not found", key)
+		}
+		if version != 0 && prev.Version != uint64(version) {
+			return aerrors.New(aerrors.Conflict, "request version %d does not match stored entry version %d", version, prev.Version)
+		}
+		if err := bucket.Delete([]byte(key)); err != nil {
+			return err
+		}
+		event.Entry = prev.entry(key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.database.notify(m.name, event)
+	return event.Entry, nil
+}
+
+func (m *embeddedMap) Len(ctx context.Context) (int, error) {
+	if err := checkContext(ctx); err != nil {
+		return 0, err
+	}
+
+	var n int
+	err := m.view(func(bucket *bolt.Bucket) error {
+		n = bucket.Stats().KeyN
+		return nil
+	})
+	return n, err
+}
+
+func (m *embeddedMap) Clear(ctx context.Context) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	m.database.mu.Lock()
+	defer m.database.mu.Unlock()
+	var events []*_map.Event
+	err := m.update(func(bucket *bolt.Bucket) error {
+		entries, err := getEntries(bucket)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := bucket.Delete([]byte(entry.Key)); err != nil {
+				return err
+			}
+			events = append(events, &_map.Event{Type: _map.EventRemoved, Entry: entry})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		m.database.notify(m.name, event)
+	}
+	return nil
+}
+
+func (m *embeddedMap) Entries(ctx context.Context, ch chan<- *_map.Entry) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	var entries []*_map.Entry
+	err := m.view(func(bucket *bolt.Bucket) error {
+		var err error
+		entries, err = getEntries(bucket)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		defer close(ch)
+		for _, entry := range entries {
+			select {
+			case ch <- entry:
+			case <-ctx.Done():
+				return
+			case <-m.closed:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (m *embeddedMap) Watch(ctx context.Context, ch chan<- *_map.Event, opts ..._map.WatchOption) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	replay, key, err := getWatchOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	w := &watcher{
+		name:     m.name,
+		key:      key,
+		events:   make(chan *_map.Event, m.database.watchBufferSize),
+		policy:   m.database.watchPolicy,
+		overflow: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	// Register the watcher while holding the lock so that no mutation falls between the replay and the events
+	m.database.mu.Lock()
+	if replay {
+		err := m.view(func(bucket *bolt.Bucket) error {
+			entries, err := getEntries(bucket)
+			if err != nil {
+				return err
+			}
+			for _, entry := range entries {
+				if key == "" || key == entry.Key {
+					w.replay = append(w.replay, &_map.Event{Type: _map.EventNone, Entry: entry})
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			m.database.mu.Unlock()
+			return err
+		}
+	}
+	watchers, ok := m.database.watchers[m.name]
+	if !ok {
+		watchers = make(map[*watcher]bool)
+		m.database.watchers[m.name] = watchers
+	}
+	watchers[w] = true
+	m.database.mu.Unlock()
+
+	go func() {
+		defer func() {
+			// Release a mutation blocked on the full queue before waiting for the lock it holds
+			close(w.done)
+			m.database.mu.Lock()
+			delete(m.database.watchers[m.name], w)
+			m.database.mu.Unlock()
+			close(ch)
+		}()
+		w.run(ctx, ch, m.closed)
+	}()
+	return nil
+}
+
+func (m *embeddedMap) Close(ctx context.Context) error {
+	var err error
+	m.once.Do(func() {
+		close(m.closed)
+		err = m.database.Close()
+	})
+	return err
+}
+
+func (m *embeddedMap) Delete(ctx context.Context) error {
+	m.database.mu.Lock()
+	err := m.database.db.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket([]byte(m.name))
+	})
+	m.database.mu.Unlock()
+	if err != nil && err != bolt.ErrBucketNotFound {
+		return aerrors.New(aerrors.Unavailable, "failed to delete map %s: %s", m.name, err)
+	}
+	return m.Close(ctx)
+}
+
+// update runs a read-write transaction on the map's bucket
+func (m *embeddedMap) update(f func(bucket *bolt.Bucket) error) error {
+	return toError(m.database.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(m.name))
+		if bucket == nil {
+			return aerrors.New(aerrors.NotFound, "map %s not found", m.name)
+		}
+		return f(bucket)
+	}))
+}
+
+// view runs a read-only transaction on the map's bucket
+func (m *embeddedMap) view(f func(bucket *bolt.Bucket) error) error {
+	return toError(m.database.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(m.name))
+		if bucket == nil {
+			return aerrors.New(aerrors.NotFound, "map %s not found", m.name)
+		}
+		return f(bucket)
+	}))
+}
+
+var _ _map.Map = &embeddedMap{}
+
+// watcher queues the events of a map watch so that mutations do not wait for slow consumers
+// The existing entries replayed to the watch are delivered before the queued events and do not
+// count against the queue's bound.
+type watcher struct {
+	name     string
+	key      string
+	replay   []*_map.Event
+	events   chan *_map.Event
+	policy   watch.Policy
+	overflow chan struct{}
+	once     sync.Once
+	done     chan struct{}
+}
+
+// push queues an event, applying the policy if the queue is full
+// Events carry no gap marker, so a watch cannot skip events: under the drop and disconnect policies
+// an overflowing watch is ended, and its consumer must watch again with replay to catch up. Under
+// the block policy the mutation waits for the watch to make room.
+// The caller must hold the database lock.
+func (w *watcher) push(event *_map.Event) {
+	if w.policy == watch.PolicyBlock {
+		select {
+		case w.events <- event:
+		case <-w.done:
+		}
+		return
+	}
+
+	select {
+	case w.events <- event:
+	default:
+		w.once.Do(func() {
+			log.Warnf("Ending slow %s watch", w.name)
+			close(w.overflow)
+		})
+	}
+}
+
+// run delivers the replayed and queued events to the channel until the context is canceled, the
+// map is closed or the queue overflows
+func (w *watcher) run(ctx context.Context, ch chan<- *_map.Event, closed <-chan struct{}) {
+	for _, event := range w.replay {
+		select {
+		case ch <- event:
+		case <-ctx.Done():
+			return
+		case <-closed:
+			return
+		}
+	}
+	w.replay = nil
+
+	for {
+		select {
+		case event := <-w.events:
+			select {
+			case ch <- event:
+			case <-ctx.Done():
+				return
+			case <-closed:
+				return
+			}
+		case <-w.overflow:
+			return
+		case <-ctx.Done():
+			return
+		case <-closed:
+			return
+		}
+	}
+}
+
+func getRecord(bucket *bolt.Bucket, key string) (*record, error) {
+	bytes := bucket.Get([]byte(key))
+	if bytes == nil {
+		return nil, nil
+	}
+	rec := &record{}
+	if err := json.Unmarshal(bytes, rec); err != nil {
+		return nil, aerrors.New(aerrors.Internal, "failed to decode key %s: %s", key, err)
+	}
+	return rec, nil
+}
+
+func putRecord(bucket *bolt.Bucket, key string, rec *record) error {
+	bytes, err := json.Marshal(rec)
+	if err != nil {
+		return aerrors.New(aerrors.Internal, "failed to encode key %s: %s", key, err)
+	}
+	return bucket.Put([]byte(key), bytes)
+}
+
+func getEntries(bucket *bolt.Bucket) ([]*_map.Entry, error) {
+	var entries []*_map.Entry
+	err := bucket.ForEach(func(key, _ []byte) error {
+		rec, err := getRecord(bucket, string(key))
+		if err != nil {
+			return err
+		}
+		entries = append(entries, rec.entry(string(key)))
+		return nil
+	})
+	return entries, err
+}
+
+// checkContext returns the Atomix error for a context that is already done
+func checkContext(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return aerrors.NewCanceled(ctx.Err().Error())
+	case context.DeadlineExceeded:
+		return aerrors.NewTimeout(ctx.Err().Error())
+	}
+	return nil
+}
+
+// toError returns the Atomix error for an error returned by a transaction
+func toError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*aerrors.TypedError); ok {
+		return err
+	}
+	return aerrors.NewUnavailable(err.Error())
+}
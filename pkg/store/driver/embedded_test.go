@@ -0,0 +1,265 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	aerrors "github.com/atomix/go-client/pkg/client/errors"
+	_map "github.com/atomix/go-client/pkg/client/map"
+	"github.com/onosproject/onos-e2sub/pkg/store/watch"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestMap(t *testing.T, path string, name string) _map.Map {
+	database, err := OpenDatabase(path, 0, "")
+	assert.NoError(t, err)
+	defer database.Close()
+	m, err := database.GetMap(context.Background(), name)
+	assert.NoError(t, err)
+	return m
+}
+
+func tempPath(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "e2sub")
+	assert.NoError(t, err)
+	return filepath.Join(dir, "data", "e2sub.db")
+}
+
+func nextEvent(t *testing.T, ch <-chan *_map.Event) *_map.Event {
+	select {
+	case event := <-ch:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+		return nil
+	}
+}
+
+func TestEmbeddedMap(t *testing.T) {
+	path := tempPath(t)
+	defer os.RemoveAll(filepath.Dir(filepath.Dir(path)))
+	m := newTestMap(t, path, "test")
+	defer m.Close(context.Background())
+	ctx := context.Background()
+
+	entry1, err := m.Put(ctx, "foo", []byte("bar"), IfNotSet())
+	assert.NoError(t, err)
+	assert.NotEqual(t, _map.Version(0), entry1.Version)
+
+	_, err = m.Put(ctx, "foo", []byte("baz"), IfNotSet())
+	assert.True(t, errors.IsAlreadyExists(errors.FromAtomix(err)))
+
+	// Verify conditional puts behave as they do on Atomix
+	_, err = m.Put(ctx, "foo", []byte("baz"), IfVersion(entry1.Version+1))
+	assert.True(t, errors.IsConflict(errors.FromAtomix(err)))
+	_, err = m.Put(ctx, "none", []byte("baz"), IfVersion(entry1.Version))
+	assert.True(t, errors.IsAlreadyExists(errors.FromAtomix(err)))
+
+	entry2, err := m.Put(ctx, "foo", []byte("baz"), IfVersion(entry1.Version))
+	assert.NoError(t, err)
+	assert.True(t, entry2.Version > entry1.Version)
+	assert.True(t, entry2.Created.Equal(entry1.Created))
+
+	entry, err := m.Get(ctx, "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "baz", string(entry.Value))
+	assert.Equal(t, entry2.Version, entry.Version)
+
+	_, err = m.Get(ctx, "none")
+	assert.True(t, errors.IsNotFound(errors.FromAtomix(err)))
+	entry, err = m.Get(ctx, "none", WithDefault([]byte("default")))
+	assert.NoError(t, err)
+	assert.Equal(t, "default", string(entry.Value))
+
+	n, err := m.Len(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	_, err = m.Remove(ctx, "foo", IfVersion(entry1.Version))
+	assert.True(t, errors.IsConflict(errors.FromAtomix(err)))
+	entry, err = m.Remove(ctx, "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "baz", string(entry.Value))
+	_, err = m.Remove(ctx, "foo")
+	assert.True(t, errors.IsNotFound(errors.FromAtomix(err)))
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = m.Get(canceled, "foo")
+	assert.True(t, aerrors.IsType(err, aerrors.Canceled))
+}
+
+func TestEmbeddedWatch(t *testing.T) {
+	path := tempPath(t)
+	defer os.RemoveAll(filepath.Dir(filepath.Dir(path)))
+	m1 := newTestMap(t, path, "test")
+	defer m1.Close(context.Background())
+	m2 := newTestMap(t, path, "test")
+	defer m2.Close(context.Background())
+	ctx := context.Background()
+
+	_, err := m1.Put(ctx, "foo", []byte("1"))
+	assert.NoError(t, err)
+
+	// Verify the watch replays existing entries followed by changes made through any instance of the map
+	watchCtx, cancel := context.WithCancel(context.Background())
+	ch := make(chan *_map.Event)
+	assert.NoError(t, m2.Watch(watchCtx, ch, WithReplay()))
+	filterCh := make(chan *_map.Event)
+	assert.NoError(t, m2.Watch(ctx, filterCh, WithFilter(_map.Filter{Key: "bar"})))
+
+	_, err = m1.Put(ctx, "foo", []byte("2"))
+	assert.NoError(t, err)
+	_, err = m1.Put(ctx, "bar", []byte("3"))
+	assert.NoError(t, err)
+	_, err = m1.Remove(ctx, "foo")
+	assert.NoError(t, err)
+
+	event := nextEvent(t, ch)
+	assert.Equal(t, _map.EventNone, event.Type)
+	assert.Equal(t, "1", string(event.Entry.Value))
+	event = nextEvent(t, ch)
+	assert.Equal(t, _map.EventUpdated, event.Type)
+	assert.Equal(t, "2", string(event.Entry.Value))
+	event = nextEvent(t, ch)
+	assert.Equal(t, _map.EventInserted, event.Type)
+	assert.Equal(t, "bar", event.Entry.Key)
+	event = nextEvent(t, ch)
+	assert.Equal(t, _map.EventRemoved, event.Type)
+	assert.Equal(t, "foo", event.Entry.Key)
+
+	event = nextEvent(t, filterCh)
+	assert.Equal(t, "bar", event.Entry.Key)
+
+	entryCh := make(chan *_map.Entry)
+	assert.NoError(t, m1.Entries(ctx, entryCh))
+	var keys []string
+	for entry := range entryCh {
+		keys = append(keys, entry.Key)
+	}
+	assert.Equal(t, []string{"bar"}, keys)
+
+	// Verify canceling the watch closes its channel
+	cancel()
+	for range ch {
+	}
+
+	// Verify closing the map closes its watches
+	assert.NoError(t, m2.Close(ctx))
+	for range filterCh {
+	}
+}
+
+func TestEmbeddedPersistence(t *testing.T) {
+	path := tempPath(t)
+	defer os.RemoveAll(filepath.Dir(filepath.Dir(path)))
+	ctx := context.Background()
+
+	m := newTestMap(t, path, "test")
+	entry1, err := m.Put(ctx, "foo", []byte("bar"))
+	assert.NoError(t, err)
+	assert.NoError(t, m.Close(ctx))
+
+	// Verify the entries and their versions survive reopening the database
+	m = newTestMap(t, path, "test")
+	defer m.Close(ctx)
+	entry, err := m.Get(ctx, "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", string(entry.Value))
+	assert.Equal(t, entry1.Version, entry.Version)
+
+	entry2, err := m.Put(ctx, "foo", []byte("baz"), IfVersion(entry.Version))
+	assert.NoError(t, err)
+	assert.True(t, entry2.Version > entry1.Version)
+
+	other := newTestMap(t, path, "other")
+	defer other.Close(ctx)
+	_, err = other.Get(ctx, "foo")
+	assert.True(t, errors.IsNotFound(errors.FromAtomix(err)))
+}
+
+func TestEmbeddedWatchOverflow(t *testing.T) {
+	path := tempPath(t)
+	defer os.RemoveAll(filepath.Dir(filepath.Dir(path)))
+	database, err := OpenDatabase(path, 2, watch.PolicyDisconnect)
+	assert.NoError(t, err)
+	m, err := database.GetMap(context.Background(), "test")
+	assert.NoError(t, err)
+	assert.NoError(t, database.Close())
+	defer m.Close(context.Background())
+	ctx := context.Background()
+
+	_, err = m.Put(ctx, "foo", []byte("1"))
+	assert.NoError(t, err)
+	_, err = m.Put(ctx, "bar", []byte("2"))
+	assert.NoError(t, err)
+	_, err = m.Put(ctx, "baz", []byte("3"))
+	assert.NoError(t, err)
+
+	// Verify replayed entries do not count against the queue, and an overflowing watch is ended
+	// without blocking mutations
+	ch := make(chan *_map.Event)
+	assert.NoError(t, m.Watch(ctx, ch, WithReplay()))
+	for i := 0; i < 5; i++ {
+		_, err = m.Put(ctx, "foo", []byte("4"))
+		assert.NoError(t, err)
+	}
+	n := 0
+	for range ch {
+		n++
+	}
+	assert.True(t, n >= 3 && n < 8)
+}
+
+func TestEmbeddedWatchBlock(t *testing.T) {
+	path := tempPath(t)
+	defer os.RemoveAll(filepath.Dir(filepath.Dir(path)))
+	database, err := OpenDatabase(path, 1, watch.PolicyBlock)
+	assert.NoError(t, err)
+	m, err := database.GetMap(context.Background(), "test")
+	assert.NoError(t, err)
+	assert.NoError(t, database.Close())
+	defer m.Close(context.Background())
+	ctx := context.Background()
+
+	ch := make(chan *_map.Event)
+	assert.NoError(t, m.Watch(ctx, ch))
+
+	// Verify mutations wait for the watch to make room, and no events are lost
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 5; i++ {
+			_, err := m.Put(ctx, "foo", []byte{byte(i)})
+			assert.NoError(t, err)
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		event := nextEvent(t, ch)
+		assert.Equal(t, []byte{byte(i)}, event.Entry.Value)
+	}
+	<-done
+}
+
+func TestEmbeddedOptions(t *testing.T) {
+	path := tempPath(t)
+	defer os.RemoveAll(filepath.Dir(filepath.Dir(path)))
+	m := newTestMap(t, path, "test")
+	defer m.Close(context.Background())
+
+	// Verify Atomix options, whose settings the embedded map cannot read, are rejected
+	_, err := m.Put(context.Background(), "foo", []byte("bar"), _map.IfNotSet())
+	assert.True(t, errors.IsInvalid(errors.FromAtomix(err)))
+	_, err = m.Remove(context.Background(), "foo", _map.IfVersion(1))
+	assert.True(t, errors.IsInvalid(errors.FromAtomix(err)))
+	assert.Error(t, m.Watch(context.Background(), make(chan *_map.Event), _map.WithReplay()))
+}
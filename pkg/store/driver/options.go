@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"fmt"
+
+	aerrors "github.com/atomix/go-client/pkg/client/errors"
+	_map "github.com/atomix/go-client/pkg/client/map"
+)
+
+// The Atomix map options only apply themselves to Atomix requests through unexported methods, so
+// the stores use the options below on either backend. Each embeds the equivalent Atomix option,
+// which applies it to Atomix maps, and exposes its setting to the embedded map.
+
+// IfVersion returns an option putting or removing an entry only if it has the given version
+func IfVersion(version _map.Version) VersionOption {
+	return VersionOption{
+		VersionOption: _map.IfVersion(version),
+		version:       version,
+	}
+}
+
+// VersionOption is a put and remove option conditioning the mutation on the entry's version
+type VersionOption struct {
+	_map.VersionOption
+	version _map.Version
+}
+
+// IfNotSet returns an option putting an entry only if it is not set
+func IfNotSet() _map.PutOption {
+	return notSetOption{
+		PutOption: _map.IfNotSet(),
+	}
+}
+
+// notSetOption is a put option conditioning the put on the entry not being set
+type notSetOption struct {
+	_map.PutOption
+}
+
+// WithDefault returns an option getting the given value if the entry is not set
+func WithDefault(def []byte) _map.GetOption {
+	return defaultOption{
+		GetOption: _map.WithDefault(def),
+		def:       def,
+	}
+}
+
+// defaultOption is a get option returning a default value for entries that are not set
+type defaultOption struct {
+	_map.GetOption
+	def []byte
+}
+
+// WithReplay returns an option replaying the existing entries to a watch
+func WithReplay() _map.WatchOption {
+	return replayOption{
+		WatchOption: _map.WithReplay(),
+	}
+}
+
+// replayOption is a watch option replaying the existing entries
+type replayOption struct {
+	_map.WatchOption
+}
+
+// WithFilter returns an option filtering the events of a watch
+func WithFilter(filter _map.Filter) _map.WatchOption {
+	return filterOption{
+		WatchOption: _map.WithFilter(filter),
+		filter:      filter,
+	}
+}
+
+// filterOption is a watch option filtering the events by key
+type filterOption struct {
+	_map.WatchOption
+	filter _map.Filter
+}
+
+// unsupportedOption returns the error for an Atomix option passed to the embedded map
+func unsupportedOption(opt interface{}) error {
+	return aerrors.NewInvalid(fmt.Sprintf("unsupported map option %T: use the driver options", opt))
+}
+
+func getPutOptions(opts []_map.PutOption) (ifNotSet bool, version _map.Version, err error) {
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case notSetOption:
+			ifNotSet = true
+		case VersionOption:
+			version = o.version
+		default:
+			return false, 0, unsupportedOption(opt)
+		}
+	}
+	return ifNotSet, version, nil
+}
+
+func getRemoveOptions(opts []_map.RemoveOption) (version _map.Version, err error) {
+	for _, opt := range opts {
+		o, ok := opt.(VersionOption)
+		if !ok {
+			return 0, unsupportedOption(opt)
+		}
+		version = o.version
+	}
+	return version, nil
+}
+
+func getDefault(opts []_map.GetOption) (def []byte, ok bool, err error) {
+	for _, opt := range opts {
+		o, isDefault := opt.(defaultOption)
+		if !isDefault {
+			return nil, false, unsupportedOption(opt)
+		}
+		def, ok = o.def, true
+	}
+	return def, ok, nil
+}
+
+func getWatchOptions(opts []_map.WatchOption) (replay bool, key string, err error) {
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case replayOption:
+			replay = true
+		case filterOption:
+			key = o.filter.Key
+		default:
+			return false, "", unsupportedOption(opt)
+		}
+	}
+	return replay, key, nil
+}
@@ -12,36 +12,42 @@ import (
 	"github.com/atomix/go-client/pkg/client/util/net"
 	"github.com/onosproject/onos-lib-go/pkg/errors"
 	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"google.golang.org/grpc/status"
 
 	_map "github.com/atomix/go-client/pkg/client/map"
 	"github.com/atomix/go-client/pkg/client/primitive"
 	"github.com/gogo/protobuf/proto"
 	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
-	"github.com/onosproject/onos-e2sub/pkg/config"
+	"github.com/onosproject/onos-e2sub/pkg/store/driver"
+	"github.com/onosproject/onos-e2sub/pkg/store/quarantine"
+	"github.com/onosproject/onos-e2sub/pkg/store/schema"
 	"github.com/onosproject/onos-lib-go/pkg/atomix"
 )
 
 var log = logging.GetLogger("store", "endpoint")
 
+// valueSchema is the schema of the stored termination endpoints
+var valueSchema = schema.NewSchema("TerminationEndpoint")
+
 // NewAtomixStore returns a new persistent Store
 func NewAtomixStore() (Store, error) {
-	ricConfig, err := config.GetConfig()
+	endpoints, err := driver.GetMap(context.Background(), "endpoints")
 	if err != nil {
 		return nil, err
 	}
 
-	database, err := atomix.GetDatabase(ricConfig.Atomix, ricConfig.Atomix.GetDatabase(atomix.DatabaseTypeConsensus))
-	if err != nil {
+	if _, err := schema.Migrate(context.Background(), endpoints, valueSchema); err != nil {
 		return nil, err
 	}
 
-	endpoints, err := database.GetMap(context.Background(), "endpoints")
+	quarantined, err := quarantine.NewAtomixStore()
 	if err != nil {
 		return nil, err
 	}
 
 	return &atomixStore{
-		endpoints: endpoints,
+		endpoints:  endpoints,
+		quarantine: quarantine.NewSource("endpoints", endpoints, quarantined, decodeValue),
 	}, nil
 }
 
@@ -68,8 +74,14 @@ func newLocalStore(address net.Address) (Store, error) {
 		return nil, err
 	}
 
+	quarantined, err := quarantine.NewLocalStoreAt(address)
+	if err != nil {
+		return nil, err
+	}
+
 	return &atomixStore{
-		endpoints: endpoints,
+		endpoints:  endpoints,
+		quarantine: quarantine.NewSource("endpoints", endpoints, quarantined, decodeValue),
 	}, nil
 }
 
@@ -80,6 +92,10 @@ type Store interface {
 	// Create stores an end-point in the store
 	Create(ctx context.Context, point *epapi.TerminationEndpoint) error
 
+	// Update updates an end-point in the store
+	// The update fails with a conflict if the end-point's revision does not match the stored revision.
+	Update(ctx context.Context, point *epapi.TerminationEndpoint) error
+
 	// Gets an end-point from the store
 	Get(ctx context.Context, id epapi.ID) (*epapi.TerminationEndpoint, error)
 
@@ -93,6 +109,11 @@ type Store interface {
 	Watch(ctx context.Context, ch chan<- epapi.Event, opts ...WatchOption) error
 }
 
+// EventUpdated indicates an end-point was updated
+// The onos-api endpoint EventType does not name an update event, so updates are streamed with the
+// value left unassigned between ADDED and REMOVED.
+const EventUpdated epapi.EventType = 2
+
 // WatchOption is a configuration option for Watch calls
 type WatchOption interface {
 	apply([]_map.WatchOption) []_map.WatchOption
@@ -103,7 +124,7 @@ type watchReplayOption struct {
 }
 
 func (o watchReplayOption) apply(opts []_map.WatchOption) []_map.WatchOption {
-	return append(opts, _map.WithReplay())
+	return append(opts, driver.WithReplay())
 }
 
 // WithReplay returns a WatchOption that replays past changes
@@ -113,7 +134,8 @@ func WithReplay() WatchOption {
 
 // atomixStore is the implementation of the end-point Store
 type atomixStore struct {
-	endpoints _map.Map
+	endpoints  _map.Map
+	quarantine *quarantine.Source
 }
 
 func (s *atomixStore) Create(ctx context.Context, ep *epapi.TerminationEndpoint) error {
@@ -129,7 +151,7 @@ func (s *atomixStore) Create(ctx context.Context, ep *epapi.TerminationEndpoint)
 	}
 
 	// Put the end-point in the map using an optimistic lock if this is an update
-	entry, err := s.endpoints.Put(ctx, string(ep.ID), bytes, _map.IfNotSet())
+	entry, err := s.endpoints.Put(ctx, string(ep.ID), valueSchema.Encode(bytes), driver.IfNotSet())
 	if err != nil {
 		log.Errorf("Failed to create TerminationEndpoint %+v: %s", ep, err)
 		return errors.FromAtomix(err)
@@ -138,6 +160,31 @@ func (s *atomixStore) Create(ctx context.Context, ep *epapi.TerminationEndpoint)
 	return nil
 }
 
+func (s *atomixStore) Update(ctx context.Context, ep *epapi.TerminationEndpoint) error {
+	if ep.ID == "" {
+		return errors.NewInvalid("ID cannot be empty")
+	}
+	if ep.Revision == 0 {
+		return errors.NewInvalid("object must contain a revision on update")
+	}
+
+	log.Infof("Updating TerminationEndpoint %+v", ep)
+	bytes, err := proto.Marshal(ep)
+	if err != nil {
+		log.Errorf("Failed to update TerminationEndpoint %+v: %s", ep, err)
+		return errors.NewInvalid(err.Error())
+	}
+
+	// Update the end-point in the map using an optimistic lock
+	entry, err := s.endpoints.Put(ctx, string(ep.ID), valueSchema.Encode(bytes), driver.IfVersion(_map.Version(ep.Revision)))
+	if err != nil {
+		log.Errorf("Failed to update TerminationEndpoint %+v: %s", ep, err)
+		return errors.FromAtomix(err)
+	}
+	ep.Revision = epapi.Revision(entry.Version)
+	return nil
+}
+
 func (s *atomixStore) Get(ctx context.Context, id epapi.ID) (*epapi.TerminationEndpoint, error) {
 	if id == "" {
 		return nil, errors.NewInvalid("ID cannot be empty")
@@ -146,7 +193,12 @@ func (s *atomixStore) Get(ctx context.Context, id epapi.ID) (*epapi.TerminationE
 	if err != nil {
 		return nil, errors.FromAtomix(err)
 	}
-	return decodeObject(entry)
+	obj, err := decodeObject(entry)
+	if err != nil {
+		s.quarantine.Quarantine(ctx, entry, err)
+		return nil, err
+	}
+	return obj, nil
 }
 
 func (s *atomixStore) Delete(ctx context.Context, id epapi.ID) error {
@@ -165,7 +217,7 @@ func (s *atomixStore) Delete(ctx context.Context, id epapi.ID) error {
 
 func (s *atomixStore) List(ctx context.Context) ([]epapi.TerminationEndpoint, error) {
 	mapCh := make(chan *_map.Entry)
-	if err := s.endpoints.Entries(context.Background(), mapCh); err != nil {
+	if err := s.endpoints.Entries(ctx, mapCh); err != nil {
 		return nil, errors.FromAtomix(err)
 	}
 
@@ -173,8 +225,15 @@ func (s *atomixStore) List(ctx context.Context) ([]epapi.TerminationEndpoint, er
 	for entry := range mapCh {
 		if ep, err := decodeObject(entry); err == nil {
 			eps = append(eps, *ep)
+		} else {
+			s.quarantine.Quarantine(ctx, entry, err)
 		}
 	}
+
+	// A canceled context ends the entries stream early, leaving the list incomplete
+	if err := ctx.Err(); err != nil {
+		return nil, errors.FromGRPC(status.FromContextError(err).Err())
+	}
 	return eps, nil
 }
 
@@ -199,13 +258,22 @@ func (s *atomixStore) Watch(ctx context.Context, ch chan<- epapi.Event, opts ...
 					eventType = epapi.EventType_NONE
 				case _map.EventInserted:
 					eventType = epapi.EventType_ADDED
+				case _map.EventUpdated:
+					eventType = EventUpdated
 				case _map.EventRemoved:
 					eventType = epapi.EventType_REMOVED
 				}
-				ch <- epapi.Event{
+				// Once canceled, keep draining the watch until it closes without blocking on the consumer
+				select {
+				case ch <- epapi.Event{
 					Type:     eventType,
 					Endpoint: *ep,
+				}:
+				case <-ctx.Done():
 				}
+			} else if event.Type != _map.EventRemoved {
+				// Removed entries are already gone, including those moved to the quarantine
+				s.quarantine.Quarantine(ctx, event.Entry, err)
 			}
 		}
 	}()
@@ -215,15 +283,26 @@ func (s *atomixStore) Watch(ctx context.Context, ch chan<- epapi.Event, opts ...
 func (s *atomixStore) Close() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	_ = s.endpoints.Close(ctx)
+	_ = s.quarantine.Close()
 	defer cancel()
 	return s.endpoints.Close(ctx)
 }
 
 func decodeObject(entry *_map.Entry) (*epapi.TerminationEndpoint, error) {
 	ep := &epapi.TerminationEndpoint{}
-	if err := proto.Unmarshal(entry.Value, ep); err != nil {
+	value, err := valueSchema.Decode(entry.Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := proto.Unmarshal(value, ep); err != nil {
 		return nil, errors.NewInvalid(err.Error())
 	}
 	ep.ID = epapi.ID(entry.Key)
+	ep.Revision = epapi.Revision(entry.Version)
 	return ep, nil
 }
+
+func decodeValue(value []byte) error {
+	_, err := decodeObject(&_map.Entry{Value: value})
+	return err
+}
@@ -56,6 +56,32 @@ func TestEndPointStore(t *testing.T) {
 	event = nextEvent(t, ch)
 	assert.Equal(t, epapi.ID("ep2"), event.ID)
 
+	// Update an end-point's address
+	ep, err = store2.Get(context.TODO(), "ep1")
+	assert.NoError(t, err)
+	assert.Equal(t, ep1.Revision, ep.Revision)
+	revision := ep.Revision
+	ep.IP = "10.10.10.3"
+	err = store2.Update(context.TODO(), ep)
+	assert.NoError(t, err)
+	assert.NotEqual(t, revision, ep.Revision)
+
+	// Verify an update with a stale revision fails
+	ep1.IP = "10.10.10.4"
+	err = store1.Update(context.TODO(), ep1)
+	assert.Error(t, err)
+	assert.True(t, errors.IsConflict(err))
+
+	// Verify an update event was received
+	select {
+	case event := <-ch:
+		assert.Equal(t, EventUpdated, event.Type)
+		assert.Equal(t, epapi.IP("10.10.10.3"), event.Endpoint.IP)
+		assert.Equal(t, ep.Revision, event.Endpoint.Revision)
+	case <-time.After(5 * time.Second):
+		t.FailNow()
+	}
+
 	// List the end-points
 	eps, err := store1.List(context.TODO())
 	assert.NoError(t, err)
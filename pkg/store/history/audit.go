@@ -0,0 +1,234 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package history
+
+import (
+	"context"
+	"fmt"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	taskapi "github.com/onosproject/onos-api/go/onos/e2sub/task"
+	"github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/store/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/store/task"
+)
+
+// record appends a record of a mutation performed with the given context
+// The history is an audit trail, so failing to record a mutation does not fail the mutation. The
+// old state of an updated or deleted object is taken from the history rather than read before the
+// mutation, since a concurrent mutation may change the object between the read and the mutation.
+func record(ctx context.Context, history Store, record *Record) {
+	record.Caller = GetCaller(ctx)
+	if err := history.Append(ctx, record); err != nil {
+		log.Warnf("Failed to record %s %s %s: %s", record.Kind, record.ObjectID, record.Operation, err)
+	}
+}
+
+// NewSubscriptionStore returns a subscription store recording its mutations in the given history
+func NewSubscriptionStore(subs subscription.Store, history Store) subscription.Store {
+	return &subscriptionStore{
+		Store:   subs,
+		history: history,
+	}
+}
+
+// subscriptionStore is a subscription store recording its mutations
+type subscriptionStore struct {
+	subscription.Store
+	history Store
+}
+
+func (s *subscriptionStore) Create(ctx context.Context, sub *subapi.Subscription) error {
+	if err := s.Store.Create(ctx, sub); err != nil {
+		return err
+	}
+	record(ctx, s.history, &Record{
+		Kind:           KindSubscription,
+		ObjectID:       string(sub.ID),
+		SubscriptionID: sub.ID,
+		Operation:      OperationCreated,
+		Revision:       uint64(sub.Revision),
+		New:            getSubscriptionState(sub),
+	})
+	return nil
+}
+
+func (s *subscriptionStore) Update(ctx context.Context, sub *subapi.Subscription) error {
+	previous := uint64(sub.Revision)
+	if err := s.Store.Update(ctx, sub); err != nil {
+		return err
+	}
+	record(ctx, s.history, &Record{
+		Kind:             KindSubscription,
+		ObjectID:         string(sub.ID),
+		SubscriptionID:   sub.ID,
+		Operation:        OperationUpdated,
+		Revision:         uint64(sub.Revision),
+		PreviousRevision: previous,
+		New:              getSubscriptionState(sub),
+	})
+	return nil
+}
+
+func (s *subscriptionStore) Delete(ctx context.Context, id subapi.ID, opts ...subscription.DeleteOption) error {
+	if err := s.Store.Delete(ctx, id, opts...); err != nil {
+		return err
+	}
+	record(ctx, s.history, &Record{
+		Kind:           KindSubscription,
+		ObjectID:       string(id),
+		SubscriptionID: id,
+		Operation:      OperationDeleted,
+	})
+	return nil
+}
+
+func getSubscriptionState(sub *subapi.Subscription) *State {
+	if sub == nil {
+		return nil
+	}
+	return &State{
+		Status: sub.Lifecycle.Status.String(),
+	}
+}
+
+// NewTaskStore returns a task store recording its mutations in the given history
+func NewTaskStore(tasks task.Store, history Store) task.Store {
+	return &taskStore{
+		Store:   tasks,
+		history: history,
+	}
+}
+
+// taskStore is a task store recording its mutations
+type taskStore struct {
+	task.Store
+	history Store
+}
+
+func (s *taskStore) Create(ctx context.Context, t *taskapi.SubscriptionTask) error {
+	if err := s.Store.Create(ctx, t); err != nil {
+		return err
+	}
+	record(ctx, s.history, &Record{
+		Kind:           KindTask,
+		ObjectID:       string(t.ID),
+		SubscriptionID: t.SubscriptionID,
+		Operation:      OperationCreated,
+		Revision:       uint64(t.Revision),
+		New:            getTaskState(t),
+	})
+	return nil
+}
+
+func (s *taskStore) Update(ctx context.Context, t *taskapi.SubscriptionTask) error {
+	previous := uint64(t.Revision)
+	if err := s.Store.Update(ctx, t); err != nil {
+		return err
+	}
+	record(ctx, s.history, &Record{
+		Kind:             KindTask,
+		ObjectID:         string(t.ID),
+		SubscriptionID:   t.SubscriptionID,
+		Operation:        OperationUpdated,
+		Revision:         uint64(t.Revision),
+		PreviousRevision: previous,
+		New:              getTaskState(t),
+	})
+	return nil
+}
+
+func (s *taskStore) Delete(ctx context.Context, id taskapi.ID, opts ...task.DeleteOption) error {
+	if err := s.Store.Delete(ctx, id, opts...); err != nil {
+		return err
+	}
+	record(ctx, s.history, &Record{
+		Kind:      KindTask,
+		ObjectID:  string(id),
+		Operation: OperationDeleted,
+	})
+	return nil
+}
+
+func getTaskState(t *taskapi.SubscriptionTask) *State {
+	if t == nil {
+		return nil
+	}
+	state := &State{
+		Phase:      t.Lifecycle.Phase.String(),
+		Status:     t.Lifecycle.Status.String(),
+		EndpointID: string(t.EndpointID),
+	}
+	if t.Lifecycle.Failure != nil {
+		state.Failure = fmt.Sprintf("%s: %s", t.Lifecycle.Failure.Cause, t.Lifecycle.Failure.Message)
+	}
+	return state
+}
+
+// NewEndpointStore returns a termination endpoint store recording its mutations in the given history
+func NewEndpointStore(endpoints endpoint.Store, history Store) endpoint.Store {
+	return &endpointStore{
+		Store:   endpoints,
+		history: history,
+	}
+}
+
+// endpointStore is a termination endpoint store recording its mutations
+type endpointStore struct {
+	endpoint.Store
+	history Store
+}
+
+func (s *endpointStore) Create(ctx context.Context, ep *epapi.TerminationEndpoint) error {
+	if err := s.Store.Create(ctx, ep); err != nil {
+		return err
+	}
+	record(ctx, s.history, &Record{
+		Kind:      KindEndpoint,
+		ObjectID:  string(ep.ID),
+		Operation: OperationCreated,
+		Revision:  uint64(ep.Revision),
+		New:       getEndpointState(ep),
+	})
+	return nil
+}
+
+func (s *endpointStore) Update(ctx context.Context, ep *epapi.TerminationEndpoint) error {
+	previous := uint64(ep.Revision)
+	if err := s.Store.Update(ctx, ep); err != nil {
+		return err
+	}
+	record(ctx, s.history, &Record{
+		Kind:             KindEndpoint,
+		ObjectID:         string(ep.ID),
+		Operation:        OperationUpdated,
+		Revision:         uint64(ep.Revision),
+		PreviousRevision: previous,
+		New:              getEndpointState(ep),
+	})
+	return nil
+}
+
+func (s *endpointStore) Delete(ctx context.Context, id epapi.ID) error {
+	if err := s.Store.Delete(ctx, id); err != nil {
+		return err
+	}
+	record(ctx, s.history, &Record{
+		Kind:      KindEndpoint,
+		ObjectID:  string(id),
+		Operation: OperationDeleted,
+	})
+	return nil
+}
+
+func getEndpointState(ep *epapi.TerminationEndpoint) *State {
+	if ep == nil {
+		return nil
+	}
+	return &State{
+		Address: fmt.Sprintf("%s:%d", ep.IP, ep.Port),
+	}
+}
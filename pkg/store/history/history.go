@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package history
+
+import (
+	"context"
+	"time"
+
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// Kind is the kind of object a record applies to
+type Kind string
+
+const (
+	// KindSubscription is the kind of subscription records
+	KindSubscription Kind = "Subscription"
+	// KindTask is the kind of subscription task records
+	KindTask Kind = "SubscriptionTask"
+	// KindEndpoint is the kind of termination endpoint records
+	KindEndpoint Kind = "TerminationEndpoint"
+)
+
+// Operation is a mutation of an object
+type Operation string
+
+const (
+	// OperationCreated indicates the object was created
+	OperationCreated Operation = "Created"
+	// OperationUpdated indicates the object was updated
+	OperationUpdated Operation = "Updated"
+	// OperationDeleted indicates the object was deleted
+	OperationDeleted Operation = "Deleted"
+)
+
+// Record is a record of a mutation of a subscription, task or endpoint
+type Record struct {
+	// ID is the identifier of the record, unique among the records of all objects
+	ID string `json:"-"`
+	// Sequence is the position of the record in the timeline of its object
+	Sequence uint64 `json:"sequence"`
+	// Kind is the kind of the mutated object
+	Kind Kind `json:"kind"`
+	// ObjectID is the identifier of the mutated object
+	ObjectID string `json:"objectId"`
+	// SubscriptionID is the identifier of the subscription the mutated object belongs to, if any
+	SubscriptionID subapi.ID `json:"subscriptionId,omitempty"`
+	// Operation is the mutation
+	Operation Operation `json:"operation"`
+	// Revision is the revision of the object after the mutation, or its last revision if deleted
+	Revision uint64 `json:"revision"`
+	// PreviousRevision is the revision of the object the update was conditioned on, if any
+	PreviousRevision uint64 `json:"previousRevision,omitempty"`
+	// Timestamp is the time at which the mutation was recorded
+	Timestamp time.Time `json:"timestamp"`
+	// Caller is the identity of the client or controller that performed the mutation
+	Caller string `json:"caller"`
+	// Old is the state of the object before the mutation
+	Old *State `json:"old,omitempty"`
+	// New is the state of the object after the mutation
+	New *State `json:"new,omitempty"`
+}
+
+// State is the lifecycle state of an object at a point in its history
+type State struct {
+	// Phase is the lifecycle phase of a task
+	Phase string `json:"phase,omitempty"`
+	// Status is the lifecycle status of a subscription or task
+	Status string `json:"status,omitempty"`
+	// Failure is the reason a task failed
+	Failure string `json:"failure,omitempty"`
+	// EndpointID is the endpoint a task is assigned to
+	EndpointID string `json:"endpointId,omitempty"`
+	// Address is the address of an endpoint
+	Address string `json:"address,omitempty"`
+}
+
+// callerKey is the context key for the caller performing store mutations
+type callerKey struct{}
+
+// WithCaller returns a context identifying the caller of the store mutations performed with it
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// GetCaller returns the identity of the caller performing a mutation with the given context
+// gRPC clients are identified by the common name of their certificate, if any, and their address.
+// Other callers are identified by WithCaller, defaulting to onos-e2sub itself.
+func GetCaller(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.PeerCertificates) > 0 {
+			return tlsInfo.State.PeerCertificates[0].Subject.CommonName + "@" + p.Addr.String()
+		}
+		return p.Addr.String()
+	}
+	if caller, ok := ctx.Value(callerKey{}).(string); ok {
+		return caller
+	}
+	return "onos-e2sub"
+}
+
+// StartPruning starts removing the records older than maxAge from the store at the given interval
+func StartPruning(store Store, maxAge, interval time.Duration) {
+	if maxAge == 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			if _, err := store.Prune(ctx, time.Now().Add(-maxAge)); err != nil {
+				log.Warnf("Failed to prune history: %s", err)
+			}
+			cancel()
+		}
+	}()
+}
@@ -0,0 +1,267 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	_map "github.com/atomix/go-client/pkg/client/map"
+	"github.com/atomix/go-client/pkg/client/primitive"
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	taskapi "github.com/onosproject/onos-api/go/onos/e2sub/task"
+	"github.com/onosproject/onos-e2sub/pkg/store/schema"
+	"github.com/onosproject/onos-e2sub/pkg/store/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/store/task"
+	"github.com/onosproject/onos-lib-go/pkg/atomix"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryStore(t *testing.T) {
+	_, address := atomix.StartLocalNode()
+
+	store, err := newLocalStore(address)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	err = store.Append(context.TODO(), &Record{})
+	assert.True(t, errors.IsInvalid(err))
+
+	err = store.Append(context.TODO(), &Record{
+		Kind:      KindSubscription,
+		ObjectID:  "sub-1",
+		Operation: OperationCreated,
+	})
+	assert.NoError(t, err)
+
+	records, err := store.List(context.TODO())
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "sub-1", records[0].ObjectID)
+	assert.NotEmpty(t, records[0].ID)
+	assert.False(t, records[0].Timestamp.IsZero())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = store.List(ctx)
+	assert.Error(t, err)
+}
+
+func TestTimeline(t *testing.T) {
+	historyStore, err := NewLocalStore()
+	assert.NoError(t, err)
+	defer historyStore.Close()
+
+	subStore, err := subscription.NewLocalStore()
+	assert.NoError(t, err)
+	defer subStore.Close()
+	subStore = NewSubscriptionStore(subStore, historyStore)
+
+	taskStore, err := task.NewLocalStore()
+	assert.NoError(t, err)
+	defer taskStore.Close()
+	taskStore = NewTaskStore(taskStore, historyStore)
+
+	ctx := WithCaller(context.Background(), "test")
+
+	sub := &subapi.Subscription{ID: "sub-1"}
+	assert.NoError(t, subStore.Create(ctx, sub))
+
+	tsk := &taskapi.SubscriptionTask{
+		ID:             "task-1",
+		SubscriptionID: "sub-1",
+		EndpointID:     "e2t-1",
+	}
+	assert.NoError(t, taskStore.Create(ctx, tsk))
+	tsk.Lifecycle.Status = taskapi.Status_FAILED
+	tsk.Lifecycle.Failure = &taskapi.Failure{Cause: taskapi.Cause_CAUSE_MISC_UNSPECIFIED, Message: "timeout"}
+	assert.NoError(t, taskStore.Update(ctx, tsk))
+	assert.NoError(t, taskStore.Delete(ctx, tsk.ID))
+
+	sub.Lifecycle.Status = subapi.Status_PENDING_DELETE
+	assert.NoError(t, subStore.Update(context.Background(), sub))
+
+	// A failed mutation is not recorded
+	assert.Error(t, subStore.Create(ctx, &subapi.Subscription{ID: "sub-1"}))
+
+	timeline, err := historyStore.GetTimeline(context.TODO(), "sub-1")
+	assert.NoError(t, err)
+	assert.Len(t, timeline, 5)
+
+	assert.Equal(t, KindSubscription, timeline[0].Kind)
+	assert.Equal(t, OperationCreated, timeline[0].Operation)
+	assert.Equal(t, "test", timeline[0].Caller)
+	assert.Nil(t, timeline[0].Old)
+	assert.Equal(t, subapi.Status_ACTIVE.String(), timeline[0].New.Status)
+
+	assert.Equal(t, KindTask, timeline[1].Kind)
+	assert.Equal(t, OperationCreated, timeline[1].Operation)
+	assert.Equal(t, "e2t-1", timeline[1].New.EndpointID)
+
+	assert.Equal(t, OperationUpdated, timeline[2].Operation)
+	assert.Equal(t, taskapi.Status_PENDING.String(), timeline[2].Old.Status)
+	assert.Equal(t, taskapi.Status_FAILED.String(), timeline[2].New.Status)
+	assert.Contains(t, timeline[2].New.Failure, "timeout")
+
+	assert.Equal(t, OperationDeleted, timeline[3].Operation)
+	assert.Equal(t, "task-1", timeline[3].ObjectID)
+	assert.NotNil(t, timeline[3].Old)
+	assert.Nil(t, timeline[3].New)
+
+	assert.Equal(t, KindSubscription, timeline[4].Kind)
+	assert.Equal(t, OperationUpdated, timeline[4].Operation)
+	assert.Equal(t, "onos-e2sub", timeline[4].Caller)
+	assert.Equal(t, subapi.Status_PENDING_DELETE.String(), timeline[4].New.Status)
+
+	timeline, err = historyStore.GetTimeline(context.TODO(), "sub-2")
+	assert.NoError(t, err)
+	assert.Empty(t, timeline)
+}
+
+func TestConcurrentAppend(t *testing.T) {
+	store, err := NewLocalStore()
+	assert.NoError(t, err)
+	defer store.Close()
+
+	// Records of the same object with the same timestamp are all retained
+	timestamp := time.Now()
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, store.Append(context.TODO(), &Record{
+				Kind:           KindSubscription,
+				ObjectID:       "sub-1",
+				SubscriptionID: "sub-1",
+				Operation:      OperationUpdated,
+				Timestamp:      timestamp,
+			}))
+		}()
+	}
+	wg.Wait()
+
+	timeline, err := store.GetTimeline(context.TODO(), "sub-1")
+	assert.NoError(t, err)
+	assert.Len(t, timeline, 10)
+	ids := make(map[string]bool)
+	for _, record := range timeline {
+		ids[record.ID] = true
+	}
+	assert.Len(t, ids, 10)
+}
+
+func TestOldState(t *testing.T) {
+	store, err := NewLocalStore()
+	assert.NoError(t, err)
+	defer store.Close()
+
+	ctx := context.TODO()
+	assert.NoError(t, store.Append(ctx, &Record{Kind: KindEndpoint, ObjectID: "e2t-1", Operation: OperationCreated,
+		Revision: 1, New: &State{Address: "10.0.0.1:5150"}}))
+	assert.NoError(t, store.Append(ctx, &Record{Kind: KindEndpoint, ObjectID: "e2t-1", Operation: OperationUpdated,
+		Revision: 2, PreviousRevision: 1, New: &State{Address: "10.0.0.2:5150"}}))
+
+	// The old state is the state of the revision the update was conditioned on
+	record := &Record{Kind: KindEndpoint, ObjectID: "e2t-1", Operation: OperationUpdated,
+		Revision: 3, PreviousRevision: 1, New: &State{Address: "10.0.0.3:5150"}}
+	assert.NoError(t, store.Append(ctx, record))
+	assert.Equal(t, "10.0.0.1:5150", record.Old.Address)
+	assert.Equal(t, uint64(3), record.Sequence)
+	assert.Equal(t, "TerminationEndpoint/e2t-1/3", record.ID)
+
+	// A deletion takes its old state and revision from the last record
+	record = &Record{Kind: KindEndpoint, ObjectID: "e2t-1", Operation: OperationDeleted}
+	assert.NoError(t, store.Append(ctx, record))
+	assert.Equal(t, "10.0.0.3:5150", record.Old.Address)
+	assert.Equal(t, uint64(3), record.Revision)
+}
+
+func TestRetention(t *testing.T) {
+	_, address := atomix.StartLocalNode()
+
+	store, err := newLocalStore(address)
+	assert.NoError(t, err)
+	defer store.Close()
+	store.(*atomixStore).retention = Retention{MaxRecords: 3}
+
+	ctx := context.TODO()
+	start := time.Now().Add(-time.Hour)
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, store.Append(ctx, &Record{
+			Kind:           KindSubscription,
+			ObjectID:       "sub-1",
+			SubscriptionID: "sub-1",
+			Operation:      OperationUpdated,
+			Timestamp:      start.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+	assert.NoError(t, store.Append(ctx, &Record{
+		Kind:      KindEndpoint,
+		ObjectID:  "e2t-1",
+		Operation: OperationCreated,
+		Timestamp: start,
+	}))
+
+	// Only the last records of each object are retained
+	timeline, err := store.GetTimeline(ctx, "sub-1")
+	assert.NoError(t, err)
+	assert.Len(t, timeline, 3)
+	assert.Equal(t, uint64(3), timeline[0].Sequence)
+	assert.Equal(t, uint64(5), timeline[2].Sequence)
+
+	// Pruning removes the old records, and the timelines left empty
+	pruned, err := store.Prune(ctx, start.Add(4*time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, pruned)
+	records, err := store.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, uint64(5), records[0].Sequence)
+}
+
+func TestMergeRecords(t *testing.T) {
+	_, address := atomix.StartLocalNode()
+
+	// Write records under their own keys as version 1 of the schema did
+	session, err := primitive.NewSession(context.TODO(), primitive.Partition{ID: 1, Address: address})
+	assert.NoError(t, err)
+	records, err := _map.New(context.TODO(), primitive.Name{Namespace: "local", Name: "history"}, []*primitive.Session{session})
+	assert.NoError(t, err)
+	v1 := schema.NewSchema("HistoryRecord")
+	timestamp := time.Now()
+	for i, operation := range []Operation{OperationCreated, OperationUpdated} {
+		bytes, err := json.Marshal(&Record{
+			Kind:           KindSubscription,
+			ObjectID:       "sub-1",
+			SubscriptionID: "sub-1",
+			Operation:      operation,
+			Timestamp:      timestamp.Add(time.Duration(i)),
+		})
+		assert.NoError(t, err)
+		_, err = records.Put(context.TODO(), fmt.Sprintf("Subscription/sub-1/%d", timestamp.Add(time.Duration(i)).UnixNano()), v1.Encode(bytes))
+		assert.NoError(t, err)
+	}
+
+	store, err := newLocalStore(address)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	timeline, err := store.GetTimeline(context.TODO(), "sub-1")
+	assert.NoError(t, err)
+	assert.Len(t, timeline, 2)
+	assert.Equal(t, OperationCreated, timeline[0].Operation)
+	assert.Equal(t, OperationUpdated, timeline[1].Operation)
+	assert.Equal(t, uint64(2), timeline[1].Sequence)
+
+	n, err := records.Len(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
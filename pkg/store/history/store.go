@@ -0,0 +1,510 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/atomix/go-client/pkg/client/util/net"
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"google.golang.org/grpc/status"
+
+	_map "github.com/atomix/go-client/pkg/client/map"
+	"github.com/atomix/go-client/pkg/client/primitive"
+	"github.com/onosproject/onos-e2sub/pkg/store/driver"
+	"github.com/onosproject/onos-e2sub/pkg/store/quarantine"
+	"github.com/onosproject/onos-e2sub/pkg/store/schema"
+	"github.com/onosproject/onos-lib-go/pkg/atomix"
+)
+
+var log = logging.GetLogger("store", "history")
+
+// timelinePrefix prefixes the keys of the timelines in the history map
+const timelinePrefix = "timeline/"
+
+// valueSchema is the schema of the stored timelines
+// Version 1 stored each record under its own key; migrateRecord wraps such a record in a timeline
+// which is then merged into the timeline of its object by mergeRecords.
+var valueSchema = schema.NewSchema("HistoryTimeline", migrateRecord)
+
+// Retention bounds the records retained in the history
+type Retention struct {
+	// MaxRecords is the maximum number of records retained for each object; zero retains all records
+	MaxRecords int
+	// MaxAge is the age after which records are pruned; zero retains records indefinitely
+	MaxAge time.Duration
+}
+
+// NewAtomixStore returns a new persistent Store retaining records according to the given retention
+func NewAtomixStore(retention Retention) (Store, error) {
+	records, err := driver.GetMap(context.Background(), "history")
+	if err != nil {
+		return nil, err
+	}
+
+	quarantined, err := quarantine.NewAtomixStore()
+	if err != nil {
+		return nil, err
+	}
+	return newStore(records, quarantined, retention)
+}
+
+// NewLocalStore returns a new local history store
+func NewLocalStore() (Store, error) {
+	_, address := atomix.StartLocalNode()
+	return newLocalStore(address)
+}
+
+// newLocalStore creates a new local history store
+func newLocalStore(address net.Address) (Store, error) {
+	name := primitive.Name{
+		Namespace: "local",
+		Name:      "history",
+	}
+
+	session, err := primitive.NewSession(context.TODO(), primitive.Partition{ID: 1, Address: address})
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := _map.New(context.Background(), name, []*primitive.Session{session})
+	if err != nil {
+		return nil, err
+	}
+
+	quarantined, err := quarantine.NewLocalStoreAt(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStore(records, quarantined, Retention{})
+}
+
+// newStore creates a history store in the given map, upgrading the records written with older
+// versions of the schema
+func newStore(records _map.Map, quarantined quarantine.Store, retention Retention) (Store, error) {
+	if _, err := schema.Migrate(context.Background(), records, valueSchema); err != nil {
+		return nil, err
+	}
+
+	store := &atomixStore{
+		records:    records,
+		retention:  retention,
+		quarantine: quarantine.NewSource("history", records, quarantined, decodeValue),
+	}
+	if err := store.mergeRecords(context.Background()); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Store is an append-only store of the mutations of subscriptions, tasks and endpoints
+// The records of each object are stored together as the object's timeline.
+type Store interface {
+	io.Closer
+
+	// Append appends a record to the timeline of its object
+	// A record of an update or deletion without an old state takes it from the object's record of
+	// the revision the mutation was conditioned on, or from its last record if none is given.
+	Append(ctx context.Context, record *Record) error
+
+	// GetTimeline returns the records of a subscription and its tasks in the order they were recorded
+	GetTimeline(ctx context.Context, id subapi.ID) ([]Record, error)
+
+	// List lists the records in the store
+	List(ctx context.Context) ([]Record, error)
+
+	// Prune removes the records recorded before the given time, returning the number of records removed
+	Prune(ctx context.Context, before time.Time) (int, error)
+}
+
+// timeline is the stored history of an object
+type timeline struct {
+	// Kind is the kind of the object
+	Kind Kind `json:"kind"`
+	// ObjectID is the identifier of the object
+	ObjectID string `json:"objectId"`
+	// SubscriptionID is the identifier of the subscription the object belongs to, if any
+	SubscriptionID subapi.ID `json:"subscriptionId,omitempty"`
+	// Sequence is the sequence number of the last record appended to the timeline
+	Sequence uint64 `json:"sequence"`
+	// Records are the retained records of the object in the order they were appended
+	Records []Record `json:"records"`
+	// Tasks are the identifiers of the tasks of a subscription whose timelines are part of its own
+	Tasks []string `json:"tasks,omitempty"`
+}
+
+// find returns the last record of the given revision, or the last record if the revision is zero
+func (t *timeline) find(revision uint64) *Record {
+	for i := len(t.Records) - 1; i >= 0; i-- {
+		if revision == 0 || t.Records[i].Revision == revision {
+			return &t.Records[i]
+		}
+	}
+	return nil
+}
+
+// prune removes the records recorded before the given time, returning the number of records removed
+func (t *timeline) prune(before time.Time) int {
+	i := 0
+	for i < len(t.Records) && t.Records[i].Timestamp.Before(before) {
+		i++
+	}
+	t.Records = t.Records[i:]
+	return i
+}
+
+// timelineKey returns the key of the timeline of the given object
+func timelineKey(kind Kind, objectID string) string {
+	return fmt.Sprintf("%s%s/%s", timelinePrefix, kind, objectID)
+}
+
+// atomixStore is the implementation of the history Store
+type atomixStore struct {
+	records    _map.Map
+	retention  Retention
+	quarantine *quarantine.Source
+}
+
+func (s *atomixStore) Append(ctx context.Context, record *Record) error {
+	if record.ObjectID == "" {
+		return errors.NewInvalid("object ID cannot be empty")
+	}
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now()
+	}
+
+	log.Debugf("Appending Record %+v", record)
+	appended := *record
+	created, err := s.update(ctx, record.Kind, record.ObjectID, func(t *timeline) bool {
+		appended = *record
+		s.append(t, &appended)
+		return true
+	})
+	if err != nil {
+		log.Errorf("Failed to append Record %+v: %s", record, err)
+		return err
+	}
+	*record = appended
+
+	// The timeline of a new task is linked to the timeline of its subscription
+	if created && record.Kind == KindTask && record.SubscriptionID != "" {
+		_, err := s.update(ctx, KindSubscription, string(record.SubscriptionID), func(t *timeline) bool {
+			for _, id := range t.Tasks {
+				if id == record.ObjectID {
+					return false
+				}
+			}
+			t.Tasks = append(t.Tasks, record.ObjectID)
+			return true
+		})
+		if err != nil {
+			log.Errorf("Failed to link SubscriptionTask %s timeline to Subscription %s: %s", record.ObjectID, record.SubscriptionID, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// append appends a record to a timeline, completing the record from the object's earlier records
+// and applying the retention
+func (s *atomixStore) append(t *timeline, record *Record) {
+	if record.SubscriptionID == "" {
+		record.SubscriptionID = t.SubscriptionID
+	} else if t.SubscriptionID == "" {
+		t.SubscriptionID = record.SubscriptionID
+	}
+	if record.Operation != OperationCreated {
+		if previous := t.find(record.PreviousRevision); previous != nil {
+			if record.Old == nil {
+				record.Old = previous.New
+			}
+			if record.Operation == OperationDeleted && record.Revision == 0 {
+				record.Revision = previous.Revision
+			}
+		}
+	}
+
+	t.Sequence++
+	record.Sequence = t.Sequence
+	record.ID = getRecordID(record)
+	t.Records = append(t.Records, *record)
+
+	if s.retention.MaxAge > 0 {
+		t.prune(record.Timestamp.Add(-s.retention.MaxAge))
+	}
+	if s.retention.MaxRecords > 0 && len(t.Records) > s.retention.MaxRecords {
+		t.Records = t.Records[len(t.Records)-s.retention.MaxRecords:]
+	}
+}
+
+// update applies a mutation to the timeline of an object, creating the timeline if necessary
+// The timeline is written only if it has not changed since it was read, retrying the mutation on
+// conflicts. The mutation returns false if the timeline need not be written. update returns
+// whether the timeline was created.
+func (s *atomixStore) update(ctx context.Context, kind Kind, objectID string, mutate func(*timeline) bool) (bool, error) {
+	key := timelineKey(kind, objectID)
+	for {
+		entry, err := s.records.Get(ctx, key)
+		if err != nil {
+			if err = errors.FromAtomix(err); !errors.IsNotFound(err) {
+				return false, err
+			}
+		}
+
+		t := &timeline{
+			Kind:     kind,
+			ObjectID: objectID,
+		}
+		if entry != nil {
+			if t, err = decodeTimeline(entry); err != nil {
+				s.quarantine.Quarantine(ctx, entry, err)
+				return false, err
+			}
+		}
+		if !mutate(t) {
+			return false, nil
+		}
+
+		bytes, err := json.Marshal(t)
+		if err != nil {
+			return false, errors.NewInvalid(err.Error())
+		}
+
+		opt := driver.IfNotSet()
+		if entry != nil {
+			opt = driver.IfVersion(entry.Version)
+		}
+		if _, err := s.records.Put(ctx, key, valueSchema.Encode(bytes), opt); err != nil {
+			err = errors.FromAtomix(err)
+			if errors.IsConflict(err) || errors.IsAlreadyExists(err) {
+				// The timeline was appended to concurrently
+				continue
+			}
+			return false, err
+		}
+		return entry == nil, nil
+	}
+}
+
+// get gets the timeline of an object, returning nil if the object has none
+// An undecodable timeline is quarantined and treated as missing.
+func (s *atomixStore) get(ctx context.Context, kind Kind, objectID string) (*timeline, error) {
+	entry, err := s.records.Get(ctx, timelineKey(kind, objectID))
+	if err != nil {
+		if err = errors.FromAtomix(err); errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	t, err := decodeTimeline(entry)
+	if err != nil {
+		s.quarantine.Quarantine(ctx, entry, err)
+		return nil, nil
+	}
+	return t, nil
+}
+
+func (s *atomixStore) GetTimeline(ctx context.Context, id subapi.ID) ([]Record, error) {
+	records := make([]Record, 0)
+	sub, err := s.get(ctx, KindSubscription, string(id))
+	if err != nil || sub == nil {
+		return records, err
+	}
+	records = append(records, sub.Records...)
+	for _, taskID := range sub.Tasks {
+		t, err := s.get(ctx, KindTask, taskID)
+		if err != nil {
+			return nil, err
+		}
+		if t != nil {
+			records = append(records, t.Records...)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Timestamp.Before(records[j].Timestamp)
+	})
+	return records, nil
+}
+
+func (s *atomixStore) List(ctx context.Context) ([]Record, error) {
+	timelines, err := s.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]Record, 0)
+	for _, entry := range timelines {
+		records = append(records, entry.timeline.Records...)
+	}
+	return records, nil
+}
+
+// timelineEntry is a timeline with the map entry it was read from
+type timelineEntry struct {
+	*_map.Entry
+	timeline *timeline
+}
+
+// list lists the timelines in the store
+func (s *atomixStore) list(ctx context.Context) ([]timelineEntry, error) {
+	mapCh := make(chan *_map.Entry)
+	if err := s.records.Entries(ctx, mapCh); err != nil {
+		return nil, errors.FromAtomix(err)
+	}
+
+	timelines := make([]timelineEntry, 0)
+	for entry := range mapCh {
+		if t, err := decodeTimeline(entry); err == nil {
+			timelines = append(timelines, timelineEntry{Entry: entry, timeline: t})
+		} else {
+			s.quarantine.Quarantine(ctx, entry, err)
+		}
+	}
+
+	// A canceled context ends the entries stream early, leaving the list incomplete
+	if err := ctx.Err(); err != nil {
+		return nil, errors.FromGRPC(status.FromContextError(err).Err())
+	}
+	return timelines, nil
+}
+
+func (s *atomixStore) Prune(ctx context.Context, before time.Time) (int, error) {
+	timelines, err := s.list(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	pruned := 0
+	for _, entry := range timelines {
+		n := entry.timeline.prune(before)
+		if n == 0 {
+			continue
+		}
+
+		// Timelines are only written if unchanged since they were listed; a timeline appended to
+		// concurrently is pruned on the next pass
+		if len(entry.timeline.Records) == 0 {
+			_, err = s.records.Remove(ctx, entry.Key, driver.IfVersion(entry.Version))
+		} else {
+			var bytes []byte
+			if bytes, err = json.Marshal(entry.timeline); err != nil {
+				return pruned, errors.NewInvalid(err.Error())
+			}
+			_, err = s.records.Put(ctx, entry.Key, valueSchema.Encode(bytes), driver.IfVersion(entry.Version))
+		}
+		if err != nil {
+			err = errors.FromAtomix(err)
+			if errors.IsConflict(err) || errors.IsNotFound(err) {
+				continue
+			}
+			return pruned, err
+		}
+		pruned += n
+	}
+	if pruned > 0 {
+		log.Infof("Pruned %d history records recorded before %s", pruned, before)
+	}
+	return pruned, nil
+}
+
+// mergeRecords merges the records stored under their own keys by version 1 of the schema into the
+// timelines of their objects
+func (s *atomixStore) mergeRecords(ctx context.Context) error {
+	timelines, err := s.list(ctx)
+	if err != nil {
+		return err
+	}
+
+	legacy := make([]timelineEntry, 0)
+	for _, entry := range timelines {
+		if !strings.HasPrefix(entry.Key, timelinePrefix) && len(entry.timeline.Records) > 0 {
+			legacy = append(legacy, entry)
+		}
+	}
+	sort.Slice(legacy, func(i, j int) bool {
+		return legacy[i].timeline.Records[0].Timestamp.Before(legacy[j].timeline.Records[0].Timestamp)
+	})
+
+	for _, entry := range legacy {
+		record := entry.timeline.Records[0]
+		if err := s.Append(ctx, &record); err != nil {
+			return err
+		}
+		if _, err := s.records.Remove(ctx, entry.Key, driver.IfVersion(entry.Version)); err != nil {
+			err = errors.FromAtomix(err)
+			if !errors.IsConflict(err) && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	if len(legacy) > 0 {
+		log.Infof("Merged %d history records into their timelines", len(legacy))
+	}
+	return nil
+}
+
+func (s *atomixStore) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := s.records.Close(ctx)
+	_ = s.quarantine.Close()
+	return err
+}
+
+// getRecordID returns the identifier of a record appended to the timeline of its object
+func getRecordID(record *Record) string {
+	return fmt.Sprintf("%s/%s/%d", record.Kind, record.ObjectID, record.Sequence)
+}
+
+// migrateRecord upgrades a version 1 record to a version 2 timeline of the record
+func migrateRecord(value []byte) ([]byte, error) {
+	record := Record{}
+	if err := json.Unmarshal(value, &record); err != nil {
+		return nil, err
+	}
+	record.Sequence = 1
+	return json.Marshal(&timeline{
+		Kind:           record.Kind,
+		ObjectID:       record.ObjectID,
+		SubscriptionID: record.SubscriptionID,
+		Sequence:       record.Sequence,
+		Records:        []Record{record},
+	})
+}
+
+func decodeTimeline(entry *_map.Entry) (*timeline, error) {
+	t := &timeline{}
+	value, err := valueSchema.Decode(entry.Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(value, t); err != nil {
+		return nil, errors.NewInvalid(err.Error())
+	}
+	for i := range t.Records {
+		t.Records[i].ID = getRecordID(&t.Records[i])
+	}
+	return t, nil
+}
+
+func decodeValue(value []byte) error {
+	t := &timeline{}
+	value, err := valueSchema.Decode(value)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(value, t); err != nil {
+		return errors.NewInvalid(err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package metadata
+
+import (
+	"time"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// Revision is a metadata revision
+type Revision uint64
+
+// Metadata is the onos-e2sub specific state associated with a Subscription
+type Metadata struct {
+	// ID is the identifier of the Subscription the metadata belongs to
+	ID subapi.ID `json:"-"`
+	// Revision is the revision of the metadata
+	Revision Revision `json:"-"`
+	// Schedule is the activation schedule of the Subscription
+	Schedule *Schedule `json:"schedule,omitempty"`
+	// Owner is the pod that owns the Subscription
+	Owner *Owner `json:"owner,omitempty"`
+	// Session is the token of the client session the Subscription is scoped to
+	Session string `json:"session,omitempty"`
+	// Placement constrains the termination endpoints on which the Subscription is placed
+	Placement *Placement `json:"placement,omitempty"`
+	// Evicted is the cordoned termination endpoint the Subscription is being migrated off
+	Evicted epapi.ID `json:"evicted,omitempty"`
+	// Condition is the condition preventing the Subscription from being placed
+	Condition Condition `json:"condition,omitempty"`
+	// Reason is a human readable explanation of the Condition
+	Reason string `json:"reason,omitempty"`
+}
+
+// Condition is a condition preventing a Subscription from being placed
+type Condition string
+
+const (
+	// ConditionNone indicates the Subscription can be placed
+	ConditionNone Condition = ""
+	// ConditionWaitingForNode indicates the Subscription's E2 node is not connected
+	ConditionWaitingForNode Condition = "WaitingForNode"
+	// ConditionUnschedulable indicates no termination endpoint can accept the Subscription
+	ConditionUnschedulable Condition = "Unschedulable"
+)
+
+// Placement is a set of constraints on the termination endpoints on which a Subscription is placed
+type Placement struct {
+	// Zone is the zone in which the termination endpoint must be deployed
+	Zone string `json:"zone,omitempty"`
+	// Labels are labels the termination endpoint must have
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// EventType is a metadata event type
+type EventType int
+
+const (
+	// EventNone indicates a replayed metadata object
+	EventNone EventType = iota
+	// EventCreated indicates metadata was created
+	EventCreated
+	// EventUpdated indicates metadata was updated
+	EventUpdated
+	// EventRemoved indicates metadata was removed
+	EventRemoved
+)
+
+// Event is a metadata event
+type Event struct {
+	Type     EventType
+	Metadata Metadata
+}
+
+// Schedule is an activation window for a Subscription
+// A Subscription with a Schedule only has open SubscriptionTasks while the current time is
+// within the window [Start, End). If a Period is set, the window recurs every Period.
+type Schedule struct {
+	// Start is the time at which the window opens; if zero the window is open from creation
+	Start time.Time `json:"start,omitempty"`
+	// End is the time at which the window closes; if zero the window never closes
+	End time.Time `json:"end,omitempty"`
+	// Period is the recurrence period of the window
+	Period time.Duration `json:"period,omitempty"`
+}
+
+// Validate validates the schedule
+func (s *Schedule) Validate() error {
+	if !s.Start.IsZero() && !s.End.IsZero() && !s.End.After(s.Start) {
+		return errors.NewInvalid("schedule end must be after schedule start")
+	}
+	if s.Period < 0 {
+		return errors.NewInvalid("schedule period cannot be negative")
+	}
+	if s.Period > 0 {
+		if s.Start.IsZero() || s.End.IsZero() {
+			return errors.NewInvalid("recurring schedule requires a start and end")
+		}
+		if s.End.Sub(s.Start) > s.Period {
+			return errors.NewInvalid("schedule window cannot be longer than its period")
+		}
+	}
+	return nil
+}
+
+// Window returns whether the schedule is active at the given time and the time of the next
+// transition between the active and inactive states. The next transition is zero if the
+// schedule will not change state again.
+func (s *Schedule) Window(now time.Time) (bool, time.Time) {
+	if !s.Start.IsZero() && now.Before(s.Start) {
+		return false, s.Start
+	}
+
+	if s.Period == 0 {
+		if !s.End.IsZero() && !now.Before(s.End) {
+			return false, time.Time{}
+		}
+		return true, s.End
+	}
+
+	windowStart := s.Start.Add(now.Sub(s.Start) / s.Period * s.Period)
+	windowEnd := windowStart.Add(s.End.Sub(s.Start))
+	if now.Before(windowEnd) {
+		return true, windowEnd
+	}
+	return false, windowStart.Add(s.Period)
+}
+
+// Owner is the pod that owns a Subscription
+// Subscriptions with an owner are deleted once the owner pod has been gone for longer than
+// the configured grace period.
+type Owner struct {
+	// Namespace is the namespace of the owner pod
+	Namespace string `json:"namespace"`
+	// Name is the name of the owner pod
+	Name string `json:"name"`
+	// Lost is the time at which the owner pod was found to be missing
+	Lost time.Time `json:"lost,omitempty"`
+}
+
+// String returns the owner as a namespace/name string
+func (o *Owner) String() string {
+	return o.Namespace + "/" + o.Name
+}
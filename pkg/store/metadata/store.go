@@ -0,0 +1,328 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/atomix/go-client/pkg/client/util/net"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"google.golang.org/grpc/status"
+
+	_map "github.com/atomix/go-client/pkg/client/map"
+	"github.com/atomix/go-client/pkg/client/primitive"
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/store/driver"
+	"github.com/onosproject/onos-e2sub/pkg/store/quarantine"
+	"github.com/onosproject/onos-e2sub/pkg/store/schema"
+	"github.com/onosproject/onos-lib-go/pkg/atomix"
+)
+
+var log = logging.GetLogger("store", "metadata")
+
+// valueSchema is the schema of the stored subscription metadata
+var valueSchema = schema.NewSchema("SubscriptionMetadata")
+
+// NewAtomixStore returns a new persistent Store
+func NewAtomixStore() (Store, error) {
+	metadata, err := driver.GetMap(context.Background(), "subscription-metadata")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := schema.Migrate(context.Background(), metadata, valueSchema); err != nil {
+		return nil, err
+	}
+
+	quarantined, err := quarantine.NewAtomixStore()
+	if err != nil {
+		return nil, err
+	}
+
+	return &atomixStore{
+		metadata:   metadata,
+		quarantine: quarantine.NewSource("subscription-metadata", metadata, quarantined, decodeValue),
+	}, nil
+}
+
+// NewLocalStore returns a new local metadata store
+func NewLocalStore() (Store, error) {
+	_, address := atomix.StartLocalNode()
+	return newLocalStore(address)
+}
+
+// newLocalStore creates a new local metadata store
+func newLocalStore(address net.Address) (Store, error) {
+	name := primitive.Name{
+		Namespace: "local",
+		Name:      "subscription-metadata",
+	}
+
+	session, err := primitive.NewSession(context.TODO(), primitive.Partition{ID: 1, Address: address})
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := _map.New(context.Background(), name, []*primitive.Session{session})
+	if err != nil {
+		return nil, err
+	}
+
+	quarantined, err := quarantine.NewLocalStoreAt(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &atomixStore{
+		metadata:   metadata,
+		quarantine: quarantine.NewSource("subscription-metadata", metadata, quarantined, decodeValue),
+	}, nil
+}
+
+// Store stores subscription metadata
+type Store interface {
+	io.Closer
+
+	// Create creates subscription metadata in the store
+	Create(ctx context.Context, meta *Metadata) error
+
+	// Update updates subscription metadata in the store
+	Update(ctx context.Context, meta *Metadata) error
+
+	// Get gets subscription metadata from the store
+	Get(ctx context.Context, id subapi.ID) (*Metadata, error)
+
+	// Delete deletes subscription metadata from the store
+	Delete(ctx context.Context, id subapi.ID, opts ...DeleteOption) error
+
+	// List lists the subscription metadata in the store
+	List(ctx context.Context) ([]Metadata, error)
+
+	// Watch streams metadata events to the given channel
+	Watch(ctx context.Context, ch chan<- Event, opts ...WatchOption) error
+}
+
+// WatchOption is a configuration option for Watch calls
+type WatchOption interface {
+	apply([]_map.WatchOption) []_map.WatchOption
+}
+
+// watchReplyOption is an option to replay events on watch
+type watchReplayOption struct {
+}
+
+func (o watchReplayOption) apply(opts []_map.WatchOption) []_map.WatchOption {
+	return append(opts, driver.WithReplay())
+}
+
+// WithReplay returns a WatchOption that replays past changes
+func WithReplay() WatchOption {
+	return watchReplayOption{}
+}
+
+// DeleteOption is a configuration option for Delete calls
+type DeleteOption interface {
+	apply([]_map.RemoveOption) []_map.RemoveOption
+}
+
+// deleteRevisionOption is an option to delete the metadata only at a given revision
+type deleteRevisionOption struct {
+	revision Revision
+}
+
+func (o deleteRevisionOption) apply(opts []_map.RemoveOption) []_map.RemoveOption {
+	return append(opts, driver.IfVersion(_map.Version(o.revision)))
+}
+
+// IfRevision returns a DeleteOption that deletes the metadata only if it has not changed since the given revision
+func IfRevision(revision Revision) DeleteOption {
+	return deleteRevisionOption{
+		revision: revision,
+	}
+}
+
+// atomixStore is the implementation of the metadata Store
+type atomixStore struct {
+	metadata   _map.Map
+	quarantine *quarantine.Source
+}
+
+func (s *atomixStore) Create(ctx context.Context, meta *Metadata) error {
+	if meta.ID == "" {
+		return errors.NewInvalid("ID cannot be empty")
+	}
+
+	log.Infof("Creating Metadata %+v", meta)
+	bytes, err := json.Marshal(meta)
+	if err != nil {
+		log.Errorf("Failed to create Metadata %+v: %s", meta, err)
+		return errors.NewInvalid(err.Error())
+	}
+
+	// Create the metadata in the map only if it does not already exist
+	entry, err := s.metadata.Put(ctx, string(meta.ID), valueSchema.Encode(bytes), driver.IfNotSet())
+	if err != nil {
+		log.Errorf("Failed to create Metadata %+v: %s", meta, err)
+		return errors.FromAtomix(err)
+	}
+	meta.Revision = Revision(entry.Version)
+	return nil
+}
+
+func (s *atomixStore) Update(ctx context.Context, meta *Metadata) error {
+	if meta.ID == "" {
+		return errors.NewInvalid("ID cannot be empty")
+	}
+	if meta.Revision == 0 {
+		return errors.NewInvalid("object must contain a revision on update")
+	}
+
+	log.Infof("Updating Metadata %+v", meta)
+	bytes, err := json.Marshal(meta)
+	if err != nil {
+		log.Errorf("Failed to update Metadata %+v: %s", meta, err)
+		return errors.NewInvalid(err.Error())
+	}
+
+	// Update the metadata in the map
+	entry, err := s.metadata.Put(ctx, string(meta.ID), valueSchema.Encode(bytes), driver.IfVersion(_map.Version(meta.Revision)))
+	if err != nil {
+		log.Errorf("Failed to update Metadata %+v: %s", meta, err)
+		return errors.FromAtomix(err)
+	}
+	meta.Revision = Revision(entry.Version)
+	return nil
+}
+
+func (s *atomixStore) Get(ctx context.Context, id subapi.ID) (*Metadata, error) {
+	if id == "" {
+		return nil, errors.NewInvalid("ID cannot be empty")
+	}
+
+	entry, err := s.metadata.Get(ctx, string(id))
+	if err != nil {
+		return nil, errors.FromAtomix(err)
+	}
+	obj, err := decodeObject(entry)
+	if err != nil {
+		s.quarantine.Quarantine(ctx, entry, err)
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *atomixStore) Delete(ctx context.Context, id subapi.ID, opts ...DeleteOption) error {
+	if id == "" {
+		return errors.NewInvalid("ID cannot be empty")
+	}
+
+	log.Infof("Deleting Metadata %s", id)
+	removeOpts := make([]_map.RemoveOption, 0)
+	for _, opt := range opts {
+		removeOpts = opt.apply(removeOpts)
+	}
+	_, err := s.metadata.Remove(ctx, string(id), removeOpts...)
+	if err != nil {
+		log.Errorf("Failed to delete Metadata %s: %s", id, err)
+		return errors.FromAtomix(err)
+	}
+	return nil
+}
+
+func (s *atomixStore) List(ctx context.Context) ([]Metadata, error) {
+	mapCh := make(chan *_map.Entry)
+	if err := s.metadata.Entries(ctx, mapCh); err != nil {
+		return nil, errors.FromAtomix(err)
+	}
+
+	metas := make([]Metadata, 0)
+	for entry := range mapCh {
+		if meta, err := decodeObject(entry); err == nil {
+			metas = append(metas, *meta)
+		} else {
+			s.quarantine.Quarantine(ctx, entry, err)
+		}
+	}
+
+	// A canceled context ends the entries stream early, leaving the list incomplete
+	if err := ctx.Err(); err != nil {
+		return nil, errors.FromGRPC(status.FromContextError(err).Err())
+	}
+	return metas, nil
+}
+
+func (s *atomixStore) Watch(ctx context.Context, ch chan<- Event, opts ...WatchOption) error {
+	watchOpts := make([]_map.WatchOption, 0)
+	for _, opt := range opts {
+		watchOpts = opt.apply(watchOpts)
+	}
+
+	mapCh := make(chan *_map.Event)
+	if err := s.metadata.Watch(ctx, mapCh, watchOpts...); err != nil {
+		return errors.FromAtomix(err)
+	}
+
+	go func() {
+		defer close(ch)
+		for event := range mapCh {
+			if meta, err := decodeObject(event.Entry); err == nil {
+				var eventType EventType
+				switch event.Type {
+				case _map.EventNone:
+					eventType = EventNone
+				case _map.EventInserted:
+					eventType = EventCreated
+				case _map.EventUpdated:
+					eventType = EventUpdated
+				case _map.EventRemoved:
+					eventType = EventRemoved
+				}
+				// Once canceled, keep draining the watch until it closes without blocking on the consumer
+				select {
+				case ch <- Event{
+					Type:     eventType,
+					Metadata: *meta,
+				}:
+				case <-ctx.Done():
+				}
+			} else if event.Type != _map.EventRemoved {
+				// Removed entries are already gone, including those moved to the quarantine
+				s.quarantine.Quarantine(ctx, event.Entry, err)
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *atomixStore) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := s.metadata.Close(ctx)
+	_ = s.quarantine.Close()
+	return err
+}
+
+func decodeObject(entry *_map.Entry) (*Metadata, error) {
+	meta := &Metadata{}
+	value, err := valueSchema.Decode(entry.Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(value, meta); err != nil {
+		return nil, errors.NewInvalid(err.Error())
+	}
+	meta.ID = subapi.ID(entry.Key)
+	meta.Revision = Revision(entry.Version)
+	return meta, nil
+}
+
+func decodeValue(value []byte) error {
+	_, err := decodeObject(&_map.Entry{Value: value})
+	return err
+}
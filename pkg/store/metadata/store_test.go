@@ -0,0 +1,175 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package metadata
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	"github.com/onosproject/onos-lib-go/pkg/atomix"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadataStore(t *testing.T) {
+	_, address := atomix.StartLocalNode()
+
+	store1, err := newLocalStore(address)
+	assert.NoError(t, err)
+	defer store1.Close()
+
+	store2, err := newLocalStore(address)
+	assert.NoError(t, err)
+	defer store2.Close()
+
+	ch := make(chan Event)
+	err = store2.Watch(context.Background(), ch)
+	assert.NoError(t, err)
+
+	start := time.Now().Truncate(time.Second)
+	meta1 := &Metadata{
+		ID: "sub-1",
+		Schedule: &Schedule{
+			Start: start,
+			End:   start.Add(time.Hour),
+		},
+	}
+	meta2 := &Metadata{
+		ID: "sub-2",
+	}
+
+	// Create new metadata
+	err = store1.Create(context.TODO(), meta1)
+	assert.NoError(t, err)
+	assert.NotEqual(t, Revision(0), meta1.Revision)
+
+	// Get the metadata from the other store
+	meta, err := store2.Get(context.TODO(), "sub-1")
+	assert.NoError(t, err)
+	assert.NotNil(t, meta)
+	assert.Equal(t, subapi.ID("sub-1"), meta.ID)
+	assert.NotEqual(t, Revision(0), meta.Revision)
+	assert.True(t, start.Equal(meta.Schedule.Start))
+	assert.True(t, start.Add(time.Hour).Equal(meta.Schedule.End))
+
+	// Create more metadata
+	err = store2.Create(context.TODO(), meta2)
+	assert.NoError(t, err)
+	assert.NotEqual(t, Revision(0), meta2.Revision)
+
+	// Verify events were received for the metadata
+	event := nextEvent(t, ch)
+	assert.Equal(t, EventCreated, event.Type)
+	assert.Equal(t, subapi.ID("sub-1"), event.Metadata.ID)
+	event = nextEvent(t, ch)
+	assert.Equal(t, EventCreated, event.Type)
+	assert.Equal(t, subapi.ID("sub-2"), event.Metadata.ID)
+
+	// Update the metadata
+	revision := meta2.Revision
+	meta2.Schedule = &Schedule{End: start}
+	err = store1.Update(context.TODO(), meta2)
+	assert.NoError(t, err)
+	assert.NotEqual(t, revision, meta2.Revision)
+
+	event = nextEvent(t, ch)
+	assert.Equal(t, EventUpdated, event.Type)
+	assert.Equal(t, subapi.ID("sub-2"), event.Metadata.ID)
+
+	// Verify that concurrent updates fail
+	meta11, err := store1.Get(context.TODO(), "sub-1")
+	assert.NoError(t, err)
+	meta12, err := store2.Get(context.TODO(), "sub-1")
+	assert.NoError(t, err)
+
+	meta11.Schedule = nil
+	err = store1.Update(context.TODO(), meta11)
+	assert.NoError(t, err)
+
+	meta12.Schedule = nil
+	err = store2.Update(context.TODO(), meta12)
+	assert.Error(t, err)
+
+	// List the metadata
+	metas, err := store1.List(context.TODO())
+	assert.NoError(t, err)
+	assert.Len(t, metas, 2)
+
+	// Delete the metadata
+	err = store1.Delete(context.TODO(), meta2.ID)
+	assert.NoError(t, err)
+	meta, err = store2.Get(context.TODO(), "sub-2")
+	assert.Error(t, err)
+	assert.True(t, errors.IsNotFound(err))
+	assert.Nil(t, meta)
+
+	ch = make(chan Event)
+	err = store1.Watch(context.TODO(), ch, WithReplay())
+	assert.NoError(t, err)
+
+	event = nextEvent(t, ch)
+	assert.Equal(t, EventNone, event.Type)
+	assert.Equal(t, subapi.ID("sub-1"), event.Metadata.ID)
+}
+
+func TestSchedule(t *testing.T) {
+	start := time.Date(2020, 12, 1, 8, 0, 0, 0, time.UTC)
+
+	// A one-shot window
+	schedule := &Schedule{Start: start, End: start.Add(2 * time.Hour)}
+	assert.NoError(t, schedule.Validate())
+
+	active, next := schedule.Window(start.Add(-time.Minute))
+	assert.False(t, active)
+	assert.Equal(t, start, next)
+
+	active, next = schedule.Window(start.Add(time.Hour))
+	assert.True(t, active)
+	assert.Equal(t, start.Add(2*time.Hour), next)
+
+	active, next = schedule.Window(start.Add(3 * time.Hour))
+	assert.False(t, active)
+	assert.True(t, next.IsZero())
+
+	// An open-ended window
+	schedule = &Schedule{Start: start}
+	assert.NoError(t, schedule.Validate())
+	active, next = schedule.Window(start.Add(time.Hour))
+	assert.True(t, active)
+	assert.True(t, next.IsZero())
+
+	// A daily busy hour
+	schedule = &Schedule{Start: start, End: start.Add(time.Hour), Period: 24 * time.Hour}
+	assert.NoError(t, schedule.Validate())
+
+	active, next = schedule.Window(start.Add(30 * time.Minute))
+	assert.True(t, active)
+	assert.Equal(t, start.Add(time.Hour), next)
+
+	active, next = schedule.Window(start.Add(2 * time.Hour))
+	assert.False(t, active)
+	assert.Equal(t, start.Add(24*time.Hour), next)
+
+	active, next = schedule.Window(start.Add(48*time.Hour + 15*time.Minute))
+	assert.True(t, active)
+	assert.Equal(t, start.Add(49*time.Hour), next)
+
+	// Invalid schedules
+	assert.Error(t, (&Schedule{Start: start, End: start}).Validate())
+	assert.Error(t, (&Schedule{Start: start, Period: time.Hour}).Validate())
+	assert.Error(t, (&Schedule{Start: start, End: start.Add(2 * time.Hour), Period: time.Hour}).Validate())
+}
+
+func nextEvent(t *testing.T, ch chan Event) Event {
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(5 * time.Second):
+		t.FailNow()
+	}
+	return Event{}
+}
@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package node
+
+import (
+	"time"
+)
+
+// ID is an E2 node identifier
+type ID string
+
+// Revision is an E2 node revision
+type Revision uint64
+
+// Node is the connectivity state of an E2 node
+type Node struct {
+	// ID is the E2 node identifier
+	ID ID `json:"-"`
+	// Revision is the revision of the E2 node
+	Revision Revision `json:"-"`
+	// Connected indicates whether the E2 node is connected
+	Connected bool `json:"connected"`
+	// Updated is the time at which the E2 node's connectivity last changed
+	Updated time.Time `json:"updated,omitempty"`
+}
+
+// EventType is an E2 node event type
+type EventType int
+
+const (
+	// EventNone indicates a replayed E2 node
+	EventNone EventType = iota
+	// EventCreated indicates an E2 node was created
+	EventCreated
+	// EventUpdated indicates an E2 node was updated
+	EventUpdated
+	// EventRemoved indicates an E2 node was removed
+	EventRemoved
+)
+
+// Event is an E2 node event
+type Event struct {
+	Type EventType
+	Node Node
+}
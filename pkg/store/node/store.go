@@ -0,0 +1,302 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/atomix/go-client/pkg/client/util/net"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"google.golang.org/grpc/status"
+
+	_map "github.com/atomix/go-client/pkg/client/map"
+	"github.com/atomix/go-client/pkg/client/primitive"
+	"github.com/onosproject/onos-e2sub/pkg/store/driver"
+	"github.com/onosproject/onos-e2sub/pkg/store/quarantine"
+	"github.com/onosproject/onos-e2sub/pkg/store/schema"
+	"github.com/onosproject/onos-lib-go/pkg/atomix"
+)
+
+var log = logging.GetLogger("store", "node")
+
+// valueSchema is the schema of the stored E2 nodes
+var valueSchema = schema.NewSchema("E2Node")
+
+// NewAtomixStore returns a new persistent Store
+func NewAtomixStore() (Store, error) {
+	nodes, err := driver.GetMap(context.Background(), "e2nodes")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := schema.Migrate(context.Background(), nodes, valueSchema); err != nil {
+		return nil, err
+	}
+
+	quarantined, err := quarantine.NewAtomixStore()
+	if err != nil {
+		return nil, err
+	}
+
+	return &atomixStore{
+		nodes:      nodes,
+		quarantine: quarantine.NewSource("e2nodes", nodes, quarantined, decodeValue),
+	}, nil
+}
+
+// NewLocalStore returns a new local E2 node store
+func NewLocalStore() (Store, error) {
+	_, address := atomix.StartLocalNode()
+	return newLocalStore(address)
+}
+
+// newLocalStore creates a new local E2 node store
+func newLocalStore(address net.Address) (Store, error) {
+	name := primitive.Name{
+		Namespace: "local",
+		Name:      "e2nodes",
+	}
+
+	session, err := primitive.NewSession(context.TODO(), primitive.Partition{ID: 1, Address: address})
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := _map.New(context.Background(), name, []*primitive.Session{session})
+	if err != nil {
+		return nil, err
+	}
+
+	quarantined, err := quarantine.NewLocalStoreAt(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &atomixStore{
+		nodes:      nodes,
+		quarantine: quarantine.NewSource("e2nodes", nodes, quarantined, decodeValue),
+	}, nil
+}
+
+// Store stores E2 node connectivity
+type Store interface {
+	io.Closer
+
+	// Create creates an E2 node in the store
+	Create(ctx context.Context, node *Node) error
+
+	// Update updates an E2 node in the store
+	Update(ctx context.Context, node *Node) error
+
+	// Get gets an E2 node from the store
+	Get(ctx context.Context, id ID) (*Node, error)
+
+	// Delete deletes an E2 node from the store
+	Delete(ctx context.Context, id ID) error
+
+	// List lists the E2 nodes in the store
+	List(ctx context.Context) ([]Node, error)
+
+	// Watch streams E2 node events to the given channel
+	Watch(ctx context.Context, ch chan<- Event, opts ...WatchOption) error
+}
+
+// WatchOption is a configuration option for Watch calls
+type WatchOption interface {
+	apply([]_map.WatchOption) []_map.WatchOption
+}
+
+// watchReplyOption is an option to replay events on watch
+type watchReplayOption struct {
+}
+
+func (o watchReplayOption) apply(opts []_map.WatchOption) []_map.WatchOption {
+	return append(opts, driver.WithReplay())
+}
+
+// WithReplay returns a WatchOption that replays past changes
+func WithReplay() WatchOption {
+	return watchReplayOption{}
+}
+
+// atomixStore is the implementation of the E2 node Store
+type atomixStore struct {
+	nodes      _map.Map
+	quarantine *quarantine.Source
+}
+
+func (s *atomixStore) Create(ctx context.Context, node *Node) error {
+	if node.ID == "" {
+		return errors.NewInvalid("ID cannot be empty")
+	}
+
+	log.Infof("Creating Node %+v", node)
+	bytes, err := json.Marshal(node)
+	if err != nil {
+		log.Errorf("Failed to create Node %+v: %s", node, err)
+		return errors.NewInvalid(err.Error())
+	}
+
+	// Create the E2 node in the map only if it does not already exist
+	entry, err := s.nodes.Put(ctx, string(node.ID), valueSchema.Encode(bytes), driver.IfNotSet())
+	if err != nil {
+		log.Errorf("Failed to create Node %+v: %s", node, err)
+		return errors.FromAtomix(err)
+	}
+	node.Revision = Revision(entry.Version)
+	return nil
+}
+
+func (s *atomixStore) Update(ctx context.Context, node *Node) error {
+	if node.ID == "" {
+		return errors.NewInvalid("ID cannot be empty")
+	}
+	if node.Revision == 0 {
+		return errors.NewInvalid("object must contain a revision on update")
+	}
+
+	log.Infof("Updating Node %+v", node)
+	bytes, err := json.Marshal(node)
+	if err != nil {
+		log.Errorf("Failed to update Node %+v: %s", node, err)
+		return errors.NewInvalid(err.Error())
+	}
+
+	// Update the E2 node in the map
+	entry, err := s.nodes.Put(ctx, string(node.ID), valueSchema.Encode(bytes), driver.IfVersion(_map.Version(node.Revision)))
+	if err != nil {
+		log.Errorf("Failed to update Node %+v: %s", node, err)
+		return errors.FromAtomix(err)
+	}
+	node.Revision = Revision(entry.Version)
+	return nil
+}
+
+func (s *atomixStore) Get(ctx context.Context, id ID) (*Node, error) {
+	if id == "" {
+		return nil, errors.NewInvalid("ID cannot be empty")
+	}
+
+	entry, err := s.nodes.Get(ctx, string(id))
+	if err != nil {
+		return nil, errors.FromAtomix(err)
+	}
+	obj, err := decodeObject(entry)
+	if err != nil {
+		s.quarantine.Quarantine(ctx, entry, err)
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *atomixStore) Delete(ctx context.Context, id ID) error {
+	if id == "" {
+		return errors.NewInvalid("ID cannot be empty")
+	}
+
+	log.Infof("Deleting Node %s", id)
+	_, err := s.nodes.Remove(ctx, string(id))
+	if err != nil {
+		log.Errorf("Failed to delete Node %s: %s", id, err)
+		return errors.FromAtomix(err)
+	}
+	return nil
+}
+
+func (s *atomixStore) List(ctx context.Context) ([]Node, error) {
+	mapCh := make(chan *_map.Entry)
+	if err := s.nodes.Entries(ctx, mapCh); err != nil {
+		return nil, errors.FromAtomix(err)
+	}
+
+	nodes := make([]Node, 0)
+	for entry := range mapCh {
+		if node, err := decodeObject(entry); err == nil {
+			nodes = append(nodes, *node)
+		} else {
+			s.quarantine.Quarantine(ctx, entry, err)
+		}
+	}
+
+	// A canceled context ends the entries stream early, leaving the list incomplete
+	if err := ctx.Err(); err != nil {
+		return nil, errors.FromGRPC(status.FromContextError(err).Err())
+	}
+	return nodes, nil
+}
+
+func (s *atomixStore) Watch(ctx context.Context, ch chan<- Event, opts ...WatchOption) error {
+	watchOpts := make([]_map.WatchOption, 0)
+	for _, opt := range opts {
+		watchOpts = opt.apply(watchOpts)
+	}
+
+	mapCh := make(chan *_map.Event)
+	if err := s.nodes.Watch(ctx, mapCh, watchOpts...); err != nil {
+		return errors.FromAtomix(err)
+	}
+
+	go func() {
+		defer close(ch)
+		for event := range mapCh {
+			if node, err := decodeObject(event.Entry); err == nil {
+				var eventType EventType
+				switch event.Type {
+				case _map.EventNone:
+					eventType = EventNone
+				case _map.EventInserted:
+					eventType = EventCreated
+				case _map.EventUpdated:
+					eventType = EventUpdated
+				case _map.EventRemoved:
+					eventType = EventRemoved
+				}
+				// Once canceled, keep draining the watch until it closes without blocking on the consumer
+				select {
+				case ch <- Event{
+					Type: eventType,
+					Node: *node,
+				}:
+				case <-ctx.Done():
+				}
+			} else if event.Type != _map.EventRemoved {
+				// Removed entries are already gone, including those moved to the quarantine
+				s.quarantine.Quarantine(ctx, event.Entry, err)
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *atomixStore) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := s.nodes.Close(ctx)
+	_ = s.quarantine.Close()
+	return err
+}
+
+func decodeObject(entry *_map.Entry) (*Node, error) {
+	node := &Node{}
+	value, err := valueSchema.Decode(entry.Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(value, node); err != nil {
+		return nil, errors.NewInvalid(err.Error())
+	}
+	node.ID = ID(entry.Key)
+	node.Revision = Revision(entry.Version)
+	return node, nil
+}
+
+func decodeValue(value []byte) error {
+	_, err := decodeObject(&_map.Entry{Value: value})
+	return err
+}
@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package node
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/atomix"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeStore(t *testing.T) {
+	_, address := atomix.StartLocalNode()
+
+	store1, err := newLocalStore(address)
+	assert.NoError(t, err)
+	defer store1.Close()
+
+	store2, err := newLocalStore(address)
+	assert.NoError(t, err)
+	defer store2.Close()
+
+	ch := make(chan Event)
+	err = store2.Watch(context.Background(), ch)
+	assert.NoError(t, err)
+
+	node1 := &Node{
+		ID:        "e2node-1",
+		Connected: true,
+		Updated:   time.Now(),
+	}
+
+	// Create a new node
+	err = store1.Create(context.TODO(), node1)
+	assert.NoError(t, err)
+	assert.NotEqual(t, Revision(0), node1.Revision)
+
+	// Get the node from the other store
+	node, err := store2.Get(context.TODO(), "e2node-1")
+	assert.NoError(t, err)
+	assert.Equal(t, ID("e2node-1"), node.ID)
+	assert.True(t, node.Connected)
+
+	event := nextEvent(t, ch)
+	assert.Equal(t, EventCreated, event.Type)
+	assert.Equal(t, ID("e2node-1"), event.Node.ID)
+
+	// Disconnect the node
+	revision := node.Revision
+	node.Connected = false
+	err = store2.Update(context.TODO(), node)
+	assert.NoError(t, err)
+	assert.NotEqual(t, revision, node.Revision)
+
+	event = nextEvent(t, ch)
+	assert.Equal(t, EventUpdated, event.Type)
+	assert.False(t, event.Node.Connected)
+
+	// Verify that concurrent updates fail
+	node1.Connected = false
+	err = store1.Update(context.TODO(), node1)
+	assert.Error(t, err)
+	assert.True(t, errors.IsConflict(err))
+
+	// List the nodes
+	nodes, err := store1.List(context.TODO())
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 1)
+
+	// Delete the node
+	err = store1.Delete(context.TODO(), node.ID)
+	assert.NoError(t, err)
+	node, err = store2.Get(context.TODO(), "e2node-1")
+	assert.Error(t, err)
+	assert.True(t, errors.IsNotFound(err))
+	assert.Nil(t, node)
+
+	event = nextEvent(t, ch)
+	assert.Equal(t, EventRemoved, event.Type)
+}
+
+func nextEvent(t *testing.T, ch chan Event) Event {
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(5 * time.Second):
+		t.FailNow()
+	}
+	return Event{}
+}
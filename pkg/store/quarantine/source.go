@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package quarantine
+
+import (
+	"context"
+	"expvar"
+	"sync"
+
+	_map "github.com/atomix/go-client/pkg/client/map"
+	"github.com/onosproject/onos-e2sub/pkg/store/driver"
+	"github.com/onosproject/onos-e2sub/pkg/store/schema"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// quarantined counts the entries quarantined by this instance, by map
+var quarantined = expvar.NewMap("quarantined")
+
+// skipped counts the reads of entries written with a newer schema version by this instance, by map
+var skipped = expvar.NewMap("skipped")
+
+// sources are the maps whose entries are quarantined by this instance, by name
+var (
+	sources   = make(map[string]*Source)
+	sourcesMu sync.RWMutex
+)
+
+// DecodeFunc decodes a value of a map, failing if the value must be quarantined
+type DecodeFunc func(value []byte) error
+
+// NewSource returns the source of quarantined entries of the named map
+// The source is registered so that its quarantined entries can be restored into the map.
+func NewSource(name string, source _map.Map, quarantine Store, decode DecodeFunc) *Source {
+	s := &Source{
+		name:       name,
+		source:     source,
+		quarantine: quarantine,
+		decode:     decode,
+	}
+	sourcesMu.Lock()
+	sources[name] = s
+	sourcesMu.Unlock()
+	return s
+}
+
+// Source moves the undecodable entries of a map to the quarantine
+type Source struct {
+	name       string
+	source     _map.Map
+	quarantine Store
+	decode     DecodeFunc
+}
+
+// Quarantine moves an undecodable entry out of the map into the quarantine
+// The entry is put in the quarantine before it is removed from the map, and is only removed if it
+// has not changed since it was read, so that no value is ever lost. Failures are logged, leaving
+// the entry to be quarantined when next read. Entries written with a newer schema version, e.g. by
+// newer replicas during a rolling upgrade, are valid and are left in place.
+func (s *Source) Quarantine(ctx context.Context, entry *_map.Entry, cause error) {
+	if schema.IsUnsupportedVersion(cause) {
+		log.Debugf("Skipping %s entry %s: %s", s.name, entry.Key, cause)
+		skipped.Add(s.name, 1)
+		return
+	}
+	log.Warnf("Quarantining undecodable %s entry %s: %s", s.name, entry.Key, cause)
+	err := s.quarantine.Put(ctx, &Entry{
+		Map:     s.name,
+		Key:     entry.Key,
+		Version: uint64(entry.Version),
+		Value:   entry.Value,
+		Error:   cause.Error(),
+	})
+	if err != nil {
+		log.Errorf("Failed to quarantine %s entry %s: %s", s.name, entry.Key, err)
+		return
+	}
+
+	if _, err := s.source.Remove(ctx, entry.Key, driver.IfVersion(entry.Version)); err != nil {
+		err = errors.FromAtomix(err)
+		// The entry was already moved, e.g. by another watcher, or was rewritten since it was read
+		if !errors.IsNotFound(err) && !errors.IsConflict(err) {
+			log.Errorf("Failed to quarantine %s entry %s: %s", s.name, entry.Key, err)
+		}
+		return
+	}
+	quarantined.Add(s.name, 1)
+}
+
+// Restore moves a quarantined entry back into its map
+// If a value is given it replaces the quarantined value, e.g. to repair it. The value must be
+// decodable, and is restored only if its key has not been set since it was quarantined.
+func Restore(ctx context.Context, quarantine Store, id string, value []byte) error {
+	entry, err := quarantine.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	sourcesMu.RLock()
+	source, ok := sources[entry.Map]
+	sourcesMu.RUnlock()
+	if !ok {
+		return errors.NewUnavailable("map %s is not served by this instance", entry.Map)
+	}
+
+	if value == nil {
+		value = entry.Value
+	}
+	if err := source.decode(value); err != nil {
+		return errors.NewInvalid("cannot restore undecodable %s entry %s: %s", entry.Map, entry.Key, err)
+	}
+
+	log.Infof("Restoring quarantined %s entry %s", entry.Map, entry.Key)
+	if _, err := source.source.Put(ctx, entry.Key, value, driver.IfNotSet()); err != nil {
+		return errors.FromAtomix(err)
+	}
+	return quarantine.Delete(ctx, id)
+}
+
+// Close unregisters the source and closes its quarantine
+func (s *Source) Close() error {
+	sourcesMu.Lock()
+	if sources[s.name] == s {
+		delete(sources, s.name)
+	}
+	sourcesMu.Unlock()
+	return s.quarantine.Close()
+}
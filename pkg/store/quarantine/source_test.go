@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package quarantine
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	_map "github.com/atomix/go-client/pkg/client/map"
+	"github.com/atomix/go-client/pkg/client/primitive"
+	"github.com/onosproject/onos-e2sub/pkg/store/schema"
+	"github.com/onosproject/onos-lib-go/pkg/atomix"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// decodeNumber accepts only numeric values
+func decodeNumber(value []byte) error {
+	if _, err := strconv.Atoi(string(value)); err != nil {
+		return errors.NewInvalid(err.Error())
+	}
+	return nil
+}
+
+func TestQuarantine(t *testing.T) {
+	_, address := atomix.StartLocalNode()
+	store, err := NewLocalStoreAt(address)
+	assert.NoError(t, err)
+
+	session, err := primitive.NewSession(context.TODO(), primitive.Partition{ID: 1, Address: address})
+	assert.NoError(t, err)
+	numbers, err := _map.New(context.Background(), primitive.Name{Namespace: "local", Name: "numbers"}, []*primitive.Session{session})
+	assert.NoError(t, err)
+	source := NewSource("numbers", numbers, store, decodeNumber)
+	defer source.Close()
+
+	ctx := context.Background()
+	entry, err := numbers.Put(ctx, "one", []byte("not a number"))
+	assert.NoError(t, err)
+
+	// Verify an entry changed since it was read is not quarantined
+	stale := *entry
+	stale.Version--
+	source.Quarantine(ctx, &stale, decodeNumber(entry.Value))
+	_, err = numbers.Get(ctx, "one")
+	assert.NoError(t, err)
+
+	// Verify an entry written with a newer schema version is left in place
+	v1 := schema.NewSchema("Number")
+	v2 := schema.NewSchema("Number", func(value []byte) ([]byte, error) {
+		return value, nil
+	})
+	newer, err := numbers.Put(ctx, "two", v2.Encode([]byte("2")))
+	assert.NoError(t, err)
+	_, err = v1.Decode(newer.Value)
+	source.Quarantine(ctx, newer, err)
+	_, err = numbers.Get(ctx, "two")
+	assert.NoError(t, err)
+
+	count := quarantined.Get("numbers")
+	source.Quarantine(ctx, entry, decodeNumber(entry.Value))
+	_, err = numbers.Get(ctx, "one")
+	assert.True(t, errors.IsNotFound(errors.FromAtomix(err)))
+	assert.NotNil(t, quarantined.Get("numbers"))
+	assert.NotEqual(t, count, quarantined.Get("numbers"))
+
+	entries, err := store.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "numbers/one", entries[0].ID)
+	assert.Equal(t, "numbers", entries[0].Map)
+	assert.Equal(t, "one", entries[0].Key)
+	assert.Equal(t, "not a number", string(entries[0].Value))
+	assert.NotEmpty(t, entries[0].Error)
+
+	// Verify an undecodable entry is not restored
+	err = Restore(ctx, store, "numbers/one", nil)
+	assert.True(t, errors.IsInvalid(err))
+
+	// Verify a repaired entry is restored and removed from the quarantine
+	err = Restore(ctx, store, "numbers/one", []byte("1"))
+	assert.NoError(t, err)
+	entry, err = numbers.Get(ctx, "one")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", string(entry.Value))
+	_, err = store.Get(ctx, "numbers/one")
+	assert.True(t, errors.IsNotFound(err))
+
+	err = Restore(ctx, store, "numbers/one", nil)
+	assert.True(t, errors.IsNotFound(err))
+
+	// Verify entries of maps without a source cannot be restored
+	assert.NoError(t, store.Put(ctx, &Entry{Map: "letters", Key: "a", Value: []byte("a")}))
+	err = Restore(ctx, store, "letters/a", nil)
+	assert.True(t, errors.IsUnavailable(err))
+}
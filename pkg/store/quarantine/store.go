@@ -0,0 +1,208 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package quarantine
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/atomix/go-client/pkg/client/util/net"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"google.golang.org/grpc/status"
+
+	_map "github.com/atomix/go-client/pkg/client/map"
+	"github.com/atomix/go-client/pkg/client/primitive"
+	"github.com/onosproject/onos-e2sub/pkg/store/driver"
+	"github.com/onosproject/onos-e2sub/pkg/store/schema"
+	"github.com/onosproject/onos-lib-go/pkg/atomix"
+)
+
+var log = logging.GetLogger("store", "quarantine")
+
+// valueSchema is the schema of the stored quarantined entries
+var valueSchema = schema.NewSchema("QuarantinedEntry")
+
+// NewAtomixStore returns a new persistent Store
+func NewAtomixStore() (Store, error) {
+	entries, err := driver.GetMap(context.Background(), "quarantine")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := schema.Migrate(context.Background(), entries, valueSchema); err != nil {
+		return nil, err
+	}
+
+	return &atomixStore{
+		entries: entries,
+	}, nil
+}
+
+// NewLocalStore returns a new local quarantine store
+func NewLocalStore() (Store, error) {
+	_, address := atomix.StartLocalNode()
+	return NewLocalStoreAt(address)
+}
+
+// NewLocalStoreAt returns a new local quarantine store on the local node at the given address
+// Local stores share the quarantine of the node they are created on.
+func NewLocalStoreAt(address net.Address) (Store, error) {
+	name := primitive.Name{
+		Namespace: "local",
+		Name:      "quarantine",
+	}
+
+	session, err := primitive.NewSession(context.TODO(), primitive.Partition{ID: 1, Address: address})
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := _map.New(context.Background(), name, []*primitive.Session{session})
+	if err != nil {
+		return nil, err
+	}
+
+	return &atomixStore{
+		entries: entries,
+	}, nil
+}
+
+// Entry is an undecodable store entry moved to the quarantine
+type Entry struct {
+	// ID is the identifier of the quarantined entry: its map and key
+	ID string `json:"id"`
+	// Map is the name of the map the entry was moved from
+	Map string `json:"map"`
+	// Key is the key of the entry in its map
+	Key string `json:"key"`
+	// Version is the version of the entry in its map
+	Version uint64 `json:"version"`
+	// Value is the raw value of the entry
+	Value []byte `json:"value"`
+	// Error is the reason the entry could not be decoded
+	Error string `json:"error"`
+	// Quarantined is the time at which the entry was quarantined
+	Quarantined time.Time `json:"quarantined"`
+}
+
+// Store is a store of quarantined entries
+type Store interface {
+	io.Closer
+
+	// Put puts an entry in the quarantine, replacing any earlier entry with the same ID
+	Put(ctx context.Context, entry *Entry) error
+
+	// Get gets a quarantined entry
+	Get(ctx context.Context, id string) (*Entry, error)
+
+	// Delete deletes a quarantined entry
+	Delete(ctx context.Context, id string) error
+
+	// List lists the quarantined entries
+	List(ctx context.Context) ([]Entry, error)
+}
+
+// atomixStore is the implementation of the quarantine Store
+type atomixStore struct {
+	entries _map.Map
+}
+
+func (s *atomixStore) Put(ctx context.Context, entry *Entry) error {
+	if entry.Map == "" || entry.Key == "" {
+		return errors.NewInvalid("map and key cannot be empty")
+	}
+	entry.ID = getID(entry.Map, entry.Key)
+	if entry.Quarantined.IsZero() {
+		entry.Quarantined = time.Now()
+	}
+
+	log.Infof("Quarantining Entry %s", entry.ID)
+	bytes, err := json.Marshal(entry)
+	if err != nil {
+		log.Errorf("Failed to quarantine Entry %s: %s", entry.ID, err)
+		return errors.NewInvalid(err.Error())
+	}
+
+	if _, err := s.entries.Put(ctx, entry.ID, valueSchema.Encode(bytes)); err != nil {
+		log.Errorf("Failed to quarantine Entry %s: %s", entry.ID, err)
+		return errors.FromAtomix(err)
+	}
+	return nil
+}
+
+func (s *atomixStore) Get(ctx context.Context, id string) (*Entry, error) {
+	if id == "" {
+		return nil, errors.NewInvalid("ID cannot be empty")
+	}
+
+	entry, err := s.entries.Get(ctx, id)
+	if err != nil {
+		return nil, errors.FromAtomix(err)
+	}
+	return decodeObject(entry)
+}
+
+func (s *atomixStore) Delete(ctx context.Context, id string) error {
+	if id == "" {
+		return errors.NewInvalid("ID cannot be empty")
+	}
+	log.Infof("Deleting Entry %s", id)
+	_, err := s.entries.Remove(ctx, id)
+	if err != nil {
+		log.Errorf("Failed to delete Entry %s: %s", id, err)
+		return errors.FromAtomix(err)
+	}
+	return nil
+}
+
+func (s *atomixStore) List(ctx context.Context) ([]Entry, error) {
+	mapCh := make(chan *_map.Entry)
+	if err := s.entries.Entries(ctx, mapCh); err != nil {
+		return nil, errors.FromAtomix(err)
+	}
+
+	entries := make([]Entry, 0)
+	for mapEntry := range mapCh {
+		// The quarantine cannot quarantine itself, so its undecodable entries are only reported
+		entry, err := decodeObject(mapEntry)
+		if err != nil {
+			log.Warnf("Failed to decode quarantined Entry %s: %s", mapEntry.Key, err)
+			continue
+		}
+		entries = append(entries, *entry)
+	}
+
+	// A canceled context ends the entries stream early, leaving the list incomplete
+	if err := ctx.Err(); err != nil {
+		return nil, errors.FromGRPC(status.FromContextError(err).Err())
+	}
+	return entries, nil
+}
+
+func (s *atomixStore) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return s.entries.Close(ctx)
+}
+
+func getID(mapName string, key string) string {
+	return mapName + "/" + key
+}
+
+func decodeObject(mapEntry *_map.Entry) (*Entry, error) {
+	entry := &Entry{}
+	value, err := valueSchema.Decode(mapEntry.Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(value, entry); err != nil {
+		return nil, errors.NewInvalid(err.Error())
+	}
+	entry.ID = mapEntry.Key
+	return entry, nil
+}
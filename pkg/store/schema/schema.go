@@ -0,0 +1,156 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+
+	_map "github.com/atomix/go-client/pkg/client/map"
+	"github.com/onosproject/onos-e2sub/pkg/store/driver"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+)
+
+var log = logging.GetLogger("store", "schema")
+
+// magic prefixes an enveloped value
+// Encoded protobuf and JSON values never start with a zero byte, so values without the prefix
+// are known to have been written before values were enveloped.
+var magic = []byte{0x00, 'e', '2', 's'}
+
+// legacyVersion is the schema version of values written without an envelope
+const legacyVersion = 1
+
+// Migration upgrades a value from one schema version to the next
+type Migration func(value []byte) ([]byte, error)
+
+// NewSchema returns the schema of the values of the given kind
+// The schema's version is one more than the number of migrations: migrations[i] upgrades a value
+// from version i+1 to version i+2.
+func NewSchema(kind string, migrations ...Migration) *Schema {
+	return &Schema{
+		kind:       kind,
+		version:    uint64(len(migrations) + legacyVersion),
+		migrations: migrations,
+	}
+}
+
+// Schema is the versioned schema of the values of a store
+type Schema struct {
+	kind       string
+	version    uint64
+	migrations []Migration
+}
+
+// Version returns the current version of the schema
+func (s *Schema) Version() uint64 {
+	return s.version
+}
+
+// Encode wraps a value of the current version in a versioned envelope
+func (s *Schema) Encode(value []byte) []byte {
+	header := make([]byte, len(magic)+binary.MaxVarintLen64)
+	copy(header, magic)
+	n := binary.PutUvarint(header[len(magic):], s.version)
+	return append(header[:len(magic)+n], value...)
+}
+
+// Decode unwraps a value from its envelope, upgrading it to the current version
+// A value written with a newer version of the schema, e.g. by a newer replica during a rolling
+// upgrade, is valid but cannot be read, and is reported with a NotSupported error for which
+// IsUnsupportedVersion returns true. Values that cannot be decoded are reported as Invalid.
+func (s *Schema) Decode(data []byte) ([]byte, error) {
+	version, value, err := unwrap(data)
+	if err != nil {
+		return nil, errors.NewInvalid("invalid %s envelope: %s", s.kind, err)
+	}
+	if version > s.version {
+		return nil, errors.NewNotSupported("%s schema version %d is newer than supported version %d", s.kind, version, s.version)
+	}
+	for ; version < s.version; version++ {
+		value, err = s.migrations[version-legacyVersion](value)
+		if err != nil {
+			return nil, errors.NewInvalid("failed to migrate %s from schema version %d: %s", s.kind, version, err)
+		}
+	}
+	return value, nil
+}
+
+// IsUnsupportedVersion returns whether a decoding error indicates the value was written with a
+// newer version of the schema
+func IsUnsupportedVersion(err error) bool {
+	return errors.IsNotSupported(err)
+}
+
+// IsCurrent returns whether the value was enveloped with the current version of the schema
+func (s *Schema) IsCurrent(value []byte) bool {
+	if !bytes.HasPrefix(value, magic) {
+		return false
+	}
+	version, _, err := unwrap(value)
+	return err == nil && version == s.version
+}
+
+// unwrap returns the version and value of an enveloped value
+func unwrap(value []byte) (uint64, []byte, error) {
+	if !bytes.HasPrefix(value, magic) {
+		return legacyVersion, value, nil
+	}
+	version, n := binary.Uvarint(value[len(magic):])
+	if n <= 0 || version == 0 {
+		return 0, nil, errors.NewInvalid("malformed version")
+	}
+	return version, value[len(magic)+n:], nil
+}
+
+// Migrate upgrades the values of the map written with older versions of the schema
+// Each value is rewritten only if it has not changed since it was read, so migrating concurrently
+// with other writers, or other replicas migrating the same map, is safe. Values that cannot be
+// decoded, and values written with a newer version of the schema, are left in place. It returns
+// the number of upgraded values.
+func Migrate(ctx context.Context, m _map.Map, s *Schema) (int, error) {
+	ch := make(chan *_map.Entry)
+	if err := m.Entries(ctx, ch); err != nil {
+		return 0, errors.FromAtomix(err)
+	}
+	var outdated []*_map.Entry
+	for entry := range ch {
+		if !s.IsCurrent(entry.Value) {
+			outdated = append(outdated, entry)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, errors.NewCanceled(err.Error())
+	}
+
+	migrated := 0
+	for _, entry := range outdated {
+		value, err := s.Decode(entry.Value)
+		if err != nil {
+			// Values written by newer replicas during a rolling upgrade are valid, and are left in place
+			if IsUnsupportedVersion(err) {
+				log.Debugf("Skipping %s %s: %s", s.kind, entry.Key, err)
+				continue
+			}
+			log.Warnf("Failed to migrate %s %s: %s", s.kind, entry.Key, err)
+			continue
+		}
+		if _, err := m.Put(ctx, entry.Key, s.Encode(value), driver.IfVersion(entry.Version)); err != nil {
+			err = errors.FromAtomix(err)
+			if errors.IsConflict(err) || errors.IsAlreadyExists(err) {
+				// The value was updated or removed since it was read, and so is current
+				continue
+			}
+			return migrated, err
+		}
+		migrated++
+	}
+	if migrated > 0 {
+		log.Infof("Migrated %d %s values to schema version %d", migrated, s.kind, s.version)
+	}
+	return migrated, nil
+}
@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/onosproject/onos-e2sub/pkg/store/driver"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// objectV1 and objectV2 are two versions of a stored object, the second renaming a field
+type objectV1 struct {
+	Name string `json:"name"`
+}
+
+type objectV2 struct {
+	DisplayName string `json:"displayName"`
+}
+
+// renameField upgrades an objectV1 to an objectV2
+func renameField(value []byte) ([]byte, error) {
+	v1 := &objectV1{}
+	if err := json.Unmarshal(value, v1); err != nil {
+		return nil, err
+	}
+	return json.Marshal(&objectV2{DisplayName: v1.Name})
+}
+
+func TestSchema(t *testing.T) {
+	v1 := NewSchema("Object")
+	v2 := NewSchema("Object", renameField)
+	assert.Equal(t, uint64(1), v1.Version())
+	assert.Equal(t, uint64(2), v2.Version())
+
+	value, err := json.Marshal(&objectV1{Name: "foo"})
+	assert.NoError(t, err)
+
+	// Verify values round-trip through the envelope
+	bytes := v1.Encode(value)
+	assert.True(t, v1.IsCurrent(bytes))
+	decoded, err := v1.Decode(bytes)
+	assert.NoError(t, err)
+	assert.Equal(t, value, decoded)
+
+	// Verify values written without an envelope are read as the first version
+	assert.False(t, v1.IsCurrent(value))
+	decoded, err = v1.Decode(value)
+	assert.NoError(t, err)
+	assert.Equal(t, value, decoded)
+
+	// Verify values of older versions are upgraded on read
+	for _, old := range [][]byte{value, bytes} {
+		assert.False(t, v2.IsCurrent(old))
+		decoded, err = v2.Decode(old)
+		assert.NoError(t, err)
+		object := &objectV2{}
+		assert.NoError(t, json.Unmarshal(decoded, object))
+		assert.Equal(t, "foo", object.DisplayName)
+	}
+
+	// Verify values of newer versions are rejected rather than misread, and are not reported as invalid
+	_, err = v1.Decode(v2.Encode(value))
+	assert.True(t, IsUnsupportedVersion(err))
+	assert.False(t, errors.IsInvalid(err))
+
+	_, err = v1.Decode(append([]byte{}, magic...))
+	assert.True(t, errors.IsInvalid(err))
+	_, err = v2.Decode([]byte("not json"))
+	assert.True(t, errors.IsInvalid(err))
+	assert.False(t, IsUnsupportedVersion(err))
+}
+
+func TestMigrate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "e2sub")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	database, err := driver.OpenDatabase(filepath.Join(dir, "e2sub.db"), 0, "")
+	assert.NoError(t, err)
+	defer database.Close()
+	m, err := database.GetMap(context.Background(), "objects")
+	assert.NoError(t, err)
+	defer m.Close(context.Background())
+
+	ctx := context.Background()
+	v1 := NewSchema("Object")
+	v2 := NewSchema("Object", renameField)
+
+	legacy, err := json.Marshal(&objectV1{Name: "legacy"})
+	assert.NoError(t, err)
+	_, err = m.Put(ctx, "legacy", legacy)
+	assert.NoError(t, err)
+	old, err := json.Marshal(&objectV1{Name: "old"})
+	assert.NoError(t, err)
+	_, err = m.Put(ctx, "old", v1.Encode(old))
+	assert.NoError(t, err)
+	current, err := json.Marshal(&objectV2{DisplayName: "current"})
+	assert.NoError(t, err)
+	_, err = m.Put(ctx, "current", v2.Encode(current))
+	assert.NoError(t, err)
+	_, err = m.Put(ctx, "corrupt", v1.Encode([]byte("not json")))
+	assert.NoError(t, err)
+	v3 := NewSchema("Object", renameField, renameField)
+	newer := v3.Encode(current)
+	_, err = m.Put(ctx, "newer", newer)
+	assert.NoError(t, err)
+
+	migrated, err := Migrate(ctx, m, v2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, migrated)
+
+	for key, name := range map[string]string{"legacy": "legacy", "old": "old", "current": "current"} {
+		entry, err := m.Get(ctx, key)
+		assert.NoError(t, err)
+		assert.True(t, v2.IsCurrent(entry.Value), key)
+		value, err := v2.Decode(entry.Value)
+		assert.NoError(t, err)
+		object := &objectV2{}
+		assert.NoError(t, json.Unmarshal(value, object))
+		assert.Equal(t, name, object.DisplayName)
+	}
+
+	// Verify undecodable values are left in place
+	entry, err := m.Get(ctx, "corrupt")
+	assert.NoError(t, err)
+	assert.False(t, v2.IsCurrent(entry.Value))
+
+	// Verify values written with a newer version are left in place
+	entry, err = m.Get(ctx, "newer")
+	assert.NoError(t, err)
+	assert.Equal(t, newer, entry.Value)
+
+	// Verify migrating again is a no-op
+	migrated, err = Migrate(ctx, m, v2)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, migrated)
+
+	_, err = m.Put(ctx, "stale", legacy, driver.IfNotSet())
+	assert.NoError(t, err)
+	migrated, err = Migrate(ctx, m, v2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, migrated)
+}
@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"time"
+)
+
+// ID is a session token
+type ID string
+
+// Revision is a session revision
+type Revision uint64
+
+// Session is a client session
+// A session is held open by a client stream. Subscriptions scoped to the session are deleted
+// once the session has been disconnected for longer than the session grace period.
+type Session struct {
+	// ID is the session token
+	ID ID `json:"-"`
+	// Revision is the revision of the session
+	Revision Revision `json:"-"`
+	// Stream is the identifier of the stream holding the session
+	Stream string `json:"stream,omitempty"`
+	// Connected indicates whether the session is held by an open stream
+	Connected bool `json:"connected"`
+	// Renewed is the time at which the session was last renewed by its stream
+	Renewed time.Time `json:"renewed,omitempty"`
+	// Disconnected is the time at which the session's stream was closed
+	Disconnected time.Time `json:"disconnected,omitempty"`
+}
+
+// EventType is a session event type
+type EventType int
+
+const (
+	// EventNone indicates a replayed session
+	EventNone EventType = iota
+	// EventCreated indicates a session was created
+	EventCreated
+	// EventUpdated indicates a session was updated
+	EventUpdated
+	// EventRemoved indicates a session was removed
+	EventRemoved
+)
+
+// Event is a session event
+type Event struct {
+	Type    EventType
+	Session Session
+}
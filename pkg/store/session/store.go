@@ -0,0 +1,302 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/atomix/go-client/pkg/client/util/net"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"google.golang.org/grpc/status"
+
+	_map "github.com/atomix/go-client/pkg/client/map"
+	"github.com/atomix/go-client/pkg/client/primitive"
+	"github.com/onosproject/onos-e2sub/pkg/store/driver"
+	"github.com/onosproject/onos-e2sub/pkg/store/quarantine"
+	"github.com/onosproject/onos-e2sub/pkg/store/schema"
+	"github.com/onosproject/onos-lib-go/pkg/atomix"
+)
+
+var log = logging.GetLogger("store", "session")
+
+// valueSchema is the schema of the stored client sessions
+var valueSchema = schema.NewSchema("Session")
+
+// NewAtomixStore returns a new persistent Store
+func NewAtomixStore() (Store, error) {
+	sessions, err := driver.GetMap(context.Background(), "sessions")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := schema.Migrate(context.Background(), sessions, valueSchema); err != nil {
+		return nil, err
+	}
+
+	quarantined, err := quarantine.NewAtomixStore()
+	if err != nil {
+		return nil, err
+	}
+
+	return &atomixStore{
+		sessions:   sessions,
+		quarantine: quarantine.NewSource("sessions", sessions, quarantined, decodeValue),
+	}, nil
+}
+
+// NewLocalStore returns a new local session store
+func NewLocalStore() (Store, error) {
+	_, address := atomix.StartLocalNode()
+	return newLocalStore(address)
+}
+
+// newLocalStore creates a new local session store
+func newLocalStore(address net.Address) (Store, error) {
+	name := primitive.Name{
+		Namespace: "local",
+		Name:      "sessions",
+	}
+
+	session, err := primitive.NewSession(context.TODO(), primitive.Partition{ID: 1, Address: address})
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := _map.New(context.Background(), name, []*primitive.Session{session})
+	if err != nil {
+		return nil, err
+	}
+
+	quarantined, err := quarantine.NewLocalStoreAt(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &atomixStore{
+		sessions:   sessions,
+		quarantine: quarantine.NewSource("sessions", sessions, quarantined, decodeValue),
+	}, nil
+}
+
+// Store stores client sessions
+type Store interface {
+	io.Closer
+
+	// Create creates a session in the store
+	Create(ctx context.Context, sess *Session) error
+
+	// Update updates a session in the store
+	Update(ctx context.Context, sess *Session) error
+
+	// Get gets a session from the store
+	Get(ctx context.Context, id ID) (*Session, error)
+
+	// Delete deletes a session from the store
+	Delete(ctx context.Context, id ID) error
+
+	// List lists the sessions in the store
+	List(ctx context.Context) ([]Session, error)
+
+	// Watch streams session events to the given channel
+	Watch(ctx context.Context, ch chan<- Event, opts ...WatchOption) error
+}
+
+// WatchOption is a configuration option for Watch calls
+type WatchOption interface {
+	apply([]_map.WatchOption) []_map.WatchOption
+}
+
+// watchReplyOption is an option to replay events on watch
+type watchReplayOption struct {
+}
+
+func (o watchReplayOption) apply(opts []_map.WatchOption) []_map.WatchOption {
+	return append(opts, driver.WithReplay())
+}
+
+// WithReplay returns a WatchOption that replays past changes
+func WithReplay() WatchOption {
+	return watchReplayOption{}
+}
+
+// atomixStore is the implementation of the session Store
+type atomixStore struct {
+	sessions   _map.Map
+	quarantine *quarantine.Source
+}
+
+func (s *atomixStore) Create(ctx context.Context, sess *Session) error {
+	if sess.ID == "" {
+		return errors.NewInvalid("ID cannot be empty")
+	}
+
+	log.Infof("Creating Session %+v", sess)
+	bytes, err := json.Marshal(sess)
+	if err != nil {
+		log.Errorf("Failed to create Session %+v: %s", sess, err)
+		return errors.NewInvalid(err.Error())
+	}
+
+	// Create the session in the map only if it does not already exist
+	entry, err := s.sessions.Put(ctx, string(sess.ID), valueSchema.Encode(bytes), driver.IfNotSet())
+	if err != nil {
+		log.Errorf("Failed to create Session %+v: %s", sess, err)
+		return errors.FromAtomix(err)
+	}
+	sess.Revision = Revision(entry.Version)
+	return nil
+}
+
+func (s *atomixStore) Update(ctx context.Context, sess *Session) error {
+	if sess.ID == "" {
+		return errors.NewInvalid("ID cannot be empty")
+	}
+	if sess.Revision == 0 {
+		return errors.NewInvalid("object must contain a revision on update")
+	}
+
+	log.Infof("Updating Session %+v", sess)
+	bytes, err := json.Marshal(sess)
+	if err != nil {
+		log.Errorf("Failed to update Session %+v: %s", sess, err)
+		return errors.NewInvalid(err.Error())
+	}
+
+	// Update the session in the map
+	entry, err := s.sessions.Put(ctx, string(sess.ID), valueSchema.Encode(bytes), driver.IfVersion(_map.Version(sess.Revision)))
+	if err != nil {
+		log.Errorf("Failed to update Session %+v: %s", sess, err)
+		return errors.FromAtomix(err)
+	}
+	sess.Revision = Revision(entry.Version)
+	return nil
+}
+
+func (s *atomixStore) Get(ctx context.Context, id ID) (*Session, error) {
+	if id == "" {
+		return nil, errors.NewInvalid("ID cannot be empty")
+	}
+
+	entry, err := s.sessions.Get(ctx, string(id))
+	if err != nil {
+		return nil, errors.FromAtomix(err)
+	}
+	obj, err := decodeObject(entry)
+	if err != nil {
+		s.quarantine.Quarantine(ctx, entry, err)
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *atomixStore) Delete(ctx context.Context, id ID) error {
+	if id == "" {
+		return errors.NewInvalid("ID cannot be empty")
+	}
+
+	log.Infof("Deleting Session %s", id)
+	_, err := s.sessions.Remove(ctx, string(id))
+	if err != nil {
+		log.Errorf("Failed to delete Session %s: %s", id, err)
+		return errors.FromAtomix(err)
+	}
+	return nil
+}
+
+func (s *atomixStore) List(ctx context.Context) ([]Session, error) {
+	mapCh := make(chan *_map.Entry)
+	if err := s.sessions.Entries(ctx, mapCh); err != nil {
+		return nil, errors.FromAtomix(err)
+	}
+
+	sessions := make([]Session, 0)
+	for entry := range mapCh {
+		if sess, err := decodeObject(entry); err == nil {
+			sessions = append(sessions, *sess)
+		} else {
+			s.quarantine.Quarantine(ctx, entry, err)
+		}
+	}
+
+	// A canceled context ends the entries stream early, leaving the list incomplete
+	if err := ctx.Err(); err != nil {
+		return nil, errors.FromGRPC(status.FromContextError(err).Err())
+	}
+	return sessions, nil
+}
+
+func (s *atomixStore) Watch(ctx context.Context, ch chan<- Event, opts ...WatchOption) error {
+	watchOpts := make([]_map.WatchOption, 0)
+	for _, opt := range opts {
+		watchOpts = opt.apply(watchOpts)
+	}
+
+	mapCh := make(chan *_map.Event)
+	if err := s.sessions.Watch(ctx, mapCh, watchOpts...); err != nil {
+		return errors.FromAtomix(err)
+	}
+
+	go func() {
+		defer close(ch)
+		for event := range mapCh {
+			if sess, err := decodeObject(event.Entry); err == nil {
+				var eventType EventType
+				switch event.Type {
+				case _map.EventNone:
+					eventType = EventNone
+				case _map.EventInserted:
+					eventType = EventCreated
+				case _map.EventUpdated:
+					eventType = EventUpdated
+				case _map.EventRemoved:
+					eventType = EventRemoved
+				}
+				// Once canceled, keep draining the watch until it closes without blocking on the consumer
+				select {
+				case ch <- Event{
+					Type:    eventType,
+					Session: *sess,
+				}:
+				case <-ctx.Done():
+				}
+			} else if event.Type != _map.EventRemoved {
+				// Removed entries are already gone, including those moved to the quarantine
+				s.quarantine.Quarantine(ctx, event.Entry, err)
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *atomixStore) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := s.sessions.Close(ctx)
+	_ = s.quarantine.Close()
+	return err
+}
+
+func decodeObject(entry *_map.Entry) (*Session, error) {
+	sess := &Session{}
+	value, err := valueSchema.Decode(entry.Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(value, sess); err != nil {
+		return nil, errors.NewInvalid(err.Error())
+	}
+	sess.ID = ID(entry.Key)
+	sess.Revision = Revision(entry.Version)
+	return sess, nil
+}
+
+func decodeValue(value []byte) error {
+	_, err := decodeObject(&_map.Entry{Value: value})
+	return err
+}
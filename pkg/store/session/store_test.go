@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/atomix"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionStore(t *testing.T) {
+	_, address := atomix.StartLocalNode()
+
+	store1, err := newLocalStore(address)
+	assert.NoError(t, err)
+	defer store1.Close()
+
+	store2, err := newLocalStore(address)
+	assert.NoError(t, err)
+	defer store2.Close()
+
+	ch := make(chan Event)
+	err = store2.Watch(context.Background(), ch)
+	assert.NoError(t, err)
+
+	now := time.Now().Truncate(time.Second)
+	sess1 := &Session{
+		ID:        "session-1",
+		Stream:    "stream-1",
+		Connected: true,
+		Renewed:   now,
+	}
+
+	// Create a new session
+	err = store1.Create(context.TODO(), sess1)
+	assert.NoError(t, err)
+	assert.NotEqual(t, Revision(0), sess1.Revision)
+
+	// Get the session from the other store
+	sess, err := store2.Get(context.TODO(), "session-1")
+	assert.NoError(t, err)
+	assert.Equal(t, ID("session-1"), sess.ID)
+	assert.Equal(t, "stream-1", sess.Stream)
+	assert.True(t, sess.Connected)
+	assert.True(t, now.Equal(sess.Renewed))
+
+	event := nextEvent(t, ch)
+	assert.Equal(t, EventCreated, event.Type)
+	assert.Equal(t, ID("session-1"), event.Session.ID)
+
+	// Disconnect the session
+	revision := sess.Revision
+	sess.Connected = false
+	sess.Disconnected = now
+	err = store2.Update(context.TODO(), sess)
+	assert.NoError(t, err)
+	assert.NotEqual(t, revision, sess.Revision)
+
+	event = nextEvent(t, ch)
+	assert.Equal(t, EventUpdated, event.Type)
+	assert.False(t, event.Session.Connected)
+
+	// Verify that concurrent updates fail
+	sess1.Stream = "stream-2"
+	err = store1.Update(context.TODO(), sess1)
+	assert.Error(t, err)
+	assert.True(t, errors.IsConflict(err))
+
+	// List the sessions
+	sessions, err := store1.List(context.TODO())
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+
+	// Delete the session
+	err = store1.Delete(context.TODO(), sess.ID)
+	assert.NoError(t, err)
+	sess, err = store2.Get(context.TODO(), "session-1")
+	assert.Error(t, err)
+	assert.True(t, errors.IsNotFound(err))
+	assert.Nil(t, sess)
+
+	event = nextEvent(t, ch)
+	assert.Equal(t, EventRemoved, event.Type)
+}
+
+func nextEvent(t *testing.T, ch chan Event) Event {
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(5 * time.Second):
+		t.FailNow()
+	}
+	return Event{}
+}
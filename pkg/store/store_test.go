@@ -0,0 +1,248 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store_test
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	taskapi "github.com/onosproject/onos-api/go/onos/e2sub/task"
+	"github.com/onosproject/onos-e2sub/pkg/store/endpoint"
+	"github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	"github.com/onosproject/onos-e2sub/pkg/store/node"
+	"github.com/onosproject/onos-e2sub/pkg/store/session"
+	"github.com/onosproject/onos-e2sub/pkg/store/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/store/task"
+	"github.com/onosproject/onos-e2sub/pkg/store/termination"
+	"github.com/stretchr/testify/assert"
+)
+
+// testStore adapts a store to the watch cancellation test
+type testStore struct {
+	io.Closer
+	// watch opens a watch of the store, returning a function reading the watch until it is closed
+	watch func(ctx context.Context) (func() bool, error)
+	// create creates an object in the store
+	create func(ctx context.Context) error
+	// list lists the objects in the store
+	list func(ctx context.Context) error
+}
+
+// testStores are the stores verified by TestWatchCancel
+var testStores = map[string]func() (*testStore, error){
+	"endpoint": func() (*testStore, error) {
+		store, err := endpoint.NewLocalStore()
+		if err != nil {
+			return nil, err
+		}
+		return &testStore{
+			Closer: store,
+			watch: func(ctx context.Context) (func() bool, error) {
+				ch := make(chan epapi.Event)
+				return func() bool { _, ok := <-ch; return ok }, store.Watch(ctx, ch)
+			},
+			create: func(ctx context.Context) error {
+				return store.Create(ctx, &epapi.TerminationEndpoint{ID: "e2t-1", IP: "10.0.0.1", Port: 5150})
+			},
+			list: func(ctx context.Context) error {
+				_, err := store.List(ctx)
+				return err
+			},
+		}, nil
+	},
+	"metadata": func() (*testStore, error) {
+		store, err := metadata.NewLocalStore()
+		if err != nil {
+			return nil, err
+		}
+		return &testStore{
+			Closer: store,
+			watch: func(ctx context.Context) (func() bool, error) {
+				ch := make(chan metadata.Event)
+				return func() bool { _, ok := <-ch; return ok }, store.Watch(ctx, ch)
+			},
+			create: func(ctx context.Context) error {
+				return store.Create(ctx, &metadata.Metadata{ID: "subscription-1"})
+			},
+			list: func(ctx context.Context) error {
+				_, err := store.List(ctx)
+				return err
+			},
+		}, nil
+	},
+	"node": func() (*testStore, error) {
+		store, err := node.NewLocalStore()
+		if err != nil {
+			return nil, err
+		}
+		return &testStore{
+			Closer: store,
+			watch: func(ctx context.Context) (func() bool, error) {
+				ch := make(chan node.Event)
+				return func() bool { _, ok := <-ch; return ok }, store.Watch(ctx, ch)
+			},
+			create: func(ctx context.Context) error {
+				return store.Create(ctx, &node.Node{ID: "node-1", Connected: true})
+			},
+			list: func(ctx context.Context) error {
+				_, err := store.List(ctx)
+				return err
+			},
+		}, nil
+	},
+	"session": func() (*testStore, error) {
+		store, err := session.NewLocalStore()
+		if err != nil {
+			return nil, err
+		}
+		return &testStore{
+			Closer: store,
+			watch: func(ctx context.Context) (func() bool, error) {
+				ch := make(chan session.Event)
+				return func() bool { _, ok := <-ch; return ok }, store.Watch(ctx, ch)
+			},
+			create: func(ctx context.Context) error {
+				return store.Create(ctx, &session.Session{ID: "session-1"})
+			},
+			list: func(ctx context.Context) error {
+				_, err := store.List(ctx)
+				return err
+			},
+		}, nil
+	},
+	"subscription": func() (*testStore, error) {
+		store, err := subscription.NewLocalStore()
+		if err != nil {
+			return nil, err
+		}
+		return &testStore{
+			Closer: store,
+			watch: func(ctx context.Context) (func() bool, error) {
+				ch := make(chan subapi.Event)
+				return func() bool { _, ok := <-ch; return ok }, store.Watch(ctx, ch)
+			},
+			create: func(ctx context.Context) error {
+				return store.Create(ctx, &subapi.Subscription{ID: "subscription-1"})
+			},
+			list: func(ctx context.Context) error {
+				_, err := store.List(ctx)
+				return err
+			},
+		}, nil
+	},
+	"task": func() (*testStore, error) {
+		store, err := task.NewLocalStore()
+		if err != nil {
+			return nil, err
+		}
+		return &testStore{
+			Closer: store,
+			watch: func(ctx context.Context) (func() bool, error) {
+				ch := make(chan taskapi.Event)
+				return func() bool { _, ok := <-ch; return ok }, store.Watch(ctx, ch)
+			},
+			create: func(ctx context.Context) error {
+				return store.Create(ctx, &taskapi.SubscriptionTask{ID: "task-1", SubscriptionID: "subscription-1", EndpointID: "e2t-1"})
+			},
+			list: func(ctx context.Context) error {
+				_, err := store.List(ctx)
+				return err
+			},
+		}, nil
+	},
+	"termination": func() (*testStore, error) {
+		store, err := termination.NewLocalStore()
+		if err != nil {
+			return nil, err
+		}
+		return &testStore{
+			Closer: store,
+			watch: func(ctx context.Context) (func() bool, error) {
+				ch := make(chan termination.Event)
+				return func() bool { _, ok := <-ch; return ok }, store.Watch(ctx, ch)
+			},
+			create: func(ctx context.Context) error {
+				return store.Create(ctx, &termination.Termination{ID: "e2t-1"})
+			},
+			list: func(ctx context.Context) error {
+				_, err := store.List(ctx)
+				return err
+			},
+		}, nil
+	},
+}
+
+// storeGoroutines counts the goroutines running store or Atomix map client code
+// Goroutines of the local Atomix node are excluded, since it only notices a canceled stream on its next event.
+func storeGoroutines() int {
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	count := 0
+	for _, stack := range strings.Split(string(buf), "\n\n") {
+		if strings.Contains(stack, "onos-e2sub/pkg/store/") || strings.Contains(stack, "go-client/pkg/client/map") {
+			count++
+		}
+	}
+	return count
+}
+
+// waitClosed reads a watch with the given function until the watch is closed, returning false if
+// it is not closed in time
+func waitClosed(recv func() bool) bool {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for recv() {
+		}
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(5 * time.Second):
+		return false
+	}
+}
+
+func TestWatchCancel(t *testing.T) {
+	for name, open := range testStores {
+		t.Run(name, func(t *testing.T) {
+			store, err := open()
+			assert.NoError(t, err)
+			defer store.Close()
+
+			goroutines := storeGoroutines()
+
+			// Open watches that are never read, and verify canceling them releases their goroutines
+			ctx, cancel := context.WithCancel(context.Background())
+			watches := make([]func() bool, 0, 10)
+			for i := 0; i < 10; i++ {
+				recv, err := store.watch(ctx)
+				assert.NoError(t, err)
+				watches = append(watches, recv)
+			}
+			assert.NoError(t, store.create(context.Background()))
+			cancel()
+			for _, recv := range watches {
+				assert.True(t, waitClosed(recv), "watch not closed")
+			}
+			deadline := time.Now().Add(5 * time.Second)
+			for storeGoroutines() > goroutines && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			assert.Equal(t, goroutines, storeGoroutines())
+
+			// Verify lists honour the caller's context
+			ctx, cancel = context.WithCancel(context.Background())
+			cancel()
+			assert.Error(t, store.list(ctx))
+		})
+	}
+}
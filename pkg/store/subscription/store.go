@@ -12,36 +12,42 @@ import (
 	"github.com/atomix/go-client/pkg/client/util/net"
 	"github.com/onosproject/onos-lib-go/pkg/errors"
 	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"google.golang.org/grpc/status"
 
 	_map "github.com/atomix/go-client/pkg/client/map"
 	"github.com/atomix/go-client/pkg/client/primitive"
 	"github.com/gogo/protobuf/proto"
 	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
-	"github.com/onosproject/onos-e2sub/pkg/config"
+	"github.com/onosproject/onos-e2sub/pkg/store/driver"
+	"github.com/onosproject/onos-e2sub/pkg/store/quarantine"
+	"github.com/onosproject/onos-e2sub/pkg/store/schema"
 	"github.com/onosproject/onos-lib-go/pkg/atomix"
 )
 
 var log = logging.GetLogger("store", "subscription")
 
+// valueSchema is the schema of the stored subscriptions
+var valueSchema = schema.NewSchema("Subscription")
+
 // NewAtomixStore returns a new persistent Store
 func NewAtomixStore() (Store, error) {
-	ricConfig, err := config.GetConfig()
+	subscriptions, err := driver.GetMap(context.Background(), "subscriptions")
 	if err != nil {
 		return nil, err
 	}
 
-	database, err := atomix.GetDatabase(ricConfig.Atomix, ricConfig.Atomix.GetDatabase(atomix.DatabaseTypeConsensus))
-	if err != nil {
+	if _, err := schema.Migrate(context.Background(), subscriptions, valueSchema); err != nil {
 		return nil, err
 	}
 
-	subscriptions, err := database.GetMap(context.Background(), "subscriptions")
+	quarantined, err := quarantine.NewAtomixStore()
 	if err != nil {
 		return nil, err
 	}
 
 	return &atomixStore{
 		subscriptions: subscriptions,
+		quarantine:    quarantine.NewSource("subscriptions", subscriptions, quarantined, decodeValue),
 	}, nil
 }
 
@@ -68,8 +74,14 @@ func newLocalStore(address net.Address) (Store, error) {
 		return nil, err
 	}
 
+	quarantined, err := quarantine.NewLocalStoreAt(address)
+	if err != nil {
+		return nil, err
+	}
+
 	return &atomixStore{
 		subscriptions: subscriptions,
+		quarantine:    quarantine.NewSource("subscriptions", subscriptions, quarantined, decodeValue),
 	}, nil
 }
 
@@ -87,7 +99,7 @@ type Store interface {
 	Get(ctx context.Context, id subapi.ID) (*subapi.Subscription, error)
 
 	// Delete deletes an subscription from the store
-	Delete(ctx context.Context, id subapi.ID) error
+	Delete(ctx context.Context, id subapi.ID, opts ...DeleteOption) error
 
 	// List streams subscriptions to the given channel
 	List(ctx context.Context) ([]subapi.Subscription, error)
@@ -106,7 +118,7 @@ type watchReplayOption struct {
 }
 
 func (o watchReplayOption) apply(opts []_map.WatchOption) []_map.WatchOption {
-	return append(opts, _map.WithReplay())
+	return append(opts, driver.WithReplay())
 }
 
 // WithReplay returns a WatchOption that replays past changes
@@ -114,9 +126,31 @@ func WithReplay() WatchOption {
 	return watchReplayOption{}
 }
 
+// DeleteOption is a configuration option for Delete calls
+type DeleteOption interface {
+	apply([]_map.RemoveOption) []_map.RemoveOption
+}
+
+// deleteRevisionOption is an option to delete the subscription only at a given revision
+type deleteRevisionOption struct {
+	revision subapi.Revision
+}
+
+func (o deleteRevisionOption) apply(opts []_map.RemoveOption) []_map.RemoveOption {
+	return append(opts, driver.IfVersion(_map.Version(o.revision)))
+}
+
+// IfRevision returns a DeleteOption that deletes the subscription only if it has not changed since the given revision
+func IfRevision(revision subapi.Revision) DeleteOption {
+	return deleteRevisionOption{
+		revision: revision,
+	}
+}
+
 // atomixStore is the implementation of the subscription Store
 type atomixStore struct {
 	subscriptions _map.Map
+	quarantine    *quarantine.Source
 }
 
 func (s *atomixStore) Create(ctx context.Context, sub *subapi.Subscription) error {
@@ -132,7 +166,7 @@ func (s *atomixStore) Create(ctx context.Context, sub *subapi.Subscription) erro
 	}
 
 	// Create the subscription in the map only if it does not already exist
-	entry, err := s.subscriptions.Put(ctx, string(sub.ID), bytes, _map.IfNotSet())
+	entry, err := s.subscriptions.Put(ctx, string(sub.ID), valueSchema.Encode(bytes), driver.IfNotSet())
 	if err != nil {
 		log.Errorf("Failed to create Subscription %+v: %s", sub, err)
 		return errors.FromAtomix(err)
@@ -157,7 +191,7 @@ func (s *atomixStore) Update(ctx context.Context, sub *subapi.Subscription) erro
 	}
 
 	// Update the subscription in the map
-	entry, err := s.subscriptions.Put(ctx, string(sub.ID), bytes, _map.IfVersion(_map.Version(sub.Revision)))
+	entry, err := s.subscriptions.Put(ctx, string(sub.ID), valueSchema.Encode(bytes), driver.IfVersion(_map.Version(sub.Revision)))
 	if err != nil {
 		log.Errorf("Failed to update Subscription %+v: %s", sub, err)
 		return errors.FromAtomix(err)
@@ -175,15 +209,24 @@ func (s *atomixStore) Get(ctx context.Context, id subapi.ID) (*subapi.Subscripti
 	if err != nil {
 		return nil, errors.FromAtomix(err)
 	}
-	return decodeObject(entry)
+	obj, err := decodeObject(entry)
+	if err != nil {
+		s.quarantine.Quarantine(ctx, entry, err)
+		return nil, err
+	}
+	return obj, nil
 }
 
-func (s *atomixStore) Delete(ctx context.Context, id subapi.ID) error {
+func (s *atomixStore) Delete(ctx context.Context, id subapi.ID, opts ...DeleteOption) error {
 	if id == "" {
 		return errors.NewInvalid("ID cannot be empty")
 	}
 	log.Infof("Deleting Subscription %s", id)
-	_, err := s.subscriptions.Remove(ctx, string(id))
+	removeOpts := make([]_map.RemoveOption, 0)
+	for _, opt := range opts {
+		removeOpts = opt.apply(removeOpts)
+	}
+	_, err := s.subscriptions.Remove(ctx, string(id), removeOpts...)
 	if err != nil {
 		log.Errorf("Failed to delete Subscription %s: %s", id, err)
 		return errors.FromAtomix(err)
@@ -193,7 +236,7 @@ func (s *atomixStore) Delete(ctx context.Context, id subapi.ID) error {
 
 func (s *atomixStore) List(ctx context.Context) ([]subapi.Subscription, error) {
 	mapCh := make(chan *_map.Entry)
-	if err := s.subscriptions.Entries(context.Background(), mapCh); err != nil {
+	if err := s.subscriptions.Entries(ctx, mapCh); err != nil {
 		return nil, errors.FromAtomix(err)
 	}
 
@@ -201,8 +244,15 @@ func (s *atomixStore) List(ctx context.Context) ([]subapi.Subscription, error) {
 	for entry := range mapCh {
 		if sub, err := decodeObject(entry); err == nil {
 			subs = append(subs, *sub)
+		} else {
+			s.quarantine.Quarantine(ctx, entry, err)
 		}
 	}
+
+	// A canceled context ends the entries stream early, leaving the list incomplete
+	if err := ctx.Err(); err != nil {
+		return nil, errors.FromGRPC(status.FromContextError(err).Err())
+	}
 	return subs, nil
 }
 
@@ -213,7 +263,7 @@ func (s *atomixStore) Watch(ctx context.Context, ch chan<- subapi.Event, opts ..
 	}
 
 	mapCh := make(chan *_map.Event)
-	if err := s.subscriptions.Watch(context.Background(), mapCh, watchOpts...); err != nil {
+	if err := s.subscriptions.Watch(ctx, mapCh, watchOpts...); err != nil {
 		return errors.FromAtomix(err)
 	}
 
@@ -232,10 +282,17 @@ func (s *atomixStore) Watch(ctx context.Context, ch chan<- subapi.Event, opts ..
 				case _map.EventRemoved:
 					eventType = subapi.EventType_REMOVED
 				}
-				ch <- subapi.Event{
+				// Once canceled, keep draining the watch until it closes without blocking on the consumer
+				select {
+				case ch <- subapi.Event{
 					Type:         eventType,
 					Subscription: *sub,
+				}:
+				case <-ctx.Done():
 				}
+			} else if event.Type != _map.EventRemoved {
+				// Removed entries are already gone, including those moved to the quarantine
+				s.quarantine.Quarantine(ctx, event.Entry, err)
 			}
 		}
 	}()
@@ -245,15 +302,26 @@ func (s *atomixStore) Watch(ctx context.Context, ch chan<- subapi.Event, opts ..
 func (s *atomixStore) Close() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	return s.subscriptions.Close(ctx)
+	err := s.subscriptions.Close(ctx)
+	_ = s.quarantine.Close()
+	return err
 }
 
 func decodeObject(entry *_map.Entry) (*subapi.Subscription, error) {
 	sub := &subapi.Subscription{}
-	if err := proto.Unmarshal(entry.Value, sub); err != nil {
+	value, err := valueSchema.Decode(entry.Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := proto.Unmarshal(value, sub); err != nil {
 		return nil, errors.NewInvalid(err.Error())
 	}
 	sub.ID = subapi.ID(entry.Key)
 	sub.Revision = subapi.Revision(entry.Version)
 	return sub, nil
 }
+
+func decodeValue(value []byte) error {
+	_, err := decodeObject(&_map.Entry{Value: value})
+	return err
+}
@@ -9,7 +9,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gogo/protobuf/proto"
 	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/store/quarantine"
+	"github.com/onosproject/onos-e2sub/pkg/store/schema"
 	"github.com/onosproject/onos-lib-go/pkg/atomix"
 	"github.com/onosproject/onos-lib-go/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -151,3 +154,81 @@ func nextEvent(t *testing.T, ch chan subapi.Event) *subapi.Subscription {
 	}
 	return nil
 }
+
+func TestLegacyValues(t *testing.T) {
+	_, address := atomix.StartLocalNode()
+	store, err := newLocalStore(address)
+	assert.NoError(t, err)
+	defer store.Close()
+	subscriptions := store.(*atomixStore).subscriptions
+
+	// Write a subscription as it was stored before values were enveloped
+	bytes, err := proto.Marshal(&subapi.Subscription{AppID: "app-1"})
+	assert.NoError(t, err)
+	_, err = subscriptions.Put(context.TODO(), "sub-1", bytes)
+	assert.NoError(t, err)
+
+	sub, err := store.Get(context.TODO(), "sub-1")
+	assert.NoError(t, err)
+	assert.Equal(t, subapi.AppID("app-1"), sub.AppID)
+
+	// Verify the migration envelopes the value and the store still reads it
+	migrated, err := schema.Migrate(context.TODO(), subscriptions, valueSchema)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, migrated)
+	entry, err := subscriptions.Get(context.TODO(), "sub-1")
+	assert.NoError(t, err)
+	assert.True(t, valueSchema.IsCurrent(entry.Value))
+
+	sub, err = store.Get(context.TODO(), "sub-1")
+	assert.NoError(t, err)
+	assert.Equal(t, subapi.AppID("app-1"), sub.AppID)
+	assert.Equal(t, subapi.Revision(entry.Version), sub.Revision)
+}
+
+func TestQuarantine(t *testing.T) {
+	_, address := atomix.StartLocalNode()
+	store, err := newLocalStore(address)
+	assert.NoError(t, err)
+	defer store.Close()
+	subscriptions := store.(*atomixStore).subscriptions
+
+	quarantined, err := quarantine.NewLocalStoreAt(address)
+	assert.NoError(t, err)
+	defer quarantined.Close()
+
+	assert.NoError(t, store.Create(context.TODO(), &subapi.Subscription{ID: "sub-1"}))
+	_, err = subscriptions.Put(context.TODO(), "sub-2", valueSchema.Encode([]byte("corrupt")))
+	assert.NoError(t, err)
+
+	// Verify the corrupt subscription is moved to the quarantine when listed
+	subs, err := store.List(context.TODO())
+	assert.NoError(t, err)
+	assert.Len(t, subs, 1)
+	_, err = subscriptions.Get(context.TODO(), "sub-2")
+	assert.Error(t, err)
+	entry, err := quarantined.Get(context.TODO(), "subscriptions/sub-2")
+	assert.NoError(t, err)
+	assert.Equal(t, "sub-2", entry.Key)
+
+	// Verify a corrupt subscription written while watching is moved to the quarantine
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := make(chan subapi.Event)
+	assert.NoError(t, store.Watch(ctx, ch))
+	_, err = subscriptions.Put(context.TODO(), "sub-3", valueSchema.Encode([]byte("corrupt")))
+	assert.NoError(t, err)
+	assert.NoError(t, store.Create(context.TODO(), &subapi.Subscription{ID: "sub-4"}))
+	event := <-ch
+	assert.Equal(t, subapi.ID("sub-4"), event.Subscription.ID)
+	_, err = quarantined.Get(context.TODO(), "subscriptions/sub-3")
+	assert.NoError(t, err)
+
+	// Verify a corrupt subscription is moved to the quarantine when read
+	_, err = subscriptions.Put(context.TODO(), "sub-5", valueSchema.Encode([]byte("corrupt")))
+	assert.NoError(t, err)
+	_, err = store.Get(context.TODO(), "sub-5")
+	assert.True(t, errors.IsInvalid(err))
+	_, err = store.Get(context.TODO(), "sub-5")
+	assert.True(t, errors.IsNotFound(err))
+}
@@ -12,36 +12,42 @@ import (
 	"github.com/atomix/go-client/pkg/client/util/net"
 	"github.com/onosproject/onos-lib-go/pkg/errors"
 	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"google.golang.org/grpc/status"
 
 	_map "github.com/atomix/go-client/pkg/client/map"
 	"github.com/atomix/go-client/pkg/client/primitive"
 	"github.com/gogo/protobuf/proto"
 	taskapi "github.com/onosproject/onos-api/go/onos/e2sub/task"
-	"github.com/onosproject/onos-e2sub/pkg/config"
+	"github.com/onosproject/onos-e2sub/pkg/store/driver"
+	"github.com/onosproject/onos-e2sub/pkg/store/quarantine"
+	"github.com/onosproject/onos-e2sub/pkg/store/schema"
 	"github.com/onosproject/onos-lib-go/pkg/atomix"
 )
 
 var log = logging.GetLogger("store", "task")
 
+// valueSchema is the schema of the stored subscription tasks
+var valueSchema = schema.NewSchema("SubscriptionTask")
+
 // NewAtomixStore returns a new persistent Store
 func NewAtomixStore() (Store, error) {
-	ricConfig, err := config.GetConfig()
+	tasks, err := driver.GetMap(context.Background(), "subscription-tasks")
 	if err != nil {
 		return nil, err
 	}
 
-	database, err := atomix.GetDatabase(ricConfig.Atomix, ricConfig.Atomix.GetDatabase(atomix.DatabaseTypeConsensus))
-	if err != nil {
+	if _, err := schema.Migrate(context.Background(), tasks, valueSchema); err != nil {
 		return nil, err
 	}
 
-	tasks, err := database.GetMap(context.Background(), "subscription-tasks")
+	quarantined, err := quarantine.NewAtomixStore()
 	if err != nil {
 		return nil, err
 	}
 
 	return &atomixStore{
-		tasks: tasks,
+		tasks:      tasks,
+		quarantine: quarantine.NewSource("subscription-tasks", tasks, quarantined, decodeValue),
 	}, nil
 }
 
@@ -68,8 +74,14 @@ func newLocalStore(address net.Address) (Store, error) {
 		return nil, err
 	}
 
+	quarantined, err := quarantine.NewLocalStoreAt(address)
+	if err != nil {
+		return nil, err
+	}
+
 	return &atomixStore{
-		tasks: tasks,
+		tasks:      tasks,
+		quarantine: quarantine.NewSource("subscription-tasks", tasks, quarantined, decodeValue),
 	}, nil
 }
 
@@ -87,7 +99,7 @@ type Store interface {
 	Get(ctx context.Context, id taskapi.ID) (*taskapi.SubscriptionTask, error)
 
 	// Delete deletes an task from the store
-	Delete(ctx context.Context, id taskapi.ID) error
+	Delete(ctx context.Context, id taskapi.ID, opts ...DeleteOption) error
 
 	// List streams tasks to the given channel
 	List(ctx context.Context) ([]taskapi.SubscriptionTask, error)
@@ -106,7 +118,7 @@ type watchReplayOption struct {
 }
 
 func (o watchReplayOption) apply(opts []_map.WatchOption) []_map.WatchOption {
-	return append(opts, _map.WithReplay())
+	return append(opts, driver.WithReplay())
 }
 
 // WithReplay returns a WatchOption that replays past changes
@@ -114,10 +126,32 @@ func WithReplay() WatchOption {
 	return watchReplayOption{}
 }
 
+// DeleteOption is a configuration option for Delete calls
+type DeleteOption interface {
+	apply([]_map.RemoveOption) []_map.RemoveOption
+}
+
+// deleteRevisionOption is an option to delete the task only at a given revision
+type deleteRevisionOption struct {
+	revision taskapi.Revision
+}
+
+func (o deleteRevisionOption) apply(opts []_map.RemoveOption) []_map.RemoveOption {
+	return append(opts, driver.IfVersion(_map.Version(o.revision)))
+}
+
+// IfRevision returns a DeleteOption that deletes the task only if it has not changed since the given revision
+func IfRevision(revision taskapi.Revision) DeleteOption {
+	return deleteRevisionOption{
+		revision: revision,
+	}
+}
+
 // atomixStore is the implementation of the task Store
 type atomixStore struct {
-	tasks  _map.Map
-	closer func() error
+	tasks      _map.Map
+	quarantine *quarantine.Source
+	closer     func() error
 }
 
 func (s *atomixStore) Create(ctx context.Context, task *taskapi.SubscriptionTask) error {
@@ -133,7 +167,7 @@ func (s *atomixStore) Create(ctx context.Context, task *taskapi.SubscriptionTask
 	}
 
 	// Create the task in the map only if it does not already exist
-	entry, err := s.tasks.Put(ctx, string(task.ID), bytes, _map.IfNotSet())
+	entry, err := s.tasks.Put(ctx, string(task.ID), valueSchema.Encode(bytes), driver.IfNotSet())
 	if err != nil {
 		log.Errorf("Failed to create SubscriptionTask %+v: %s", task, err)
 		return errors.FromAtomix(err)
@@ -158,7 +192,7 @@ func (s *atomixStore) Update(ctx context.Context, task *taskapi.SubscriptionTask
 	}
 
 	// Update the task in the map
-	entry, err := s.tasks.Put(ctx, string(task.ID), bytes, _map.IfVersion(_map.Version(task.Revision)))
+	entry, err := s.tasks.Put(ctx, string(task.ID), valueSchema.Encode(bytes), driver.IfVersion(_map.Version(task.Revision)))
 	if err != nil {
 		log.Errorf("Failed to update SubscriptionTask %+v: %s", task, err)
 		return errors.FromAtomix(err)
@@ -176,16 +210,25 @@ func (s *atomixStore) Get(ctx context.Context, id taskapi.ID) (*taskapi.Subscrip
 	if err != nil {
 		return nil, errors.FromAtomix(err)
 	}
-	return decodeObject(entry)
+	obj, err := decodeObject(entry)
+	if err != nil {
+		s.quarantine.Quarantine(ctx, entry, err)
+		return nil, err
+	}
+	return obj, nil
 }
 
-func (s *atomixStore) Delete(ctx context.Context, id taskapi.ID) error {
+func (s *atomixStore) Delete(ctx context.Context, id taskapi.ID, opts ...DeleteOption) error {
 	if id == "" {
 		return errors.NewInvalid("ID cannot be empty")
 	}
 
 	log.Infof("Deleting SubscriptionTask %s", id)
-	_, err := s.tasks.Remove(ctx, string(id))
+	removeOpts := make([]_map.RemoveOption, 0)
+	for _, opt := range opts {
+		removeOpts = opt.apply(removeOpts)
+	}
+	_, err := s.tasks.Remove(ctx, string(id), removeOpts...)
 	if err != nil {
 		log.Errorf("Failed to delete SubscriptionTask %s: %s", id, err)
 		return errors.FromAtomix(err)
@@ -195,7 +238,7 @@ func (s *atomixStore) Delete(ctx context.Context, id taskapi.ID) error {
 
 func (s *atomixStore) List(ctx context.Context) ([]taskapi.SubscriptionTask, error) {
 	mapCh := make(chan *_map.Entry)
-	if err := s.tasks.Entries(context.Background(), mapCh); err != nil {
+	if err := s.tasks.Entries(ctx, mapCh); err != nil {
 		return nil, errors.FromAtomix(err)
 	}
 
@@ -203,8 +246,15 @@ func (s *atomixStore) List(ctx context.Context) ([]taskapi.SubscriptionTask, err
 	for entry := range mapCh {
 		if task, err := decodeObject(entry); err == nil {
 			tasks = append(tasks, *task)
+		} else {
+			s.quarantine.Quarantine(ctx, entry, err)
 		}
 	}
+
+	// A canceled context ends the entries stream early, leaving the list incomplete
+	if err := ctx.Err(); err != nil {
+		return nil, errors.FromGRPC(status.FromContextError(err).Err())
+	}
 	return tasks, nil
 }
 
@@ -215,7 +265,7 @@ func (s *atomixStore) Watch(ctx context.Context, ch chan<- taskapi.Event, opts .
 	}
 
 	mapCh := make(chan *_map.Event)
-	if err := s.tasks.Watch(context.Background(), mapCh, watchOpts...); err != nil {
+	if err := s.tasks.Watch(ctx, mapCh, watchOpts...); err != nil {
 		return errors.FromAtomix(err)
 	}
 
@@ -234,10 +284,17 @@ func (s *atomixStore) Watch(ctx context.Context, ch chan<- taskapi.Event, opts .
 				case _map.EventRemoved:
 					eventType = taskapi.EventType_REMOVED
 				}
-				ch <- taskapi.Event{
+				// Once canceled, keep draining the watch until it closes without blocking on the consumer
+				select {
+				case ch <- taskapi.Event{
 					Type: eventType,
 					Task: *task,
+				}:
+				case <-ctx.Done():
 				}
+			} else if event.Type != _map.EventRemoved {
+				// Removed entries are already gone, including those moved to the quarantine
+				s.quarantine.Quarantine(ctx, event.Entry, err)
 			}
 		}
 	}()
@@ -247,6 +304,7 @@ func (s *atomixStore) Watch(ctx context.Context, ch chan<- taskapi.Event, opts .
 func (s *atomixStore) Close() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	_ = s.tasks.Close(ctx)
+	_ = s.quarantine.Close()
 	cancel()
 	if s.closer != nil {
 		return s.closer()
@@ -256,10 +314,19 @@ func (s *atomixStore) Close() error {
 
 func decodeObject(entry *_map.Entry) (*taskapi.SubscriptionTask, error) {
 	task := &taskapi.SubscriptionTask{}
-	if err := proto.Unmarshal(entry.Value, task); err != nil {
+	value, err := valueSchema.Decode(entry.Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := proto.Unmarshal(value, task); err != nil {
 		return nil, errors.NewInvalid(err.Error())
 	}
 	task.ID = taskapi.ID(entry.Key)
 	task.Revision = taskapi.Revision(entry.Version)
 	return task, nil
 }
+
+func decodeValue(value []byte) error {
+	_, err := decodeObject(&_map.Entry{Value: value})
+	return err
+}
@@ -150,3 +150,24 @@ func nextEvent(t *testing.T, ch chan taskapi.Event) *taskapi.SubscriptionTask {
 	}
 	return nil
 }
+
+func TestDeleteIfRevision(t *testing.T) {
+	store, err := NewLocalStore()
+	assert.NoError(t, err)
+	defer store.Close()
+
+	task := &taskapi.SubscriptionTask{
+		ID: "task-1",
+	}
+	assert.NoError(t, store.Create(context.TODO(), task))
+	revision := task.Revision
+	task.Lifecycle.Phase = taskapi.Phase_CLOSE
+	assert.NoError(t, store.Update(context.TODO(), task))
+
+	// Verify the task is only deleted at its current revision
+	err = store.Delete(context.TODO(), task.ID, IfRevision(revision))
+	assert.True(t, errors.IsConflict(err))
+	assert.NoError(t, store.Delete(context.TODO(), task.ID, IfRevision(task.Revision)))
+	_, err = store.Get(context.TODO(), task.ID)
+	assert.True(t, errors.IsNotFound(err))
+}
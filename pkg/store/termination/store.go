@@ -0,0 +1,303 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package termination
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/atomix/go-client/pkg/client/util/net"
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"google.golang.org/grpc/status"
+
+	_map "github.com/atomix/go-client/pkg/client/map"
+	"github.com/atomix/go-client/pkg/client/primitive"
+	"github.com/onosproject/onos-e2sub/pkg/store/driver"
+	"github.com/onosproject/onos-e2sub/pkg/store/quarantine"
+	"github.com/onosproject/onos-e2sub/pkg/store/schema"
+	"github.com/onosproject/onos-lib-go/pkg/atomix"
+)
+
+var log = logging.GetLogger("store", "termination")
+
+// valueSchema is the schema of the stored terminations
+var valueSchema = schema.NewSchema("Termination")
+
+// NewAtomixStore returns a new persistent Store
+func NewAtomixStore() (Store, error) {
+	terminations, err := driver.GetMap(context.Background(), "terminations")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := schema.Migrate(context.Background(), terminations, valueSchema); err != nil {
+		return nil, err
+	}
+
+	quarantined, err := quarantine.NewAtomixStore()
+	if err != nil {
+		return nil, err
+	}
+
+	return &atomixStore{
+		terminations: terminations,
+		quarantine:   quarantine.NewSource("terminations", terminations, quarantined, decodeValue),
+	}, nil
+}
+
+// NewLocalStore returns a new local termination store
+func NewLocalStore() (Store, error) {
+	_, address := atomix.StartLocalNode()
+	return newLocalStore(address)
+}
+
+// newLocalStore creates a new local termination store
+func newLocalStore(address net.Address) (Store, error) {
+	name := primitive.Name{
+		Namespace: "local",
+		Name:      "terminations",
+	}
+
+	session, err := primitive.NewSession(context.TODO(), primitive.Partition{ID: 1, Address: address})
+	if err != nil {
+		return nil, err
+	}
+
+	terminations, err := _map.New(context.Background(), name, []*primitive.Session{session})
+	if err != nil {
+		return nil, err
+	}
+
+	quarantined, err := quarantine.NewLocalStoreAt(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &atomixStore{
+		terminations: terminations,
+		quarantine:   quarantine.NewSource("terminations", terminations, quarantined, decodeValue),
+	}, nil
+}
+
+// Store stores the onos-e2sub specific state of termination endpoints
+type Store interface {
+	io.Closer
+
+	// Create creates a termination in the store
+	Create(ctx context.Context, term *Termination) error
+
+	// Update updates a termination in the store
+	Update(ctx context.Context, term *Termination) error
+
+	// Get gets a termination from the store
+	Get(ctx context.Context, id epapi.ID) (*Termination, error)
+
+	// Delete deletes a termination from the store
+	Delete(ctx context.Context, id epapi.ID) error
+
+	// List lists the terminations in the store
+	List(ctx context.Context) ([]Termination, error)
+
+	// Watch streams termination events to the given channel
+	Watch(ctx context.Context, ch chan<- Event, opts ...WatchOption) error
+}
+
+// WatchOption is a configuration option for Watch calls
+type WatchOption interface {
+	apply([]_map.WatchOption) []_map.WatchOption
+}
+
+// watchReplyOption is an option to replay events on watch
+type watchReplayOption struct {
+}
+
+func (o watchReplayOption) apply(opts []_map.WatchOption) []_map.WatchOption {
+	return append(opts, driver.WithReplay())
+}
+
+// WithReplay returns a WatchOption that replays past changes
+func WithReplay() WatchOption {
+	return watchReplayOption{}
+}
+
+// atomixStore is the implementation of the termination Store
+type atomixStore struct {
+	terminations _map.Map
+	quarantine   *quarantine.Source
+}
+
+func (s *atomixStore) Create(ctx context.Context, term *Termination) error {
+	if term.ID == "" {
+		return errors.NewInvalid("ID cannot be empty")
+	}
+
+	log.Infof("Creating Termination %+v", term)
+	bytes, err := json.Marshal(term)
+	if err != nil {
+		log.Errorf("Failed to create Termination %+v: %s", term, err)
+		return errors.NewInvalid(err.Error())
+	}
+
+	// Create the termination in the map only if it does not already exist
+	entry, err := s.terminations.Put(ctx, string(term.ID), valueSchema.Encode(bytes), driver.IfNotSet())
+	if err != nil {
+		log.Errorf("Failed to create Termination %+v: %s", term, err)
+		return errors.FromAtomix(err)
+	}
+	term.Revision = Revision(entry.Version)
+	return nil
+}
+
+func (s *atomixStore) Update(ctx context.Context, term *Termination) error {
+	if term.ID == "" {
+		return errors.NewInvalid("ID cannot be empty")
+	}
+	if term.Revision == 0 {
+		return errors.NewInvalid("object must contain a revision on update")
+	}
+
+	log.Infof("Updating Termination %+v", term)
+	bytes, err := json.Marshal(term)
+	if err != nil {
+		log.Errorf("Failed to update Termination %+v: %s", term, err)
+		return errors.NewInvalid(err.Error())
+	}
+
+	// Update the termination in the map
+	entry, err := s.terminations.Put(ctx, string(term.ID), valueSchema.Encode(bytes), driver.IfVersion(_map.Version(term.Revision)))
+	if err != nil {
+		log.Errorf("Failed to update Termination %+v: %s", term, err)
+		return errors.FromAtomix(err)
+	}
+	term.Revision = Revision(entry.Version)
+	return nil
+}
+
+func (s *atomixStore) Get(ctx context.Context, id epapi.ID) (*Termination, error) {
+	if id == "" {
+		return nil, errors.NewInvalid("ID cannot be empty")
+	}
+
+	entry, err := s.terminations.Get(ctx, string(id))
+	if err != nil {
+		return nil, errors.FromAtomix(err)
+	}
+	obj, err := decodeObject(entry)
+	if err != nil {
+		s.quarantine.Quarantine(ctx, entry, err)
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *atomixStore) Delete(ctx context.Context, id epapi.ID) error {
+	if id == "" {
+		return errors.NewInvalid("ID cannot be empty")
+	}
+
+	log.Infof("Deleting Termination %s", id)
+	_, err := s.terminations.Remove(ctx, string(id))
+	if err != nil {
+		log.Errorf("Failed to delete Termination %s: %s", id, err)
+		return errors.FromAtomix(err)
+	}
+	return nil
+}
+
+func (s *atomixStore) List(ctx context.Context) ([]Termination, error) {
+	mapCh := make(chan *_map.Entry)
+	if err := s.terminations.Entries(ctx, mapCh); err != nil {
+		return nil, errors.FromAtomix(err)
+	}
+
+	terminations := make([]Termination, 0)
+	for entry := range mapCh {
+		if term, err := decodeObject(entry); err == nil {
+			terminations = append(terminations, *term)
+		} else {
+			s.quarantine.Quarantine(ctx, entry, err)
+		}
+	}
+
+	// A canceled context ends the entries stream early, leaving the list incomplete
+	if err := ctx.Err(); err != nil {
+		return nil, errors.FromGRPC(status.FromContextError(err).Err())
+	}
+	return terminations, nil
+}
+
+func (s *atomixStore) Watch(ctx context.Context, ch chan<- Event, opts ...WatchOption) error {
+	watchOpts := make([]_map.WatchOption, 0)
+	for _, opt := range opts {
+		watchOpts = opt.apply(watchOpts)
+	}
+
+	mapCh := make(chan *_map.Event)
+	if err := s.terminations.Watch(ctx, mapCh, watchOpts...); err != nil {
+		return errors.FromAtomix(err)
+	}
+
+	go func() {
+		defer close(ch)
+		for event := range mapCh {
+			if term, err := decodeObject(event.Entry); err == nil {
+				var eventType EventType
+				switch event.Type {
+				case _map.EventNone:
+					eventType = EventNone
+				case _map.EventInserted:
+					eventType = EventCreated
+				case _map.EventUpdated:
+					eventType = EventUpdated
+				case _map.EventRemoved:
+					eventType = EventRemoved
+				}
+				// Once canceled, keep draining the watch until it closes without blocking on the consumer
+				select {
+				case ch <- Event{
+					Type:        eventType,
+					Termination: *term,
+				}:
+				case <-ctx.Done():
+				}
+			} else if event.Type != _map.EventRemoved {
+				// Removed entries are already gone, including those moved to the quarantine
+				s.quarantine.Quarantine(ctx, event.Entry, err)
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *atomixStore) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := s.terminations.Close(ctx)
+	_ = s.quarantine.Close()
+	return err
+}
+
+func decodeObject(entry *_map.Entry) (*Termination, error) {
+	term := &Termination{}
+	value, err := valueSchema.Decode(entry.Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(value, term); err != nil {
+		return nil, errors.NewInvalid(err.Error())
+	}
+	term.ID = epapi.ID(entry.Key)
+	term.Revision = Revision(entry.Version)
+	return term, nil
+}
+
+func decodeValue(value []byte) error {
+	_, err := decodeObject(&_map.Entry{Value: value})
+	return err
+}
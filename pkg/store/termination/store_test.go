@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package termination
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	"github.com/onosproject/onos-lib-go/pkg/atomix"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTerminationStore(t *testing.T) {
+	_, address := atomix.StartLocalNode()
+
+	store1, err := newLocalStore(address)
+	assert.NoError(t, err)
+	defer store1.Close()
+
+	store2, err := newLocalStore(address)
+	assert.NoError(t, err)
+	defer store2.Close()
+
+	ch := make(chan Event)
+	err = store2.Watch(context.Background(), ch)
+	assert.NoError(t, err)
+
+	term1 := &Termination{
+		ID:    "e2t-1",
+		Nodes: []string{"e2node-1"},
+	}
+
+	// Create a new termination
+	err = store1.Create(context.TODO(), term1)
+	assert.NoError(t, err)
+	assert.NotEqual(t, Revision(0), term1.Revision)
+
+	// Get the termination from the other store
+	term, err := store2.Get(context.TODO(), "e2t-1")
+	assert.NoError(t, err)
+	assert.Equal(t, epapi.ID("e2t-1"), term.ID)
+	assert.True(t, term.HasNode("e2node-1"))
+	assert.False(t, term.HasNode("e2node-2"))
+
+	event := nextEvent(t, ch)
+	assert.Equal(t, EventCreated, event.Type)
+	assert.Equal(t, epapi.ID("e2t-1"), event.Termination.ID)
+
+	// Re-home an E2 node to the termination
+	revision := term.Revision
+	term.Nodes = []string{"e2node-1", "e2node-2"}
+	err = store2.Update(context.TODO(), term)
+	assert.NoError(t, err)
+	assert.NotEqual(t, revision, term.Revision)
+
+	event = nextEvent(t, ch)
+	assert.Equal(t, EventUpdated, event.Type)
+	assert.True(t, event.Termination.HasNode("e2node-2"))
+
+	// Verify that concurrent updates fail
+	term1.Nodes = nil
+	err = store1.Update(context.TODO(), term1)
+	assert.Error(t, err)
+	assert.True(t, errors.IsConflict(err))
+
+	// List the terminations
+	terms, err := store1.List(context.TODO())
+	assert.NoError(t, err)
+	assert.Len(t, terms, 1)
+
+	// Delete the termination
+	err = store1.Delete(context.TODO(), term.ID)
+	assert.NoError(t, err)
+	term, err = store2.Get(context.TODO(), "e2t-1")
+	assert.Error(t, err)
+	assert.True(t, errors.IsNotFound(err))
+	assert.Nil(t, term)
+
+	event = nextEvent(t, ch)
+	assert.Equal(t, EventRemoved, event.Type)
+}
+
+func nextEvent(t *testing.T, ch chan Event) Event {
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(5 * time.Second):
+		t.FailNow()
+	}
+	return Event{}
+}
@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package termination
+
+import (
+	"time"
+
+	epapi "github.com/onosproject/onos-api/go/onos/e2sub/endpoint"
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+)
+
+// Revision is a termination revision
+type Revision uint64
+
+// Termination is the onos-e2sub specific state associated with a TerminationEndpoint
+type Termination struct {
+	// ID is the identifier of the TerminationEndpoint the state belongs to
+	ID epapi.ID `json:"-"`
+	// Revision is the revision of the termination
+	Revision Revision `json:"-"`
+	// Nodes is the set of E2 nodes connected to the termination
+	// A nil set indicates the termination does not report its E2 nodes.
+	Nodes []string `json:"nodes"`
+	// Labels are arbitrary labels used to select the termination for placement
+	Labels map[string]string `json:"labels,omitempty"`
+	// Zone is the zone in which the termination is deployed
+	Zone string `json:"zone,omitempty"`
+	// ServiceModels are the service models supported by the termination
+	// An empty set indicates the termination supports all service models.
+	ServiceModels []ServiceModel `json:"serviceModels,omitempty"`
+	// Capacity is the maximum number of open subscription tasks the termination accepts
+	// A zero capacity indicates the termination's capacity is unlimited.
+	Capacity int `json:"capacity,omitempty"`
+	// Cordoned indicates no new subscriptions may be placed on the termination
+	Cordoned bool `json:"cordoned,omitempty"`
+	// Drain is the progress of draining the termination's subscriptions
+	Drain *Drain `json:"drain,omitempty"`
+	// Health is the health of the termination observed by probes
+	// A nil health indicates the termination has not been probed.
+	Health *Health `json:"health,omitempty"`
+	// Discovered indicates the termination was registered by Kubernetes discovery
+	Discovered bool `json:"discovered,omitempty"`
+}
+
+// HealthStatus is the health status of a termination
+type HealthStatus string
+
+const (
+	// HealthUnknown indicates the failure and success thresholds have not been reached
+	HealthUnknown HealthStatus = ""
+	// HealthHealthy indicates the termination is serving
+	HealthHealthy HealthStatus = "Healthy"
+	// HealthUnhealthy indicates the termination is not serving
+	HealthUnhealthy HealthStatus = "Unhealthy"
+)
+
+// Health is the health of a termination observed by probes
+type Health struct {
+	// Status is the health status of the termination
+	Status HealthStatus `json:"status,omitempty"`
+	// Failures is the number of consecutive failed probes
+	Failures int `json:"failures,omitempty"`
+	// Successes is the number of consecutive successful probes
+	Successes int `json:"successes,omitempty"`
+	// Reason is the reason the last failed probe failed
+	Reason string `json:"reason,omitempty"`
+	// Updated is the time at which the health last changed
+	Updated time.Time `json:"updated"`
+}
+
+// Drain is the progress of migrating the subscriptions on a termination to other terminations
+type Drain struct {
+	// Started is the time at which the drain was started
+	Started time.Time `json:"started"`
+	// Evicted is the time at which the last batch of subscriptions was evicted from the termination
+	Evicted time.Time `json:"evicted,omitempty"`
+	// Completed is the time at which the last subscription was migrated off the termination
+	Completed time.Time `json:"completed,omitempty"`
+	// Total is the number of subscription tasks on the termination when the drain started
+	Total int `json:"total"`
+	// Remaining is the number of subscription tasks remaining on the termination
+	Remaining int `json:"remaining"`
+}
+
+// ServiceModel is a service model supported by a termination
+type ServiceModel struct {
+	// Name is the service model name
+	Name string `json:"name"`
+	// Version is the service model version; if empty all versions are supported
+	Version string `json:"version,omitempty"`
+}
+
+// String returns the service model as name[/version]
+func (m ServiceModel) String() string {
+	if m.Version == "" {
+		return m.Name
+	}
+	return m.Name + "/" + m.Version
+}
+
+// SupportsServiceModel returns whether the termination supports the given service model
+func (t *Termination) SupportsServiceModel(model subapi.ServiceModel) bool {
+	if len(t.ServiceModels) == 0 {
+		return true
+	}
+	for _, supported := range t.ServiceModels {
+		if supported.Name == string(model.Name) && (supported.Version == "" || supported.Version == string(model.Version)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches returns whether the termination is in the given zone and has the given labels
+// An empty zone matches any zone.
+func (t *Termination) Matches(zone string, labels map[string]string) bool {
+	if zone != "" && t.Zone != zone {
+		return false
+	}
+	for key, value := range labels {
+		if t.Labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// IsHealthy returns whether the termination may be used for placement
+// Terminations that have not been probed or whose health is unknown are considered healthy.
+func (t *Termination) IsHealthy() bool {
+	return t.Health == nil || t.Health.Status != HealthUnhealthy
+}
+
+// HasNode returns whether the given E2 node is connected to the termination
+func (t *Termination) HasNode(nodeID string) bool {
+	for _, node := range t.Nodes {
+		if node == nodeID {
+			return true
+		}
+	}
+	return false
+}
+
+// EventType is a termination event type
+type EventType int
+
+const (
+	// EventNone indicates a replayed termination
+	EventNone EventType = iota
+	// EventCreated indicates a termination was created
+	EventCreated
+	// EventUpdated indicates a termination was updated
+	EventUpdated
+	// EventRemoved indicates a termination was removed
+	EventRemoved
+)
+
+// Event is a termination event
+type Event struct {
+	Type        EventType
+	Termination Termination
+}
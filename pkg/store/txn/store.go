@@ -0,0 +1,189 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package txn
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/atomix/go-client/pkg/client/util/net"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"google.golang.org/grpc/status"
+
+	_map "github.com/atomix/go-client/pkg/client/map"
+	"github.com/atomix/go-client/pkg/client/primitive"
+	"github.com/onosproject/onos-e2sub/pkg/store/driver"
+	"github.com/onosproject/onos-e2sub/pkg/store/quarantine"
+	"github.com/onosproject/onos-e2sub/pkg/store/schema"
+	"github.com/onosproject/onos-lib-go/pkg/atomix"
+)
+
+var log = logging.GetLogger("store", "txn")
+
+// valueSchema is the schema of the stored transactions
+var valueSchema = schema.NewSchema("Transaction")
+
+// NewAtomixStore returns a new persistent Store
+func NewAtomixStore() (Store, error) {
+	transactions, err := driver.GetMap(context.Background(), "transactions")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := schema.Migrate(context.Background(), transactions, valueSchema); err != nil {
+		return nil, err
+	}
+
+	quarantined, err := quarantine.NewAtomixStore()
+	if err != nil {
+		return nil, err
+	}
+
+	return &atomixStore{
+		transactions: transactions,
+		quarantine:   quarantine.NewSource("transactions", transactions, quarantined, decodeValue),
+	}, nil
+}
+
+// NewLocalStore returns a new local transaction store
+func NewLocalStore() (Store, error) {
+	_, address := atomix.StartLocalNode()
+	return newLocalStore(address)
+}
+
+// newLocalStore creates a new local transaction store
+func newLocalStore(address net.Address) (Store, error) {
+	name := primitive.Name{
+		Namespace: "local",
+		Name:      "transactions",
+	}
+
+	session, err := primitive.NewSession(context.TODO(), primitive.Partition{ID: 1, Address: address})
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := _map.New(context.Background(), name, []*primitive.Session{session})
+	if err != nil {
+		return nil, err
+	}
+
+	quarantined, err := quarantine.NewLocalStoreAt(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &atomixStore{
+		transactions: transactions,
+		quarantine:   quarantine.NewSource("transactions", transactions, quarantined, decodeValue),
+	}, nil
+}
+
+// Store is a journal of the transactions being committed
+type Store interface {
+	io.Closer
+
+	// Create records a committed transaction in the journal
+	Create(ctx context.Context, txn *Transaction) error
+
+	// Delete removes an applied transaction from the journal
+	Delete(ctx context.Context, id ID) error
+
+	// List lists the transactions in the journal
+	List(ctx context.Context) ([]Transaction, error)
+}
+
+// atomixStore is the implementation of the transaction Store
+type atomixStore struct {
+	transactions _map.Map
+	quarantine   *quarantine.Source
+}
+
+func (s *atomixStore) Create(ctx context.Context, txn *Transaction) error {
+	if txn.ID == "" {
+		return errors.NewInvalid("ID cannot be empty")
+	}
+	if txn.Committed.IsZero() {
+		txn.Committed = time.Now()
+	}
+
+	log.Infof("Creating Transaction %s", txn.ID)
+	bytes, err := json.Marshal(txn)
+	if err != nil {
+		log.Errorf("Failed to create Transaction %s: %s", txn.ID, err)
+		return errors.NewInvalid(err.Error())
+	}
+
+	// Create the transaction in the map only if it does not already exist
+	if _, err := s.transactions.Put(ctx, string(txn.ID), valueSchema.Encode(bytes), driver.IfNotSet()); err != nil {
+		log.Errorf("Failed to create Transaction %s: %s", txn.ID, err)
+		return errors.FromAtomix(err)
+	}
+	return nil
+}
+
+func (s *atomixStore) Delete(ctx context.Context, id ID) error {
+	if id == "" {
+		return errors.NewInvalid("ID cannot be empty")
+	}
+
+	log.Infof("Deleting Transaction %s", id)
+	if _, err := s.transactions.Remove(ctx, string(id)); err != nil {
+		log.Errorf("Failed to delete Transaction %s: %s", id, err)
+		return errors.FromAtomix(err)
+	}
+	return nil
+}
+
+func (s *atomixStore) List(ctx context.Context) ([]Transaction, error) {
+	mapCh := make(chan *_map.Entry)
+	if err := s.transactions.Entries(ctx, mapCh); err != nil {
+		return nil, errors.FromAtomix(err)
+	}
+
+	txns := make([]Transaction, 0)
+	for entry := range mapCh {
+		if txn, err := decodeObject(entry); err == nil {
+			txns = append(txns, *txn)
+		} else {
+			s.quarantine.Quarantine(ctx, entry, err)
+		}
+	}
+
+	// A canceled context ends the entries stream early, leaving the list incomplete
+	if err := ctx.Err(); err != nil {
+		return nil, errors.FromGRPC(status.FromContextError(err).Err())
+	}
+	return txns, nil
+}
+
+func (s *atomixStore) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := s.transactions.Close(ctx)
+	_ = s.quarantine.Close()
+	return err
+}
+
+func decodeObject(entry *_map.Entry) (*Transaction, error) {
+	txn := &Transaction{}
+	value, err := valueSchema.Decode(entry.Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(value, txn); err != nil {
+		return nil, errors.NewInvalid(err.Error())
+	}
+	txn.ID = ID(entry.Key)
+	return txn, nil
+}
+
+func decodeValue(value []byte) error {
+	_, err := decodeObject(&_map.Entry{Value: value})
+	return err
+}
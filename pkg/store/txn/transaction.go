@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package txn
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ID is a transaction identifier
+type ID string
+
+// Kind is the kind of object changed by an operation
+type Kind string
+
+const (
+	// KindSubscription is a subscription
+	KindSubscription Kind = "Subscription"
+	// KindTask is a subscription task
+	KindTask Kind = "SubscriptionTask"
+	// KindMetadata is subscription metadata
+	KindMetadata Kind = "Metadata"
+)
+
+// OpType is the type of an operation
+type OpType string
+
+const (
+	// OpCreate creates an object that must not exist
+	OpCreate OpType = "Create"
+	// OpUpdate updates an object that must be at the operation's revision
+	OpUpdate OpType = "Update"
+	// OpDelete deletes an object that must be at the operation's revision
+	OpDelete OpType = "Delete"
+)
+
+// Transaction is a set of changes to subscriptions, tasks and subscription metadata committed together
+type Transaction struct {
+	// ID is the transaction identifier
+	ID ID `json:"id"`
+	// Committed is the time at which the transaction was committed
+	Committed time.Time `json:"committed"`
+	// Owner is the instance that committed the transaction and is applying it
+	Owner *Owner `json:"owner,omitempty"`
+	// Ops are the operations of the transaction in the order in which they're applied
+	Ops []Op `json:"ops"`
+}
+
+// Owner identifies a run of the instance committing transactions
+type Owner struct {
+	// Name is the name of the instance, e.g. its pod name
+	Name string `json:"name"`
+	// Incarnation distinguishes the runs of an instance; it changes each time the instance starts
+	Incarnation string `json:"incarnation"`
+}
+
+// Op is an operation on an object in a transaction
+type Op struct {
+	// Kind is the kind of object
+	Kind Kind `json:"kind"`
+	// Type is the type of operation
+	Type OpType `json:"type"`
+	// ID is the identifier of the object
+	ID string `json:"id"`
+	// Revision is the revision of the object the operation was validated against
+	Revision uint64 `json:"revision,omitempty"`
+	// Object is the object to create or update
+	Object json.RawMessage `json:"object,omitempty"`
+	// Before is the object to be updated or deleted as it was validated, used to roll back the operation
+	Before json.RawMessage `json:"before,omitempty"`
+}
@@ -0,0 +1,687 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package txn
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	taskapi "github.com/onosproject/onos-api/go/onos/e2sub/task"
+	"github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	"github.com/onosproject/onos-e2sub/pkg/store/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/store/task"
+	"github.com/onosproject/onos-lib-go/pkg/env"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// Stores are the stores whose objects are changed by transactions
+type Stores struct {
+	Subscriptions subscription.Store
+	Tasks         task.Store
+	Metadata      metadata.Store
+}
+
+// self is the owner of the transactions committed by this process
+var self = &Owner{
+	Name:        instanceName(),
+	Incarnation: string(newID()),
+}
+
+// instanceName returns the name of this instance: its pod name or else its host name
+func instanceName() string {
+	if name := env.GetPodName(); name != "" {
+		return name
+	}
+	name, _ := os.Hostname()
+	return name
+}
+
+// NewTransactor returns a Transactor committing transactions to the given stores
+// Committed transactions are recorded in the journal until they have been applied, so that a
+// transaction interrupted by a crash is completed by Recover rather than left partially applied.
+func NewTransactor(journal Store, stores Stores) *Transactor {
+	return &Transactor{
+		journal: journal,
+		stores:  stores,
+		owner:   self,
+		pending: make(map[ID]*Transaction),
+	}
+}
+
+// Transactor commits transactions across the subscription, task and metadata stores
+type Transactor struct {
+	journal Store
+	stores  Stores
+	// owner is recorded in the transactions committed by the transactor
+	owner *Owner
+	// pending are the committed transactions that failed to be applied
+	pending map[ID]*Transaction
+	mu      sync.Mutex
+}
+
+// Begin begins a new transaction
+func (t *Transactor) Begin() *Txn {
+	return &Txn{
+		transactor: t,
+	}
+}
+
+// Recover completes the transactions in the journal that were committed but not applied, e.g.
+// because the instance committing them crashed. Transactions committed by an earlier run of this
+// instance are recovered at once, since that run is gone. The transactions of other instances, and
+// those without an owner, are only recovered once they were committed at least timeout ago, so
+// that the transactions those instances are still applying are left to them. A transaction that
+// can no longer be completed is rolled back.
+// It returns the number of recovered transactions.
+func (t *Transactor) Recover(ctx context.Context, timeout time.Duration) (int, error) {
+	txns, err := t.journal.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sort.Slice(txns, func(i, j int) bool {
+		return txns[i].Committed.Before(txns[j].Committed)
+	})
+
+	recovered := 0
+	for i, txn := range txns {
+		if !t.isAbandoned(&txn, timeout) {
+			log.Debugf("Not recovering Transaction %s committed at %s by %+v", txn.ID, txn.Committed, txn.Owner)
+			continue
+		}
+		log.Infof("Recovering Transaction %s committed at %s by %+v", txn.ID, txn.Committed, txn.Owner)
+		if err := t.apply(ctx, &txns[i]); err != nil && !errors.IsConflict(err) {
+			return recovered, err
+		}
+		recovered++
+	}
+	return recovered, nil
+}
+
+// isAbandoned returns whether a journaled transaction was abandoned by the instance that committed it
+func (t *Transactor) isAbandoned(txn *Transaction, timeout time.Duration) bool {
+	if txn.Owner != nil && txn.Owner.Name == t.owner.Name && txn.Owner.Incarnation != t.owner.Incarnation {
+		return true
+	}
+	return time.Since(txn.Committed) >= timeout
+}
+
+// StartRecovery starts recovering the transactions abandoned by other instances, e.g. when they
+// crash, every timeout
+func (t *Transactor) StartRecovery(ctx context.Context, timeout time.Duration) {
+	go func() {
+		ticker := time.NewTicker(timeout)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				recoverCtx, cancel := context.WithTimeout(ctx, timeout)
+				if _, err := t.Recover(recoverCtx, timeout); err != nil {
+					log.Warnf("Failed to recover transactions: %s", err)
+				}
+				cancel()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// commit validates, journals and applies the operations of a transaction
+func (t *Transactor) commit(ctx context.Context, ops []Op) error {
+	// Complete the transactions that failed to be applied first so that they cannot later
+	// overwrite the changes of this one
+	if err := t.applyPending(ctx); err != nil {
+		return err
+	}
+
+	if err := t.validate(ctx, ops); err != nil {
+		return err
+	}
+
+	txn := &Transaction{
+		ID:    newID(),
+		Owner: t.owner,
+		Ops:   ops,
+	}
+	if err := t.journal.Create(ctx, txn); err != nil {
+		return err
+	}
+	return t.apply(ctx, txn)
+}
+
+// applyPending applies the committed transactions that failed to be applied
+func (t *Transactor) applyPending(ctx context.Context) error {
+	t.mu.Lock()
+	pending := make([]*Transaction, 0, len(t.pending))
+	for _, txn := range t.pending {
+		pending = append(pending, txn)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].Committed.Before(pending[j].Committed)
+	})
+	for _, txn := range pending {
+		log.Infof("Retrying Transaction %s", txn.ID)
+		if err := t.apply(ctx, txn); err != nil && !errors.IsConflict(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate verifies that no object changed by the operations has changed since it was read
+// The current state of each updated or deleted object is recorded in its operation so that the
+// transaction can be rolled back.
+func (t *Transactor) validate(ctx context.Context, ops []Op) error {
+	changed := make(map[Kind]map[string]bool)
+	for _, op := range ops {
+		if changed[op.Kind] == nil {
+			changed[op.Kind] = make(map[string]bool)
+		}
+		if changed[op.Kind][op.ID] {
+			return errors.NewInvalid("%s %s is changed more than once", op.Kind, op.ID)
+		}
+		changed[op.Kind][op.ID] = true
+	}
+
+	objects, err := t.read(ctx, changed)
+	if err != nil {
+		return err
+	}
+	for i, op := range ops {
+		current, ok := objects[op.Kind][op.ID]
+		switch op.Type {
+		case OpCreate:
+			if ok {
+				return errors.NewAlreadyExists("%s %s already exists", op.Kind, op.ID)
+			}
+		case OpUpdate, OpDelete:
+			if !ok {
+				return errors.NewNotFound("%s %s not found", op.Kind, op.ID)
+			}
+			if current.revision != op.Revision {
+				return errors.NewConflict("%s %s has changed since revision %d", op.Kind, op.ID, op.Revision)
+			}
+			ops[i].Before = current.value
+		default:
+			return errors.NewInvalid("unknown operation %s", op.Type)
+		}
+	}
+	return nil
+}
+
+// object is the current revision of an object and the object without its revision
+type object struct {
+	revision uint64
+	value    json.RawMessage
+}
+
+// read reads the current state of the given objects, omitting those that do not exist
+// The objects of a kind are read with a single List when more than one of them is changed, so
+// that a large transaction is validated without reading each of its objects in turn.
+func (t *Transactor) read(ctx context.Context, ids map[Kind]map[string]bool) (map[Kind]map[string]*object, error) {
+	objects := make(map[Kind]map[string]*object)
+	for kind, kindIDs := range ids {
+		objects[kind] = make(map[string]*object)
+		if len(kindIDs) > 1 {
+			all, err := t.listObjects(ctx, kind)
+			if err != nil {
+				return nil, err
+			}
+			for id := range kindIDs {
+				if obj, ok := all[id]; ok {
+					objects[kind][id] = obj
+				}
+			}
+			continue
+		}
+		for id := range kindIDs {
+			revision, value, err := t.getObject(ctx, kind, id)
+			if err != nil {
+				if errors.IsNotFound(err) {
+					continue
+				}
+				return nil, err
+			}
+			objects[kind][id] = &object{revision: revision, value: value}
+		}
+	}
+	return objects, nil
+}
+
+// listObjects returns the current state of all objects of a kind
+func (t *Transactor) listObjects(ctx context.Context, kind Kind) (map[string]*object, error) {
+	objects := make(map[string]*object)
+	add := func(id string, revision uint64, value interface{}) error {
+		bytes, err := json.Marshal(value)
+		if err != nil {
+			return errors.NewInvalid(err.Error())
+		}
+		objects[id] = &object{revision: revision, value: bytes}
+		return nil
+	}
+	switch kind {
+	case KindSubscription:
+		subs, err := t.stores.Subscriptions.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for i := range subs {
+			sub := &subs[i]
+			revision := uint64(sub.Revision)
+			sub.Revision = 0
+			if err := add(string(sub.ID), revision, sub); err != nil {
+				return nil, err
+			}
+		}
+	case KindTask:
+		tasks, err := t.stores.Tasks.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for i := range tasks {
+			tk := &tasks[i]
+			revision := uint64(tk.Revision)
+			tk.Revision = 0
+			if err := add(string(tk.ID), revision, tk); err != nil {
+				return nil, err
+			}
+		}
+	case KindMetadata:
+		metas, err := t.stores.Metadata.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for i := range metas {
+			meta := &metas[i]
+			revision := uint64(meta.Revision)
+			meta.Revision = 0
+			if err := add(string(meta.ID), revision, meta); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, errors.NewInvalid("unknown kind %s", kind)
+	}
+	return objects, nil
+}
+
+// getObject returns the current revision of an object and the object without its revision
+func (t *Transactor) getObject(ctx context.Context, kind Kind, id string) (uint64, json.RawMessage, error) {
+	var revision uint64
+	var object interface{}
+	switch kind {
+	case KindSubscription:
+		sub, err := t.stores.Subscriptions.Get(ctx, subapi.ID(id))
+		if err != nil {
+			return 0, nil, err
+		}
+		revision = uint64(sub.Revision)
+		sub.Revision = 0
+		object = sub
+	case KindTask:
+		task, err := t.stores.Tasks.Get(ctx, taskapi.ID(id))
+		if err != nil {
+			return 0, nil, err
+		}
+		revision = uint64(task.Revision)
+		task.Revision = 0
+		object = task
+	case KindMetadata:
+		meta, err := t.stores.Metadata.Get(ctx, subapi.ID(id))
+		if err != nil {
+			return 0, nil, err
+		}
+		revision = uint64(meta.Revision)
+		meta.Revision = 0
+		object = meta
+	default:
+		return 0, nil, errors.NewInvalid("unknown kind %s", kind)
+	}
+	bytes, err := json.Marshal(object)
+	if err != nil {
+		return 0, nil, errors.NewInvalid(err.Error())
+	}
+	return revision, bytes, nil
+}
+
+// apply applies the operations of a journaled transaction and removes it from the journal
+// An operation found already applied, e.g. by an earlier attempt to apply the transaction, is
+// skipped. If an operation cannot be applied because another writer changed its object after the
+// transaction was validated, the operations applied so far are reverted and a Conflict error is
+// returned, so that a transaction is either applied entirely or not at all. If an operation fails
+// otherwise, the transaction is left to be retried.
+func (t *Transactor) apply(ctx context.Context, txn *Transaction) error {
+	for i, op := range txn.Ops {
+		err := t.applyOp(ctx, op)
+		if err == nil {
+			continue
+		}
+		if !isSuperseded(err) {
+			log.Warnf("Failed to apply Transaction %s: %s", txn.ID, err)
+			t.setPending(txn, true)
+			return err
+		}
+
+		applied, _, aerr := t.isApplied(ctx, op)
+		if aerr != nil {
+			log.Warnf("Failed to apply Transaction %s: %s", txn.ID, aerr)
+			t.setPending(txn, true)
+			return aerr
+		}
+		if applied {
+			continue
+		}
+
+		log.Infof("Rolling back Transaction %s: %s of %s %s failed: %s", txn.ID, op.Type, op.Kind, op.ID, err)
+		if err := t.rollback(ctx, txn, i); err != nil {
+			log.Warnf("Failed to roll back Transaction %s: %s", txn.ID, err)
+			t.setPending(txn, true)
+			return err
+		}
+		if err := t.finish(ctx, txn); err != nil {
+			return err
+		}
+		return errors.NewConflict("Transaction %s was rolled back: %s %s was changed by another writer", txn.ID, op.Kind, op.ID)
+	}
+	return t.finish(ctx, txn)
+}
+
+// rollback reverts the first n operations of a transaction in reverse order
+// An operation whose object was changed again by another writer since it was applied is left as
+// that writer changed it.
+func (t *Transactor) rollback(ctx context.Context, txn *Transaction, n int) error {
+	for i := n - 1; i >= 0; i-- {
+		op := txn.Ops[i]
+		applied, revision, err := t.isApplied(ctx, op)
+		if err != nil {
+			return err
+		}
+		if !applied {
+			log.Infof("Not reverting %s of %s %s in Transaction %s: the object has changed", op.Type, op.Kind, op.ID, txn.ID)
+			continue
+		}
+
+		if op.Type != OpCreate && op.Before == nil {
+			log.Errorf("Cannot revert %s of %s %s in Transaction %s: the object was not recorded", op.Type, op.Kind, op.ID, txn.ID)
+			continue
+		}
+		var revert Op
+		switch op.Type {
+		case OpCreate:
+			revert = Op{Kind: op.Kind, Type: OpDelete, ID: op.ID, Revision: revision}
+		case OpUpdate:
+			revert = Op{Kind: op.Kind, Type: OpUpdate, ID: op.ID, Revision: revision, Object: op.Before}
+		case OpDelete:
+			revert = Op{Kind: op.Kind, Type: OpCreate, ID: op.ID, Object: op.Before}
+		}
+		if err := t.applyOp(ctx, revert); err != nil {
+			if !isSuperseded(err) {
+				return err
+			}
+			log.Infof("Not reverting %s of %s %s in Transaction %s: %s", op.Type, op.Kind, op.ID, txn.ID, err)
+		}
+	}
+	return nil
+}
+
+// isApplied returns whether the current state of an operation's object is the state the operation
+// leaves it in, along with the current revision of the object
+func (t *Transactor) isApplied(ctx context.Context, op Op) (bool, uint64, error) {
+	revision, current, err := t.getObject(ctx, op.Kind, op.ID)
+	if errors.IsNotFound(err) {
+		return op.Type == OpDelete, 0, nil
+	} else if err != nil {
+		return false, 0, err
+	}
+	if op.Type == OpDelete {
+		return false, revision, nil
+	}
+	object, err := t.canonical(op.Kind, op.Object)
+	if err != nil {
+		// An operation whose object cannot be decoded can never have been applied
+		return false, revision, nil
+	}
+	return bytes.Equal(object, current), revision, nil
+}
+
+// canonical returns an operation's object as it is read back from its store, without its revision
+func (t *Transactor) canonical(kind Kind, object json.RawMessage) (json.RawMessage, error) {
+	var value interface{}
+	switch kind {
+	case KindSubscription:
+		sub := &subapi.Subscription{}
+		if err := json.Unmarshal(object, sub); err != nil {
+			return nil, err
+		}
+		data, err := proto.Marshal(sub)
+		if err != nil {
+			return nil, err
+		}
+		sub = &subapi.Subscription{}
+		if err := proto.Unmarshal(data, sub); err != nil {
+			return nil, err
+		}
+		sub.Revision = 0
+		value = sub
+	case KindTask:
+		tk := &taskapi.SubscriptionTask{}
+		if err := json.Unmarshal(object, tk); err != nil {
+			return nil, err
+		}
+		data, err := proto.Marshal(tk)
+		if err != nil {
+			return nil, err
+		}
+		tk = &taskapi.SubscriptionTask{}
+		if err := proto.Unmarshal(data, tk); err != nil {
+			return nil, err
+		}
+		tk.Revision = 0
+		value = tk
+	case KindMetadata:
+		meta := &metadata.Metadata{}
+		if err := json.Unmarshal(object, meta); err != nil {
+			return nil, err
+		}
+		meta.Revision = 0
+		value = meta
+	default:
+		return nil, errors.NewInvalid("unknown kind %s", kind)
+	}
+	return json.Marshal(value)
+}
+
+// finish removes an applied or rolled back transaction from the journal
+func (t *Transactor) finish(ctx context.Context, txn *Transaction) error {
+	if err := t.journal.Delete(ctx, txn.ID); err != nil && !errors.IsNotFound(err) {
+		log.Warnf("Failed to apply Transaction %s: %s", txn.ID, err)
+		t.setPending(txn, true)
+		return err
+	}
+	t.setPending(txn, false)
+	return nil
+}
+
+// isSuperseded returns whether an operation failed because its object is not in the expected state
+func isSuperseded(err error) bool {
+	return errors.IsConflict(err) || errors.IsAlreadyExists(err) || errors.IsNotFound(err) || errors.IsInvalid(err)
+}
+
+func (t *Transactor) setPending(txn *Transaction, pending bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if pending {
+		t.pending[txn.ID] = txn
+	} else {
+		delete(t.pending, txn.ID)
+	}
+}
+
+// applyOp applies an operation to its store
+func (t *Transactor) applyOp(ctx context.Context, op Op) error {
+	switch op.Kind {
+	case KindSubscription:
+		if op.Type == OpDelete {
+			return t.stores.Subscriptions.Delete(ctx, subapi.ID(op.ID), subscription.IfRevision(subapi.Revision(op.Revision)))
+		}
+		sub := &subapi.Subscription{}
+		if err := json.Unmarshal(op.Object, sub); err != nil {
+			return errors.NewInvalid(err.Error())
+		}
+		sub.ID = subapi.ID(op.ID)
+		sub.Revision = subapi.Revision(op.Revision)
+		if op.Type == OpCreate {
+			return t.stores.Subscriptions.Create(ctx, sub)
+		}
+		return t.stores.Subscriptions.Update(ctx, sub)
+	case KindTask:
+		if op.Type == OpDelete {
+			return t.stores.Tasks.Delete(ctx, taskapi.ID(op.ID), task.IfRevision(taskapi.Revision(op.Revision)))
+		}
+		tk := &taskapi.SubscriptionTask{}
+		if err := json.Unmarshal(op.Object, tk); err != nil {
+			return errors.NewInvalid(err.Error())
+		}
+		tk.ID = taskapi.ID(op.ID)
+		tk.Revision = taskapi.Revision(op.Revision)
+		if op.Type == OpCreate {
+			return t.stores.Tasks.Create(ctx, tk)
+		}
+		return t.stores.Tasks.Update(ctx, tk)
+	case KindMetadata:
+		if op.Type == OpDelete {
+			return t.stores.Metadata.Delete(ctx, subapi.ID(op.ID), metadata.IfRevision(metadata.Revision(op.Revision)))
+		}
+		meta := &metadata.Metadata{}
+		if err := json.Unmarshal(op.Object, meta); err != nil {
+			return errors.NewInvalid(err.Error())
+		}
+		meta.ID = subapi.ID(op.ID)
+		meta.Revision = metadata.Revision(op.Revision)
+		if op.Type == OpCreate {
+			return t.stores.Metadata.Create(ctx, meta)
+		}
+		return t.stores.Metadata.Update(ctx, meta)
+	}
+	return errors.NewInvalid("unknown kind %s", op.Kind)
+}
+
+// Txn is a transaction changing subscriptions, tasks and subscription metadata
+// Updates and deletes are conditional on the revision of the given objects.
+type Txn struct {
+	transactor *Transactor
+	ops        []Op
+	err        error
+}
+
+// CreateSubscription creates a subscription in the transaction
+func (t *Txn) CreateSubscription(sub *subapi.Subscription) {
+	t.add(KindSubscription, OpCreate, string(sub.ID), 0, sub)
+}
+
+// UpdateSubscription updates a subscription in the transaction
+func (t *Txn) UpdateSubscription(sub *subapi.Subscription) {
+	t.add(KindSubscription, OpUpdate, string(sub.ID), uint64(sub.Revision), sub)
+}
+
+// DeleteSubscription deletes a subscription in the transaction
+func (t *Txn) DeleteSubscription(sub *subapi.Subscription) {
+	t.add(KindSubscription, OpDelete, string(sub.ID), uint64(sub.Revision), nil)
+}
+
+// CreateTask creates a subscription task in the transaction
+func (t *Txn) CreateTask(task *taskapi.SubscriptionTask) {
+	t.add(KindTask, OpCreate, string(task.ID), 0, task)
+}
+
+// UpdateTask updates a subscription task in the transaction
+func (t *Txn) UpdateTask(task *taskapi.SubscriptionTask) {
+	t.add(KindTask, OpUpdate, string(task.ID), uint64(task.Revision), task)
+}
+
+// DeleteTask deletes a subscription task in the transaction
+func (t *Txn) DeleteTask(task *taskapi.SubscriptionTask) {
+	t.add(KindTask, OpDelete, string(task.ID), uint64(task.Revision), nil)
+}
+
+// CreateMetadata creates subscription metadata in the transaction
+func (t *Txn) CreateMetadata(meta *metadata.Metadata) {
+	t.add(KindMetadata, OpCreate, string(meta.ID), 0, meta)
+}
+
+// UpdateMetadata updates subscription metadata in the transaction
+func (t *Txn) UpdateMetadata(meta *metadata.Metadata) {
+	t.add(KindMetadata, OpUpdate, string(meta.ID), uint64(meta.Revision), meta)
+}
+
+// DeleteMetadata deletes subscription metadata in the transaction
+func (t *Txn) DeleteMetadata(meta *metadata.Metadata) {
+	t.add(KindMetadata, OpDelete, string(meta.ID), uint64(meta.Revision), nil)
+}
+
+func (t *Txn) add(kind Kind, opType OpType, id string, revision uint64, object interface{}) {
+	if t.err != nil {
+		return
+	}
+	if id == "" {
+		t.err = errors.NewInvalid("ID cannot be empty")
+		return
+	}
+	if opType != OpCreate && revision == 0 {
+		t.err = errors.NewInvalid("%s %s must contain a revision", kind, id)
+		return
+	}
+
+	op := Op{
+		Kind:     kind,
+		Type:     opType,
+		ID:       id,
+		Revision: revision,
+	}
+	if object != nil {
+		bytes, err := json.Marshal(object)
+		if err != nil {
+			t.err = errors.NewInvalid(err.Error())
+			return
+		}
+		op.Object = bytes
+	}
+	t.ops = append(t.ops, op)
+}
+
+// Commit commits the transaction
+// The objects are first validated against the revisions at which they were read, failing with a
+// Conflict error without changing anything if any has changed since. The transaction is then
+// recorded in the journal and applied. If an object is changed by another writer after the
+// transaction was validated, the changes already applied are reverted and a Conflict error is
+// returned so that the caller reads the objects again; a transaction is never left partially
+// applied.
+func (t *Txn) Commit(ctx context.Context) error {
+	if t.err != nil {
+		return t.err
+	}
+	if len(t.ops) == 0 {
+		return nil
+	}
+	return t.transactor.commit(ctx, t.ops)
+}
+
+// newID returns a new random transaction ID
+func newID() ID {
+	bytes := make([]byte, 16)
+	_, _ = rand.Read(bytes)
+	return ID(hex.EncodeToString(bytes))
+}
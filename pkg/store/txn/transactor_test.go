@@ -0,0 +1,270 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package txn
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	subapi "github.com/onosproject/onos-api/go/onos/e2sub/subscription"
+	taskapi "github.com/onosproject/onos-api/go/onos/e2sub/task"
+	"github.com/onosproject/onos-e2sub/pkg/store/metadata"
+	"github.com/onosproject/onos-e2sub/pkg/store/subscription"
+	"github.com/onosproject/onos-e2sub/pkg/store/task"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTransactor(t *testing.T) (*Transactor, Store, Stores) {
+	journal, err := NewLocalStore()
+	assert.NoError(t, err)
+	subs, err := subscription.NewLocalStore()
+	assert.NoError(t, err)
+	tasks, err := task.NewLocalStore()
+	assert.NoError(t, err)
+	meta, err := metadata.NewLocalStore()
+	assert.NoError(t, err)
+	stores := Stores{
+		Subscriptions: subs,
+		Tasks:         tasks,
+		Metadata:      meta,
+	}
+	return NewTransactor(journal, stores), journal, stores
+}
+
+func closeTestTransactor(t *testing.T, journal Store, stores Stores) {
+	assert.NoError(t, journal.Close())
+	assert.NoError(t, stores.Subscriptions.Close())
+	assert.NoError(t, stores.Tasks.Close())
+	assert.NoError(t, stores.Metadata.Close())
+}
+
+func TestCommit(t *testing.T) {
+	transactor, journal, stores := newTestTransactor(t)
+	defer closeTestTransactor(t, journal, stores)
+	ctx := context.Background()
+
+	sub := &subapi.Subscription{ID: "sub-1", AppID: "app-1"}
+	assert.NoError(t, stores.Subscriptions.Create(ctx, sub))
+	task1 := &taskapi.SubscriptionTask{ID: "sub-1:e2t-1", SubscriptionID: "sub-1", EndpointID: "e2t-1"}
+	assert.NoError(t, stores.Tasks.Create(ctx, task1))
+
+	// Migrate the subscription from one endpoint to another
+	tx := transactor.Begin()
+	tx.CreateTask(&taskapi.SubscriptionTask{ID: "sub-1:e2t-2", SubscriptionID: "sub-1", EndpointID: "e2t-2"})
+	closed := *task1
+	closed.Lifecycle.Phase = taskapi.Phase_CLOSE
+	tx.UpdateTask(&closed)
+	tx.CreateMetadata(&metadata.Metadata{ID: "sub-1", Condition: metadata.ConditionNone})
+	assert.NoError(t, tx.Commit(ctx))
+
+	task2, err := stores.Tasks.Get(ctx, "sub-1:e2t-2")
+	assert.NoError(t, err)
+	assert.Equal(t, subapi.ID("sub-1"), task2.SubscriptionID)
+	updated, err := stores.Tasks.Get(ctx, task1.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, taskapi.Phase_CLOSE, updated.Lifecycle.Phase)
+	_, err = stores.Metadata.Get(ctx, "sub-1")
+	assert.NoError(t, err)
+	txns, err := journal.List(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, txns)
+
+	// Verify nothing is changed if any object changed since it was read
+	tx = transactor.Begin()
+	tx.DeleteTask(task2)
+	tx.DeleteTask(task1)
+	tx.DeleteSubscription(sub)
+	err = tx.Commit(ctx)
+	assert.True(t, errors.IsConflict(err))
+	_, err = stores.Tasks.Get(ctx, task2.ID)
+	assert.NoError(t, err)
+	_, err = stores.Subscriptions.Get(ctx, sub.ID)
+	assert.NoError(t, err)
+
+	// Verify objects cannot be created twice or changed twice in a transaction
+	tx = transactor.Begin()
+	tx.CreateTask(task2)
+	assert.True(t, errors.IsAlreadyExists(tx.Commit(ctx)))
+	tx = transactor.Begin()
+	tx.UpdateTask(task2)
+	tx.DeleteTask(task2)
+	assert.True(t, errors.IsInvalid(tx.Commit(ctx)))
+	tx = transactor.Begin()
+	tx.UpdateTask(&taskapi.SubscriptionTask{ID: "sub-1:e2t-3"})
+	assert.True(t, errors.IsInvalid(tx.Commit(ctx)))
+
+	// Delete the subscription and its tasks and metadata
+	meta, err := stores.Metadata.Get(ctx, "sub-1")
+	assert.NoError(t, err)
+	tx = transactor.Begin()
+	tx.DeleteTask(task2)
+	tx.DeleteTask(updated)
+	tx.DeleteSubscription(sub)
+	tx.DeleteMetadata(meta)
+	assert.NoError(t, tx.Commit(ctx))
+	tasks, err := stores.Tasks.List(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, tasks)
+	_, err = stores.Subscriptions.Get(ctx, sub.ID)
+	assert.True(t, errors.IsNotFound(err))
+	_, err = stores.Metadata.Get(ctx, "sub-1")
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestRecover(t *testing.T) {
+	transactor, journal, stores := newTestTransactor(t)
+	defer closeTestTransactor(t, journal, stores)
+	ctx := context.Background()
+
+	sub := &subapi.Subscription{ID: "sub-1", AppID: "app-1"}
+	assert.NoError(t, stores.Subscriptions.Create(ctx, sub))
+	task1 := &taskapi.SubscriptionTask{ID: "sub-1:e2t-1", SubscriptionID: "sub-1", EndpointID: "e2t-1"}
+	assert.NoError(t, stores.Tasks.Create(ctx, task1))
+
+	// Journal a transaction and apply only its first operation as if an earlier run of this
+	// instance crashed while committing it
+	tx := transactor.Begin()
+	closed := *task1
+	closed.Lifecycle.Phase = taskapi.Phase_CLOSE
+	tx.UpdateTask(&closed)
+	tx.CreateTask(&taskapi.SubscriptionTask{ID: "sub-1:e2t-2", SubscriptionID: "sub-1", EndpointID: "e2t-2"})
+	tx.DeleteSubscription(sub)
+	previous := &Owner{Name: self.Name, Incarnation: "previous"}
+	assert.NoError(t, journal.Create(ctx, &Transaction{ID: "txn-1", Owner: previous, Ops: tx.ops}))
+	assert.NoError(t, stores.Tasks.Update(ctx, &closed))
+
+	n, err := transactor.Recover(ctx, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// Verify the applied operation was not applied again and the others were applied
+	recovered, err := stores.Tasks.Get(ctx, task1.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, closed.Revision, recovered.Revision)
+	_, err = stores.Tasks.Get(ctx, "sub-1:e2t-2")
+	assert.NoError(t, err)
+	_, err = stores.Subscriptions.Get(ctx, sub.ID)
+	assert.True(t, errors.IsNotFound(err))
+
+	txns, err := journal.List(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, txns)
+	n, err = transactor.Recover(ctx, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+// blockingStore is a subscription store whose creates wait until released
+type blockingStore struct {
+	subscription.Store
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStore) Create(ctx context.Context, sub *subapi.Subscription) error {
+	close(s.started)
+	<-s.release
+	return s.Store.Create(ctx, sub)
+}
+
+func TestRecoverConcurrent(t *testing.T) {
+	transactor, journal, stores := newTestTransactor(t)
+	defer closeTestTransactor(t, journal, stores)
+	ctx := context.Background()
+
+	// Start committing a transaction on another instance, blocking it once it is journaled
+	blocking := &blockingStore{
+		Store:   stores.Subscriptions,
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	other := NewTransactor(journal, Stores{
+		Subscriptions: blocking,
+		Tasks:         stores.Tasks,
+		Metadata:      stores.Metadata,
+	})
+	other.owner = &Owner{Name: "other", Incarnation: "1"}
+	committed := make(chan error)
+	go func() {
+		tx := other.Begin()
+		tx.CreateMetadata(&metadata.Metadata{ID: "sub-1"})
+		tx.CreateSubscription(&subapi.Subscription{ID: "sub-1", AppID: "app-1"})
+		committed <- tx.Commit(ctx)
+	}()
+	<-blocking.started
+
+	// Verify the transaction still being applied by the other instance is not recovered
+	n, err := transactor.Recover(ctx, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	txns, err := journal.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, txns, 1)
+	assert.Equal(t, "other", txns[0].Owner.Name)
+	_, err = stores.Subscriptions.Get(ctx, "sub-1")
+	assert.True(t, errors.IsNotFound(err))
+
+	close(blocking.release)
+	assert.NoError(t, <-committed)
+	_, err = stores.Subscriptions.Get(ctx, "sub-1")
+	assert.NoError(t, err)
+	txns, err = journal.List(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, txns)
+
+	// Verify a transaction abandoned by another instance is recovered once the timeout has passed
+	tx := transactor.Begin()
+	tx.CreateSubscription(&subapi.Subscription{ID: "sub-2", AppID: "app-1"})
+	abandoned := &Transaction{ID: "txn-2", Owner: other.owner, Committed: time.Now().Add(-2 * time.Minute), Ops: tx.ops}
+	assert.NoError(t, journal.Create(ctx, abandoned))
+	n, err = transactor.Recover(ctx, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	_, err = stores.Subscriptions.Get(ctx, "sub-2")
+	assert.NoError(t, err)
+}
+
+func TestRollback(t *testing.T) {
+	transactor, journal, stores := newTestTransactor(t)
+	defer closeTestTransactor(t, journal, stores)
+	ctx := context.Background()
+
+	sub := &subapi.Subscription{ID: "sub-1", AppID: "app-1"}
+	assert.NoError(t, stores.Subscriptions.Create(ctx, sub))
+	task1 := &taskapi.SubscriptionTask{ID: "sub-1:e2t-1", SubscriptionID: "sub-1", EndpointID: "e2t-1"}
+	assert.NoError(t, stores.Tasks.Create(ctx, task1))
+
+	// Validate a transaction and change its last object before it is applied
+	tx := transactor.Begin()
+	closed := *task1
+	closed.Lifecycle.Phase = taskapi.Phase_CLOSE
+	tx.UpdateTask(&closed)
+	tx.CreateTask(&taskapi.SubscriptionTask{ID: "sub-1:e2t-2", SubscriptionID: "sub-1", EndpointID: "e2t-2"})
+	tx.DeleteSubscription(sub)
+	assert.NoError(t, transactor.validate(ctx, tx.ops))
+	txn := &Transaction{ID: "txn-1", Ops: tx.ops}
+	assert.NoError(t, journal.Create(ctx, txn))
+	changed := *sub
+	changed.AppID = "app-2"
+	assert.NoError(t, stores.Subscriptions.Update(ctx, &changed))
+
+	// Verify the operations already applied are reverted
+	err := transactor.apply(ctx, txn)
+	assert.True(t, errors.IsConflict(err))
+	reverted, err := stores.Tasks.Get(ctx, task1.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, taskapi.Phase_OPEN, reverted.Lifecycle.Phase)
+	_, err = stores.Tasks.Get(ctx, "sub-1:e2t-2")
+	assert.True(t, errors.IsNotFound(err))
+	current, err := stores.Subscriptions.Get(ctx, sub.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, subapi.AppID("app-2"), current.AppID)
+
+	txns, err := journal.List(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, txns)
+}
@@ -0,0 +1,263 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package watch
+
+import (
+	"context"
+	"sync"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+)
+
+var log = logging.GetLogger("store", "watch")
+
+// Policy is the policy applied to a subscriber whose buffer is full
+type Policy string
+
+const (
+	// PolicyBlock blocks delivery to all subscribers until the slow subscriber's buffer has room
+	PolicyBlock Policy = "block"
+	// PolicyDrop drops events for the slow subscriber, delivering a gap marker once its buffer has room
+	PolicyDrop Policy = "drop"
+	// PolicyDisconnect disconnects the slow subscriber
+	PolicyDisconnect Policy = "disconnect"
+)
+
+const defaultBufferSize = 1000
+
+// ParsePolicy parses a slow subscriber policy
+func ParsePolicy(policy string) (Policy, error) {
+	switch Policy(policy) {
+	case PolicyBlock, PolicyDrop, PolicyDisconnect:
+		return Policy(policy), nil
+	}
+	return "", errors.NewInvalid("unknown watch policy %s", policy)
+}
+
+// Event is an event delivered to a subscriber
+type Event struct {
+	// Value is the store event
+	Value interface{}
+	// Gap indicates events were dropped for the subscriber before this marker; Value is nil
+	Gap bool
+}
+
+// OpenFunc opens the upstream store watch, streaming store events to the given channel
+// The channel must be closed when the watch ends.
+type OpenFunc func(ctx context.Context, ch chan<- interface{}) error
+
+// NewHub returns a new hub fanning out the events of a single upstream store watch to its subscribers
+// Each subscriber is buffered up to bufferSize events, beyond which the given policy is applied.
+// If no buffer size or policy is given, 1000 events are buffered and slow subscribers are disconnected.
+func NewHub(name string, open OpenFunc, bufferSize int, policy Policy) *Hub {
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+	if policy == "" {
+		policy = PolicyDisconnect
+	}
+	return &Hub{
+		name:        name,
+		open:        open,
+		bufferSize:  bufferSize,
+		policy:      policy,
+		subscribers: make(map[*Subscriber]bool),
+	}
+}
+
+// Hub fans out the events of a single upstream store watch to any number of subscribers
+// The upstream watch is opened by the first subscriber and closed once the last subscriber leaves
+// or is disconnected.
+type Hub struct {
+	name        string
+	open        OpenFunc
+	bufferSize  int
+	policy      Policy
+	subscribers map[*Subscriber]bool
+	upstream    *upstream
+	mu          sync.Mutex
+}
+
+// upstream is an open upstream store watch
+type upstream struct {
+	cancel context.CancelFunc
+}
+
+// Subscribe subscribes to the hub's events until the given context is canceled
+func (h *Hub) Subscribe(ctx context.Context) (*Subscriber, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.upstream == nil {
+		upstreamCtx, cancel := context.WithCancel(context.Background())
+		ch := make(chan interface{}, h.bufferSize)
+		if err := h.open(upstreamCtx, ch); err != nil {
+			cancel()
+			return nil, err
+		}
+		h.upstream = &upstream{cancel: cancel}
+		go h.dispatch(h.upstream, ch)
+	}
+
+	sub := &Subscriber{
+		ch:   make(chan Event, h.bufferSize),
+		done: make(chan struct{}),
+	}
+	h.subscribers[sub] = true
+	go func() {
+		select {
+		case <-ctx.Done():
+			h.unsubscribe(sub, nil)
+		case <-sub.done:
+		}
+	}()
+	return sub, nil
+}
+
+// dispatch delivers upstream events to the subscribers until the upstream watch ends
+// A closed upstream watch is drained until it ends, without delivering its events.
+func (h *Hub) dispatch(u *upstream, ch <-chan interface{}) {
+	for value := range ch {
+		h.mu.Lock()
+		if h.upstream != u {
+			h.mu.Unlock()
+			continue
+		}
+		subs := make([]*Subscriber, 0, len(h.subscribers))
+		for sub := range h.subscribers {
+			subs = append(subs, sub)
+		}
+		h.mu.Unlock()
+
+		for _, sub := range subs {
+			if !sub.send(Event{Value: value}, h.policy) {
+				log.Warnf("Disconnecting slow %s watch subscriber", h.name)
+				h.unsubscribe(sub, errors.NewUnavailable("%s watch subscriber fell behind", h.name))
+			}
+		}
+	}
+
+	h.mu.Lock()
+	if h.upstream != u {
+		h.mu.Unlock()
+		return
+	}
+	log.Warnf("Upstream %s watch ended", h.name)
+	h.upstream = nil
+	subs := h.subscribers
+	h.subscribers = make(map[*Subscriber]bool)
+	h.mu.Unlock()
+	for sub := range subs {
+		sub.close(errors.NewUnavailable("%s watch ended", h.name))
+	}
+}
+
+// unsubscribe removes the subscriber from the hub and closes it with the given error
+// The upstream watch is closed once no subscribers remain.
+func (h *Hub) unsubscribe(sub *Subscriber, err error) {
+	h.mu.Lock()
+	delete(h.subscribers, sub)
+	if len(h.subscribers) == 0 && h.upstream != nil {
+		h.upstream.cancel()
+		h.upstream = nil
+	}
+	h.mu.Unlock()
+	sub.close(err)
+}
+
+// Close closes the hub's upstream watch and its subscribers
+func (h *Hub) Close() {
+	h.mu.Lock()
+	if h.upstream != nil {
+		h.upstream.cancel()
+		h.upstream = nil
+	}
+	subs := h.subscribers
+	h.subscribers = make(map[*Subscriber]bool)
+	h.mu.Unlock()
+	for sub := range subs {
+		sub.close(nil)
+	}
+}
+
+// Subscriber is a subscriber to a Hub's events
+type Subscriber struct {
+	ch     chan Event
+	done   chan struct{}
+	once   sync.Once
+	err    error
+	gap    bool
+	closed bool
+	mu     sync.Mutex
+}
+
+// Events returns the subscriber's event channel
+// The channel is closed once the subscription's context is canceled, the subscriber is
+// disconnected, or the upstream watch ends.
+func (s *Subscriber) Events() <-chan Event {
+	return s.ch
+}
+
+// Err returns the error with which the subscriber was closed, if any
+func (s *Subscriber) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// send delivers an event to the subscriber according to the policy
+// It returns false if the subscriber must be disconnected.
+func (s *Subscriber) send(event Event, policy Policy) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return true
+	}
+
+	switch policy {
+	case PolicyBlock:
+		select {
+		case s.ch <- event:
+		case <-s.done:
+		}
+		return true
+	case PolicyDrop:
+		// Events remain dropped until the gap marker can be delivered
+		if s.gap {
+			select {
+			case s.ch <- Event{Gap: true}:
+				s.gap = false
+			default:
+				return true
+			}
+		}
+		select {
+		case s.ch <- event:
+		default:
+			s.gap = true
+		}
+		return true
+	default:
+		select {
+		case s.ch <- event:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// close closes the subscriber's event channel
+func (s *Subscriber) close(err error) {
+	s.once.Do(func() {
+		// Close done first to release a sender blocked on a full buffer
+		close(s.done)
+		s.mu.Lock()
+		s.closed = true
+		s.err = err
+		close(s.ch)
+		s.mu.Unlock()
+	})
+}
@@ -0,0 +1,215 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package watch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// testUpstream is an upstream watch controlled by the test
+type testUpstream struct {
+	opens int
+	ctx   context.Context
+	ch    chan<- interface{}
+}
+
+func (u *testUpstream) open(ctx context.Context, ch chan<- interface{}) error {
+	u.opens++
+	u.ctx = ctx
+	u.ch = ch
+	return nil
+}
+
+func nextEvent(t *testing.T, sub *Subscriber) (Event, bool) {
+	select {
+	case event, ok := <-sub.Events():
+		return event, ok
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+		return Event{}, false
+	}
+}
+
+func TestHub(t *testing.T) {
+	upstream := &testUpstream{}
+	hub := NewHub("test", upstream.open, 10, PolicyDisconnect)
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	sub1, err := hub.Subscribe(ctx1)
+	assert.NoError(t, err)
+	sub2, err := hub.Subscribe(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, upstream.opens)
+
+	// Verify events are fanned out to all subscribers
+	upstream.ch <- 1
+	event, ok := nextEvent(t, sub1)
+	assert.True(t, ok)
+	assert.Equal(t, 1, event.Value)
+	event, ok = nextEvent(t, sub2)
+	assert.True(t, ok)
+	assert.Equal(t, 1, event.Value)
+
+	// Verify canceling a subscription closes it without an error
+	cancel1()
+	_, ok = nextEvent(t, sub1)
+	assert.False(t, ok)
+	assert.NoError(t, sub1.Err())
+
+	// Verify the subscribers are closed when the upstream watch ends
+	close(upstream.ch)
+	_, ok = nextEvent(t, sub2)
+	assert.False(t, ok)
+	assert.True(t, errors.IsUnavailable(sub2.Err()))
+
+	// Verify the upstream watch is re-opened by the next subscriber
+	sub3, err := hub.Subscribe(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, upstream.opens)
+	upstream.ch <- 2
+	event, ok = nextEvent(t, sub3)
+	assert.True(t, ok)
+	assert.Equal(t, 2, event.Value)
+}
+
+func TestHubTeardown(t *testing.T) {
+	upstream := &testUpstream{}
+	hub := NewHub("test", upstream.open, 10, PolicyDisconnect)
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	_, err := hub.Subscribe(ctx1)
+	assert.NoError(t, err)
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	sub2, err := hub.Subscribe(ctx2)
+	assert.NoError(t, err)
+	upstreamCtx, upstreamCh := upstream.ctx, upstream.ch
+
+	// Verify the upstream watch remains open while any subscriber remains
+	cancel1()
+	upstreamCh <- 1
+	event, ok := nextEvent(t, sub2)
+	assert.True(t, ok)
+	assert.Equal(t, 1, event.Value)
+	assert.NoError(t, upstreamCtx.Err())
+
+	// Verify the upstream watch is closed once the last subscriber leaves
+	cancel2()
+	select {
+	case <-upstreamCtx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream watch not closed")
+	}
+
+	// Verify a new subscriber opens a new upstream watch, unaffected by the end of the old one
+	sub3, err := hub.Subscribe(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, upstream.opens)
+	upstreamCh <- 2
+	close(upstreamCh)
+	upstream.ch <- 3
+	event, ok = nextEvent(t, sub3)
+	assert.True(t, ok)
+	assert.Equal(t, 3, event.Value)
+}
+
+func TestDropPolicy(t *testing.T) {
+	upstream := &testUpstream{}
+	hub := NewHub("test", upstream.open, 2, PolicyDrop)
+
+	slow, err := hub.Subscribe(context.Background())
+	assert.NoError(t, err)
+	fast, err := hub.Subscribe(context.Background())
+	assert.NoError(t, err)
+
+	// Overflow the slow subscriber's buffer while the fast subscriber keeps up
+	for i := 1; i <= 5; i++ {
+		upstream.ch <- i
+		event, _ := nextEvent(t, fast)
+		assert.Equal(t, i, event.Value)
+	}
+	upstream.ch <- 6
+	event, _ := nextEvent(t, fast)
+	assert.Equal(t, 6, event.Value)
+
+	// Verify the buffered events are delivered and the rest are dropped
+	event, _ = nextEvent(t, slow)
+	assert.Equal(t, 1, event.Value)
+	event, _ = nextEvent(t, slow)
+	assert.Equal(t, 2, event.Value)
+
+	// Verify a gap marker precedes the next event
+	upstream.ch <- 7
+	event, _ = nextEvent(t, slow)
+	assert.True(t, event.Gap)
+	event, _ = nextEvent(t, slow)
+	assert.False(t, event.Gap)
+	assert.Equal(t, 7, event.Value)
+	assert.NoError(t, slow.Err())
+}
+
+func TestDisconnectPolicy(t *testing.T) {
+	upstream := &testUpstream{}
+	hub := NewHub("test", upstream.open, 1, PolicyDisconnect)
+
+	slow, err := hub.Subscribe(context.Background())
+	assert.NoError(t, err)
+	fast, err := hub.Subscribe(context.Background())
+	assert.NoError(t, err)
+
+	for i := 1; i <= 2; i++ {
+		upstream.ch <- i
+		event, _ := nextEvent(t, fast)
+		assert.Equal(t, i, event.Value)
+	}
+
+	// Verify the slow subscriber is disconnected once its buffer overflows
+	event, ok := nextEvent(t, slow)
+	assert.True(t, ok)
+	assert.Equal(t, 1, event.Value)
+	_, ok = nextEvent(t, slow)
+	assert.False(t, ok)
+	assert.True(t, errors.IsUnavailable(slow.Err()))
+
+	// Verify the fast subscriber is unaffected
+	upstream.ch <- 3
+	event, ok = nextEvent(t, fast)
+	assert.True(t, ok)
+	assert.Equal(t, 3, event.Value)
+}
+
+func TestBlockPolicy(t *testing.T) {
+	upstream := &testUpstream{}
+	hub := NewHub("test", upstream.open, 1, PolicyBlock)
+
+	sub, err := hub.Subscribe(context.Background())
+	assert.NoError(t, err)
+
+	go func() {
+		for i := 1; i <= 5; i++ {
+			upstream.ch <- i
+		}
+	}()
+
+	// Verify no events are lost however far the subscriber falls behind
+	for i := 1; i <= 5; i++ {
+		time.Sleep(10 * time.Millisecond)
+		event, ok := nextEvent(t, sub)
+		assert.True(t, ok)
+		assert.Equal(t, i, event.Value)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy("drop")
+	assert.NoError(t, err)
+	assert.Equal(t, PolicyDrop, policy)
+	_, err = ParsePolicy("ignore")
+	assert.True(t, errors.IsInvalid(err))
+}